@@ -0,0 +1,17 @@
+package main
+
+import "time"
+
+// runDaily runs job once at start-up and then every 24 hours until the process exits, logging
+// its errors. Jobs have to be safe to run again after a restart.
+func (app *application) runDaily(name string, job func() error) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := job(); err != nil {
+			app.shared_deps.logger.Error().Err(err).Str("job", name).Msg("Background job failed")
+		}
+		<-ticker.C
+	}
+}
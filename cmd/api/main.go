@@ -27,26 +27,39 @@ type config struct {
 }
 
 type dependencies struct {
-	logger                    zerolog.Logger
-	JSONutils                 jsonutils.JSONutils
-	AuthService               services.AuthService
-	RetailerProductsService   services.RetailerProductsService
-	RetailersService          services.RetailersService
-	WholesalersService        services.WholesalersService
-	WholesalerProductService  services.WholesalerProductService
-	WholesalerProductsService services.WholesalerProductsService
-	ProductService            services.ProductService
-	CartService               services.CartService
-	RetailerCartService       services.RetailerCartService
-	UserAddressesService      services.UserAddressesService
-	RetailerAddressesService  services.RetailerAddressesService
-	ProductReviewsService     services.ProductReviewsService
-	ProductQueriesService     services.ProductQueriesService
-	UploadService             *services.UploadService
-	UsersRepo                 repositories.IUsersRepo
-	OrdersRepo                repositories.IOrdersRepo
-	StripeService             *services.StripeService
-	OrdersService             services.OrdersService
+	logger                     zerolog.Logger
+	JSONutils                  jsonutils.JSONutils
+	AuthService                services.AuthService
+	RetailerProductsService    services.RetailerProductsService
+	RetailersService           services.RetailersService
+	WholesalersService         services.WholesalersService
+	WholesalerProductService   services.WholesalerProductService
+	WholesalerProductsService  services.WholesalerProductsService
+	ProductService             services.ProductService
+	CartService                services.CartService
+	RetailerCartService        services.RetailerCartService
+	UserAddressesService       services.UserAddressesService
+	RetailerAddressesService   services.RetailerAddressesService
+	ProductReviewsService      services.ProductReviewsService
+	ProductQueriesService      services.ProductQueriesService
+	CategoriesService          services.CategoriesService
+	RetailerVariantsService    services.ProductVariantsService
+	WholesalerVariantsService  services.ProductVariantsService
+	RetailerCatalogService     services.ProductCatalogService
+	WholesalerCatalogService   services.ProductCatalogService
+	PriceListsService          services.PriceListsService
+	CatalogAccessService       services.CatalogAccessService
+	QuotesService              services.QuotesService
+	InvoicesService            services.InvoicesService
+	StockAlertsService         services.StockAlertsService
+	RetailerInventoryService   services.InventoryService
+	WholesalerInventoryService services.InventoryService
+	UploadService              *services.UploadService
+	UsersRepo                  repositories.IUsersRepo
+	OrdersRepo                 repositories.IOrdersRepo
+	PaymentGateway             services.PaymentGateway
+	FakePaymentGateway         *services.FakePaymentGateway // nil unless PAYMENT_GATEWAY=fake
+	OrdersService              services.OrdersService
 }
 
 type application struct {
@@ -79,34 +92,66 @@ func main() {
 
 	defer db.Close()
 
+	// PAYMENT_GATEWAY=fake runs checkout against an in-process gateway for offline development
+	var paymentGateway services.PaymentGateway
+	var fakePaymentGateway *services.FakePaymentGateway
+	if os.Getenv("PAYMENT_GATEWAY") == "fake" {
+		fakePaymentGateway = services.NewFakePaymentGateway(fmt.Sprintf("http://localhost:%d", cfg.port))
+		paymentGateway = fakePaymentGateway
+		logger.Warn().Msg("Using the fake payment gateway, no real payments will be taken")
+	} else {
+		paymentGateway = services.NewStripeService(os.Getenv("STRIPE_SECRET_KEY"), os.Getenv("STRIPE_WEBHOOK_SECRET"))
+	}
+
 	app := &application{
 		config: cfg,
 		shared_deps: dependencies{
-			logger:                    logger,
-			JSONutils:                 jsonutils.NewJSONutils(),
-			AuthService:               *services.NewAuthService(repositories.NewUsersRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db)),
-			RetailerProductsService:   *services.NewRetailerProductsService(repositories.NewRetailerProductsRepo(db)),
-			RetailersService:          *services.NewRetailersService(repositories.NewRetailersRepo(db)),
-			WholesalersService:        *services.NewWholesalersService(repositories.NewWholesalersRepo(db)),
-			WholesalerProductService:  *services.NewWholesalerProductService(repositories.NewWholesalerProductRepository(db)),
-			WholesalerProductsService: *services.NewWholesalerProductsService(repositories.NewWholesalerProductRepository(db)),
-			ProductService:            *services.NewProductService(repositories.NewProductRepository(db)),
-			CartService:               *services.NewCartService(repositories.NewCartRepo(db), repositories.NewUsersRepo(db)),
-			RetailerCartService:       *services.NewRetailerCartService(repositories.NewRetailerCartRepo(db), repositories.NewRetailersRepo(db)),
-			UserAddressesService:      *services.NewUserAddressesService(repositories.NewUserAddressesRepo(db), repositories.NewUsersRepo(db)),
-			RetailerAddressesService:  *services.NewRetailerAddressesService(repositories.NewRetailerAddressesRepo(db), repositories.NewRetailersRepo(db)),
-			ProductReviewsService:     *services.NewProductReviewsService(repositories.NewProductReviewsRepo(db)),
-			ProductQueriesService:     *services.NewProductQueriesService(repositories.NewProductQueriesRepo(db), repositories.NewUsersRepo(db), services.NewEmailService(os.Getenv("MAILTRAP_API_TOKEN"))),
-			UploadService:             services.NewUploadService(),
-			UsersRepo:                 repositories.NewUsersRepo(db),
-			OrdersRepo:                repositories.NewOrdersRepo(db),
-			StripeService:             services.NewStripeService(os.Getenv("STRIPE_SECRET_KEY")),
-			OrdersService:             *services.NewOrdersService(repositories.NewOrdersRepo(db), *services.NewCartService(repositories.NewCartRepo(db), repositories.NewUsersRepo(db)), *services.NewRetailerCartService(repositories.NewRetailerCartRepo(db), repositories.NewRetailersRepo(db)), services.NewStripeService(os.Getenv("STRIPE_SECRET_KEY")), services.NewEmailService(os.Getenv("MAILTRAP_API_TOKEN")), repositories.NewUsersRepo(db), repositories.NewRetailersRepo(db)),
+			logger:                     logger,
+			JSONutils:                  jsonutils.NewJSONutils(),
+			AuthService:                *services.NewAuthService(repositories.NewUsersRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db)),
+			RetailerProductsService:    *services.NewRetailerProductsService(repositories.NewRetailerProductsRepo(db)),
+			RetailersService:           *services.NewRetailersService(repositories.NewRetailersRepo(db)),
+			WholesalersService:         *services.NewWholesalersService(repositories.NewWholesalersRepo(db)),
+			WholesalerProductService:   *services.NewWholesalerProductService(repositories.NewWholesalerProductRepository(db)),
+			WholesalerProductsService:  *services.NewWholesalerProductsService(repositories.NewWholesalerProductRepository(db)),
+			ProductService:             *services.NewProductService(repositories.NewProductRepository(db), repositories.NewWholesalerProductRepository(db)),
+			CartService:                *services.NewCartService(repositories.NewCartRepo(db), repositories.NewUsersRepo(db)),
+			RetailerCartService:        *services.NewRetailerCartService(repositories.NewRetailerCartRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db), repositories.NewPriceListsRepo(db), repositories.NewCatalogAccessRepo(db)),
+			UserAddressesService:       *services.NewUserAddressesService(repositories.NewUserAddressesRepo(db), repositories.NewUsersRepo(db)),
+			RetailerAddressesService:   *services.NewRetailerAddressesService(repositories.NewRetailerAddressesRepo(db), repositories.NewRetailersRepo(db)),
+			ProductReviewsService:      *services.NewProductReviewsService(repositories.NewProductReviewsRepo(db), services.NewUploadService()),
+			ProductQueriesService:      *services.NewProductQueriesService(repositories.NewProductQueriesRepo(db), repositories.NewUsersRepo(db), services.NewEmailService(os.Getenv("MAILTRAP_API_TOKEN"))),
+			CategoriesService:          *services.NewCategoriesService(repositories.NewCategoriesRepo(db)),
+			RetailerVariantsService:    *services.NewProductVariantsService(repositories.NewRetailerVariantsRepo(db)),
+			WholesalerVariantsService:  *services.NewProductVariantsService(repositories.NewWholesalerVariantsRepo(db)),
+			RetailerCatalogService:     *services.NewProductCatalogService(repositories.NewRetailerCatalogRepo(db)),
+			WholesalerCatalogService:   *services.NewProductCatalogService(repositories.NewWholesalerCatalogRepo(db)),
+			PriceListsService:          *services.NewPriceListsService(repositories.NewPriceListsRepo(db), repositories.NewRetailersRepo(db)),
+			CatalogAccessService:       *services.NewCatalogAccessService(repositories.NewCatalogAccessRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db)),
+			QuotesService:              *services.NewQuotesService(repositories.NewQuotesRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db)),
+			InvoicesService:            *services.NewInvoicesService(repositories.NewInvoicesRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db)),
+			StockAlertsService:         *services.NewStockAlertsService(repositories.NewStockAlertsRepo(db), repositories.NewWholesalerProductRepository(db), *services.NewRetailerCartService(repositories.NewRetailerCartRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db), repositories.NewPriceListsRepo(db), repositories.NewCatalogAccessRepo(db)), services.NewEmailService(os.Getenv("MAILTRAP_API_TOKEN"))),
+			RetailerInventoryService:   *services.NewInventoryService(repositories.NewRetailerInventoryRepo(db)),
+			WholesalerInventoryService: *services.NewInventoryService(repositories.NewWholesalerInventoryRepo(db)),
+			UploadService:              services.NewUploadService(),
+			UsersRepo:                  repositories.NewUsersRepo(db),
+			OrdersRepo:                 repositories.NewOrdersRepo(db),
+			PaymentGateway:             paymentGateway,
+			FakePaymentGateway:         fakePaymentGateway,
+			OrdersService:              *services.NewOrdersService(repositories.NewOrdersRepo(db), *services.NewCartService(repositories.NewCartRepo(db), repositories.NewUsersRepo(db)), *services.NewRetailerCartService(repositories.NewRetailerCartRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db), repositories.NewPriceListsRepo(db), repositories.NewCatalogAccessRepo(db)), paymentGateway, services.NewEmailService(os.Getenv("MAILTRAP_API_TOKEN")), repositories.NewUsersRepo(db), repositories.NewRetailersRepo(db), repositories.NewWholesalersRepo(db), repositories.NewStripeEventsRepo(db), repositories.NewRefundsRepo(db), repositories.NewInvoicesRepo(db)),
 		},
 	}
 
 	auth.NewAuth(app.shared_deps.logger, app.config.Env)
 
+	go app.runDaily("low stock alerts", func() error {
+		sent, err := app.shared_deps.StockAlertsService.SendLowStockAlerts()
+		if sent > 0 {
+			logger.Info().Int("retailers", sent).Msg("Sent low stock alerts")
+		}
+		return err
+	})
+
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.port),
 		Handler:      app.newRouter(),
@@ -3,11 +3,16 @@ package main
 import (
 	"Obsonarium-backend/internal/handlers/auth"
 	"Obsonarium-backend/internal/handlers/cart"
+	"Obsonarium-backend/internal/handlers/catalog_handler"
+	"Obsonarium-backend/internal/handlers/categories"
+	"Obsonarium-backend/internal/handlers/dev_payments"
 	"Obsonarium-backend/internal/handlers/healthcheck"
+	"Obsonarium-backend/internal/handlers/invoices"
 	"Obsonarium-backend/internal/handlers/orders"
 	"Obsonarium-backend/internal/handlers/product_handler"
 	"Obsonarium-backend/internal/handlers/product_queries"
 	"Obsonarium-backend/internal/handlers/product_reviews"
+	"Obsonarium-backend/internal/handlers/quotes"
 	"Obsonarium-backend/internal/handlers/retailer_addresses"
 	"Obsonarium-backend/internal/handlers/retailer_cart"
 	"Obsonarium-backend/internal/handlers/retailer_products"
@@ -46,9 +51,17 @@ func (app *application) newRouter() *chi.Mux {
 	r.Get("/api/auth/{provider}", auth.AuthProvider)
 	r.Get("/api/logout/{provider}", auth.AuthLogout)
 	r.Get("/api/shop", retailer_products.GetProducts(&app.shared_deps.RetailerProductsService, app.shared_deps.JSONutils.Writer))
-	r.Get("/api/shop/{id}", retailer_products.GetProduct(&app.shared_deps.RetailerProductsService, app.shared_deps.JSONutils.Writer))
-	r.Get("/api/wholesale", wholesaler_products.GetProducts(&app.shared_deps.WholesalerProductsService, app.shared_deps.JSONutils.Writer))
-	r.Get("/api/wholesale/{id}", wholesaler_products.GetProduct(&app.shared_deps.WholesalerProductsService, app.shared_deps.JSONutils.Writer))
+	r.Get("/api/shop/{id}", retailer_products.GetProduct(&app.shared_deps.RetailerProductsService, &app.shared_deps.RetailerVariantsService, app.shared_deps.JSONutils.Writer))
+	// Wholesale browsing is public; signed-in retailers see their negotiated prices
+	identifyRetailer := auth.IdentifyRetailer(&app.shared_deps.AuthService, app.shared_deps.logger)
+	r.With(identifyRetailer).Get("/api/wholesale", wholesaler_products.GetProducts(&app.shared_deps.WholesalerProductsService, &app.shared_deps.PriceListsService, &app.shared_deps.CatalogAccessService, app.shared_deps.JSONutils.Writer))
+	r.With(identifyRetailer).Get("/api/wholesale/{id}", wholesaler_products.GetProduct(&app.shared_deps.WholesalerProductsService, &app.shared_deps.WholesalerVariantsService, &app.shared_deps.PriceListsService, &app.shared_deps.CatalogAccessService, app.shared_deps.JSONutils.Writer))
+
+	// Category routes, public like the catalogs they browse
+	r.Get("/api/shop/categories", categories.GetShopCategories(&app.shared_deps.CategoriesService, app.shared_deps.JSONutils.Writer))
+	r.Get("/api/shop/categories/{slug}", categories.BrowseShopCategory(&app.shared_deps.CategoriesService, &app.shared_deps.RetailerProductsService, app.shared_deps.JSONutils.Writer))
+	r.With(identifyRetailer).Get("/api/wholesale/categories", categories.GetWholesaleCategories(&app.shared_deps.CategoriesService, &app.shared_deps.CatalogAccessService, app.shared_deps.JSONutils.Writer))
+	r.With(identifyRetailer).Get("/api/wholesale/categories/{slug}", categories.BrowseWholesaleCategory(&app.shared_deps.CategoriesService, &app.shared_deps.WholesalerProductsService, &app.shared_deps.PriceListsService, &app.shared_deps.CatalogAccessService, app.shared_deps.JSONutils.Writer))
 
 	// Product reviews routes
 	r.Route("/api/products/{product_id}/reviews", func(r chi.Router) {
@@ -56,6 +69,27 @@ func (app *application) newRouter() *chi.Mux {
 		r.Get("/", product_reviews.GetReviews(&app.shared_deps.ProductReviewsService, app.shared_deps.JSONutils.Writer))
 		// Protected POST endpoint (requires consumer auth)
 		r.With(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer)).Post("/", product_reviews.CreateReview(&app.shared_deps.ProductReviewsService, app.shared_deps.UsersRepo, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		// Authors can edit or remove their own review
+		r.With(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer)).Put("/{id}", product_reviews.UpdateReview(&app.shared_deps.ProductReviewsService, app.shared_deps.UsersRepo, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.With(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer)).Delete("/{id}", product_reviews.DeleteReview(&app.shared_deps.ProductReviewsService, app.shared_deps.UsersRepo, app.shared_deps.JSONutils.Writer))
+		r.With(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer)).Post("/{id}/report", product_reviews.ReportReview(&app.shared_deps.ProductReviewsService, app.shared_deps.UsersRepo, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		// One helpful or unhelpful vote per customer on each review
+		r.With(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer)).Put("/{id}/vote", product_reviews.VoteReview(&app.shared_deps.ProductReviewsService, app.shared_deps.UsersRepo, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.With(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer)).Delete("/{id}/vote", product_reviews.RemoveVote(&app.shared_deps.ProductReviewsService, app.shared_deps.UsersRepo, app.shared_deps.JSONutils.Writer))
+		// Authors can attach a few photos to their review
+		r.With(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer)).Post("/{id}/images", product_reviews.UploadReviewImage(&app.shared_deps.ProductReviewsService, app.shared_deps.UsersRepo, app.shared_deps.JSONutils.Writer))
+		r.With(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer)).Delete("/{id}/images/{image_id}", product_reviews.DeleteReviewImage(&app.shared_deps.ProductReviewsService, app.shared_deps.UsersRepo, app.shared_deps.JSONutils.Writer))
+	})
+
+	// Reported reviews of the retailer's products and its replies to reviews
+	r.Route("/api/retailer/reviews", func(r chi.Router) {
+		r.Use(auth.RequireRetailer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/reported", product_reviews.GetModerationQueue(&app.shared_deps.ProductReviewsService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
+		r.Post("/{id}/hide", product_reviews.HideReview(&app.shared_deps.ProductReviewsService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
+		r.Post("/{id}/restore", product_reviews.RestoreReview(&app.shared_deps.ProductReviewsService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
+		r.Delete("/{id}", product_reviews.DeleteReportedReview(&app.shared_deps.ProductReviewsService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
+		r.Put("/{id}/reply", product_reviews.SetSellerReply(&app.shared_deps.ProductReviewsService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Delete("/{id}/reply", product_reviews.DeleteSellerReply(&app.shared_deps.ProductReviewsService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
 	})
 
 	// Product queries routes
@@ -86,11 +120,26 @@ func (app *application) newRouter() *chi.Mux {
 	// Retailer product management routes
 	r.Route("/api/retailer/products", func(r chi.Router) {
 		r.Use(auth.RequireRetailer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		retailerCatalog := product_handler.Catalog(&app.shared_deps.ProductService, &app.shared_deps.RetailersService)
 		r.Get("/", product_handler.ListRetailerProducts(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
 		r.Post("/", product_handler.CreateProduct(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Post("/import", catalog_handler.ImportProducts(&app.shared_deps.RetailerCatalogService, retailerCatalog, app.shared_deps.JSONutils.Writer))
+		r.Get("/export", catalog_handler.ExportProducts(&app.shared_deps.RetailerCatalogService, retailerCatalog, app.shared_deps.JSONutils.Writer))
+		r.Get("/reorder-suggestions", product_handler.GetReorderSuggestions(&app.shared_deps.StockAlertsService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
+		r.Post("/from-wholesale", product_handler.ImportWholesaleProduct(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
 		r.Get("/{id}", product_handler.GetRetailerProduct(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
 		r.Put("/{id}", product_handler.UpdateProduct(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
 		r.Delete("/{id}", product_handler.DeleteProduct(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
+		r.Put("/{id}/source", product_handler.LinkSourceProduct(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Delete("/{id}/source", product_handler.UnlinkSourceProduct(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer))
+		r.Put("/{id}/reorder-threshold", product_handler.SetReorderThreshold(&app.shared_deps.ProductService, &app.shared_deps.RetailersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Get("/{id}/stock-movements", catalog_handler.GetStockLedger(&app.shared_deps.RetailerInventoryService, retailerCatalog, app.shared_deps.JSONutils.Writer))
+		r.Post("/{id}/stock-movements", catalog_handler.AdjustStock(&app.shared_deps.RetailerInventoryService, retailerCatalog, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Get("/{id}/variants", catalog_handler.ListVariants(&app.shared_deps.RetailerVariantsService, retailerCatalog, app.shared_deps.JSONutils.Writer))
+		r.Put("/{id}/options", catalog_handler.SetOptions(&app.shared_deps.RetailerVariantsService, retailerCatalog, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Post("/{id}/variants", catalog_handler.CreateVariant(&app.shared_deps.RetailerVariantsService, retailerCatalog, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Put("/{id}/variants/{variant_id}", catalog_handler.UpdateVariant(&app.shared_deps.RetailerVariantsService, retailerCatalog, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Delete("/{id}/variants/{variant_id}", catalog_handler.DeleteVariant(&app.shared_deps.RetailerVariantsService, retailerCatalog, app.shared_deps.JSONutils.Writer))
 	})
 
 	// Cart routes with consumer authentication middleware
@@ -158,11 +207,56 @@ func (app *application) newRouter() *chi.Mux {
 	// Wholesaler product management routes
 	r.Route("/api/wholesaler/products", func(r chi.Router) {
 		r.Use(auth.RequireWholesaler(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		wholesalerCatalog := wholesaler_product_handler.Catalog(&app.shared_deps.WholesalerProductService, &app.shared_deps.WholesalersService)
 		r.Get("/", wholesaler_product_handler.ListWholesalerProducts(&app.shared_deps.WholesalerProductService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
 		r.Post("/", wholesaler_product_handler.CreateProduct(&app.shared_deps.WholesalerProductService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Post("/import", catalog_handler.ImportProducts(&app.shared_deps.WholesalerCatalogService, wholesalerCatalog, app.shared_deps.JSONutils.Writer))
+		r.Get("/export", catalog_handler.ExportProducts(&app.shared_deps.WholesalerCatalogService, wholesalerCatalog, app.shared_deps.JSONutils.Writer))
 		r.Get("/{id}", wholesaler_product_handler.GetWholesalerProduct(&app.shared_deps.WholesalerProductService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
 		r.Put("/{id}", wholesaler_product_handler.UpdateProduct(&app.shared_deps.WholesalerProductService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
 		r.Delete("/{id}", wholesaler_product_handler.DeleteProduct(&app.shared_deps.WholesalerProductService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+		r.Put("/{id}/pricing", wholesaler_product_handler.SetPricing(&app.shared_deps.WholesalerProductService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Get("/{id}/stock-movements", catalog_handler.GetStockLedger(&app.shared_deps.WholesalerInventoryService, wholesalerCatalog, app.shared_deps.JSONutils.Writer))
+		r.Post("/{id}/stock-movements", catalog_handler.AdjustStock(&app.shared_deps.WholesalerInventoryService, wholesalerCatalog, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Get("/{id}/variants", catalog_handler.ListVariants(&app.shared_deps.WholesalerVariantsService, wholesalerCatalog, app.shared_deps.JSONutils.Writer))
+		r.Put("/{id}/options", catalog_handler.SetOptions(&app.shared_deps.WholesalerVariantsService, wholesalerCatalog, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Post("/{id}/variants", catalog_handler.CreateVariant(&app.shared_deps.WholesalerVariantsService, wholesalerCatalog, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Put("/{id}/variants/{variant_id}", catalog_handler.UpdateVariant(&app.shared_deps.WholesalerVariantsService, wholesalerCatalog, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Delete("/{id}/variants/{variant_id}", catalog_handler.DeleteVariant(&app.shared_deps.WholesalerVariantsService, wholesalerCatalog, app.shared_deps.JSONutils.Writer))
+	})
+
+	// Negotiated price lists and the retailer groups they are assigned to
+	r.Route("/api/wholesaler/price-lists", func(r chi.Router) {
+		r.Use(auth.RequireWholesaler(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", wholesaler_product_handler.ListPriceLists(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+		r.Post("/", wholesaler_product_handler.CreatePriceList(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Get("/{id}", wholesaler_product_handler.GetPriceList(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+		r.Put("/{id}", wholesaler_product_handler.UpdatePriceList(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Delete("/{id}", wholesaler_product_handler.DeletePriceList(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+	})
+
+	r.Route("/api/wholesaler/retailer-groups", func(r chi.Router) {
+		r.Use(auth.RequireWholesaler(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", wholesaler_product_handler.ListRetailerGroups(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+		r.Post("/", wholesaler_product_handler.CreateRetailerGroup(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Get("/{id}", wholesaler_product_handler.GetRetailerGroup(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+		r.Put("/{id}", wholesaler_product_handler.UpdateRetailerGroup(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+		r.Delete("/{id}", wholesaler_product_handler.DeleteRetailerGroup(&app.shared_deps.PriceListsService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+	})
+
+	// Access requests for private catalogs, from both sides
+	r.Route("/api/retailer/catalog-access", func(r chi.Router) {
+		r.Use(auth.RequireRetailer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", retailers.ListCatalogAccess(&app.shared_deps.CatalogAccessService, app.shared_deps.JSONutils.Writer))
+		r.Post("/{wholesaler_id}", retailers.RequestCatalogAccess(&app.shared_deps.CatalogAccessService, app.shared_deps.JSONutils.Writer, app.shared_deps.JSONutils.Reader))
+	})
+
+	r.Route("/api/wholesaler/catalog-access", func(r chi.Router) {
+		r.Use(auth.RequireWholesaler(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", wholesaler_product_handler.ListCatalogAccess(&app.shared_deps.CatalogAccessService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+		r.Post("/{retailer_id}/approve", wholesaler_product_handler.ApproveCatalogAccess(&app.shared_deps.CatalogAccessService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+		r.Post("/{retailer_id}/reject", wholesaler_product_handler.RejectCatalogAccess(&app.shared_deps.CatalogAccessService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
+		r.Post("/{retailer_id}/revoke", wholesaler_product_handler.RevokeCatalogAccess(&app.shared_deps.CatalogAccessService, &app.shared_deps.WholesalersService, app.shared_deps.JSONutils.Writer))
 	})
 
 	// Checkout routes
@@ -176,8 +270,90 @@ func (app *application) newRouter() *chi.Mux {
 		r.Post("/", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).CreateRetailerCheckout)
 	})
 
+	// Request-for-quote negotiations; an accepted quote is paid through the retailer checkout
+	r.Route("/api/retailer/quotes", func(r chi.Router) {
+		r.Use(auth.RequireRetailer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).ListRetailerQuotes)
+		r.Post("/", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).RequestQuote)
+		r.Get("/{id}", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).GetRetailerQuote)
+		r.Post("/{id}/counter", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).CounterQuote)
+		r.Post("/{id}/accept", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).AcceptQuote)
+		r.Post("/{id}/decline", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).DeclineRetailerQuote)
+	})
+
+	r.Route("/api/wholesaler/quotes", func(r chi.Router) {
+		r.Use(auth.RequireWholesaler(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).ListWholesalerQuotes)
+		r.Get("/{id}", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).GetWholesalerQuote)
+		r.Post("/{id}/offer", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).OfferQuote)
+		r.Post("/{id}/decline", quotes.NewQuotesHandler(&app.shared_deps.QuotesService, &app.shared_deps.OrdersService, app.shared_deps.JSONutils).DeclineWholesalerQuote)
+	})
+
+	// Trade credit: wholesalers grant retailers terms, orders placed on account are invoiced and
+	// wholesalers record the payments they receive for them
+	r.Route("/api/retailer/invoices", func(r chi.Router) {
+		r.Use(auth.RequireRetailer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).ListRetailerInvoices)
+		r.Get("/credit-terms", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).ListRetailerCreditTerms)
+		r.Get("/{id}", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).GetRetailerInvoice)
+	})
+
+	r.Route("/api/wholesaler/invoices", func(r chi.Router) {
+		r.Use(auth.RequireWholesaler(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).ListWholesalerInvoices)
+		r.Get("/{id}", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).GetWholesalerInvoice)
+		r.Post("/{id}/payments", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).RecordPayment)
+	})
+
+	r.Route("/api/wholesaler/credit-terms", func(r chi.Router) {
+		r.Use(auth.RequireWholesaler(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).ListWholesalerCreditTerms)
+		r.Put("/{retailer_id}", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).SetCreditTerms)
+		r.Delete("/{retailer_id}", invoices.NewInvoicesHandler(&app.shared_deps.InvoicesService, app.shared_deps.JSONutils).DeleteCreditTerms)
+	})
+
+	// Order history routes
+	r.Route("/api/orders", func(r chi.Router) {
+		r.Use(auth.RequireConsumer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).ListConsumerOrders)
+		r.Get("/{id}", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).GetConsumerOrder)
+	})
+
+	r.Route("/api/retailer/orders", func(r chi.Router) {
+		r.Use(auth.RequireRetailer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).ListRetailerOrders)
+		r.Get("/{id}", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).GetRetailerOrder)
+		r.Post("/{id}/status", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).UpdateRetailerOrderStatus)
+		r.Get("/{id}/refunds", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).ListRetailerOrderRefunds)
+		r.Post("/{id}/refunds", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).RefundRetailerOrder)
+	})
+
+	r.Route("/api/retailer/purchases", func(r chi.Router) {
+		r.Use(auth.RequireRetailer(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).ListRetailerPurchases)
+		r.Get("/{id}", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).GetRetailerPurchase)
+	})
+
+	r.Route("/api/wholesaler/orders", func(r chi.Router) {
+		r.Use(auth.RequireWholesaler(&app.shared_deps.AuthService, app.shared_deps.logger, app.shared_deps.JSONutils.Writer))
+		r.Get("/", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).ListWholesalerOrders)
+		r.Get("/{id}", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).GetWholesalerOrder)
+		r.Post("/{id}/status", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).UpdateWholesalerOrderStatus)
+		r.Get("/{id}/refunds", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).ListWholesalerOrderRefunds)
+		r.Post("/{id}/refunds", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).RefundWholesalerOrder)
+	})
+
 	// Webhook route
-	r.Post("/api/webhook", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).HandleStripeWebhook)
+	r.Post("/api/webhook", orders.NewOrdersHandler(&app.shared_deps.OrdersService, app.shared_deps.JSONutils).HandlePaymentWebhook)
+
+	// Stand-in for the hosted checkout page when running on the fake payment gateway
+	if app.shared_deps.FakePaymentGateway != nil {
+		r.Route("/api/dev/payments/sessions/{id}", func(r chi.Router) {
+			r.Get("/", dev_payments.GetSession(app.shared_deps.FakePaymentGateway, app.shared_deps.JSONutils.Writer))
+			r.Post("/complete", dev_payments.CompleteSession(app.shared_deps.FakePaymentGateway, &app.shared_deps.OrdersService, app.shared_deps.JSONutils.Writer))
+			r.Post("/expire", dev_payments.ExpireSession(app.shared_deps.FakePaymentGateway, &app.shared_deps.OrdersService, app.shared_deps.JSONutils.Writer))
+		})
+	}
 
 	return r
 }
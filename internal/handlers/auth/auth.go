@@ -380,6 +380,38 @@ func RequireRetailer(authService *services.AuthService, logger zerolog.Logger, w
 	}
 }
 
+// IdentifyRetailer is a middleware for public endpoints that show retailers something of their
+// own, such as negotiated prices. It adds the email of a signed-in retailer to the request
+// context and lets every other request through unchanged.
+func IdentifyRetailer(authService *services.AuthService, logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie("jwt")
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := authService.VerifySelfToken(cookie.Value)
+			if err != nil {
+				logger.Debug().Err(err).Str("path", r.URL.Path).Msg("Ignoring invalid JWT on public endpoint")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			role, _ := (*claims)["role"].(string)
+			email, _ := (*claims)["sub"].(string)
+			if role != "retailer" || email == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserEmailKey, email)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 // RequireWholesaler is a middleware that checks authentication status and verifies the user has "wholesaler" role
 // If authentication fails or role is not "wholesaler", it returns 401 Unauthorized and stops the request
 func RequireWholesaler(authService *services.AuthService, logger zerolog.Logger, writeJSON jsonutils.JSONwriter) func(http.Handler) http.Handler {
@@ -40,6 +40,7 @@ func AddCartItem(cartService *services.CartService, writeJSON jsonutils.JSONwrit
 
 		var requestBody struct {
 			ProductID int `json:"product_id"`
+			VariantID int `json:"variant_id"`
 			Quantity  int `json:"quantity"`
 		}
 
@@ -54,8 +55,21 @@ func AddCartItem(cartService *services.CartService, writeJSON jsonutils.JSONwrit
 			return
 		}
 
-		newQty, err := cartService.AddCartItem(email, requestBody.ProductID, requestBody.Quantity)
+		if requestBody.VariantID < 0 {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid variant ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		newQty, err := cartService.AddCartItem(email, requestBody.ProductID, requestBody.VariantID, requestBody.Quantity)
 		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrVariantRequired):
+				writeJSON(w, jsonutils.Envelope{"error": "Choose a variant of this product"}, http.StatusBadRequest, nil)
+				return
+			case errors.Is(err, repositories.ErrVariantNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "Variant not found"}, http.StatusBadRequest, nil)
+				return
+			}
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to add item to cart"}, http.StatusInternalServerError, nil)
 			return
 		}
@@ -79,7 +93,17 @@ func RemoveCartItem(cartService *services.CartService, writeJSON jsonutils.JSONw
 			return
 		}
 
-		err = cartService.RemoveCartItem(email, productID)
+		// Lines for a variant are removed with ?variant_id=
+		variantID := 0
+		if param := r.URL.Query().Get("variant_id"); param != "" {
+			variantID, err = strconv.Atoi(param)
+			if err != nil || variantID <= 0 {
+				writeJSON(w, jsonutils.Envelope{"error": "Invalid variant ID"}, http.StatusBadRequest, nil)
+				return
+			}
+		}
+
+		err = cartService.RemoveCartItem(email, productID, variantID)
 		if err != nil {
 			if errors.Is(err, repositories.ErrCartItemNotFound) {
 				writeJSON(w, jsonutils.Envelope{"error": "Cart item not found"}, http.StatusNotFound, nil)
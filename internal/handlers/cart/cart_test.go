@@ -17,8 +17,8 @@ import (
 // MockCartServiceForTesting wraps the service for testing
 type MockCartServiceForTesting struct {
 	GetCartItemsByEmailFunc  func(email string) ([]models.CartItem, error)
-	AddCartItemFunc          func(email string, productID int, quantity int) (int, error)
-	RemoveCartItemFunc       func(email string, productID int) error
+	AddCartItemFunc          func(email string, productID int, variantID int, quantity int) (int, error)
+	RemoveCartItemFunc       func(email string, productID int, variantID int) error
 	GetCartNumberByEmailFunc func(email string) (int, error)
 }
 
@@ -72,15 +72,15 @@ func (m *MockCartRepoForTesting) GetCartItemsByUserID(userID int) ([]models.Cart
 	return []models.CartItem{{Id: 1, User_id: userID, Product_id: 1, Quantity: 1}}, nil
 }
 
-func (m *MockCartRepoForTesting) AddCartItem(userID int, productID int, quantity int) (int, error) {
+func (m *MockCartRepoForTesting) AddCartItem(userID int, productID int, variantID int, quantity int) (int, error) {
 	return quantity, nil
 }
 
-func (m *MockCartRepoForTesting) RemoveCartItem(userID int, productID int) error {
+func (m *MockCartRepoForTesting) RemoveCartItem(userID int, productID int, variantID int) error {
 	return nil
 }
 
-func (m *MockCartRepoForTesting) DecreaseCartItem(userID int, productID int) (int, error) {
+func (m *MockCartRepoForTesting) DecreaseCartItem(userID int, productID int, variantID int) (int, error) {
 	return 0, nil
 }
 
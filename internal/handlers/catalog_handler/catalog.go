@@ -0,0 +1,24 @@
+package catalog_handler
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"net/http"
+)
+
+// Catalog describes the products a seller manages through these handlers: how the authenticated
+// seller is found, and how the seller's products are looked up and validated. product_handler
+// and wholesaler_product_handler each provide one.
+type Catalog struct {
+	// SellerID returns the ID of the authenticated seller.
+	SellerID func(r *http.Request) (int, error)
+	// HandleSellerError writes the response for an error returned by SellerID.
+	HandleSellerError func(w http.ResponseWriter, err error, writeJSON jsonutils.JSONwriter)
+	// GetProduct returns ProductNotFound unless the seller has a product with the given ID.
+	GetProduct func(productID, sellerID int) error
+	// ProductNotFound is the error returned when a product of this catalog does not exist.
+	ProductNotFound error
+	// ValidateLines applies the rules of a single product to every imported line that could be
+	// read, setting Error on those that break them.
+	ValidateLines func(lines []models.CatalogLine)
+}
@@ -0,0 +1,114 @@
+package catalog_handler
+
+import (
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"net/http"
+	"strconv"
+)
+
+const maxCatalogUploadSize = 10 << 20 // 10MB
+
+// ImportProducts creates and updates the seller's products from a CSV file uploaded as the
+// "file" form field, matching them on SKU. Each line is held to the same rules as a single
+// product. With ?dry_run=true, or when any line fails, nothing is written and the report says
+// what the import would have done.
+func ImportProducts(
+	catalogService *services.ProductCatalogService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		dryRun := false
+		if value := r.URL.Query().Get("dry_run"); value != "" {
+			dryRun, err = strconv.ParseBool(value)
+			if err != nil {
+				writeJSON(w, jsonutils.Envelope{"error": "dry_run must be true or false"}, http.StatusBadRequest, nil)
+				return
+			}
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxCatalogUploadSize)
+		if err := r.ParseMultipartForm(maxCatalogUploadSize); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to parse multipart form"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			if err == http.ErrMissingFile {
+				writeJSON(w, jsonutils.Envelope{"error": "No file provided. Use 'file' as the form field name"}, http.StatusBadRequest, nil)
+				return
+			}
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to retrieve file"}, http.StatusBadRequest, nil)
+			return
+		}
+		defer file.Close()
+
+		lines, err := catalogService.ParseCSV(file)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+		catalog.ValidateLines(lines)
+
+		report, err := catalogService.ImportProducts(sellerID, lines, dryRun)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to import products"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		status := http.StatusOK
+		if !report.DryRun && !report.Applied {
+			status = http.StatusUnprocessableEntity
+		}
+		writeJSON(w, jsonutils.Envelope{"report": report}, status, nil)
+	}
+}
+
+// ExportProducts streams the seller's whole catalog as a CSV file that ImportProducts accepts.
+func ExportProducts(
+	catalogService *services.ProductCatalogService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="products.csv"`)
+
+		out := &startedWriter{ResponseWriter: w}
+		if err := catalogService.ExportCSV(sellerID, out); err != nil {
+			if !out.started {
+				w.Header().Del("Content-Disposition")
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to export products"}, http.StatusInternalServerError, nil)
+				return
+			}
+			// Part of the file has gone out under a 200, so abort the connection rather than let
+			// the client take a truncated file for the whole catalog.
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// startedWriter records whether anything has been written to the response yet.
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,107 @@
+package catalog_handler
+
+import (
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// GetStockLedger returns the stock movements of one of the seller's products, newest first,
+// with ?limit= capping how many, and whether its stock still matches the sum of its movements.
+func GetStockLedger(
+	inventoryService *services.InventoryService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				writeJSON(w, jsonutils.Envelope{"error": "Invalid limit"}, http.StatusBadRequest, nil)
+				return
+			}
+		}
+
+		ledger, err := inventoryService.GetLedger(productID, sellerID, limit)
+		if err != nil {
+			stockMovementError(w, catalog, err, "Failed to fetch stock movements", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"ledger": ledger}, http.StatusOK, nil)
+	}
+}
+
+// AdjustStock posts a manual stock correction to one of the seller's products, e.g.
+// {"quantity": -2, "note": "Damaged in storage"}, or to one of its variants with "variant_id".
+func AdjustStock(
+	inventoryService *services.InventoryService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req struct {
+			Quantity  int    `json:"quantity"`
+			VariantId int    `json:"variant_id"`
+			Note      string `json:"note"`
+		}
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		movement, err := inventoryService.AdjustStock(productID, sellerID, req.VariantId, req.Quantity, req.Note)
+		if err != nil {
+			stockMovementError(w, catalog, err, "Failed to adjust stock", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"movement": movement}, http.StatusCreated, nil)
+	}
+}
+
+func stockMovementError(w http.ResponseWriter, catalog Catalog, err error, message string, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAdjustment):
+		writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+	case errors.Is(err, catalog.ProductNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrVariantNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Variant not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrStockBelowReserved):
+		writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusConflict, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": message}, http.StatusInternalServerError, nil)
+	}
+}
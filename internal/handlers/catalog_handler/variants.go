@@ -0,0 +1,299 @@
+package catalog_handler
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+type optionsRequest struct {
+	Options []string `json:"options"`
+}
+
+type variantRequest struct {
+	Sku      string            `json:"sku"`
+	Options  map[string]string `json:"options"`
+	Price    models.Money      `json:"price"`
+	StockQty int               `json:"stock_qty"`
+	ImageURL string            `json:"image_url"`
+}
+
+// ListVariants returns the option types and variants of one of the seller's products.
+func ListVariants(
+	variantsService *services.ProductVariantsService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if err := catalog.GetProduct(productID, sellerID); err != nil {
+			handleVariantError(w, catalog, err, "Failed to fetch variants", writeJSON)
+			return
+		}
+
+		matrix, err := variantsService.GetVariantMatrix(productID)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch variants"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"options": matrix.Options, "variants": matrix.Variants}, http.StatusOK, nil)
+	}
+}
+
+// SetOptions replaces the option types of one of the seller's products, e.g. ["size", "colour"].
+func SetOptions(
+	variantsService *services.ProductVariantsService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req optionsRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		sanitizeOptionsRequest(&req)
+		if err := validateOptionsRequest(req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if err := variantsService.SetOptions(productID, sellerID, req.Options); err != nil {
+			handleVariantError(w, catalog, err, "Failed to set options", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"options": req.Options}, http.StatusOK, nil)
+	}
+}
+
+func CreateVariant(
+	variantsService *services.ProductVariantsService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req variantRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		sanitizeVariantRequest(&req)
+		if err := validateVariantRequest(req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		created, err := variantsService.CreateVariant(sellerID, &models.ProductVariant{
+			ProductId: productID,
+			Sku:       req.Sku,
+			Options:   req.Options,
+			Price:     req.Price,
+			Stock_qty: req.StockQty,
+			Image_url: req.ImageURL,
+		})
+		if err != nil {
+			handleVariantError(w, catalog, err, "Failed to create variant", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"variant": created}, http.StatusCreated, nil)
+	}
+}
+
+func UpdateVariant(
+	variantsService *services.ProductVariantsService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+		variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid variant ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req variantRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		sanitizeVariantRequest(&req)
+		if err := validateVariantRequest(req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		updated, err := variantsService.UpdateVariant(sellerID, &models.ProductVariant{
+			Id:        variantID,
+			ProductId: productID,
+			Sku:       req.Sku,
+			Options:   req.Options,
+			Price:     req.Price,
+			Stock_qty: req.StockQty,
+			Image_url: req.ImageURL,
+		})
+		if err != nil {
+			handleVariantError(w, catalog, err, "Failed to update variant", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"variant": updated}, http.StatusOK, nil)
+	}
+}
+
+func DeleteVariant(
+	variantsService *services.ProductVariantsService,
+	catalog Catalog,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sellerID, err := catalog.SellerID(r)
+		if err != nil {
+			catalog.HandleSellerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+		variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid variant ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if err := variantsService.DeleteVariant(productID, variantID, sellerID); err != nil {
+			handleVariantError(w, catalog, err, "Failed to delete variant", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"message": "Variant deleted"}, http.StatusOK, nil)
+	}
+}
+
+func sanitizeOptionsRequest(req *optionsRequest) {
+	for i, name := range req.Options {
+		req.Options[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+}
+
+func validateOptionsRequest(req optionsRequest) error {
+	seen := make(map[string]bool)
+	for _, name := range req.Options {
+		if name == "" {
+			return errors.New("Option names cannot be empty")
+		}
+		if seen[name] {
+			return errors.New("Option names must be unique")
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func sanitizeVariantRequest(req *variantRequest) {
+	req.Sku = strings.TrimSpace(req.Sku)
+	req.ImageURL = strings.TrimSpace(req.ImageURL)
+	options := make(map[string]string, len(req.Options))
+	for name, value := range req.Options {
+		options[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	req.Options = options
+}
+
+func validateVariantRequest(req variantRequest) error {
+	if req.Sku == "" {
+		return errors.New("SKU is required")
+	}
+	if req.Price <= 0 {
+		return errors.New("Price must be greater than zero")
+	}
+	if req.StockQty < 0 {
+		return errors.New("Stock quantity cannot be negative")
+	}
+	return nil
+}
+
+func handleVariantError(w http.ResponseWriter, catalog Catalog, err error, fallback string, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, catalog.ProductNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrVariantNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Variant not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, services.ErrVariantOptionsMismatch):
+		writeJSON(w, jsonutils.Envelope{"error": "Variant must have exactly one value for each of the product's options"}, http.StatusBadRequest, nil)
+	case errors.Is(err, repositories.ErrDuplicateSKU):
+		writeJSON(w, jsonutils.Envelope{"error": "SKU is already in use"}, http.StatusConflict, nil)
+	case errors.Is(err, repositories.ErrDuplicateVariant):
+		writeJSON(w, jsonutils.Envelope{"error": "A variant with these options already exists"}, http.StatusConflict, nil)
+	case errors.Is(err, repositories.ErrVariantOrdered):
+		writeJSON(w, jsonutils.Envelope{"error": "Variant has been ordered and cannot be deleted"}, http.StatusConflict, nil)
+	case errors.Is(err, repositories.ErrVariantsExist):
+		writeJSON(w, jsonutils.Envelope{"error": "Options cannot change while the product has variants"}, http.StatusConflict, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": fallback}, http.StatusInternalServerError, nil)
+	}
+}
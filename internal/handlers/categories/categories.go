@@ -0,0 +1,132 @@
+package categories
+
+import (
+	"Obsonarium-backend/internal/handlers/auth"
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// GetShopCategories lists the category tree with the number of shop products under each category.
+func GetShopCategories(categoriesService *services.CategoriesService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tree, err := categoriesService.GetShopCategoryTree()
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch categories"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"categories": tree}, http.StatusOK, nil)
+	}
+}
+
+// GetWholesaleCategories lists the category tree with the number of wholesale products under
+// each category. Products of private catalogs only count for retailers approved to see them.
+func GetWholesaleCategories(categoriesService *services.CategoriesService, accessService *services.CatalogAccessService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		viewerID, err := accessService.ViewerID(auth.GetUserEmailFromContext(r))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch categories"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		tree, err := categoriesService.GetWholesaleCategoryTree(viewerID)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch categories"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"categories": tree}, http.StatusOK, nil)
+	}
+}
+
+// BrowseShopCategory lists one page of the shop products in the category named by the slug
+// and in its descendants. It takes the same query parameters as the shop listing.
+func BrowseShopCategory(categoriesService *services.CategoriesService, productsService *services.RetailerProductsService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		category, filter, ok := categoryFilter(w, r, categoriesService, writeJSON)
+		if !ok {
+			return
+		}
+
+		products, page, err := productsService.GetProducts(filter)
+		if err != nil {
+			handleListError(w, err, filter, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"category": category, "products": products, "next_cursor": page.NextCursor, "total": page.Total}, http.StatusOK, nil)
+	}
+}
+
+// BrowseWholesaleCategory is the wholesale catalog counterpart of BrowseShopCategory. A
+// signed-in retailer sees its negotiated prices and the private catalogs it was approved for.
+func BrowseWholesaleCategory(categoriesService *services.CategoriesService, productsService *services.WholesalerProductsService, priceListsService *services.PriceListsService, accessService *services.CatalogAccessService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		category, filter, ok := categoryFilter(w, r, categoriesService, writeJSON)
+		if !ok {
+			return
+		}
+
+		viewerID, err := accessService.ViewerID(auth.GetUserEmailFromContext(r))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch products"}, http.StatusInternalServerError, nil)
+			return
+		}
+		filter.ViewerID = viewerID
+
+		products, page, err := productsService.GetProducts(filter)
+		if err != nil {
+			handleListError(w, err, filter, writeJSON)
+			return
+		}
+
+		if err := priceListsService.ApplyToProducts(auth.GetUserEmailFromContext(r), products); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch products"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"category": category, "products": products, "next_cursor": page.NextCursor, "total": page.Total}, http.StatusOK, nil)
+	}
+}
+
+// categoryFilter looks up the category in the URL and reads the listing filter for it from the
+// query string. It writes the error response itself and returns false if either fails.
+func categoryFilter(w http.ResponseWriter, r *http.Request, categoriesService *services.CategoriesService, writeJSON jsonutils.JSONwriter) (*models.Category, models.ProductFilter, bool) {
+	filter, err := models.ParseProductFilter(r.URL.Query())
+	if err != nil {
+		writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+		return nil, models.ProductFilter{}, false
+	}
+
+	category, err := categoriesService.GetCategoryBySlug(chi.URLParam(r, "slug"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrCategoryNotFound) {
+			writeJSON(w, jsonutils.Envelope{"error": "Category not found"}, http.StatusNotFound, nil)
+			return nil, models.ProductFilter{}, false
+		}
+		writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch category"}, http.StatusInternalServerError, nil)
+		return nil, models.ProductFilter{}, false
+	}
+
+	filter.CategoryID = category.Id
+	return category, filter, true
+}
+
+func handleListError(w http.ResponseWriter, err error, filter models.ProductFilter, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, repositories.ErrInvalidCursor):
+		writeJSON(w, jsonutils.Envelope{"error": "Invalid cursor"}, http.StatusBadRequest, nil)
+	case errors.Is(err, repositories.ErrUnsupportedSort):
+		writeJSON(w, jsonutils.Envelope{"error": "This catalog cannot be sorted by " + string(filter.Sort)}, http.StatusBadRequest, nil)
+	case errors.Is(err, repositories.ErrUnsupportedFilter):
+		writeJSON(w, jsonutils.Envelope{"error": "This catalog cannot be filtered by rating"}, http.StatusBadRequest, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch products"}, http.StatusInternalServerError, nil)
+	}
+}
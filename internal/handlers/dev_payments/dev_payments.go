@@ -0,0 +1,88 @@
+package dev_payments
+
+import (
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// These handlers stand in for the hosted checkout page when the API runs with the fake
+// payment gateway. They are only mounted when PAYMENT_GATEWAY=fake.
+
+// GetSession shows a fake checkout session, which is where its session URL points
+func GetSession(gateway *services.FakePaymentGateway, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, err := gateway.GetSession(chi.URLParam(r, "id"))
+		if err != nil {
+			sessionError(w, err, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"session": session}, http.StatusOK, nil)
+	}
+}
+
+// CompleteSession pays a fake checkout session and applies the resulting event to its orders,
+// as if the gateway had delivered a webhook
+func CompleteSession(gateway *services.FakePaymentGateway, ordersService *services.OrdersService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "id")
+
+		event, err := gateway.CompleteSession(sessionID)
+		if err != nil {
+			sessionError(w, err, writeJSON)
+			return
+		}
+		if err := ordersService.HandlePaymentEvent(event); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		session, err := gateway.GetSession(sessionID)
+		if err != nil {
+			sessionError(w, err, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"session": session, "event": event, "redirect_url": session.SuccessURL}, http.StatusOK, nil)
+	}
+}
+
+// ExpireSession abandons a fake checkout session and applies the resulting event to its orders
+func ExpireSession(gateway *services.FakePaymentGateway, ordersService *services.OrdersService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "id")
+
+		event, err := gateway.ExpireSession(sessionID)
+		if err != nil {
+			sessionError(w, err, writeJSON)
+			return
+		}
+		if err := ordersService.HandlePaymentEvent(event); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		session, err := gateway.GetSession(sessionID)
+		if err != nil {
+			sessionError(w, err, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"session": session, "event": event, "redirect_url": session.CancelURL}, http.StatusOK, nil)
+	}
+}
+
+func sessionError(w http.ResponseWriter, err error, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, services.ErrFakeSessionNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Session not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, services.ErrFakeSessionClosed):
+		writeJSON(w, jsonutils.Envelope{"error": "Session is no longer open"}, http.StatusConflict, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusInternalServerError, nil)
+	}
+}
@@ -0,0 +1,271 @@
+package invoices
+
+import (
+	"Obsonarium-backend/internal/handlers/auth"
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+type InvoicesHandler struct {
+	invoicesService *services.InvoicesService
+	jsonUtils       jsonutils.JSONutils
+}
+
+func NewInvoicesHandler(invoicesService *services.InvoicesService, jsonUtils jsonutils.JSONutils) *InvoicesHandler {
+	return &InvoicesHandler{
+		invoicesService: invoicesService,
+		jsonUtils:       jsonUtils,
+	}
+}
+
+// ListWholesalerCreditTerms returns the retailers the authenticated wholesaler has granted credit
+// to, with their outstanding balances.
+func (h *InvoicesHandler) ListWholesalerCreditTerms(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return
+	}
+
+	terms, err := h.invoicesService.GetWholesalerCreditTermsByEmail(email)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch credit terms"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"credit_terms": terms}, http.StatusOK, nil)
+}
+
+// SetCreditTerms grants the retailer in the URL credit, or changes its terms. credit_limit is in
+// the wholesaler's currency and terms_days is how long invoices have to be paid, e.g. 30.
+func (h *InvoicesHandler) SetCreditTerms(w http.ResponseWriter, r *http.Request) {
+	email, retailerID, ok := h.idRequest(w, r, "retailer_id", "Invalid retailer ID")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CreditLimit models.Money `json:"credit_limit"`
+		TermsDays   int          `json:"terms_days"`
+	}
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	terms, err := h.invoicesService.SetCreditTermsByEmail(email, retailerID, req.CreditLimit, req.TermsDays)
+	if err != nil {
+		h.invoiceError(w, err, "Failed to set credit terms")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"credit_terms": terms}, http.StatusOK, nil)
+}
+
+// DeleteCreditTerms withdraws the credit of the retailer in the URL
+func (h *InvoicesHandler) DeleteCreditTerms(w http.ResponseWriter, r *http.Request) {
+	email, retailerID, ok := h.idRequest(w, r, "retailer_id", "Invalid retailer ID")
+	if !ok {
+		return
+	}
+
+	if err := h.invoicesService.DeleteCreditTermsByEmail(email, retailerID); err != nil {
+		h.invoiceError(w, err, "Failed to delete credit terms")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"message": "Credit terms deleted"}, http.StatusOK, nil)
+}
+
+// ListRetailerCreditTerms returns the wholesalers the authenticated retailer may order from on
+// account, with how much it owes each.
+func (h *InvoicesHandler) ListRetailerCreditTerms(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return
+	}
+
+	terms, err := h.invoicesService.GetRetailerCreditTermsByEmail(email)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch credit terms"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"credit_terms": terms}, http.StatusOK, nil)
+}
+
+// ListWholesalerInvoices returns the invoices of the authenticated wholesaler, soonest due first.
+// ?status= narrows it down to open, overdue, paid or void invoices.
+func (h *InvoicesHandler) ListWholesalerInvoices(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return
+	}
+	status, ok := h.statusFilter(w, r)
+	if !ok {
+		return
+	}
+
+	invoices, err := h.invoicesService.GetWholesalerInvoicesByEmail(email, status)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch invoices"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"invoices": invoices}, http.StatusOK, nil)
+}
+
+// GetWholesalerInvoice returns an invoice of the authenticated wholesaler with its payments
+func (h *InvoicesHandler) GetWholesalerInvoice(w http.ResponseWriter, r *http.Request) {
+	email, invoiceID, ok := h.idRequest(w, r, "id", "Invalid invoice ID")
+	if !ok {
+		return
+	}
+
+	invoice, err := h.invoicesService.GetWholesalerInvoiceByEmail(email, invoiceID)
+	if err != nil {
+		h.invoiceError(w, err, "Failed to fetch invoice")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"invoice": invoice}, http.StatusOK, nil)
+}
+
+// RecordPayment records money the wholesaler received for an invoice outside the platform.
+// received_at is an optional RFC 3339 time and defaults to now.
+func (h *InvoicesHandler) RecordPayment(w http.ResponseWriter, r *http.Request) {
+	email, invoiceID, ok := h.idRequest(w, r, "id", "Invalid invoice ID")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount     models.Money `json:"amount"`
+		Method     string       `json:"method"`
+		Reference  string       `json:"reference"`
+		ReceivedAt string       `json:"received_at"`
+	}
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	var receivedAt time.Time
+	if req.ReceivedAt != "" {
+		var err error
+		receivedAt, err = time.Parse(time.RFC3339, req.ReceivedAt)
+		if err != nil {
+			h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "received_at must be an RFC 3339 time"}, http.StatusBadRequest, nil)
+			return
+		}
+	}
+
+	invoice, err := h.invoicesService.RecordPaymentByEmail(email, invoiceID, req.Amount, req.Method, req.Reference, receivedAt)
+	if err != nil {
+		h.invoiceError(w, err, "Failed to record payment")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"invoice": invoice}, http.StatusOK, nil)
+}
+
+// ListRetailerInvoices returns the invoices the authenticated retailer owes, soonest due first.
+// It takes the same ?status= filter as the wholesaler list.
+func (h *InvoicesHandler) ListRetailerInvoices(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return
+	}
+	status, ok := h.statusFilter(w, r)
+	if !ok {
+		return
+	}
+
+	invoices, err := h.invoicesService.GetRetailerInvoicesByEmail(email, status)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch invoices"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"invoices": invoices}, http.StatusOK, nil)
+}
+
+// GetRetailerInvoice returns an invoice of the authenticated retailer with its payments
+func (h *InvoicesHandler) GetRetailerInvoice(w http.ResponseWriter, r *http.Request) {
+	email, invoiceID, ok := h.idRequest(w, r, "id", "Invalid invoice ID")
+	if !ok {
+		return
+	}
+
+	invoice, err := h.invoicesService.GetRetailerInvoiceByEmail(email, invoiceID)
+	if err != nil {
+		h.invoiceError(w, err, "Failed to fetch invoice")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"invoice": invoice}, http.StatusOK, nil)
+}
+
+func (h *InvoicesHandler) requireEmail(w http.ResponseWriter, r *http.Request) (string, bool) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return "", false
+	}
+	return email, true
+}
+
+// idRequest reads the caller's email and the ID in the URL parameter param, writing the error
+// response itself and returning false if either is missing.
+func (h *InvoicesHandler) idRequest(w http.ResponseWriter, r *http.Request, param string, invalid string) (string, int, bool) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return "", 0, false
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, param))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": invalid}, http.StatusBadRequest, nil)
+		return "", 0, false
+	}
+	return email, id, true
+}
+
+func (h *InvoicesHandler) statusFilter(w http.ResponseWriter, r *http.Request) (models.InvoiceStatus, bool) {
+	status := models.InvoiceStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.IsValid() {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid status"}, http.StatusBadRequest, nil)
+		return "", false
+	}
+	return status, true
+}
+
+func (h *InvoicesHandler) invoiceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCreditTerms), errors.Is(err, services.ErrInvalidPayment),
+		errors.Is(err, repositories.ErrPaymentExceedsBalance):
+		h.errorJSON(w, err, http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrInvoiceNotPayable):
+		h.errorJSON(w, err, http.StatusConflict)
+	case errors.Is(err, repositories.ErrInvoiceNotFound):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invoice not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrCreditTermsNotFound):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Credit terms not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrRetailerNotFound):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Retailer not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, services.ErrInvoiceForbidden):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Forbidden"}, http.StatusForbidden, nil)
+	default:
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": fallback}, http.StatusInternalServerError, nil)
+	}
+}
+
+func (h *InvoicesHandler) errorJSON(w http.ResponseWriter, err error, status int) {
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"error": err.Error()}, status, nil)
+}
@@ -2,11 +2,16 @@ package orders
 
 import (
 	"Obsonarium-backend/internal/handlers/auth"
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
 	"Obsonarium-backend/internal/services"
 	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
 	"io"
 	"net/http"
-	"os"
+	"strconv"
+
+	"github.com/go-chi/chi"
 )
 
 type OrdersHandler struct {
@@ -35,21 +40,25 @@ func (h *OrdersHandler) CreateConsumerCheckout(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	sessionURL, err := h.ordersService.CreateConsumerCheckoutByEmail(email, req.SuccessURL, req.CancelURL, req.AddressID)
+	checkout, err := h.ordersService.CreateConsumerCheckoutByEmail(email, req.SuccessURL, req.CancelURL, req.AddressID)
 	if err != nil {
-		h.errorJSON(w, err, http.StatusInternalServerError)
+		h.checkoutError(w, err)
 		return
 	}
 
-	h.jsonUtils.Writer(w, jsonutils.Envelope{"url": sessionURL}, http.StatusOK, nil)
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"url": checkout.URL, "orders": checkout.Orders}, http.StatusOK, nil)
 }
 
+// CreateRetailerCheckout starts a card checkout for the retailer's cart. With payment_method
+// "invoice" the cart is placed on account instead, using the credit terms its wholesalers
+// granted, and the confirmed orders and their invoices are returned rather than a payment URL.
 func (h *OrdersHandler) CreateRetailerCheckout(w http.ResponseWriter, r *http.Request) {
-	email := r.Context().Value("user_email").(string)
+	email := auth.GetUserEmailFromContext(r)
 
 	var req struct {
-		SuccessURL string `json:"success_url"`
-		CancelURL  string `json:"cancel_url"`
+		SuccessURL    string `json:"success_url"`
+		CancelURL     string `json:"cancel_url"`
+		PaymentMethod string `json:"payment_method"`
 	}
 
 	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
@@ -57,16 +66,31 @@ func (h *OrdersHandler) CreateRetailerCheckout(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	switch req.PaymentMethod {
+	case "", "card":
+	case "invoice":
+		checkout, err := h.ordersService.CreateRetailerInvoiceCheckoutByEmail(email)
+		if err != nil {
+			h.checkoutError(w, err)
+			return
+		}
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"orders": checkout.Orders, "invoices": checkout.Invoices}, http.StatusCreated, nil)
+		return
+	default:
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "payment_method must be card or invoice"}, http.StatusBadRequest, nil)
+		return
+	}
+
 	sessionURL, err := h.ordersService.CreateRetailerCheckoutByEmail(email, req.SuccessURL, req.CancelURL)
 	if err != nil {
-		h.errorJSON(w, err, http.StatusInternalServerError)
+		h.checkoutError(w, err)
 		return
 	}
 
 	h.jsonUtils.Writer(w, jsonutils.Envelope{"url": sessionURL}, http.StatusOK, nil)
 }
 
-func (h *OrdersHandler) HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
+func (h *OrdersHandler) HandlePaymentWebhook(w http.ResponseWriter, r *http.Request) {
 	const MaxBodyBytes = int64(65536)
 	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
 	payload, err := io.ReadAll(r.Body)
@@ -75,11 +99,15 @@ func (h *OrdersHandler) HandleStripeWebhook(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Verify webhook signature
-	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	// The gateway verifies the signature with its own webhook secret
 	header := r.Header.Get("Stripe-Signature")
 
-	if err := h.ordersService.HandleStripeWebhook(payload, header, webhookSecret); err != nil {
+	if err := h.ordersService.HandlePaymentWebhook(payload, header); err != nil {
+		// Any non-2xx answer makes the gateway deliver the event again later
+		if errors.Is(err, services.ErrPaymentEventInProgress) {
+			h.errorJSON(w, err, http.StatusConflict)
+			return
+		}
 		h.errorJSON(w, err, http.StatusBadRequest)
 		return
 	}
@@ -87,6 +115,416 @@ func (h *OrdersHandler) HandleStripeWebhook(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+// ListConsumerOrders returns the authenticated consumer's order history
+func (h *OrdersHandler) ListConsumerOrders(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orders, err := h.ordersService.GetConsumerOrdersByEmail(email)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch orders"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"orders": orders}, http.StatusOK, nil)
+}
+
+// GetConsumerOrder returns one of the authenticated consumer's orders
+func (h *OrdersHandler) GetConsumerOrder(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	order, err := h.ordersService.GetConsumerOrderByEmail(email, orderID)
+	if err != nil {
+		h.orderLookupError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"order": order}, http.StatusOK, nil)
+}
+
+// ListRetailerOrders returns the consumer orders received by the authenticated retailer
+func (h *OrdersHandler) ListRetailerOrders(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orders, err := h.ordersService.GetRetailerSalesByEmail(email)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch orders"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"orders": orders}, http.StatusOK, nil)
+}
+
+// GetRetailerOrder returns one consumer order received by the authenticated retailer
+func (h *OrdersHandler) GetRetailerOrder(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	order, err := h.ordersService.GetRetailerSaleByEmail(email, orderID)
+	if err != nil {
+		h.orderLookupError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"order": order}, http.StatusOK, nil)
+}
+
+// ListRetailerPurchases returns the wholesale orders placed by the authenticated retailer
+func (h *OrdersHandler) ListRetailerPurchases(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orders, err := h.ordersService.GetRetailerPurchasesByEmail(email)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch orders"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"orders": orders}, http.StatusOK, nil)
+}
+
+// GetRetailerPurchase returns one wholesale order placed by the authenticated retailer
+func (h *OrdersHandler) GetRetailerPurchase(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	order, err := h.ordersService.GetRetailerPurchaseByEmail(email, orderID)
+	if err != nil {
+		h.orderLookupError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"order": order}, http.StatusOK, nil)
+}
+
+// ListWholesalerOrders returns the retailer orders received by the authenticated wholesaler
+func (h *OrdersHandler) ListWholesalerOrders(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orders, err := h.ordersService.GetWholesalerOrdersByEmail(email)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch orders"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"orders": orders}, http.StatusOK, nil)
+}
+
+// GetWholesalerOrder returns one retailer order received by the authenticated wholesaler
+func (h *OrdersHandler) GetWholesalerOrder(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	order, err := h.ordersService.GetWholesalerOrderByEmail(email, orderID)
+	if err != nil {
+		h.orderLookupError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"order": order}, http.StatusOK, nil)
+}
+
+type updateStatusRequest struct {
+	Status models.OrderStatus `json:"status"`
+}
+
+// UpdateRetailerOrderStatus moves a consumer order received by the authenticated retailer
+// to shipped, delivered or cancelled
+func (h *OrdersHandler) UpdateRetailerOrderStatus(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	var req updateStatusRequest
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.ordersService.UpdateRetailerSaleStatusByEmail(email, orderID, req.Status)
+	if err != nil {
+		h.orderUpdateError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"order": order}, http.StatusOK, nil)
+}
+
+// UpdateWholesalerOrderStatus moves a retailer order received by the authenticated wholesaler
+// to shipped, delivered or cancelled
+func (h *OrdersHandler) UpdateWholesalerOrderStatus(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	var req updateStatusRequest
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.ordersService.UpdateWholesalerOrderStatusByEmail(email, orderID, req.Status)
+	if err != nil {
+		h.orderUpdateError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"order": order}, http.StatusOK, nil)
+}
+
+type refundRequest struct {
+	Items   []models.RefundItem `json:"items"`
+	Restock bool                `json:"restock"`
+	Reason  string              `json:"reason"`
+}
+
+// RefundRetailerOrder refunds a consumer order received by the authenticated retailer, either
+// whole or the item lines given in the body
+func (h *OrdersHandler) RefundRetailerOrder(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	var req refundRequest
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.ordersService.RefundRetailerSaleByEmail(email, orderID, req.Items, req.Restock, req.Reason)
+	if err != nil {
+		h.refundError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"refund": refund}, http.StatusCreated, nil)
+}
+
+// ListRetailerOrderRefunds returns the refunds of a consumer order received by the authenticated retailer
+func (h *OrdersHandler) ListRetailerOrderRefunds(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	refunds, err := h.ordersService.GetRetailerSaleRefundsByEmail(email, orderID)
+	if err != nil {
+		h.orderLookupError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"refunds": refunds}, http.StatusOK, nil)
+}
+
+// RefundWholesalerOrder refunds a retailer order received by the authenticated wholesaler, either
+// whole or the item lines given in the body
+func (h *OrdersHandler) RefundWholesalerOrder(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	var req refundRequest
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.ordersService.RefundWholesalerOrderByEmail(email, orderID, req.Items, req.Restock, req.Reason)
+	if err != nil {
+		h.refundError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"refund": refund}, http.StatusCreated, nil)
+}
+
+// ListWholesalerOrderRefunds returns the refunds of a retailer order received by the authenticated wholesaler
+func (h *OrdersHandler) ListWholesalerOrderRefunds(w http.ResponseWriter, r *http.Request) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return
+	}
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid order ID"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	refunds, err := h.ordersService.GetWholesalerOrderRefundsByEmail(email, orderID)
+	if err != nil {
+		h.orderLookupError(w, err)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"refunds": refunds}, http.StatusOK, nil)
+}
+
+func (h *OrdersHandler) refundError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRefund):
+		h.errorJSON(w, err, http.StatusBadRequest)
+	case errors.Is(err, services.ErrOrderNotRefundable), errors.Is(err, services.ErrNothingToRefund),
+		errors.Is(err, repositories.ErrRefundExceedsQuantity), errors.Is(err, repositories.ErrOrderNotPaid):
+		h.errorJSON(w, err, http.StatusConflict)
+	case errors.Is(err, services.ErrRefundFailed):
+		h.errorJSON(w, err, http.StatusBadGateway)
+	case errors.Is(err, repositories.ErrOrderNotFound), errors.Is(err, services.ErrOrderForbidden):
+		h.orderLookupError(w, err)
+	default:
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to refund order"}, http.StatusInternalServerError, nil)
+	}
+}
+
+func (h *OrdersHandler) orderUpdateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrStatusNotSettable):
+		h.errorJSON(w, err, http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidStatusTransition), errors.Is(err, repositories.ErrOrderStatusConflict):
+		h.errorJSON(w, err, http.StatusConflict)
+	case errors.Is(err, repositories.ErrOrderNotFound), errors.Is(err, services.ErrOrderForbidden):
+		h.orderLookupError(w, err)
+	default:
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to update order status"}, http.StatusInternalServerError, nil)
+	}
+}
+
+func (h *OrdersHandler) orderLookupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrOrderNotFound):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Order not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, services.ErrOrderForbidden):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Forbidden"}, http.StatusForbidden, nil)
+	default:
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch order"}, http.StatusInternalServerError, nil)
+	}
+}
+
+// checkoutError reports stock shortages and unmet order minimums per item so the client can show which lines to change,
+// and which wholesaler's credit limit an order on account would exceed
+func (h *OrdersHandler) checkoutError(w http.ResponseWriter, err error) {
+	var stockErr *repositories.InsufficientStockError
+	if errors.As(err, &stockErr) {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Insufficient stock", "items": stockErr.Items}, http.StatusConflict, nil)
+		return
+	}
+	var minimumErr *services.MinimumOrderError
+	if errors.As(err, &minimumErr) {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Order minimums not met", "items": minimumErr.Issues}, http.StatusConflict, nil)
+		return
+	}
+	// A cart line of a product that has since been given variants must be replaced by one of them
+	if errors.Is(err, services.ErrMixedCurrencies) || errors.Is(err, repositories.ErrVariantRequired) {
+		h.errorJSON(w, err, http.StatusConflict)
+		return
+	}
+	var creditErr *repositories.CreditLimitError
+	if errors.As(err, &creditErr) {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Credit limit exceeded", "credit": creditErr}, http.StatusConflict, nil)
+		return
+	}
+	if errors.Is(err, repositories.ErrCreditCurrency) {
+		h.errorJSON(w, err, http.StatusConflict)
+		return
+	}
+	if errors.Is(err, repositories.ErrCatalogAccessDenied) || errors.Is(err, repositories.ErrCreditTermsNotFound) {
+		h.errorJSON(w, err, http.StatusForbidden)
+		return
+	}
+	h.errorJSON(w, err, http.StatusInternalServerError)
+}
+
 func (h *OrdersHandler) errorJSON(w http.ResponseWriter, err error, status int) {
 	h.jsonUtils.Writer(w, jsonutils.Envelope{"error": err.Error()}, status, nil)
 }
@@ -0,0 +1,53 @@
+package product_handler
+
+import (
+	"Obsonarium-backend/internal/handlers/catalog_handler"
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"net/http"
+)
+
+// Catalog describes the authenticated retailer's products for the variant, inventory and CSV
+// handlers in catalog_handler.
+func Catalog(productService *services.ProductService, retailersService *services.RetailersService) catalog_handler.Catalog {
+	return catalog_handler.Catalog{
+		SellerID: func(r *http.Request) (int, error) {
+			retailer, err := getAuthenticatedRetailer(r, retailersService)
+			return retailer.Id, err
+		},
+		HandleSellerError: handleRetailerError,
+		GetProduct: func(productID, retailerID int) error {
+			_, err := productService.GetProductByID(productID, retailerID)
+			return err
+		},
+		ProductNotFound: repositories.ErrProductNotFound,
+		ValidateLines:   validateCatalogLines,
+	}
+}
+
+// validateCatalogLines applies the rules of a single product to every line that could be read.
+func validateCatalogLines(lines []models.CatalogLine) {
+	for i := range lines {
+		line := &lines[i]
+		if line.Error != "" {
+			continue
+		}
+
+		req := productRequest{
+			Name:        line.Row.Name,
+			Price:       line.Row.Price,
+			StockQty:    line.Row.StockQty,
+			ImageURL:    line.Row.ImageURL,
+			Description: line.Row.Description,
+			CategoryID:  line.Row.CategoryID,
+			Sku:         line.Row.Sku,
+		}
+		sanitizeProductRequest(&req)
+		if err := validateProductRequest(req); err != nil {
+			line.Error = err.Error()
+			continue
+		}
+		line.Row.Name, line.Row.ImageURL, line.Row.Description, line.Row.Sku = req.Name, req.ImageURL, req.Description, req.Sku
+	}
+}
@@ -0,0 +1,136 @@
+package product_handler
+
+import (
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// LinkSourceProduct links the product in the URL to the wholesaler product it is bought as, so
+// its stock goes up when an order of that product is delivered.
+func LinkSourceProduct(
+	productService *services.ProductService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req struct {
+			WholesalerProductID int `json:"wholesaler_product_id"`
+		}
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+		if req.WholesalerProductID <= 0 {
+			writeJSON(w, jsonutils.Envelope{"error": "wholesaler_product_id is required"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		product, err := productService.LinkSourceProduct(productID, retailer.Id, req.WholesalerProductID)
+		if err != nil {
+			sourceProductError(w, err, "Failed to link product", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"product": product}, http.StatusOK, nil)
+	}
+}
+
+func UnlinkSourceProduct(
+	productService *services.ProductService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		product, err := productService.UnlinkSourceProduct(productID, retailer.Id)
+		if err != nil {
+			sourceProductError(w, err, "Failed to unlink product", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"product": product}, http.StatusOK, nil)
+	}
+}
+
+// ImportWholesaleProduct creates a retailer product from a wholesaler product, priced at the
+// wholesale price plus markup_percent, e.g. 40 for 40% on top.
+func ImportWholesaleProduct(
+	productService *services.ProductService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		var req struct {
+			WholesalerProductID int     `json:"wholesaler_product_id"`
+			MarkupPercent       float64 `json:"markup_percent"`
+		}
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+		if req.WholesalerProductID <= 0 {
+			writeJSON(w, jsonutils.Envelope{"error": "wholesaler_product_id is required"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		product, err := productService.ImportWholesaleProduct(retailer, req.WholesalerProductID, req.MarkupPercent)
+		if err != nil {
+			sourceProductError(w, err, "Failed to import product", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"product": product}, http.StatusCreated, nil)
+	}
+}
+
+func sourceProductError(w http.ResponseWriter, err error, fallback string, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMarkup):
+		writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+	case errors.Is(err, services.ErrSourceCurrency), errors.Is(err, repositories.ErrSourceAlreadyLinked):
+		writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusConflict, nil)
+	case errors.Is(err, repositories.ErrProductNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrWholesalerProductNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Wholesale product not found"}, http.StatusNotFound, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": fallback}, http.StatusInternalServerError, nil)
+	}
+}
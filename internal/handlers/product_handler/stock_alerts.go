@@ -0,0 +1,100 @@
+package product_handler
+
+import (
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// SetReorderThreshold sets the available stock below which the retailer gets a low-stock email
+// about the product in the URL. A threshold of 0 turns the emails off.
+func SetReorderThreshold(
+	productService *services.ProductService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req struct {
+			ReorderThreshold int `json:"reorder_threshold"`
+		}
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		product, err := productService.SetReorderThreshold(productID, retailer.Id, req.ReorderThreshold)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidThreshold):
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			case errors.Is(err, repositories.ErrProductNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusNotFound, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to set reorder threshold"}, http.StatusInternalServerError, nil)
+			}
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"product": product}, http.StatusOK, nil)
+	}
+}
+
+// GetReorderSuggestions proposes wholesale cart lines that restock the retailer's linked
+// products. ?window_days= is how many days of sales to size them from, 30 by default, and
+// ?cover_days= how many days of sales they should last, 14 by default.
+func GetReorderSuggestions(
+	stockAlertsService *services.StockAlertsService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		var days [2]int
+		for i, param := range []string{"window_days", "cover_days"} {
+			value := r.URL.Query().Get(param)
+			if value == "" {
+				continue
+			}
+			days[i], err = strconv.Atoi(value)
+			if err != nil || days[i] <= 0 {
+				writeJSON(w, jsonutils.Envelope{"error": "Invalid " + param}, http.StatusBadRequest, nil)
+				return
+			}
+		}
+
+		proposal, err := stockAlertsService.GetReorderSuggestions(retailer.Id, days[0], days[1])
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidReorderWindow) {
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+				return
+			}
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to suggest reorders"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"reorder": proposal}, http.StatusOK, nil)
+	}
+}
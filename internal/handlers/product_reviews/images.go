@@ -0,0 +1,105 @@
+package product_reviews
+
+import (
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// UploadReviewImage attaches a photo, sent as the multipart field "image", to the customer's own
+// review (protected route - requires consumer authentication). The same file types and size
+// limit as product images apply.
+func UploadReviewImage(
+	reviewsService *services.ProductReviewsService,
+	usersRepo repositories.IUsersRepo,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getAuthenticatedUser(r, usersRepo)
+		if err != nil {
+			handleUserError(w, err, writeJSON)
+			return
+		}
+
+		productID, reviewID, ok := reviewURLParams(w, r, writeJSON)
+		if !ok {
+			return
+		}
+
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to parse multipart form"}, http.StatusBadRequest, nil)
+			return
+		}
+		file, header, err := r.FormFile("image")
+		if err != nil {
+			if errors.Is(err, http.ErrMissingFile) {
+				writeJSON(w, jsonutils.Envelope{"error": "No file provided. Use 'image' as the form field name"}, http.StatusBadRequest, nil)
+				return
+			}
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to retrieve file"}, http.StatusBadRequest, nil)
+			return
+		}
+		defer file.Close()
+
+		image, err := reviewsService.AddReviewImage(reviewID, productID, user.Id, file, header)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidFileExtension):
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			case errors.Is(err, services.ErrFileTooLarge):
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusRequestEntityTooLarge, nil)
+			case errors.Is(err, repositories.ErrReviewNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "Review not found"}, http.StatusNotFound, nil)
+			case errors.Is(err, repositories.ErrTooManyImages):
+				writeJSON(w, jsonutils.Envelope{"error": "A review can have at most 4 images"}, http.StatusConflict, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to save image"}, http.StatusInternalServerError, nil)
+			}
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"image": image}, http.StatusCreated, nil)
+	}
+}
+
+// DeleteReviewImage removes a photo from the customer's own review (protected route - requires
+// consumer authentication)
+func DeleteReviewImage(
+	reviewsService *services.ProductReviewsService,
+	usersRepo repositories.IUsersRepo,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getAuthenticatedUser(r, usersRepo)
+		if err != nil {
+			handleUserError(w, err, writeJSON)
+			return
+		}
+
+		productID, reviewID, ok := reviewURLParams(w, r, writeJSON)
+		if !ok {
+			return
+		}
+		imageID, err := strconv.Atoi(chi.URLParam(r, "image_id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid image ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if err := reviewsService.DeleteReviewImage(imageID, reviewID, productID, user.Id); err != nil {
+			if errors.Is(err, repositories.ErrImageNotFound) {
+				writeJSON(w, jsonutils.Envelope{"error": "Image not found"}, http.StatusNotFound, nil)
+				return
+			}
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to delete image"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"message": "Image deleted"}, http.StatusOK, nil)
+	}
+}
@@ -0,0 +1,196 @@
+package product_reviews
+
+import (
+	"Obsonarium-backend/internal/handlers/auth"
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// GetModerationQueue lists the reviews of the retailer's products that customers have reported,
+// or that the retailer has hidden, with the open reports against each (protected route -
+// requires retailer authentication)
+func GetModerationQueue(
+	reviewsService *services.ProductReviewsService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		queue, err := reviewsService.GetModerationQueue(retailer.Id)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch reported reviews"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"reviews": queue}, http.StatusOK, nil)
+	}
+}
+
+// HideReview hides a reported review from the product page and its ratings
+func HideReview(
+	reviewsService *services.ProductReviewsService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return moderateReview(reviewsService.HideReview, retailersService, writeJSON)
+}
+
+// RestoreReview dismisses the reports against a review and shows it again if it was hidden
+func RestoreReview(
+	reviewsService *services.ProductReviewsService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return moderateReview(reviewsService.RestoreReview, retailersService, writeJSON)
+}
+
+func moderateReview(
+	moderate func(reviewID int, retailerID int) (*models.ProductReview, error),
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		reviewID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid review ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		review, err := moderate(reviewID, retailer.Id)
+		if err != nil {
+			handleModerationError(w, err, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"review": review}, http.StatusOK, nil)
+	}
+}
+
+// DeleteReportedReview removes a reported or hidden review of one of the retailer's products
+func DeleteReportedReview(
+	reviewsService *services.ProductReviewsService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		reviewID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid review ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if err := reviewsService.DeleteReportedReview(reviewID, retailer.Id); err != nil {
+			handleModerationError(w, err, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"message": "Review deleted"}, http.StatusOK, nil)
+	}
+}
+
+// SetSellerReply posts the retailer's public reply to a review of one of its products, e.g.
+// {"reply": "Thanks, glad you like it!"}, replacing any earlier reply
+func SetSellerReply(
+	reviewsService *services.ProductReviewsService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		retailer, err := getAuthenticatedRetailer(r, retailersService)
+		if err != nil {
+			handleRetailerError(w, err, writeJSON)
+			return
+		}
+
+		reviewID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid review ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req struct {
+			Reply string `json:"reply"`
+		}
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		review, err := reviewsService.SetSellerReply(reviewID, retailer.Id, req.Reply)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidReply) {
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+				return
+			}
+			handleModerationError(w, err, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"review": review}, http.StatusOK, nil)
+	}
+}
+
+// DeleteSellerReply removes the retailer's reply to a review of one of its products
+func DeleteSellerReply(
+	reviewsService *services.ProductReviewsService,
+	retailersService *services.RetailersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return moderateReview(reviewsService.DeleteSellerReply, retailersService, writeJSON)
+}
+
+func handleModerationError(w http.ResponseWriter, err error, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, repositories.ErrReviewNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Review not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrReviewNotReported):
+		writeJSON(w, jsonutils.Envelope{"error": "Only reported reviews can be moderated"}, http.StatusConflict, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": "Failed to moderate review"}, http.StatusInternalServerError, nil)
+	}
+}
+
+// getAuthenticatedRetailer looks up the retailer set on the request by RequireRetailer
+func getAuthenticatedRetailer(r *http.Request, retailersService *services.RetailersService) (*models.Retailer, error) {
+	email, ok := r.Context().Value(auth.UserEmailKey).(string)
+	if !ok || email == "" {
+		return nil, errUnauthorized
+	}
+	return retailersService.GetRetailerByEmail(email)
+}
+
+func handleRetailerError(w http.ResponseWriter, err error, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, errUnauthorized):
+		writeJSON(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+	case errors.Is(err, repositories.ErrRetailerNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Retailer not found"}, http.StatusNotFound, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": "Failed to resolve retailer"}, http.StatusInternalServerError, nil)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"Obsonarium-backend/internal/repositories"
 	"Obsonarium-backend/internal/services"
 	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -18,7 +19,9 @@ type CreateReviewRequest struct {
 	Comment string `json:"comment"`
 }
 
-// GetReviews gets all reviews for a specific product (public endpoint, no auth required)
+// GetReviews lists one page of a product's reviews (public endpoint, no auth required). See
+// models.ParseReviewFilter for the query parameters; pass next_cursor back as cursor to get the
+// following page.
 func GetReviews(
 	reviewsService *services.ProductReviewsService,
 	writeJSON jsonutils.JSONwriter,
@@ -31,17 +34,28 @@ func GetReviews(
 			return
 		}
 
-		reviews, err := reviewsService.GetReviewsByProductID(productID)
+		filter, err := models.ParseReviewFilter(r.URL.Query())
 		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		reviews, page, err := reviewsService.GetReviewsByProductID(productID, filter)
+		if err != nil {
+			if errors.Is(err, repositories.ErrInvalidCursor) {
+				writeJSON(w, jsonutils.Envelope{"error": "Invalid cursor"}, http.StatusBadRequest, nil)
+				return
+			}
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch reviews"}, http.StatusInternalServerError, nil)
 			return
 		}
 
-		writeJSON(w, jsonutils.Envelope{"reviews": reviews}, http.StatusOK, nil)
+		writeJSON(w, jsonutils.Envelope{"reviews": reviews, "next_cursor": page.NextCursor, "total": page.Total}, http.StatusOK, nil)
 	}
 }
 
-// CreateReview creates a new review (protected route - requires consumer authentication)
+// CreateReview creates a new review (protected route - requires consumer authentication).
+// Only customers who have paid for the product can review it, once each.
 func CreateReview(
 	reviewsService *services.ProductReviewsService,
 	usersRepo repositories.IUsersRepo,
@@ -49,21 +63,9 @@ func CreateReview(
 	readJSON jsonutils.JSONreader,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Get user email from context (set by RequireConsumer middleware)
-		email, ok := r.Context().Value(auth.UserEmailKey).(string)
-		if !ok || email == "" {
-			writeJSON(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
-			return
-		}
-
-		// Get user ID
-		user, err := usersRepo.GetUserByEmail(email)
+		user, err := getAuthenticatedUser(r, usersRepo)
 		if err != nil {
-			if err == repositories.ErrUserNotFound {
-				writeJSON(w, jsonutils.Envelope{"error": "User not found"}, http.StatusNotFound, nil)
-				return
-			}
-			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch user"}, http.StatusInternalServerError, nil)
+			handleUserError(w, err, writeJSON)
 			return
 		}
 
@@ -80,16 +82,8 @@ func CreateReview(
 			return
 		}
 
-		// Validate rating
-		if req.Rating < 1 || req.Rating > 5 {
-			writeJSON(w, jsonutils.Envelope{"error": "Rating must be between 1 and 5"}, http.StatusBadRequest, nil)
-			return
-		}
-
-		// Validate comment
-		req.Comment = strings.TrimSpace(req.Comment)
-		if req.Comment == "" {
-			writeJSON(w, jsonutils.Envelope{"error": "Comment is required"}, http.StatusBadRequest, nil)
+		if message := validateReviewRequest(&req); message != "" {
+			writeJSON(w, jsonutils.Envelope{"error": message}, http.StatusBadRequest, nil)
 			return
 		}
 
@@ -103,13 +97,282 @@ func CreateReview(
 
 		createdReview, err := reviewsService.CreateReview(review)
 		if err != nil {
-			writeJSON(w, jsonutils.Envelope{"error": "Failed to create review"}, http.StatusInternalServerError, nil)
+			switch {
+			case errors.Is(err, services.ErrNotVerifiedPurchase):
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusForbidden, nil)
+			case errors.Is(err, repositories.ErrDuplicateReview):
+				writeJSON(w, jsonutils.Envelope{"error": "You have already reviewed this product"}, http.StatusConflict, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to create review"}, http.StatusInternalServerError, nil)
+			}
 			return
 		}
 
-		// Populate reviewer_name from user data
-		createdReview.Reviewer_name = user.Name
-
 		writeJSON(w, jsonutils.Envelope{"review": createdReview}, http.StatusCreated, nil)
 	}
 }
+
+// UpdateReview lets the author of a review change its rating and comment (protected route -
+// requires consumer authentication)
+func UpdateReview(
+	reviewsService *services.ProductReviewsService,
+	usersRepo repositories.IUsersRepo,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getAuthenticatedUser(r, usersRepo)
+		if err != nil {
+			handleUserError(w, err, writeJSON)
+			return
+		}
+
+		productID, reviewID, ok := reviewURLParams(w, r, writeJSON)
+		if !ok {
+			return
+		}
+
+		var req CreateReviewRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if message := validateReviewRequest(&req); message != "" {
+			writeJSON(w, jsonutils.Envelope{"error": message}, http.StatusBadRequest, nil)
+			return
+		}
+
+		review, err := reviewsService.UpdateReview(&models.ProductReview{
+			Id:         reviewID,
+			Product_id: productID,
+			User_id:    user.Id,
+			Rating:     req.Rating,
+			Comment:    req.Comment,
+		})
+		if err != nil {
+			if errors.Is(err, repositories.ErrReviewNotFound) {
+				writeJSON(w, jsonutils.Envelope{"error": "Review not found"}, http.StatusNotFound, nil)
+				return
+			}
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to update review"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"review": review}, http.StatusOK, nil)
+	}
+}
+
+// DeleteReview lets the author of a review remove it (protected route - requires consumer
+// authentication)
+func DeleteReview(
+	reviewsService *services.ProductReviewsService,
+	usersRepo repositories.IUsersRepo,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getAuthenticatedUser(r, usersRepo)
+		if err != nil {
+			handleUserError(w, err, writeJSON)
+			return
+		}
+
+		productID, reviewID, ok := reviewURLParams(w, r, writeJSON)
+		if !ok {
+			return
+		}
+
+		if err := reviewsService.DeleteReview(reviewID, productID, user.Id); err != nil {
+			if errors.Is(err, repositories.ErrReviewNotFound) {
+				writeJSON(w, jsonutils.Envelope{"error": "Review not found"}, http.StatusNotFound, nil)
+				return
+			}
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to delete review"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"message": "Review deleted"}, http.StatusOK, nil)
+	}
+}
+
+// ReportReview lets a customer report someone else's review to the retailer for moderation
+// (protected route - requires consumer authentication)
+func ReportReview(
+	reviewsService *services.ProductReviewsService,
+	usersRepo repositories.IUsersRepo,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getAuthenticatedUser(r, usersRepo)
+		if err != nil {
+			handleUserError(w, err, writeJSON)
+			return
+		}
+
+		productID, reviewID, ok := reviewURLParams(w, r, writeJSON)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		report, err := reviewsService.ReportReview(reviewID, productID, user.Id, req.Reason)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidReport):
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			case errors.Is(err, repositories.ErrReviewNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "Review not found"}, http.StatusNotFound, nil)
+			case errors.Is(err, repositories.ErrOwnReview):
+				writeJSON(w, jsonutils.Envelope{"error": "You cannot report your own review"}, http.StatusBadRequest, nil)
+			case errors.Is(err, repositories.ErrAlreadyReported):
+				writeJSON(w, jsonutils.Envelope{"error": "You have already reported this review"}, http.StatusConflict, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to report review"}, http.StatusInternalServerError, nil)
+			}
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"report": report}, http.StatusCreated, nil)
+	}
+}
+
+// VoteReview records whether the customer found someone else's review helpful, replacing any
+// earlier vote (protected route - requires consumer authentication)
+func VoteReview(
+	reviewsService *services.ProductReviewsService,
+	usersRepo repositories.IUsersRepo,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getAuthenticatedUser(r, usersRepo)
+		if err != nil {
+			handleUserError(w, err, writeJSON)
+			return
+		}
+
+		productID, reviewID, ok := reviewURLParams(w, r, writeJSON)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Helpful *bool `json:"helpful"`
+		}
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+		if req.Helpful == nil {
+			writeJSON(w, jsonutils.Envelope{"error": "helpful is required"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		review, err := reviewsService.VoteReview(reviewID, productID, user.Id, *req.Helpful)
+		if err != nil {
+			handleVoteError(w, err, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"review": review}, http.StatusOK, nil)
+	}
+}
+
+// RemoveVote takes back the customer's vote on a review (protected route - requires consumer
+// authentication)
+func RemoveVote(
+	reviewsService *services.ProductReviewsService,
+	usersRepo repositories.IUsersRepo,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getAuthenticatedUser(r, usersRepo)
+		if err != nil {
+			handleUserError(w, err, writeJSON)
+			return
+		}
+
+		productID, reviewID, ok := reviewURLParams(w, r, writeJSON)
+		if !ok {
+			return
+		}
+
+		review, err := reviewsService.RemoveVote(reviewID, productID, user.Id)
+		if err != nil {
+			handleVoteError(w, err, writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"review": review}, http.StatusOK, nil)
+	}
+}
+
+func handleVoteError(w http.ResponseWriter, err error, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, repositories.ErrReviewNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Review not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrVoteNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "You have not voted on this review"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrOwnReview):
+		writeJSON(w, jsonutils.Envelope{"error": "You cannot vote on your own review"}, http.StatusBadRequest, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": "Failed to save vote"}, http.StatusInternalServerError, nil)
+	}
+}
+
+// validateReviewRequest trims the comment and returns what is wrong with the request, or "" if
+// nothing is.
+func validateReviewRequest(req *CreateReviewRequest) string {
+	if req.Rating < 1 || req.Rating > 5 {
+		return "Rating must be between 1 and 5"
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if req.Comment == "" {
+		return "Comment is required"
+	}
+	return ""
+}
+
+func reviewURLParams(w http.ResponseWriter, r *http.Request, writeJSON jsonutils.JSONwriter) (int, int, bool) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "product_id"))
+	if err != nil {
+		writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+		return 0, 0, false
+	}
+	reviewID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, jsonutils.Envelope{"error": "Invalid review ID"}, http.StatusBadRequest, nil)
+		return 0, 0, false
+	}
+	return productID, reviewID, true
+}
+
+var errUnauthorized = errors.New("unauthorized")
+
+// getAuthenticatedUser looks up the consumer set on the request by RequireConsumer
+func getAuthenticatedUser(r *http.Request, usersRepo repositories.IUsersRepo) (*models.User, error) {
+	email, ok := r.Context().Value(auth.UserEmailKey).(string)
+	if !ok || email == "" {
+		return nil, errUnauthorized
+	}
+	return usersRepo.GetUserByEmail(email)
+}
+
+func handleUserError(w http.ResponseWriter, err error, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, errUnauthorized):
+		writeJSON(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+	case errors.Is(err, repositories.ErrUserNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "User not found"}, http.StatusNotFound, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch user"}, http.StatusInternalServerError, nil)
+	}
+}
@@ -0,0 +1,291 @@
+package quotes
+
+import (
+	"Obsonarium-backend/internal/handlers/auth"
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+type QuotesHandler struct {
+	quotesService *services.QuotesService
+	ordersService *services.OrdersService
+	jsonUtils     jsonutils.JSONutils
+}
+
+func NewQuotesHandler(quotesService *services.QuotesService, ordersService *services.OrdersService, jsonUtils jsonutils.JSONutils) *QuotesHandler {
+	return &QuotesHandler{
+		quotesService: quotesService,
+		ordersService: ordersService,
+		jsonUtils:     jsonUtils,
+	}
+}
+
+type termsRequest struct {
+	Items []models.QuoteItem `json:"items"`
+	Note  string             `json:"note"`
+}
+
+// RequestQuote asks a wholesaler to quote for products and quantities. Prices on the items are
+// optional and tell the wholesaler what the retailer hopes to pay.
+func (h *QuotesHandler) RequestQuote(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		WholesalerID int `json:"wholesaler_id"`
+		termsRequest
+	}
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.quotesService.RequestQuoteByEmail(email, req.WholesalerID, req.Items, req.Note)
+	if err != nil {
+		h.quoteError(w, err, "Failed to request quote")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quote": quote}, http.StatusCreated, nil)
+}
+
+// ListRetailerQuotes returns the quotes requested by the authenticated retailer
+func (h *QuotesHandler) ListRetailerQuotes(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return
+	}
+
+	quotes, err := h.quotesService.GetRetailerQuotesByEmail(email)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch quotes"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quotes": quotes}, http.StatusOK, nil)
+}
+
+// GetRetailerQuote returns a quote of the authenticated retailer with its revisions
+func (h *QuotesHandler) GetRetailerQuote(w http.ResponseWriter, r *http.Request) {
+	email, quoteID, ok := h.quoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := h.quotesService.GetRetailerQuoteByEmail(email, quoteID)
+	if err != nil {
+		h.quoteError(w, err, "Failed to fetch quote")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quote": quote}, http.StatusOK, nil)
+}
+
+// CounterQuote answers the wholesaler's offer with the retailer's own prices and quantities.
+func (h *QuotesHandler) CounterQuote(w http.ResponseWriter, r *http.Request) {
+	email, quoteID, ok := h.quoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req termsRequest
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.quotesService.CounterQuoteByEmail(email, quoteID, req.Items, req.Note)
+	if err != nil {
+		h.quoteError(w, err, "Failed to counter quote")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quote": quote}, http.StatusOK, nil)
+}
+
+// AcceptQuote accepts the wholesaler's offer, which places the order, and starts its checkout.
+// It takes the same success_url and cancel_url as the retailer checkout.
+func (h *QuotesHandler) AcceptQuote(w http.ResponseWriter, r *http.Request) {
+	email, quoteID, ok := h.quoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		SuccessURL string `json:"success_url"`
+		CancelURL  string `json:"cancel_url"`
+	}
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.quotesService.AcceptQuoteByEmail(email, quoteID)
+	if err != nil {
+		h.quoteError(w, err, "Failed to accept quote")
+		return
+	}
+
+	sessionURL, err := h.ordersService.CreateQuoteCheckout(order, req.SuccessURL, req.CancelURL)
+	if err != nil {
+		h.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"url": sessionURL, "order": order}, http.StatusOK, nil)
+}
+
+// DeclineRetailerQuote ends a negotiation on the retailer's side
+func (h *QuotesHandler) DeclineRetailerQuote(w http.ResponseWriter, r *http.Request) {
+	email, quoteID, ok := h.quoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := h.quotesService.DeclineQuoteAsRetailer(email, quoteID)
+	if err != nil {
+		h.quoteError(w, err, "Failed to decline quote")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quote": quote}, http.StatusOK, nil)
+}
+
+// ListWholesalerQuotes returns the quotes asked of the authenticated wholesaler
+func (h *QuotesHandler) ListWholesalerQuotes(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return
+	}
+
+	quotes, err := h.quotesService.GetWholesalerQuotesByEmail(email)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Failed to fetch quotes"}, http.StatusInternalServerError, nil)
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quotes": quotes}, http.StatusOK, nil)
+}
+
+// GetWholesalerQuote returns a quote asked of the authenticated wholesaler with its revisions
+func (h *QuotesHandler) GetWholesalerQuote(w http.ResponseWriter, r *http.Request) {
+	email, quoteID, ok := h.quoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := h.quotesService.GetWholesalerQuoteByEmail(email, quoteID)
+	if err != nil {
+		h.quoteError(w, err, "Failed to fetch quote")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quote": quote}, http.StatusOK, nil)
+}
+
+// OfferQuote prices a request or counter-offer. expires_at is an RFC 3339 time after which the
+// offer can no longer be accepted.
+func (h *QuotesHandler) OfferQuote(w http.ResponseWriter, r *http.Request) {
+	email, quoteID, ok := h.quoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		termsRequest
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := h.jsonUtils.Reader(w, r, &req); err != nil {
+		h.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "expires_at must be an RFC 3339 time"}, http.StatusBadRequest, nil)
+		return
+	}
+
+	quote, err := h.quotesService.OfferQuoteByEmail(email, quoteID, req.Items, req.Note, expiresAt)
+	if err != nil {
+		h.quoteError(w, err, "Failed to offer quote")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quote": quote}, http.StatusOK, nil)
+}
+
+// DeclineWholesalerQuote ends a negotiation on the wholesaler's side
+func (h *QuotesHandler) DeclineWholesalerQuote(w http.ResponseWriter, r *http.Request) {
+	email, quoteID, ok := h.quoteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := h.quotesService.DeclineQuoteAsWholesaler(email, quoteID)
+	if err != nil {
+		h.quoteError(w, err, "Failed to decline quote")
+		return
+	}
+
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"quote": quote}, http.StatusOK, nil)
+}
+
+func (h *QuotesHandler) requireEmail(w http.ResponseWriter, r *http.Request) (string, bool) {
+	email := auth.GetUserEmailFromContext(r)
+	if email == "" {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+		return "", false
+	}
+	return email, true
+}
+
+// quoteRequest reads the caller's email and the quote ID in the URL, writing the error response
+// itself and returning false if either is missing.
+func (h *QuotesHandler) quoteRequest(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	email, ok := h.requireEmail(w, r)
+	if !ok {
+		return "", 0, false
+	}
+
+	quoteID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Invalid quote ID"}, http.StatusBadRequest, nil)
+		return "", 0, false
+	}
+	return email, quoteID, true
+}
+
+func (h *QuotesHandler) quoteError(w http.ResponseWriter, err error, fallback string) {
+	var stockErr *repositories.InsufficientStockError
+	switch {
+	case errors.As(err, &stockErr):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Insufficient stock", "items": stockErr.Items}, http.StatusConflict, nil)
+	case errors.Is(err, services.ErrInvalidQuote), errors.Is(err, repositories.ErrWholesalerProductNotFound),
+		errors.Is(err, repositories.ErrVariantRequired), errors.Is(err, repositories.ErrVariantNotFound):
+		h.errorJSON(w, err, http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrQuoteStatusConflict), errors.Is(err, repositories.ErrQuoteExpired):
+		h.errorJSON(w, err, http.StatusConflict)
+	case errors.Is(err, repositories.ErrQuoteNotFound):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Quote not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrWholesalerNotFound):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Wholesaler not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, services.ErrQuoteForbidden):
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": "Forbidden"}, http.StatusForbidden, nil)
+	default:
+		h.jsonUtils.Writer(w, jsonutils.Envelope{"error": fallback}, http.StatusInternalServerError, nil)
+	}
+}
+
+func (h *QuotesHandler) errorJSON(w http.ResponseWriter, err error, status int) {
+	h.jsonUtils.Writer(w, jsonutils.Envelope{"error": err.Error()}, status, nil)
+}
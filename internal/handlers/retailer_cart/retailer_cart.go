@@ -20,13 +20,13 @@ func GetCart(cartService *services.RetailerCartService, writeJSON jsonutils.JSON
 			return
 		}
 
-		cartItems, err := cartService.GetCartItemsByEmail(email)
+		cart, err := cartService.GetCartByEmail(email)
 		if err != nil {
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch cart"}, http.StatusInternalServerError, nil)
 			return
 		}
 
-		writeJSON(w, jsonutils.Envelope{"cart": cartItems}, http.StatusOK, nil)
+		writeJSON(w, jsonutils.Envelope{"cart": cart.Items, "total": cart.Total, "issues": cart.Issues}, http.StatusOK, nil)
 	}
 }
 
@@ -40,6 +40,7 @@ func AddCartItem(cartService *services.RetailerCartService, writeJSON jsonutils.
 
 		var requestBody struct {
 			ProductID int `json:"product_id"`
+			VariantID int `json:"variant_id"`
 			Quantity  int `json:"quantity"`
 		}
 
@@ -54,8 +55,33 @@ func AddCartItem(cartService *services.RetailerCartService, writeJSON jsonutils.
 			return
 		}
 
-		newQty, err := cartService.AddCartItem(email, requestBody.ProductID, requestBody.Quantity)
+		if requestBody.VariantID < 0 {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid variant ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		// Wholesale lines are bought in bulk, so any number of units can be added at once
+		if requestBody.Quantity == 0 || requestBody.Quantity < -1 {
+			writeJSON(w, jsonutils.Envelope{"error": "Quantity must be positive, or -1 to remove one unit"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		newQty, err := cartService.AddCartItem(email, requestBody.ProductID, requestBody.VariantID, requestBody.Quantity)
 		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrVariantRequired):
+				writeJSON(w, jsonutils.Envelope{"error": "Choose a variant of this product"}, http.StatusBadRequest, nil)
+				return
+			case errors.Is(err, repositories.ErrVariantNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "Variant not found"}, http.StatusBadRequest, nil)
+				return
+			case errors.Is(err, repositories.ErrWholesalerProductNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusNotFound, nil)
+				return
+			case errors.Is(err, repositories.ErrCatalogAccessDenied):
+				writeJSON(w, jsonutils.Envelope{"error": "You have not been approved to order from this wholesaler"}, http.StatusForbidden, nil)
+				return
+			}
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to add item to cart"}, http.StatusInternalServerError, nil)
 			return
 		}
@@ -79,7 +105,17 @@ func RemoveCartItem(cartService *services.RetailerCartService, writeJSON jsonuti
 			return
 		}
 
-		err = cartService.RemoveCartItem(email, productID)
+		// Lines for a variant are removed with ?variant_id=
+		variantID := 0
+		if param := r.URL.Query().Get("variant_id"); param != "" {
+			variantID, err = strconv.Atoi(param)
+			if err != nil || variantID <= 0 {
+				writeJSON(w, jsonutils.Envelope{"error": "Invalid variant ID"}, http.StatusBadRequest, nil)
+				return
+			}
+		}
+
+		err = cartService.RemoveCartItem(email, productID, variantID)
 		if err != nil {
 			if errors.Is(err, repositories.ErrRetailerCartItemNotFound) {
 				writeJSON(w, jsonutils.Envelope{"error": "Cart item not found"}, http.StatusNotFound, nil)
@@ -1,6 +1,7 @@
 package retailer_products
 
 import (
+	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
 	"Obsonarium-backend/internal/services"
 	"Obsonarium-backend/internal/utils/jsonutils"
@@ -11,20 +12,38 @@ import (
 	"github.com/go-chi/chi"
 )
 
+// GetProducts lists one page of the catalog. See models.ParseProductFilter for the query
+// parameters; pass next_cursor back as cursor to get the following page.
 func GetProducts(productsService *services.RetailerProductsService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		q := r.URL.Query().Get("q")
-		products, err := productsService.GetProducts(q)
+		filter, err := models.ParseProductFilter(r.URL.Query())
 		if err != nil {
-			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch products"}, http.StatusInternalServerError, nil)
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
 			return
 		}
 
-		writeJSON(w, jsonutils.Envelope{"products": products}, http.StatusOK, nil)
+		products, page, err := productsService.GetProducts(filter)
+		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrInvalidCursor):
+				writeJSON(w, jsonutils.Envelope{"error": "Invalid cursor"}, http.StatusBadRequest, nil)
+			case errors.Is(err, repositories.ErrUnsupportedSort):
+				writeJSON(w, jsonutils.Envelope{"error": "This catalog cannot be sorted by " + string(filter.Sort)}, http.StatusBadRequest, nil)
+			case errors.Is(err, repositories.ErrUnsupportedFilter):
+				writeJSON(w, jsonutils.Envelope{"error": "This catalog cannot be filtered by rating"}, http.StatusBadRequest, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch products"}, http.StatusInternalServerError, nil)
+			}
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"products": products, "next_cursor": page.NextCursor, "total": page.Total}, http.StatusOK, nil)
 	}
 }
 
-func GetProduct(productsService *services.RetailerProductsService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+// GetProduct returns a product along with its variant matrix: the option types buyers choose
+// from and the variants they resolve to. Both are empty for a product without variants.
+func GetProduct(productsService *services.RetailerProductsService, variantsService *services.ProductVariantsService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		idParam := chi.URLParam(r, "id")
 		id, err := strconv.Atoi(idParam)
@@ -43,6 +62,12 @@ func GetProduct(productsService *services.RetailerProductsService, writeJSON jso
 			return
 		}
 
-		writeJSON(w, jsonutils.Envelope{"product": product}, http.StatusOK, nil)
+		matrix, err := variantsService.GetVariantMatrix(id)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch product"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"product": product, "options": matrix.Options, "variants": matrix.Variants}, http.StatusOK, nil)
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"github.com/go-chi/chi"
@@ -15,57 +16,70 @@ import (
 
 // MockRetailerProductsServiceForTesting is a mock for testing
 type MockRetailerProductsServiceForTesting struct {
-	GetProductsFunc func(q string) ([]models.RetailerProduct, error)
+	GetProductsFunc func(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error)
 	GetProductFunc  func(id int) (*models.RetailerProduct, error)
 }
 
 func TestGetProducts(t *testing.T) {
+	listing := func(products ...models.RetailerProduct) func(models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+		return func(models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+			return products, models.PageInfo{Total: len(products)}, nil
+		}
+	}
+
 	tests := []struct {
 		name           string
 		query          string
-		setupService   func() *services.RetailerProductsService
+		listProducts   func(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error)
+		expectedFilter *models.ProductFilter
 		expectedStatus int
 	}{
 		{
-			name:  "successful retrieval - no query",
-			query: "",
-			setupService: func() *services.RetailerProductsService {
-				mockRepo := &MockRetailerProductsRepoForTesting{
-					GetProductsFunc: func() ([]models.RetailerProduct, error) {
-						return []models.RetailerProduct{
-							{Id: 1, Name: "Product 1"},
-						}, nil
-					},
-				}
-				return services.NewRetailerProductsService(mockRepo)
-			},
+			name:           "successful retrieval - no query",
+			listProducts:   listing(models.RetailerProduct{Id: 1, Name: "Product 1"}),
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name:  "successful search - with query",
-			query: "telescope",
-			setupService: func() *services.RetailerProductsService {
-				mockRepo := &MockRetailerProductsRepoForTesting{
-					SearchProductsFunc: func(keyword string) ([]models.RetailerProduct, error) {
-						return []models.RetailerProduct{
-							{Id: 1, Name: "Telescope Product"},
-						}, nil
-					},
-				}
-				return services.NewRetailerProductsService(mockRepo)
+			name:         "search with filters",
+			query:        "q=telescope&sort=price_asc&min_price=10.50&in_stock=true&seller_id=3&limit=5",
+			listProducts: listing(models.RetailerProduct{Id: 1, Name: "Telescope Product"}),
+			expectedFilter: &models.ProductFilter{
+				Query:    "telescope",
+				Sort:     models.ProductSortPriceAsc,
+				MinPrice: func() *models.Money { m := models.Money(1050); return &m }(),
+				InStock:  true,
+				SellerID: 3,
+				Limit:    5,
 			},
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name:  "service error",
-			query: "",
-			setupService: func() *services.RetailerProductsService {
-				mockRepo := &MockRetailerProductsRepoForTesting{
-					GetProductsFunc: func() ([]models.RetailerProduct, error) {
-						return nil, errors.New("database error")
-					},
-				}
-				return services.NewRetailerProductsService(mockRepo)
+			name:           "unknown sort",
+			query:          "sort=cheapest",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "inverted price range",
+			query:          "min_price=20&max_price=10",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "rating out of range",
+			query:          "min_rating=6",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid cursor",
+			query: "cursor=bogus",
+			listProducts: func(models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+				return nil, models.PageInfo{}, repositories.ErrInvalidCursor
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service error",
+			listProducts: func(models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+				return nil, models.PageInfo{}, errors.New("database error")
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -73,12 +87,18 @@ func TestGetProducts(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := tt.setupService()
-			handler := GetProducts(service, jsonutils.WriteJSON)
+			var received models.ProductFilter
+			mockRepo := &MockRetailerProductsRepoForTesting{
+				ListProductsFunc: func(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+					received = filter
+					return tt.listProducts(filter)
+				},
+			}
+			handler := GetProducts(services.NewRetailerProductsService(mockRepo), jsonutils.WriteJSON)
 
 			url := "/api/shop"
 			if tt.query != "" {
-				url += "?q=" + tt.query
+				url += "?" + tt.query
 			}
 			r := httptest.NewRequest("GET", url, nil)
 			w := httptest.NewRecorder()
@@ -88,6 +108,9 @@ func TestGetProducts(t *testing.T) {
 			if w.Code != tt.expectedStatus {
 				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
 			}
+			if tt.expectedFilter != nil && !reflect.DeepEqual(received, *tt.expectedFilter) {
+				t.Errorf("Expected filter %+v, got %+v", *tt.expectedFilter, received)
+			}
 		})
 	}
 }
@@ -139,7 +162,7 @@ func TestGetProduct(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			service := tt.setupService()
-			handler := GetProduct(service, jsonutils.WriteJSON)
+			handler := GetProduct(service, services.NewProductVariantsService(&MockProductVariantsRepoForTesting{}), jsonutils.WriteJSON)
 
 			// Use chi router to properly set URL params
 			router := chi.NewRouter()
@@ -159,24 +182,16 @@ func TestGetProduct(t *testing.T) {
 
 // MockRetailerProductsRepoForTesting implements IRetailerProductsRepo
 type MockRetailerProductsRepoForTesting struct {
-	GetProductsFunc             func() ([]models.RetailerProduct, error)
-	SearchProductsFunc          func(keyword string) ([]models.RetailerProduct, error)
+	ListProductsFunc            func(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error)
 	GetProductFunc              func(id int) (*models.RetailerProduct, error)
 	GetProductsByRetailerIDFunc func(retailerID int) ([]models.RetailerProduct, error)
 }
 
-func (m *MockRetailerProductsRepoForTesting) GetProducts() ([]models.RetailerProduct, error) {
-	if m.GetProductsFunc != nil {
-		return m.GetProductsFunc()
+func (m *MockRetailerProductsRepoForTesting) ListProducts(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+	if m.ListProductsFunc != nil {
+		return m.ListProductsFunc(filter)
 	}
-	return nil, errors.New("not implemented")
-}
-
-func (m *MockRetailerProductsRepoForTesting) SearchProducts(keyword string) ([]models.RetailerProduct, error) {
-	if m.SearchProductsFunc != nil {
-		return m.SearchProductsFunc(keyword)
-	}
-	return nil, errors.New("not implemented")
+	return nil, models.PageInfo{}, errors.New("not implemented")
 }
 
 func (m *MockRetailerProductsRepoForTesting) GetProduct(id int) (*models.RetailerProduct, error) {
@@ -192,3 +207,30 @@ func (m *MockRetailerProductsRepoForTesting) GetProductsByRetailerID(retailerID
 	}
 	return nil, errors.New("not implemented")
 }
+
+// MockProductVariantsRepoForTesting implements IProductVariantsRepo for products without variants
+type MockProductVariantsRepoForTesting struct{}
+
+func (m *MockProductVariantsRepoForTesting) GetOptions(productID int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *MockProductVariantsRepoForTesting) SetOptions(productID int, sellerID int, names []string) error {
+	return errors.New("not implemented")
+}
+
+func (m *MockProductVariantsRepoForTesting) GetVariants(productID int) ([]models.ProductVariant, error) {
+	return []models.ProductVariant{}, nil
+}
+
+func (m *MockProductVariantsRepoForTesting) CreateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductVariantsRepoForTesting) UpdateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductVariantsRepoForTesting) DeleteVariant(productID int, variantID int, sellerID int) error {
+	return errors.New("not implemented")
+}
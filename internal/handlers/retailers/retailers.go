@@ -2,6 +2,7 @@ package retailers
 
 import (
 	"Obsonarium-backend/internal/handlers/auth"
+	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
 	"Obsonarium-backend/internal/services"
 	"Obsonarium-backend/internal/utils/jsonutils"
@@ -74,6 +75,7 @@ type UpdateRetailerRequest struct {
 	BusinessName string `json:"business_name"`
 	Phone        string `json:"phone"`
 	Address      string `json:"address"`
+	Currency     string `json:"currency"`
 }
 
 // UpdateCurrentRetailer updates the current authenticated retailer's profile (onboarding)
@@ -142,13 +144,27 @@ func UpdateCurrentRetailer(retailersService *services.RetailersService, writeJSO
 			return
 		}
 
+		// Currency is optional, the current one is kept when it is left out
+		if req.Currency != "" {
+			currency, ok := models.NormalizeCurrency(req.Currency)
+			if !ok {
+				writeJSON(w, jsonutils.Envelope{"error": "Currency must be one of " + strings.Join(models.SupportedCurrencies, ", ")}, http.StatusBadRequest, nil)
+				return
+			}
+			req.Currency = currency
+		}
+
 		// Name comes from Google OAuth, not from user input
-		retailer, err := retailersService.UpdateRetailer(email, req.BusinessName, req.Phone, req.Address)
+		retailer, err := retailersService.UpdateRetailer(email, req.BusinessName, req.Phone, req.Address, req.Currency)
 		if err != nil {
 			if errors.Is(err, repositories.ErrRetailerNotFound) {
 				writeJSON(w, jsonutils.Envelope{"error": "Retailer not found"}, http.StatusNotFound, nil)
 				return
 			}
+			if errors.Is(err, repositories.ErrCurrencyInUse) {
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusConflict, nil)
+				return
+			}
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to update retailer"}, http.StatusInternalServerError, nil)
 			return
 		}
@@ -160,3 +176,71 @@ func UpdateCurrentRetailer(retailersService *services.RetailersService, writeJSO
 		}, http.StatusOK, nil)
 	}
 }
+
+// ListCatalogAccess lists the private wholesale catalogs the current retailer has asked to see
+// and where each request stands.
+func ListCatalogAccess(accessService *services.CatalogAccessService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := auth.GetUserEmailFromContext(r)
+		if email == "" {
+			writeJSON(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+			return
+		}
+
+		list, err := accessService.GetRetailerAccess(email)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch catalog access"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"catalog_access": list}, http.StatusOK, nil)
+	}
+}
+
+// RequestCatalogAccess asks the wholesaler in the URL to let the current retailer see its
+// catalog, with an optional message. A rejected or revoked retailer may ask again.
+func RequestCatalogAccess(accessService *services.CatalogAccessService, writeJSON jsonutils.JSONwriter, readJSON jsonutils.JSONreader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := auth.GetUserEmailFromContext(r)
+		if email == "" {
+			writeJSON(w, jsonutils.Envelope{"error": "Unauthorized"}, http.StatusUnauthorized, nil)
+			return
+		}
+
+		wholesalerID, err := strconv.Atoi(chi.URLParam(r, "wholesaler_id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid wholesaler ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var requestBody struct {
+			Message string `json:"message"`
+		}
+		if r.ContentLength != 0 {
+			if err := readJSON(w, r, &requestBody); err != nil {
+				writeJSON(w, jsonutils.Envelope{"error": "Invalid request body"}, http.StatusBadRequest, nil)
+				return
+			}
+		}
+		requestBody.Message = strings.TrimSpace(requestBody.Message)
+		if len(requestBody.Message) > 1000 {
+			writeJSON(w, jsonutils.Envelope{"error": "Message must be at most 1000 characters"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		access, err := accessService.RequestAccess(email, wholesalerID, requestBody.Message)
+		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrWholesalerNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "Wholesaler not found"}, http.StatusNotFound, nil)
+			case errors.Is(err, repositories.ErrCatalogAccessExists):
+				writeJSON(w, jsonutils.Envelope{"error": "Access has already been requested or granted"}, http.StatusConflict, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to request catalog access"}, http.StatusInternalServerError, nil)
+			}
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"catalog_access": access}, http.StatusCreated, nil)
+	}
+}
@@ -0,0 +1,53 @@
+package wholesaler_product_handler
+
+import (
+	"Obsonarium-backend/internal/handlers/catalog_handler"
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"net/http"
+)
+
+// Catalog describes the authenticated wholesaler's products for the variant, inventory and CSV
+// handlers in catalog_handler.
+func Catalog(productService *services.WholesalerProductService, wholesalersService *services.WholesalersService) catalog_handler.Catalog {
+	return catalog_handler.Catalog{
+		SellerID: func(r *http.Request) (int, error) {
+			wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+			return wholesaler.Id, err
+		},
+		HandleSellerError: handleWholesalerError,
+		GetProduct: func(productID, wholesalerID int) error {
+			_, err := productService.GetProductByIDForWholesaler(productID, wholesalerID)
+			return err
+		},
+		ProductNotFound: repositories.ErrWholesalerProductNotFound,
+		ValidateLines:   validateCatalogLines,
+	}
+}
+
+// validateCatalogLines applies the rules of a single product to every line that could be read.
+func validateCatalogLines(lines []models.CatalogLine) {
+	for i := range lines {
+		line := &lines[i]
+		if line.Error != "" {
+			continue
+		}
+
+		req := productRequest{
+			Name:        line.Row.Name,
+			Price:       line.Row.Price,
+			StockQty:    line.Row.StockQty,
+			ImageURL:    line.Row.ImageURL,
+			Description: line.Row.Description,
+			CategoryID:  line.Row.CategoryID,
+			Sku:         line.Row.Sku,
+		}
+		sanitizeProductRequest(&req)
+		if err := validateProductRequest(req); err != nil {
+			line.Error = err.Error()
+			continue
+		}
+		line.Row.Name, line.Row.ImageURL, line.Row.Description, line.Row.Sku = req.Name, req.ImageURL, req.Description, req.Sku
+	}
+}
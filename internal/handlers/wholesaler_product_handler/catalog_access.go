@@ -0,0 +1,105 @@
+package wholesaler_product_handler
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// ListCatalogAccess lists the retailers that have asked to see the wholesaler's catalog.
+// ?status= narrows it down to pending, approved, rejected or revoked requests.
+func ListCatalogAccess(
+	accessService *services.CatalogAccessService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		status := models.CatalogAccessStatus(r.URL.Query().Get("status"))
+		if status != "" && !status.IsValid() {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid status"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		list, err := accessService.GetWholesalerAccess(wholesaler.Id, status)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch catalog access"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"catalog_access": list}, http.StatusOK, nil)
+	}
+}
+
+// ApproveCatalogAccess lets the retailer in the URL see the wholesaler's catalog.
+func ApproveCatalogAccess(
+	accessService *services.CatalogAccessService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return setCatalogAccess(accessService.Approve, wholesalersService, writeJSON)
+}
+
+// RejectCatalogAccess turns down the pending request of the retailer in the URL.
+func RejectCatalogAccess(
+	accessService *services.CatalogAccessService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return setCatalogAccess(accessService.Reject, wholesalersService, writeJSON)
+}
+
+// RevokeCatalogAccess withdraws the approved access of the retailer in the URL.
+func RevokeCatalogAccess(
+	accessService *services.CatalogAccessService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return setCatalogAccess(accessService.Revoke, wholesalersService, writeJSON)
+}
+
+func setCatalogAccess(
+	set func(wholesalerID int, retailerID int) (*models.CatalogAccess, error),
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		retailerID, err := strconv.Atoi(chi.URLParam(r, "retailer_id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid retailer ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		access, err := set(wholesaler.Id, retailerID)
+		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrCatalogAccessNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "This retailer has not requested access"}, http.StatusNotFound, nil)
+			case errors.Is(err, repositories.ErrInvalidAccessChange):
+				writeJSON(w, jsonutils.Envelope{"error": "Access cannot change from its current status"}, http.StatusConflict, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to update catalog access"}, http.StatusInternalServerError, nil)
+			}
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"catalog_access": access}, http.StatusOK, nil)
+	}
+}
@@ -0,0 +1,460 @@
+package wholesaler_product_handler
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+type priceListRequest struct {
+	Name            string                 `json:"name"`
+	DiscountPercent float64                `json:"discount_percent"`
+	Items           []models.PriceListItem `json:"items"`
+	RetailerIds     []int                  `json:"retailer_ids"`
+	GroupIds        []int                  `json:"group_ids"`
+}
+
+type retailerGroupRequest struct {
+	Name        string `json:"name"`
+	RetailerIds []int  `json:"retailer_ids"`
+}
+
+func ListPriceLists(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		lists, err := priceListsService.GetPriceLists(wholesaler.Id)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch price lists"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"price_lists": lists}, http.StatusOK, nil)
+	}
+}
+
+func GetPriceList(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid price list ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		list, err := priceListsService.GetPriceList(id, wholesaler.Id)
+		if err != nil {
+			handlePriceListError(w, err, "Failed to fetch price list", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"price_list": list}, http.StatusOK, nil)
+	}
+}
+
+// CreatePriceList saves a price list and assigns it, e.g. {"name": "Gold", "discount_percent": 5,
+// "items": [{"product_id": 3, "price": 40}, {"product_id": 4, "discount_percent": 12.5}],
+// "retailer_ids": [7], "group_ids": [2]}.
+func CreatePriceList(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		var req priceListRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if err := validatePriceListRequest(req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		list, err := priceListsService.CreatePriceList(&models.PriceList{
+			WholesalerId:    wholesaler.Id,
+			Name:            req.Name,
+			DiscountPercent: req.DiscountPercent,
+			Items:           req.Items,
+			RetailerIds:     req.RetailerIds,
+			GroupIds:        req.GroupIds,
+		})
+		if err != nil {
+			handlePriceListError(w, err, "Failed to create price list", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"price_list": list}, http.StatusCreated, nil)
+	}
+}
+
+// UpdatePriceList replaces a price list, including its items and assignments.
+func UpdatePriceList(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid price list ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req priceListRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if err := validatePriceListRequest(req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		list, err := priceListsService.UpdatePriceList(&models.PriceList{
+			Id:              id,
+			WholesalerId:    wholesaler.Id,
+			Name:            req.Name,
+			DiscountPercent: req.DiscountPercent,
+			Items:           req.Items,
+			RetailerIds:     req.RetailerIds,
+			GroupIds:        req.GroupIds,
+		})
+		if err != nil {
+			handlePriceListError(w, err, "Failed to update price list", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"price_list": list}, http.StatusOK, nil)
+	}
+}
+
+func DeletePriceList(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid price list ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if err := priceListsService.DeletePriceList(id, wholesaler.Id); err != nil {
+			handlePriceListError(w, err, "Failed to delete price list", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"message": "Price list deleted successfully"}, http.StatusOK, nil)
+	}
+}
+
+func ListRetailerGroups(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		groups, err := priceListsService.GetRetailerGroups(wholesaler.Id)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch retailer groups"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"groups": groups}, http.StatusOK, nil)
+	}
+}
+
+func GetRetailerGroup(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid group ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		group, err := priceListsService.GetRetailerGroup(id, wholesaler.Id)
+		if err != nil {
+			handlePriceListError(w, err, "Failed to fetch retailer group", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"group": group}, http.StatusOK, nil)
+	}
+}
+
+// CreateRetailerGroup saves a group of retailers, e.g. {"name": "Clubs", "retailer_ids": [7, 9]}.
+func CreateRetailerGroup(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		var req retailerGroupRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if err := validateRetailerGroupRequest(req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		group, err := priceListsService.CreateRetailerGroup(&models.RetailerGroup{
+			WholesalerId: wholesaler.Id,
+			Name:         req.Name,
+			RetailerIds:  req.RetailerIds,
+		})
+		if err != nil {
+			handlePriceListError(w, err, "Failed to create retailer group", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"group": group}, http.StatusCreated, nil)
+	}
+}
+
+// UpdateRetailerGroup renames a group and replaces its members.
+func UpdateRetailerGroup(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid group ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req retailerGroupRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if err := validateRetailerGroupRequest(req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		group, err := priceListsService.UpdateRetailerGroup(&models.RetailerGroup{
+			Id:           id,
+			WholesalerId: wholesaler.Id,
+			Name:         req.Name,
+			RetailerIds:  req.RetailerIds,
+		})
+		if err != nil {
+			handlePriceListError(w, err, "Failed to update retailer group", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"group": group}, http.StatusOK, nil)
+	}
+}
+
+func DeleteRetailerGroup(
+	priceListsService *services.PriceListsService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid group ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if err := priceListsService.DeleteRetailerGroup(id, wholesaler.Id); err != nil {
+			handlePriceListError(w, err, "Failed to delete retailer group", writeJSON)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"message": "Retailer group deleted successfully"}, http.StatusOK, nil)
+	}
+}
+
+func validatePriceListRequest(req priceListRequest) error {
+	if req.Name == "" {
+		return errors.New("Price list name is required")
+	}
+	if len(req.Name) > 100 {
+		return errors.New("Price list name must be 100 characters or less")
+	}
+	if err := validateDiscountPercent(req.DiscountPercent); err != nil {
+		return err
+	}
+
+	products := make(map[int]bool)
+	for _, item := range req.Items {
+		if item.ProductId <= 0 {
+			return errors.New("Invalid product ID")
+		}
+		if products[item.ProductId] {
+			return errors.New("Each product can only be listed once")
+		}
+		products[item.ProductId] = true
+
+		if (item.Price > 0) == (item.DiscountPercent > 0) {
+			return errors.New("Each item needs either a price or a discount percent")
+		}
+		if item.Price < 0 {
+			return errors.New("Price must be greater than zero")
+		}
+		if err := validateDiscountPercent(item.DiscountPercent); err != nil {
+			return err
+		}
+	}
+
+	if err := validateIDs(req.RetailerIds, "retailer"); err != nil {
+		return err
+	}
+	return validateIDs(req.GroupIds, "group")
+}
+
+func validateRetailerGroupRequest(req retailerGroupRequest) error {
+	if req.Name == "" {
+		return errors.New("Group name is required")
+	}
+	if len(req.Name) > 100 {
+		return errors.New("Group name must be 100 characters or less")
+	}
+	return validateIDs(req.RetailerIds, "retailer")
+}
+
+// validateDiscountPercent checks a percentage is below 100 with at most two decimal places. 0
+// means no discount.
+func validateDiscountPercent(percent float64) error {
+	if percent == 0 {
+		return nil
+	}
+	if percent <= 0 || percent >= 100 {
+		return errors.New("Discount percent must be greater than 0 and less than 100")
+	}
+	if math.Abs(percent*100-math.Round(percent*100)) > 1e-9 {
+		return errors.New("Discount percent can have at most two decimal places")
+	}
+	return nil
+}
+
+func validateIDs(ids []int, kind string) error {
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		if id <= 0 {
+			return errors.New("Invalid " + kind + " ID")
+		}
+		if seen[id] {
+			return errors.New("Each " + kind + " can only be listed once")
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+func handlePriceListError(w http.ResponseWriter, err error, fallback string, writeJSON jsonutils.JSONwriter) {
+	switch {
+	case errors.Is(err, repositories.ErrPriceListNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Price list not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrRetailerGroupNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Retailer group not found"}, http.StatusNotFound, nil)
+	case errors.Is(err, repositories.ErrDuplicatePriceList):
+		writeJSON(w, jsonutils.Envelope{"error": "A price list with this name already exists"}, http.StatusConflict, nil)
+	case errors.Is(err, repositories.ErrDuplicateGroup):
+		writeJSON(w, jsonutils.Envelope{"error": "A retailer group with this name already exists"}, http.StatusConflict, nil)
+	case errors.Is(err, repositories.ErrRetailerNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Retailer not found"}, http.StatusBadRequest, nil)
+	case errors.Is(err, repositories.ErrWholesalerProductNotFound):
+		writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusBadRequest, nil)
+	default:
+		writeJSON(w, jsonutils.Envelope{"error": fallback}, http.StatusInternalServerError, nil)
+	}
+}
@@ -0,0 +1,89 @@
+package wholesaler_product_handler
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"Obsonarium-backend/internal/services"
+	"Obsonarium-backend/internal/utils/jsonutils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type pricingRequest struct {
+	MinOrderQty int                `json:"min_order_qty"`
+	PriceTiers  []models.PriceTier `json:"price_tiers"`
+}
+
+// SetPricing replaces the minimum order quantity and quantity breaks of one of the wholesaler's
+// products, e.g. {"min_order_qty": 10, "price_tiers": [{"min_qty": 50, "price": 9.5}]}.
+func SetPricing(
+	productService *services.WholesalerProductService,
+	wholesalersService *services.WholesalersService,
+	writeJSON jsonutils.JSONwriter,
+	readJSON jsonutils.JSONreader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wholesaler, err := getAuthenticatedWholesaler(r, wholesalersService)
+		if err != nil {
+			handleWholesalerError(w, err, writeJSON)
+			return
+		}
+
+		productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Invalid product ID"}, http.StatusBadRequest, nil)
+			return
+		}
+
+		var req pricingRequest
+		if err := readJSON(w, r, &req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		if err := validatePricingRequest(req); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		product, err := productService.SetPricing(productID, wholesaler.Id, req.MinOrderQty, req.PriceTiers)
+		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrWholesalerProductNotFound):
+				writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusNotFound, nil)
+			case errors.Is(err, services.ErrTierNotCheaper):
+				writeJSON(w, jsonutils.Envelope{"error": "Each quantity break must be cheaper than the price below it"}, http.StatusBadRequest, nil)
+			case errors.Is(err, services.ErrTierBelowMinimum):
+				writeJSON(w, jsonutils.Envelope{"error": "Quantity breaks must be above the minimum order quantity"}, http.StatusBadRequest, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to set pricing"}, http.StatusInternalServerError, nil)
+			}
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"product": product}, http.StatusOK, nil)
+	}
+}
+
+func validatePricingRequest(req pricingRequest) error {
+	if req.MinOrderQty < 1 {
+		return errors.New("Minimum order quantity must be at least 1")
+	}
+	seen := make(map[int]bool)
+	for _, tier := range req.PriceTiers {
+		if tier.MinQty < 2 {
+			return errors.New("Quantity breaks must be for at least 2 units")
+		}
+		if seen[tier.MinQty] {
+			return errors.New("Quantity breaks must be for different quantities")
+		}
+		seen[tier.MinQty] = true
+		if tier.Price <= 0 {
+			return errors.New("Price must be greater than zero")
+		}
+	}
+	return nil
+}
@@ -15,11 +15,13 @@ import (
 )
 
 type productRequest struct {
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	StockQty    int     `json:"stock_qty"`
-	ImageURL    string  `json:"image_url"`
-	Description string  `json:"description"`
+	Name        string       `json:"name"`
+	Price       models.Money `json:"price"`
+	StockQty    int          `json:"stock_qty"`
+	ImageURL    string       `json:"image_url"`
+	Description string       `json:"description"`
+	CategoryID  int          `json:"category_id"`
+	Sku         string       `json:"sku"`
 }
 
 func CreateProduct(
@@ -54,10 +56,20 @@ func CreateProduct(
 			Stock_qty:     req.StockQty,
 			Image_url:     req.ImageURL,
 			Description:   req.Description,
+			CategoryId:    req.CategoryID,
+			Sku:           req.Sku,
 		}
 
 		created, err := productService.CreateProduct(product)
 		if err != nil {
+			if errors.Is(err, repositories.ErrCategoryNotFound) {
+				writeJSON(w, jsonutils.Envelope{"error": "Category not found"}, http.StatusBadRequest, nil)
+				return
+			}
+			if errors.Is(err, repositories.ErrDuplicateSKU) {
+				writeJSON(w, jsonutils.Envelope{"error": "SKU is already in use"}, http.StatusConflict, nil)
+				return
+			}
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to create product"}, http.StatusInternalServerError, nil)
 			return
 		}
@@ -106,6 +118,8 @@ func UpdateProduct(
 			Stock_qty:     req.StockQty,
 			Image_url:     req.ImageURL,
 			Description:   req.Description,
+			CategoryId:    req.CategoryID,
+			Sku:           req.Sku,
 		}
 
 		updated, err := productService.UpdateProduct(product)
@@ -114,6 +128,14 @@ func UpdateProduct(
 				writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusNotFound, nil)
 				return
 			}
+			if errors.Is(err, repositories.ErrCategoryNotFound) {
+				writeJSON(w, jsonutils.Envelope{"error": "Category not found"}, http.StatusBadRequest, nil)
+				return
+			}
+			if errors.Is(err, repositories.ErrDuplicateSKU) {
+				writeJSON(w, jsonutils.Envelope{"error": "SKU is already in use"}, http.StatusConflict, nil)
+				return
+			}
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to update product"}, http.StatusInternalServerError, nil)
 			return
 		}
@@ -214,6 +236,7 @@ func sanitizeProductRequest(req *productRequest) {
 	req.Name = strings.TrimSpace(req.Name)
 	req.Description = strings.TrimSpace(req.Description)
 	req.ImageURL = strings.TrimSpace(req.ImageURL)
+	req.Sku = strings.TrimSpace(req.Sku)
 }
 
 func validateProductRequest(req productRequest) error {
@@ -229,6 +252,9 @@ func validateProductRequest(req productRequest) error {
 	if req.ImageURL == "" {
 		return errors.New("Image URL is required")
 	}
+	if req.CategoryID < 0 {
+		return errors.New("Category ID cannot be negative")
+	}
 	return nil
 }
 
@@ -1,6 +1,8 @@
 package wholesaler_products
 
 import (
+	"Obsonarium-backend/internal/handlers/auth"
+	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
 	"Obsonarium-backend/internal/services"
 	"Obsonarium-backend/internal/utils/jsonutils"
@@ -11,20 +13,53 @@ import (
 	"github.com/go-chi/chi"
 )
 
-func GetProducts(productsService *services.WholesalerProductsService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+// GetProducts lists one page of the catalog. See models.ParseProductFilter for the query
+// parameters; pass next_cursor back as cursor to get the following page. A signed-in retailer
+// sees its negotiated prices, with the catalog price in list_price, and the products of the
+// private catalogs it was approved for.
+func GetProducts(productsService *services.WholesalerProductsService, priceListsService *services.PriceListsService, accessService *services.CatalogAccessService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		q := r.URL.Query().Get("q")
-		products, err := productsService.GetProducts(q)
+		filter, err := models.ParseProductFilter(r.URL.Query())
 		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusBadRequest, nil)
+			return
+		}
+
+		filter.ViewerID, err = accessService.ViewerID(auth.GetUserEmailFromContext(r))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch products"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		products, page, err := productsService.GetProducts(filter)
+		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrInvalidCursor):
+				writeJSON(w, jsonutils.Envelope{"error": "Invalid cursor"}, http.StatusBadRequest, nil)
+			case errors.Is(err, repositories.ErrUnsupportedSort):
+				writeJSON(w, jsonutils.Envelope{"error": "This catalog cannot be sorted by " + string(filter.Sort)}, http.StatusBadRequest, nil)
+			case errors.Is(err, repositories.ErrUnsupportedFilter):
+				writeJSON(w, jsonutils.Envelope{"error": "This catalog cannot be filtered by rating"}, http.StatusBadRequest, nil)
+			default:
+				writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch products"}, http.StatusInternalServerError, nil)
+			}
+			return
+		}
+
+		if err := priceListsService.ApplyToProducts(auth.GetUserEmailFromContext(r), products); err != nil {
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch products"}, http.StatusInternalServerError, nil)
 			return
 		}
 
-		writeJSON(w, jsonutils.Envelope{"products": products}, http.StatusOK, nil)
+		writeJSON(w, jsonutils.Envelope{"products": products, "next_cursor": page.NextCursor, "total": page.Total}, http.StatusOK, nil)
 	}
 }
 
-func GetProduct(productsService *services.WholesalerProductsService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
+// GetProduct returns a product along with its variant matrix: the option types buyers choose
+// from and the variants they resolve to. Both are empty for a product without variants. Prices
+// are negotiated ones for a signed-in retailer, as in GetProducts. A product of a private
+// catalog is not found for retailers that have not been approved for it.
+func GetProduct(productsService *services.WholesalerProductsService, variantsService *services.ProductVariantsService, priceListsService *services.PriceListsService, accessService *services.CatalogAccessService, writeJSON jsonutils.JSONwriter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		idParam := chi.URLParam(r, "id")
 		id, err := strconv.Atoi(idParam)
@@ -33,7 +68,13 @@ func GetProduct(productsService *services.WholesalerProductsService, writeJSON j
 			return
 		}
 
-		product, err := productsService.GetProduct(id)
+		viewerID, err := accessService.ViewerID(auth.GetUserEmailFromContext(r))
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch product"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		product, err := productsService.GetProduct(id, viewerID)
 		if err != nil {
 			if errors.Is(err, repositories.ErrWholesalerProductNotFound) {
 				writeJSON(w, jsonutils.Envelope{"error": "Product not found"}, http.StatusNotFound, nil)
@@ -43,6 +84,17 @@ func GetProduct(productsService *services.WholesalerProductsService, writeJSON j
 			return
 		}
 
-		writeJSON(w, jsonutils.Envelope{"product": product}, http.StatusOK, nil)
+		matrix, err := variantsService.GetVariantMatrix(id)
+		if err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch product"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		if err := priceListsService.ApplyToProduct(auth.GetUserEmailFromContext(r), product, matrix.Variants); err != nil {
+			writeJSON(w, jsonutils.Envelope{"error": "Failed to fetch product"}, http.StatusInternalServerError, nil)
+			return
+		}
+
+		writeJSON(w, jsonutils.Envelope{"product": product, "options": matrix.Options, "variants": matrix.Variants}, http.StatusOK, nil)
 	}
 }
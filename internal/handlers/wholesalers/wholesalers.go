@@ -2,6 +2,7 @@ package wholesalers
 
 import (
 	"Obsonarium-backend/internal/handlers/auth"
+	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
 	"Obsonarium-backend/internal/services"
 	"Obsonarium-backend/internal/utils/jsonutils"
@@ -74,6 +75,11 @@ type UpdateWholesalerRequest struct {
 	BusinessName string `json:"business_name"`
 	Phone        string `json:"phone"`
 	Address      string `json:"address"`
+	Currency     string `json:"currency"`
+	// MinOrderValue is optional, the current minimum is kept when it is left out
+	MinOrderValue *models.Money `json:"min_order_value"`
+	// PrivateCatalog is optional too; a private catalog is only shown to approved retailers
+	PrivateCatalog *bool `json:"private_catalog"`
 }
 
 // UpdateCurrentWholesaler updates the current authenticated wholesaler's profile (onboarding)
@@ -142,13 +148,32 @@ func UpdateCurrentWholesaler(wholesalersService *services.WholesalersService, wr
 			return
 		}
 
+		// Currency is optional, the current one is kept when it is left out
+		if req.Currency != "" {
+			currency, ok := models.NormalizeCurrency(req.Currency)
+			if !ok {
+				writeJSON(w, jsonutils.Envelope{"error": "Currency must be one of " + strings.Join(models.SupportedCurrencies, ", ")}, http.StatusBadRequest, nil)
+				return
+			}
+			req.Currency = currency
+		}
+
+		if req.MinOrderValue != nil && *req.MinOrderValue < 0 {
+			writeJSON(w, jsonutils.Envelope{"error": "Minimum order value cannot be negative"}, http.StatusBadRequest, nil)
+			return
+		}
+
 		// Name comes from Google OAuth, not from user input
-		wholesaler, err := wholesalersService.UpdateWholesaler(email, req.BusinessName, req.Phone, req.Address)
+		wholesaler, err := wholesalersService.UpdateWholesaler(email, req.BusinessName, req.Phone, req.Address, req.Currency, req.MinOrderValue, req.PrivateCatalog)
 		if err != nil {
 			if errors.Is(err, repositories.ErrWholesalerNotFound) {
 				writeJSON(w, jsonutils.Envelope{"error": "Wholesaler not found"}, http.StatusNotFound, nil)
 				return
 			}
+			if errors.Is(err, repositories.ErrCurrencyInUse) {
+				writeJSON(w, jsonutils.Envelope{"error": err.Error()}, http.StatusConflict, nil)
+				return
+			}
 			writeJSON(w, jsonutils.Envelope{"error": "Failed to update wholesaler"}, http.StatusInternalServerError, nil)
 			return
 		}
@@ -4,6 +4,16 @@ type CartItem struct {
 	Id         int             `json:"id"`
 	User_id    int             `json:"user_id"`
 	Product_id int             `json:"product_id"`
+	Variant_id int             `json:"variant_id,omitempty"`
 	Quantity   int             `json:"quantity"`
 	Product    RetailerProduct `json:"product"`
+	Variant    *ProductVariant `json:"variant,omitempty"`
+}
+
+// UnitPrice is the price of one unit of the line: the variant's if it names one, else the product's.
+func (c CartItem) UnitPrice() Money {
+	if c.Variant != nil {
+		return c.Variant.Price
+	}
+	return c.Product.Price
 }
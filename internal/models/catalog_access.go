@@ -0,0 +1,34 @@
+package models
+
+// CatalogAccessStatus is where a retailer's access to a wholesaler's catalog stands.
+type CatalogAccessStatus string
+
+const (
+	CatalogAccessPending  CatalogAccessStatus = "pending"
+	CatalogAccessApproved CatalogAccessStatus = "approved"
+	CatalogAccessRejected CatalogAccessStatus = "rejected"
+	CatalogAccessRevoked  CatalogAccessStatus = "revoked"
+)
+
+// IsValid reports whether s is one of the known statuses.
+func (s CatalogAccessStatus) IsValid() bool {
+	switch s {
+	case CatalogAccessPending, CatalogAccessApproved, CatalogAccessRejected, CatalogAccessRevoked:
+		return true
+	}
+	return false
+}
+
+// CatalogAccess is the relationship between a wholesaler and a retailer that has asked to see its
+// catalog. Only approved access counts, and only for wholesalers with a private catalog.
+type CatalogAccess struct {
+	Id             int                 `json:"id"`
+	WholesalerId   int                 `json:"wholesaler_id"`
+	WholesalerName string              `json:"wholesaler_name"`
+	RetailerId     int                 `json:"retailer_id"`
+	RetailerName   string              `json:"retailer_name"`
+	Status         CatalogAccessStatus `json:"status"`
+	Message        string              `json:"message"`
+	CreatedAt      string              `json:"created_at"`
+	UpdatedAt      string              `json:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// Category is a node of the category tree shared by the shop and wholesale catalogs.
+// ProductCount covers the category and all of its descendants in one catalog; it and Children
+// are only filled in when the tree is listed.
+type Category struct {
+	Id           int         `json:"id"`
+	ParentId     int         `json:"parent_id,omitempty"`
+	Name         string      `json:"name"`
+	Slug         string      `json:"slug"`
+	ProductCount int         `json:"product_count"`
+	Children     []*Category `json:"children,omitempty"`
+}
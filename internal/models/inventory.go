@@ -0,0 +1,44 @@
+package models
+
+// MovementReason says why a product's stock changed
+type MovementReason string
+
+const (
+	MovementAdjustment       MovementReason = "adjustment"
+	MovementSale             MovementReason = "sale"
+	MovementCancellation     MovementReason = "cancellation"
+	MovementRefundRestock    MovementReason = "refund_restock"
+	MovementWholesaleReceipt MovementReason = "wholesale_receipt"
+	MovementImport           MovementReason = "import"
+)
+
+// InventoryMovement is one change to the stock of a product, or of one of its variants when
+// VariantId is set. Quantity is negative for stock that left and StockAfter is the stock the
+// change left behind.
+type InventoryMovement struct {
+	Id         int            `json:"id"`
+	ProductId  int            `json:"product_id"`
+	VariantId  int            `json:"variant_id,omitempty"`
+	Quantity   int            `json:"quantity"`
+	StockAfter int            `json:"stock_after"`
+	Reason     MovementReason `json:"reason"`
+	Note       string         `json:"note,omitempty"`
+	OrderId    int            `json:"order_id,omitempty"`
+	CreatedAt  string         `json:"created_at"`
+}
+
+// StockBalance compares the stock held on a product, or on one of its variants, with the sum of
+// its movements. The two only differ if stock was changed without going through the ledger.
+type StockBalance struct {
+	VariantId  int  `json:"variant_id,omitempty"`
+	StockQty   int  `json:"stock_qty"`
+	LedgerQty  int  `json:"ledger_qty"`
+	Reconciled bool `json:"reconciled"`
+}
+
+// StockLedger is a product's stock movements, newest first, with its current balances.
+type StockLedger struct {
+	ProductId int                 `json:"product_id"`
+	Balances  []StockBalance      `json:"balances"`
+	Movements []InventoryMovement `json:"movements"`
+}
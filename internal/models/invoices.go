@@ -0,0 +1,77 @@
+package models
+
+// InvoiceStatus is where an invoice for an order placed on account stands. Overdue is an open
+// invoice whose due date has passed.
+type InvoiceStatus string
+
+const (
+	InvoiceOpen    InvoiceStatus = "open"
+	InvoiceOverdue InvoiceStatus = "overdue"
+	InvoicePaid    InvoiceStatus = "paid"
+	InvoiceVoid    InvoiceStatus = "void"
+)
+
+// IsValid reports whether s is one of the known statuses.
+func (s InvoiceStatus) IsValid() bool {
+	switch s {
+	case InvoiceOpen, InvoiceOverdue, InvoicePaid, InvoiceVoid:
+		return true
+	}
+	return false
+}
+
+// CreditTerms let a retailer order from a wholesaler on account instead of paying at checkout.
+// Outstanding is what the retailer owes on open and overdue invoices, which together with a new
+// order may not exceed CreditLimit.
+type CreditTerms struct {
+	Id             int    `json:"id"`
+	WholesalerId   int    `json:"wholesaler_id"`
+	WholesalerName string `json:"wholesaler_name"`
+	RetailerId     int    `json:"retailer_id"`
+	RetailerName   string `json:"retailer_name"`
+	CreditLimit    Money  `json:"credit_limit"`
+	Currency       string `json:"currency"`
+	TermsDays      int    `json:"terms_days"`
+	Outstanding    Money  `json:"outstanding"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+// Available is how much more the retailer can order on account.
+func (t CreditTerms) Available() Money {
+	if t.Outstanding >= t.CreditLimit {
+		return 0
+	}
+	return t.CreditLimit - t.Outstanding
+}
+
+type Invoice struct {
+	Id             int              `json:"id"`
+	OrderId        int              `json:"order_id"`
+	WholesalerId   int              `json:"wholesaler_id"`
+	WholesalerName string           `json:"wholesaler_name"`
+	RetailerId     int              `json:"retailer_id"`
+	RetailerName   string           `json:"retailer_name"`
+	Amount         Money            `json:"amount"`
+	AmountPaid     Money            `json:"amount_paid"`
+	Balance        Money            `json:"balance"`
+	Currency       string           `json:"currency"`
+	Status         InvoiceStatus    `json:"status"`
+	DueAt          string           `json:"due_at"`
+	PaidAt         string           `json:"paid_at,omitempty"`
+	CreatedAt      string           `json:"created_at"`
+	UpdatedAt      string           `json:"updated_at"`
+	Payments       []InvoicePayment `json:"payments,omitempty"`
+}
+
+// InvoicePayment is money received against an invoice outside the platform. Method and
+// Reference are free text, such as "bank transfer" and the transfer's reference number.
+type InvoicePayment struct {
+	Id         int    `json:"id"`
+	InvoiceId  int    `json:"invoice_id"`
+	Amount     Money  `json:"amount"`
+	Method     string `json:"method"`
+	Reference  string `json:"reference"`
+	ReceivedAt string `json:"received_at"`
+	CreatedAt  string `json:"created_at"`
+}
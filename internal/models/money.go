@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidMoney = errors.New("amount must be a decimal number with at most two decimal places")
+
+// Money is an amount in the minor unit of its currency, e.g. paise or cents. Every supported
+// currency has two decimal places. It is stored as BIGINT and reads and writes JSON as a decimal
+// number of major units, so 12.5 in a request body is 1250 here and is sent back as 12.50.
+type Money int64
+
+// ParseMoney reads a decimal amount of major units such as "12", "12.5" or "-0.99" exactly,
+// without going through float64.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || (hasFrac && (frac == "" || len(frac) > 2)) {
+		return 0, ErrInvalidMoney
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, ErrInvalidMoney
+		}
+	}
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMoney
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money(amount), nil
+}
+
+// Times returns the amount for qty units.
+func (m Money) Times(qty int) Money {
+	return m * Money(qty)
+}
+
+// Discount returns the amount less percent of it, rounded to the nearest minor unit.
+func (m Money) Discount(percent float64) Money {
+	return Money(math.Round(float64(m) * (100 - percent) / 100))
+}
+
+// Markup returns the amount plus percent of it, rounded to the nearest minor unit.
+func (m Money) Markup(percent float64) Money {
+	return Money(math.Round(float64(m) * (100 + percent) / 100))
+}
+
+// String formats the amount in major units with two decimal places.
+func (m Money) String() string {
+	sign := ""
+	amount := int64(m)
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// SupportedCurrencies are the ISO 4217 codes, lowercase as the payment gateway expects them,
+// that sellers can price in. All of them have two decimal places.
+var SupportedCurrencies = []string{"inr", "usd", "eur", "gbp", "aud", "cad", "sgd", "aed"}
+
+// NormalizeCurrency lowercases a currency code and reports whether it is supported.
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for _, supported := range SupportedCurrencies {
+		if code == supported {
+			return code, true
+		}
+	}
+	return code, false
+}
@@ -5,17 +5,36 @@ type OrderStatus string
 const (
 	OrderStatusPending   OrderStatus = "pending"
 	OrderStatusPaid      OrderStatus = "paid"
+	OrderStatusConfirmed OrderStatus = "confirmed"
 	OrderStatusFailed    OrderStatus = "failed"
 	OrderStatusShipped   OrderStatus = "shipped"
 	OrderStatusDelivered OrderStatus = "delivered"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRefunded  OrderStatus = "refunded"
 )
 
+type OrderActorType string
+
+const (
+	OrderActorConsumer   OrderActorType = "consumer"
+	OrderActorRetailer   OrderActorType = "retailer"
+	OrderActorWholesaler OrderActorType = "wholesaler"
+	OrderActorSystem     OrderActorType = "system"
+)
+
+// OrderActor identifies who moved an order to a new status. Id is 0 for the system actor.
+type OrderActor struct {
+	Type OrderActorType `json:"type"`
+	Id   int            `json:"id"`
+}
+
 type ConsumerOrder struct {
 	Id              int                 `json:"id"`
 	RetailerId      int                 `json:"retailer_id"`
 	UserId          int                 `json:"user_id"`
 	AddressId       int                 `json:"address_id"`
-	TotalPrice      float64             `json:"total_price"`
+	TotalPrice      Money               `json:"total_price"`
+	Currency        string              `json:"currency"`
 	Status          OrderStatus         `json:"status"`
 	StripeSessionId string              `json:"stripe_session_id"`
 	CreatedAt       string              `json:"created_at"`
@@ -24,12 +43,15 @@ type ConsumerOrder struct {
 }
 
 type ConsumerOrderItem struct {
-	Id        int              `json:"id"`
-	OrderId   int              `json:"order_id"`
-	ProductId int              `json:"product_id"`
-	Quantity  int              `json:"quantity"`
-	Price     float64          `json:"price"`
-	Product   *RetailerProduct `json:"product,omitempty"`
+	Id          int              `json:"id"`
+	OrderId     int              `json:"order_id"`
+	ProductId   int              `json:"product_id"`
+	VariantId   int              `json:"variant_id,omitempty"`
+	Quantity    int              `json:"quantity"`
+	Price       Money            `json:"price"`
+	RefundedQty int              `json:"refunded_qty"`
+	Product     *RetailerProduct `json:"product,omitempty"`
+	Variant     *ProductVariant  `json:"variant,omitempty"`
 }
 
 type RetailerOrder struct {
@@ -37,7 +59,8 @@ type RetailerOrder struct {
 	WholesalerId    int                 `json:"wholesaler_id"`
 	RetailerId      int                 `json:"retailer_id"`
 	AddressId       int                 `json:"address_id"`
-	TotalPrice      float64             `json:"total_price"`
+	TotalPrice      Money               `json:"total_price"`
+	Currency        string              `json:"currency"`
 	Status          OrderStatus         `json:"status"`
 	StripeSessionId string              `json:"stripe_session_id"`
 	CreatedAt       string              `json:"created_at"`
@@ -46,10 +69,44 @@ type RetailerOrder struct {
 }
 
 type RetailerOrderItem struct {
-	Id        int                `json:"id"`
-	OrderId   int                `json:"order_id"`
-	ProductId int                `json:"product_id"`
-	Quantity  int                `json:"quantity"`
-	Price     float64            `json:"price"`
-	Product   *WholesalerProduct `json:"product,omitempty"`
+	Id          int                `json:"id"`
+	OrderId     int                `json:"order_id"`
+	ProductId   int                `json:"product_id"`
+	VariantId   int                `json:"variant_id,omitempty"`
+	Quantity    int                `json:"quantity"`
+	Price       Money              `json:"price"`
+	RefundedQty int                `json:"refunded_qty"`
+	Product     *WholesalerProduct `json:"product,omitempty"`
+	Variant     *ProductVariant    `json:"variant,omitempty"`
+}
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// Refund is money returned to the buyer of a consumer or retailer order, covering some or all
+// of its item lines.
+type Refund struct {
+	Id              int          `json:"id"`
+	OrderId         int          `json:"order_id"`
+	Amount          Money        `json:"amount"`
+	Currency        string       `json:"currency"`
+	Reason          string       `json:"reason"`
+	Restock         bool         `json:"restock"`
+	Status          RefundStatus `json:"status"`
+	StripeRefundId  string       `json:"stripe_refund_id,omitempty"`
+	PaymentIntentId string       `json:"-"`
+	Actor           OrderActor   `json:"actor"`
+	CreatedAt       string       `json:"created_at"`
+	Items           []RefundItem `json:"items"`
+}
+
+type RefundItem struct {
+	OrderItemId int   `json:"order_item_id"`
+	Quantity    int   `json:"quantity"`
+	Amount      Money `json:"amount"`
 }
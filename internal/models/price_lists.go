@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// PriceList is a set of negotiated prices a wholesaler gives to the retailers and retailer groups
+// it is assigned to. DiscountPercent, when not 0, takes that much off every product of the
+// wholesaler; Items override it for single products.
+type PriceList struct {
+	Id              int            `json:"id"`
+	WholesalerId    int            `json:"wholesaler_id"`
+	Name            string         `json:"name"`
+	DiscountPercent float64        `json:"discount_percent"`
+	Items           PriceListItems `json:"items"`
+	RetailerIds     []int          `json:"retailer_ids"`
+	GroupIds        []int          `json:"group_ids"`
+}
+
+// PriceListItem sets the negotiated price of one product, either as a fixed Price or as a
+// DiscountPercent off its list price. Exactly one of them is set.
+type PriceListItem struct {
+	ProductId       int     `json:"product_id"`
+	Price           Money   `json:"price,omitempty"`
+	DiscountPercent float64 `json:"discount_percent,omitempty"`
+}
+
+// PriceListItems are read from the database as a JSON array with prices in minor units.
+type PriceListItems []PriceListItem
+
+func (items *PriceListItems) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*items = PriceListItems{}
+		return nil
+	default:
+		return errors.New("price list items must be JSON")
+	}
+
+	var rows []struct {
+		ProductId       int     `json:"product_id"`
+		Price           int64   `json:"price"`
+		DiscountPercent float64 `json:"discount_percent"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+	scanned := make(PriceListItems, len(rows))
+	for i, row := range rows {
+		scanned[i] = PriceListItem{ProductId: row.ProductId, Price: Money(row.Price), DiscountPercent: row.DiscountPercent}
+	}
+	*items = scanned
+	return nil
+}
+
+// RetailerGroup is a named set of retailers that a wholesaler assigns price lists to together.
+type RetailerGroup struct {
+	Id           int    `json:"id"`
+	WholesalerId int    `json:"wholesaler_id"`
+	Name         string `json:"name"`
+	RetailerIds  []int  `json:"retailer_ids"`
+}
+
+// PriceRule is what one of the price lists that apply to a retailer says about one product.
+// Price, when not 0, is a fixed price for the product itself; DiscountPercent comes off the
+// product's list price otherwise, and off the prices of its variants either way.
+type PriceRule struct {
+	PriceListId     int
+	PriceList       string
+	ProductId       int
+	Price           Money
+	DiscountPercent float64
+}
+
+// NegotiatedPrices are the price rules that apply to one retailer, by product ID. When several
+// lists cover a product the retailer gets the lowest price any of them gives.
+type NegotiatedPrices map[int][]PriceRule
+
+// NewNegotiatedPrices groups rules by the product they are for.
+func NewNegotiatedPrices(rules []PriceRule) NegotiatedPrices {
+	prices := make(NegotiatedPrices)
+	for _, rule := range rules {
+		prices[rule.ProductId] = append(prices[rule.ProductId], rule)
+	}
+	return prices
+}
+
+// Apply replaces the product's price with the best negotiated one, keeping the list price in
+// ListPrice. Quantity breaks still apply where they are cheaper; see PriceFor.
+func (n NegotiatedPrices) Apply(p *WholesalerProduct) {
+	best, list := p.Price, ""
+	for _, rule := range n[p.Id] {
+		price := rule.Price
+		if price == 0 {
+			price = p.Price.Discount(rule.DiscountPercent)
+		}
+		if price < best {
+			best, list = price, rule.PriceList
+		}
+	}
+	if best < p.Price {
+		p.ListPrice, p.Price, p.PriceList = p.Price, best, list
+	}
+}
+
+// ApplyToVariant takes the largest negotiated discount off the variant's price. Fixed prices are
+// for the product itself and do not apply to its variants.
+func (n NegotiatedPrices) ApplyToVariant(v *ProductVariant) {
+	var percent float64
+	for _, rule := range n[v.ProductId] {
+		if rule.DiscountPercent > percent {
+			percent = rule.DiscountPercent
+		}
+	}
+	if percent > 0 {
+		v.ListPrice, v.Price = v.Price, v.Price.Discount(percent)
+	}
+}
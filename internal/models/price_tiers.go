@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// PriceTier is a quantity break of a wholesale product: an order line of at least MinQty units
+// is charged Price per unit.
+type PriceTier struct {
+	MinQty int   `json:"min_qty"`
+	Price  Money `json:"price"`
+}
+
+// PriceTiers are a product's quantity breaks, ordered by MinQty. They are read from the database
+// as a JSON array with prices in minor units.
+type PriceTiers []PriceTier
+
+func (t *PriceTiers) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*t = PriceTiers{}
+		return nil
+	default:
+		return errors.New("price tiers must be JSON")
+	}
+
+	var rows []struct {
+		MinQty int   `json:"min_qty"`
+		Price  int64 `json:"price"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+	tiers := make(PriceTiers, len(rows))
+	for i, row := range rows {
+		tiers[i] = PriceTier{MinQty: row.MinQty, Price: Money(row.Price)}
+	}
+	*t = tiers
+	return nil
+}
+
+// MinimumIssueType names the wholesale minimum a cart does not meet.
+type MinimumIssueType string
+
+const (
+	MinimumOrderQty   MinimumIssueType = "min_order_qty"
+	MinimumOrderValue MinimumIssueType = "min_order_value"
+)
+
+// MinimumIssue describes a minimum of a wholesale order that a retailer's cart falls short of:
+// too few units of a product, or too small an order from a wholesaler.
+type MinimumIssue struct {
+	Type         MinimumIssueType `json:"type"`
+	WholesalerId int              `json:"wholesaler_id"`
+	ProductId    int              `json:"product_id,omitempty"`
+	Name         string           `json:"name"`
+	MinQty       int              `json:"min_qty,omitempty"`
+	Quantity     int              `json:"quantity,omitempty"`
+	MinValue     Money            `json:"min_value,omitempty"`
+	Value        Money            `json:"value,omitempty"`
+}
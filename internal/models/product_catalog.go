@@ -0,0 +1,52 @@
+package models
+
+// CatalogColumns are the CSV columns of a catalog import or export, in export order. Imports may
+// put them in any order and leave out the optional description and category_id.
+var CatalogColumns = []string{"sku", "name", "price", "stock_qty", "image_url", "description", "category_id"}
+
+// CatalogRow is one product of a seller's catalog as it appears in a CSV file. The SKU is the
+// seller's own and is what an import matches existing products on.
+type CatalogRow struct {
+	Sku         string
+	Name        string
+	Price       Money
+	StockQty    int
+	ImageURL    string
+	Description string
+	CategoryID  int
+}
+
+// CatalogLine is one data line of an uploaded catalog CSV.
+type CatalogLine struct {
+	Line int
+	Row  CatalogRow
+	// Error is why the line cannot be imported, or "" if it can
+	Error string
+}
+
+type CatalogImportAction string
+
+const (
+	CatalogImportCreate CatalogImportAction = "create"
+	CatalogImportUpdate CatalogImportAction = "update"
+)
+
+// CatalogImportRow reports what an import did, or would do, with one line of the file.
+type CatalogImportRow struct {
+	Line      int                 `json:"line"`
+	Sku       string              `json:"sku"`
+	Action    CatalogImportAction `json:"action,omitempty"`
+	ProductId int                 `json:"product_id,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// CatalogImportReport is the outcome of a catalog import. An import is all or nothing: Applied
+// is false on a dry run and whenever any line failed, in which case nothing was written.
+type CatalogImportReport struct {
+	DryRun  bool               `json:"dry_run"`
+	Applied bool               `json:"applied"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Failed  int                `json:"failed"`
+	Rows    []CatalogImportRow `json:"rows"`
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ProductSort is the order a catalog listing is returned in.
+type ProductSort string
+
+const (
+	ProductSortNewest    ProductSort = "newest"
+	ProductSortPriceAsc  ProductSort = "price_asc"
+	ProductSortPriceDesc ProductSort = "price_desc"
+	ProductSortRating    ProductSort = "rating"
+)
+
+func (s ProductSort) Valid() bool {
+	switch s {
+	case ProductSortNewest, ProductSortPriceAsc, ProductSortPriceDesc, ProductSortRating:
+		return true
+	}
+	return false
+}
+
+// ProductFilter selects one page of a catalog listing. Zero values mean "no filter"; MinPrice
+// and MaxPrice are nil when not set so that a bound of 0 can still be asked for.
+type ProductFilter struct {
+	Query    string
+	Sort     ProductSort
+	MinPrice *Money
+	MaxPrice *Money
+	InStock  bool
+	// MinRating leaves out products whose average rating is below it, including unreviewed ones
+	MinRating float64
+	SellerID  int
+	// CategoryID matches products in the category or any category below it
+	CategoryID int
+	Cursor     string
+	Limit      int
+	// ViewerID is the retailer browsing the wholesale catalog, 0 for anyone else. It is not read
+	// from the query string; private catalogs are left out unless the viewer has been approved.
+	ViewerID int
+}
+
+// PageInfo describes where a page sits in a listing. NextCursor is empty on the last page and
+// Total counts every match of the filter, not just this page.
+type PageInfo struct {
+	NextCursor string `json:"next_cursor"`
+	Total      int    `json:"total"`
+}
+
+// ParseProductFilter reads a ProductFilter from the query string of a catalog request:
+// q, sort, min_price, max_price, in_stock, min_rating, seller_id, cursor and limit.
+func ParseProductFilter(values url.Values) (ProductFilter, error) {
+	filter := ProductFilter{
+		Query:  strings.TrimSpace(values.Get("q")),
+		Sort:   ProductSort(values.Get("sort")),
+		Cursor: values.Get("cursor"),
+	}
+	if filter.Sort == "" {
+		filter.Sort = ProductSortNewest
+	}
+	if !filter.Sort.Valid() {
+		return ProductFilter{}, errors.New("sort must be one of newest, price_asc, price_desc, rating")
+	}
+
+	for _, bound := range []struct {
+		param string
+		dest  **Money
+	}{{"min_price", &filter.MinPrice}, {"max_price", &filter.MaxPrice}} {
+		raw := values.Get(bound.param)
+		if raw == "" {
+			continue
+		}
+		price, err := ParseMoney(raw)
+		if err != nil || price < 0 {
+			return ProductFilter{}, errors.New(bound.param + " must be a non-negative amount")
+		}
+		*bound.dest = &price
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return ProductFilter{}, errors.New("min_price cannot be greater than max_price")
+	}
+
+	if raw := values.Get("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ProductFilter{}, errors.New("in_stock must be true or false")
+		}
+		filter.InStock = inStock
+	}
+
+	if raw := values.Get("min_rating"); raw != "" {
+		minRating, err := strconv.ParseFloat(raw, 64)
+		if err != nil || minRating < 1 || minRating > 5 {
+			return ProductFilter{}, errors.New("min_rating must be between 1 and 5")
+		}
+		filter.MinRating = minRating
+	}
+
+	if raw := values.Get("seller_id"); raw != "" {
+		sellerID, err := strconv.Atoi(raw)
+		if err != nil || sellerID <= 0 {
+			return ProductFilter{}, errors.New("seller_id must be a positive integer")
+		}
+		filter.SellerID = sellerID
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return ProductFilter{}, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -1,13 +1,152 @@
 package models
 
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
 type ProductReview struct {
-	Id            int    `json:"id"`
-	Product_id    int    `json:"product_id"`
-	User_id       int    `json:"user_id"`
-	Reviewer_name string `json:"reviewer_name"`
-	Rating        int    `json:"rating"`
-	Comment       string `json:"comment"`
-	Created_at    string `json:"created_at"`
-	Updated_at    string `json:"updated_at"`
+	Id                int    `json:"id"`
+	Product_id        int    `json:"product_id"`
+	User_id           int    `json:"user_id"`
+	Reviewer_name     string `json:"reviewer_name"`
+	Rating            int    `json:"rating"`
+	Comment           string `json:"comment"`
+	Verified_purchase bool   `json:"verified_purchase"`
+	// Seller_reply is the retailer's public answer to the review, if it has posted one
+	Seller_reply      *string      `json:"seller_reply,omitempty"`
+	Seller_replied_at *string      `json:"seller_replied_at,omitempty"`
+	Helpful_count     int          `json:"helpful_count"`
+	Unhelpful_count   int          `json:"unhelpful_count"`
+	Images            ReviewImages `json:"images"`
+	Created_at        string       `json:"created_at"`
+	Updated_at        string       `json:"updated_at"`
+}
+
+// ReviewImage is a photo the author attached to a review.
+type ReviewImage struct {
+	Id  int    `json:"id"`
+	Url string `json:"url"`
+}
+
+// ReviewImages are read from the database as a JSON array of id and url.
+type ReviewImages []ReviewImage
+
+func (images *ReviewImages) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*images = ReviewImages{}
+		return nil
+	default:
+		return errors.New("review images must be JSON")
+	}
+
+	scanned := ReviewImages{}
+	if err := json.Unmarshal(data, &scanned); err != nil {
+		return err
+	}
+	*images = scanned
+	return nil
+}
+
+// RatingSummary is the average, count and star histogram of a product's reviews. Histogram[i]
+// counts the reviews of i+1 stars.
+type RatingSummary struct {
+	Average   float64 `json:"average"`
+	Count     int     `json:"count"`
+	Histogram [5]int  `json:"histogram"`
+}
+
+// ReviewReport is a customer's complaint about someone else's review.
+type ReviewReport struct {
+	Id         int    `json:"id"`
+	Review_id  int    `json:"review_id"`
+	User_id    int    `json:"user_id"`
+	Reason     string `json:"reason"`
+	Created_at string `json:"created_at"`
+}
+
+// ReportedReview is a review in a retailer's moderation queue: one of its products' reviews
+// that has open reports or has been hidden.
+type ReportedReview struct {
+	ProductReview
+	Product_name string         `json:"product_name"`
+	Hidden       bool           `json:"hidden"`
+	Reports      []ReviewReport `json:"reports"`
+}
+
+// ReviewSort is the order a product's reviews are returned in.
+type ReviewSort string
+
+const (
+	ReviewSortHelpful ReviewSort = "helpful"
+	ReviewSortNewest  ReviewSort = "newest"
+	ReviewSortHighest ReviewSort = "highest"
+	ReviewSortLowest  ReviewSort = "lowest"
+)
+
+func (s ReviewSort) Valid() bool {
+	switch s {
+	case ReviewSortHelpful, ReviewSortNewest, ReviewSortHighest, ReviewSortLowest:
+		return true
+	}
+	return false
+}
+
+// ReviewFilter selects one page of a product's reviews. Zero values mean "no filter".
+type ReviewFilter struct {
+	Sort ReviewSort
+	// Rating keeps only the reviews of this many stars
+	Rating       int
+	VerifiedOnly bool
+	Cursor       string
+	Limit        int
 }
 
+// ParseReviewFilter reads a ReviewFilter from the query string of a review feed request:
+// sort, rating, verified, cursor and limit.
+func ParseReviewFilter(values url.Values) (ReviewFilter, error) {
+	filter := ReviewFilter{
+		Sort:   ReviewSort(values.Get("sort")),
+		Cursor: values.Get("cursor"),
+	}
+	if filter.Sort == "" {
+		filter.Sort = ReviewSortNewest
+	}
+	if !filter.Sort.Valid() {
+		return ReviewFilter{}, errors.New("sort must be one of helpful, newest, highest, lowest")
+	}
+
+	if raw := values.Get("rating"); raw != "" {
+		rating, err := strconv.Atoi(raw)
+		if err != nil || rating < 1 || rating > 5 {
+			return ReviewFilter{}, errors.New("rating must be between 1 and 5")
+		}
+		filter.Rating = rating
+	}
+
+	if raw := values.Get("verified"); raw != "" {
+		verified, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ReviewFilter{}, errors.New("verified must be true or false")
+		}
+		filter.VerifiedOnly = verified
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return ReviewFilter{}, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
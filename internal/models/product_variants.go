@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// VariantOptions maps each option type of a product to the value a variant has for it, e.g.
+// {"size": "M", "colour": "red"}. It is stored as JSONB.
+type VariantOptions map[string]string
+
+// Value encodes the options as a JSON string; lib/pq would send []byte as bytea.
+func (o VariantOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(o)
+	return string(data), err
+}
+
+func (o *VariantOptions) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*o = VariantOptions{}
+		return nil
+	default:
+		return errors.New("variant options must be JSON")
+	}
+	return json.Unmarshal(data, o)
+}
+
+// ProductVariant is one purchasable combination of a product's options. Price and stock replace
+// the product's own once a product has variants; Currency is always the product's.
+type ProductVariant struct {
+	Id        int            `json:"id"`
+	ProductId int            `json:"product_id"`
+	Sku       string         `json:"sku"`
+	Options   VariantOptions `json:"options"`
+	Price     Money          `json:"price"`
+	Currency  string         `json:"currency"`
+	Stock_qty int            `json:"stock_qty"`
+	Image_url string         `json:"image_url"`
+	// ListPrice is set to the catalog price when Price is a negotiated one
+	ListPrice Money `json:"list_price,omitempty"`
+}
+
+// ProductOption is an option type of a product and the values its variants take for it, in the
+// order the variants were added.
+type ProductOption struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// VariantMatrix is what a product detail page needs to let a buyer pick a variant.
+type VariantMatrix struct {
+	Options  []ProductOption  `json:"options"`
+	Variants []ProductVariant `json:"variants"`
+}
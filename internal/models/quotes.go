@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+type QuoteStatus string
+
+const (
+	QuoteRequested QuoteStatus = "requested"
+	QuoteOffered   QuoteStatus = "offered"
+	QuoteCountered QuoteStatus = "countered"
+	QuoteAccepted  QuoteStatus = "accepted"
+	QuoteDeclined  QuoteStatus = "declined"
+	// QuoteExpired is never stored: it is how an offer reads once its expiry has passed
+	QuoteExpired QuoteStatus = "expired"
+)
+
+// Quote is a negotiation between a retailer and a wholesaler over a wholesale order. Items are
+// the terms currently on the table; Revisions, when loaded, are every request, offer and
+// counter-offer made, oldest first. OrderId is set once the retailer accepts an offer.
+type Quote struct {
+	Id             int             `json:"id"`
+	WholesalerId   int             `json:"wholesaler_id"`
+	WholesalerName string          `json:"wholesaler_name"`
+	RetailerId     int             `json:"retailer_id"`
+	RetailerName   string          `json:"retailer_name"`
+	Status         QuoteStatus     `json:"status"`
+	Currency       string          `json:"currency"`
+	Total          Money           `json:"total"`
+	ExpiresAt      string          `json:"expires_at,omitempty"`
+	OrderId        int             `json:"order_id,omitempty"`
+	Items          QuoteItems      `json:"items"`
+	Revisions      []QuoteRevision `json:"revisions,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	UpdatedAt      string          `json:"updated_at"`
+}
+
+// QuoteItem is a line of a quote. Price is per unit, and 0 on a request that names no price.
+type QuoteItem struct {
+	ProductId int    `json:"product_id"`
+	VariantId int    `json:"variant_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Sku       string `json:"sku,omitempty"`
+	Quantity  int    `json:"quantity"`
+	Price     Money  `json:"price"`
+}
+
+// QuoteItems are read from the database as a JSON array with prices in minor units.
+type QuoteItems []QuoteItem
+
+func (items *QuoteItems) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*items = QuoteItems{}
+		return nil
+	default:
+		return errors.New("quote items must be JSON")
+	}
+
+	var rows []struct {
+		ProductId int    `json:"product_id"`
+		VariantId int    `json:"variant_id"`
+		Name      string `json:"name"`
+		Sku       string `json:"sku"`
+		Quantity  int    `json:"quantity"`
+		Price     int64  `json:"price"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+	scanned := make(QuoteItems, len(rows))
+	for i, row := range rows {
+		scanned[i] = QuoteItem{
+			ProductId: row.ProductId,
+			VariantId: row.VariantId,
+			Name:      row.Name,
+			Sku:       row.Sku,
+			Quantity:  row.Quantity,
+			Price:     Money(row.Price),
+		}
+	}
+	*items = scanned
+	return nil
+}
+
+// Total is what the items come to at their quoted prices.
+func (items QuoteItems) Total() Money {
+	var total Money
+	for _, item := range items {
+		total += item.Price.Times(item.Quantity)
+	}
+	return total
+}
+
+// QuoteRevision is one set of terms put forward on a quote by Author.
+type QuoteRevision struct {
+	Id        int            `json:"id"`
+	Author    OrderActorType `json:"author"`
+	Items     QuoteItems     `json:"items"`
+	Note      string         `json:"note"`
+	ExpiresAt string         `json:"expires_at,omitempty"`
+	CreatedAt string         `json:"created_at"`
+}
@@ -4,6 +4,27 @@ type RetailerCartItem struct {
 	Id          int               `json:"id"`
 	Retailer_id int               `json:"retailer_id"`
 	Product_id  int               `json:"product_id"`
+	Variant_id  int               `json:"variant_id,omitempty"`
 	Quantity    int               `json:"quantity"`
 	Product     WholesalerProduct `json:"product"`
+	Variant     *ProductVariant   `json:"variant,omitempty"`
+	// Unit_price is UnitPrice at the line's current quantity, filled in when the cart is priced
+	Unit_price Money `json:"unit_price"`
+}
+
+// UnitPrice is the price of one unit of the line: the variant's if it names one, else the
+// product's at the quantity break the line reaches.
+func (c RetailerCartItem) UnitPrice() Money {
+	if c.Variant != nil {
+		return c.Variant.Price
+	}
+	return c.Product.PriceFor(c.Quantity)
+}
+
+// RetailerCart is a retailer's cart priced at the quantity breaks it reaches, with the wholesale
+// minimums it does not meet yet. It cannot be checked out while Issues is not empty.
+type RetailerCart struct {
+	Items  []RetailerCartItem `json:"items"`
+	Total  Money              `json:"total"`
+	Issues []MinimumIssue     `json:"issues"`
 }
@@ -1,11 +1,20 @@
 package models
 
 type RetailerProduct struct {
-	Id          int     `json:"id"`
-	Retailer_id int     `json:"retailer_id"`
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Stock_qty   int     `json:"stock_qty"`
-	Image_url   string  `json:"image_url"`
-	Description string  `json:"description"`
+	Id          int    `json:"id"`
+	Retailer_id int    `json:"retailer_id"`
+	Name        string `json:"name"`
+	Price       Money  `json:"price"`
+	Currency    string `json:"currency"`
+	Stock_qty   int    `json:"stock_qty"`
+	Image_url   string `json:"image_url"`
+	Description string `json:"description"`
+	CategoryId  int    `json:"category_id,omitempty"`
+	Sku         string `json:"sku,omitempty"`
+	// SourceProductId is the wholesaler product this product is restocked from, see LinkSourceProduct
+	SourceProductId int `json:"source_product_id,omitempty"`
+	// ReorderThreshold is the available stock below which the retailer is alerted, 0 for never
+	ReorderThreshold int `json:"reorder_threshold"`
+	// Rating summarises the product's reviews on shop listings and product pages
+	Rating *RatingSummary `json:"rating,omitempty"`
 }
@@ -7,4 +7,5 @@ type Retailer struct {
 	Email        string `json:"email"`
 	Phone        string `json:"phone"`
 	Address      string `json:"address"`
+	Currency     string `json:"currency"`
 }
@@ -0,0 +1,12 @@
+package models
+
+// StockShortage describes an order line that could not be reserved because the product, or
+// the variant of it the line names, does not have enough unreserved stock.
+type StockShortage struct {
+	ProductId int    `json:"product_id"`
+	VariantId int    `json:"variant_id,omitempty"`
+	Sku       string `json:"sku,omitempty"`
+	Name      string `json:"name"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+}
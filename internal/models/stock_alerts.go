@@ -0,0 +1,61 @@
+package models
+
+// LowStockProduct is a retailer product whose available stock has fallen below its reorder
+// threshold and that the retailer has not been alerted about yet.
+type LowStockProduct struct {
+	RetailerId       int    `json:"retailer_id"`
+	RetailerEmail    string `json:"-"`
+	ProductId        int    `json:"product_id"`
+	Name             string `json:"name"`
+	Sku              string `json:"sku,omitempty"`
+	Available        int    `json:"available"`
+	ReorderThreshold int    `json:"reorder_threshold"`
+}
+
+// ReorderCandidate is a retailer product linked to a wholesale source, with what is needed to
+// size a reorder of it: its available stock, the units it sold over the sales window and the
+// units already ordered from the wholesaler but not delivered yet.
+type ReorderCandidate struct {
+	ProductId        int    `json:"product_id"`
+	Name             string `json:"name"`
+	SourceProductId  int    `json:"wholesaler_product_id"`
+	ReorderThreshold int    `json:"reorder_threshold"`
+	Available        int    `json:"available"`
+	SoldQty          int    `json:"sold_qty"`
+	IncomingQty      int    `json:"incoming_qty"`
+	SalesWindowDays  int    `json:"sales_window_days"`
+	// SourceHasVariants is set when the wholesale product is sold only through its variants
+	SourceHasVariants bool `json:"-"`
+}
+
+// SuggestedQty is how many units to order so the product stays at its reorder threshold for
+// coverDays more days of sales at the rate of the sales window, counting the stock on hand and
+// on its way. It is 0 when nothing needs ordering.
+func (c ReorderCandidate) SuggestedQty(coverDays int) int {
+	demand := 0
+	if c.SalesWindowDays > 0 {
+		// Round up: a product that sells at all should not be suggested 0 units of demand
+		demand = (c.SoldQty*coverDays + c.SalesWindowDays - 1) / c.SalesWindowDays
+	}
+	need := c.ReorderThreshold + demand - c.Available - c.IncomingQty
+	if need < 0 {
+		return 0
+	}
+	return need
+}
+
+// ReorderSuggestion is a candidate with the quantity proposed for it, which is at least the
+// wholesale product's minimum order quantity.
+type ReorderSuggestion struct {
+	ReorderCandidate
+	Quantity int `json:"quantity"`
+}
+
+// ReorderProposal is a suggested retailer cart: the suggestions, and the lines they make priced
+// as the retailer's cart would be. NeedsVariant lists the suggestions whose wholesale product is
+// sold in variants; they are left out of the cart until the retailer picks which to order.
+type ReorderProposal struct {
+	Suggestions  []ReorderSuggestion `json:"suggestions"`
+	NeedsVariant []ReorderSuggestion `json:"needs_variant"`
+	Cart         *RetailerCart       `json:"cart"`
+}
@@ -1,11 +1,32 @@
 package models
 
 type WholesalerProduct struct {
-	Id            int     `json:"id"`
-	Wholesaler_id int     `json:"wholesaler_id"`
-	Name          string  `json:"name"`
-	Price         float64 `json:"price"`
-	Stock_qty     int     `json:"stock_qty"`
-	Image_url     string  `json:"image_url"`
-	Description   string  `json:"description"`
+	Id            int    `json:"id"`
+	Wholesaler_id int    `json:"wholesaler_id"`
+	Name          string `json:"name"`
+	Price         Money  `json:"price"`
+	Currency      string `json:"currency"`
+	Stock_qty     int    `json:"stock_qty"`
+	Image_url     string `json:"image_url"`
+	Description   string `json:"description"`
+	CategoryId    int    `json:"category_id,omitempty"`
+	Sku           string `json:"sku,omitempty"`
+	// MinOrderQty is the fewest units of the product an order may contain
+	MinOrderQty int        `json:"min_order_qty"`
+	PriceTiers  PriceTiers `json:"price_tiers"`
+	// ListPrice is set to the catalog price when Price is a negotiated one, see NegotiatedPrices
+	ListPrice Money  `json:"list_price,omitempty"`
+	PriceList string `json:"price_list,omitempty"`
+}
+
+// PriceFor is the unit price of the product on an order line of qty units: the price of the
+// largest quantity break qty reaches, or Price below the first one or when Price is lower.
+func (p WholesalerProduct) PriceFor(qty int) Money {
+	price := p.Price
+	for _, tier := range p.PriceTiers {
+		if qty >= tier.MinQty && tier.Price < price {
+			price = tier.Price
+		}
+	}
+	return price
 }
@@ -7,5 +7,9 @@ type Wholesaler struct {
 	Email        string `json:"email"`
 	Phone        string `json:"phone"`
 	Address      string `json:"address"`
+	Currency     string `json:"currency"`
+	// MinOrderValue is the smallest order the wholesaler accepts, 0 for none
+	MinOrderValue Money `json:"min_order_value"`
+	// PrivateCatalog limits the wholesaler's catalog to the retailers it has approved
+	PrivateCatalog bool `json:"private_catalog"`
 }
-
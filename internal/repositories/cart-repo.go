@@ -10,9 +10,9 @@ var ErrCartItemNotFound = errors.New("cart item not found")
 
 type ICartRepo interface {
 	GetCartItemsByUserID(userID int) ([]models.CartItem, error)
-	AddCartItem(userID int, productID int, quantity int) (int, error)
-	RemoveCartItem(userID int, productID int) error
-	DecreaseCartItem(userID int, productID int) (int, error)
+	AddCartItem(userID int, productID int, variantID int, quantity int) (int, error)
+	RemoveCartItem(userID int, productID int, variantID int) error
+	DecreaseCartItem(userID int, productID int, variantID int) (int, error)
 	GetCartNumber(userID int) (int, error)
 }
 
@@ -26,10 +26,12 @@ func NewCartRepo(db *sql.DB) *CartRepo {
 
 func (repo *CartRepo) GetCartItemsByUserID(userID int) ([]models.CartItem, error) {
 	query := `
-		SELECT c.id, c.user_id, c.product_id, c.quantity,
-			   p.id, p.retailer_id, p.name, p.price, p.stock_qty, p.image_url, p.description
+		SELECT c.id, c.user_id, c.product_id, COALESCE(c.variant_id, 0), c.quantity,
+			   p.id, p.retailer_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description,
+			   v.id, v.sku, v.options, v.price, v.stock_qty, v.image_url
 		FROM cart_items c
 		JOIN retailer_products p ON p.id = c.product_id
+		LEFT JOIN retailer_product_variants v ON v.id = c.variant_id
 		WHERE c.user_id = $1
 		ORDER BY c.id`
 
@@ -43,23 +45,27 @@ func (repo *CartRepo) GetCartItemsByUserID(userID int) ([]models.CartItem, error
 
 	for rows.Next() {
 		var item models.CartItem
-		// scan cart item fields then product fields
-		err := rows.Scan(
+		var variant lineVariant
+		// scan cart item fields, then product fields, then the variant's
+		err := rows.Scan(append([]any{
 			&item.Id,
 			&item.User_id,
 			&item.Product_id,
+			&item.Variant_id,
 			&item.Quantity,
 			&item.Product.Id,
 			&item.Product.Retailer_id,
 			&item.Product.Name,
 			&item.Product.Price,
+			&item.Product.Currency,
 			&item.Product.Stock_qty,
 			&item.Product.Image_url,
 			&item.Product.Description,
-		)
+		}, variant.dest()...)...)
 		if err != nil {
 			return nil, err
 		}
+		item.Variant = variant.variant(item.Product.Id, item.Product.Currency)
 		cartItems = append(cartItems, item)
 	}
 
@@ -70,32 +76,44 @@ func (repo *CartRepo) GetCartItemsByUserID(userID int) ([]models.CartItem, error
 	return cartItems, nil
 }
 
-func (repo *CartRepo) AddCartItem(userID int, productID int, quantity int) (int, error) {
+// AddCartItem adds quantity units of a product to the cart. variantID is 0 for a product
+// without variants and must name one of its variants otherwise.
+func (repo *CartRepo) AddCartItem(userID int, productID int, variantID int, quantity int) (int, error) {
 	query := `
-		INSERT INTO cart_items (user_id, product_id, quantity)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, product_id) DO UPDATE
+		INSERT INTO cart_items (user_id, product_id, variant_id, quantity)
+		SELECT $1::int, $2::int, NULLIF($3::int, 0), $4::int
+		WHERE CASE WHEN $3 = 0
+			THEN NOT EXISTS (SELECT 1 FROM retailer_product_variants WHERE product_id = $2)
+			ELSE EXISTS (SELECT 1 FROM retailer_product_variants WHERE id = $3 AND product_id = $2)
+		END
+		ON CONFLICT (user_id, product_id, (COALESCE(variant_id, 0))) DO UPDATE
 		SET quantity = cart_items.quantity + EXCLUDED.quantity
 		RETURNING quantity
 		`
 
 	var newQuantity int
-	err := repo.DB.QueryRow(query, userID, productID, quantity).Scan(&newQuantity)
+	err := repo.DB.QueryRow(query, userID, productID, variantID, quantity).Scan(&newQuantity)
+	if errors.Is(err, sql.ErrNoRows) {
+		if variantID == 0 {
+			return 0, ErrVariantRequired
+		}
+		return 0, ErrVariantNotFound
+	}
 
 	return newQuantity, err
 }
 
-func (repo *CartRepo) DecreaseCartItem(userID int, productID int) (int, error) {
+func (repo *CartRepo) DecreaseCartItem(userID int, productID int, variantID int) (int, error) {
 	// First decrease quantity
 	query := `
 		UPDATE cart_items
 		SET quantity = quantity - 1
-		WHERE user_id = $1 AND product_id = $2
+		WHERE user_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3
 		RETURNING quantity
 	`
 
 	var newQty int
-	err := repo.DB.QueryRow(query, userID, productID).Scan(&newQty)
+	err := repo.DB.QueryRow(query, userID, productID, variantID).Scan(&newQty)
 
 	if err == sql.ErrNoRows {
 		// no cart item found
@@ -109,8 +127,8 @@ func (repo *CartRepo) DecreaseCartItem(userID int, productID int) (int, error) {
 	// If quantity is now 0 → delete the row
 	if newQty <= 0 {
 		_, err := repo.DB.Exec(
-			`DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2`,
-			userID, productID,
+			`DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3`,
+			userID, productID, variantID,
 		)
 		return newQty, err
 	}
@@ -118,12 +136,12 @@ func (repo *CartRepo) DecreaseCartItem(userID int, productID int) (int, error) {
 	return newQty, nil
 }
 
-func (repo *CartRepo) RemoveCartItem(userID int, productID int) error {
+func (repo *CartRepo) RemoveCartItem(userID int, productID int, variantID int) error {
 	query := `
 		DELETE FROM cart_items
-		WHERE user_id = $1 AND product_id = $2`
+		WHERE user_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3`
 
-	result, err := repo.DB.Exec(query, userID, productID)
+	result, err := repo.DB.Exec(query, userID, productID, variantID)
 	if err != nil {
 		return err
 	}
@@ -31,11 +31,13 @@ func TestCartRepo_GetCartItemsByUserID(t *testing.T) {
 
 	t.Run("successful retrieval", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
-			"c.id", "c.user_id", "c.product_id", "c.quantity",
-			"p.id", "p.retailer_id", "p.name", "p.price", "p.stock_qty", "p.image_url", "p.description",
+			"c.id", "c.user_id", "c.product_id", "c.variant_id", "c.quantity",
+			"p.id", "p.retailer_id", "p.name", "p.price", "p.currency", "p.stock_qty", "p.image_url", "p.description",
+			"v.id", "v.sku", "v.options", "v.price", "v.stock_qty", "v.image_url",
 		}).
-			AddRow(1, 1, 1, 2, 1, 1, "Product", 99.99, 10, "https://example.com/img.jpg", "Description")
-		mock.ExpectQuery("SELECT c.id, c.user_id, c.product_id, c.quantity").
+			AddRow(1, 1, 1, 0, 2, 1, 1, "Product", 9999, "inr", 10, "https://example.com/img.jpg", "Description", nil, nil, nil, nil, nil, nil).
+			AddRow(2, 1, 2, 7, 1, 2, 1, "Shirt", 1999, "inr", 0, "", "Shirt", 7, "SHIRT-M", []byte(`{"size":"M"}`), 2499, 4, nil)
+		mock.ExpectQuery("SELECT c.id, c.user_id, c.product_id").
 			WithArgs(1).
 			WillReturnRows(rows)
 
@@ -43,12 +45,21 @@ func TestCartRepo_GetCartItemsByUserID(t *testing.T) {
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
-		if len(items) != 1 {
-			t.Errorf("Expected 1 cart item, got %d", len(items))
+		if len(items) != 2 {
+			t.Fatalf("Expected 2 cart items, got %d", len(items))
 		}
 		if items[0].Quantity != 2 {
 			t.Errorf("Expected quantity 2, got %d", items[0].Quantity)
 		}
+		if items[0].Variant != nil {
+			t.Errorf("Expected no variant, got %+v", items[0].Variant)
+		}
+		if items[1].Variant == nil || items[1].Variant.Sku != "SHIRT-M" || items[1].Variant.Options["size"] != "M" {
+			t.Errorf("Expected variant SHIRT-M, got %+v", items[1].Variant)
+		}
+		if items[1].UnitPrice() != 2499 {
+			t.Errorf("Expected the variant's price 2499, got %d", items[1].UnitPrice())
+		}
 	})
 }
 
@@ -64,10 +75,10 @@ func TestCartRepo_AddCartItem(t *testing.T) {
 	t.Run("successful add", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"quantity"}).AddRow(3)
 		mock.ExpectQuery("INSERT INTO cart_items").
-			WithArgs(1, 1, 2).
+			WithArgs(1, 1, 0, 2).
 			WillReturnRows(rows)
 
-		qty, err := repo.AddCartItem(1, 1, 2)
+		qty, err := repo.AddCartItem(1, 1, 0, 2)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -75,6 +86,28 @@ func TestCartRepo_AddCartItem(t *testing.T) {
 			t.Errorf("Expected quantity 3, got %d", qty)
 		}
 	})
+
+	t.Run("product sold in variants", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO cart_items").
+			WithArgs(1, 2, 0, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity"}))
+
+		_, err := repo.AddCartItem(1, 2, 0, 1)
+		if !IsError(err, ErrVariantRequired) {
+			t.Errorf("Expected ErrVariantRequired, got %v", err)
+		}
+	})
+
+	t.Run("variant of another product", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO cart_items").
+			WithArgs(1, 2, 9, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"quantity"}))
+
+		_, err := repo.AddCartItem(1, 2, 9, 1)
+		if !IsError(err, ErrVariantNotFound) {
+			t.Errorf("Expected ErrVariantNotFound, got %v", err)
+		}
+	})
 }
 
 func TestCartRepo_DecreaseCartItem(t *testing.T) {
@@ -89,10 +122,10 @@ func TestCartRepo_DecreaseCartItem(t *testing.T) {
 	t.Run("successful decrease", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"quantity"}).AddRow(1)
 		mock.ExpectQuery("UPDATE cart_items").
-			WithArgs(1, 1).
+			WithArgs(1, 1, 0).
 			WillReturnRows(rows)
 
-		qty, err := repo.DecreaseCartItem(1, 1)
+		qty, err := repo.DecreaseCartItem(1, 1, 0)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -103,10 +136,10 @@ func TestCartRepo_DecreaseCartItem(t *testing.T) {
 
 	t.Run("item not found", func(t *testing.T) {
 		mock.ExpectQuery("UPDATE cart_items").
-			WithArgs(1, 999).
+			WithArgs(1, 999, 0).
 			WillReturnError(sql.ErrNoRows)
 
-		_, err := repo.DecreaseCartItem(1, 999)
+		_, err := repo.DecreaseCartItem(1, 999, 0)
 		if err == nil {
 			t.Fatal("Expected error, got nil")
 		}
@@ -127,10 +160,10 @@ func TestCartRepo_RemoveCartItem(t *testing.T) {
 
 	t.Run("successful removal", func(t *testing.T) {
 		mock.ExpectExec("DELETE FROM cart_items").
-			WithArgs(1, 1).
+			WithArgs(1, 1, 0).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.RemoveCartItem(1, 1)
+		err := repo.RemoveCartItem(1, 1, 0)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -138,10 +171,10 @@ func TestCartRepo_RemoveCartItem(t *testing.T) {
 
 	t.Run("item not found", func(t *testing.T) {
 		mock.ExpectExec("DELETE FROM cart_items").
-			WithArgs(1, 999).
+			WithArgs(1, 999, 0).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 
-		err := repo.RemoveCartItem(1, 999)
+		err := repo.RemoveCartItem(1, 999, 0)
 		if err == nil {
 			t.Fatal("Expected error, got nil")
 		}
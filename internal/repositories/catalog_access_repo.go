@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCatalogAccessNotFound = errors.New("catalog access request not found")
+	ErrCatalogAccessExists   = errors.New("catalog access has already been requested or granted")
+	ErrCatalogAccessDenied   = errors.New("retailer has not been approved for this catalog")
+	ErrInvalidAccessChange   = errors.New("catalog access cannot change from its current status")
+)
+
+type ICatalogAccessRepo interface {
+	RequestAccess(wholesalerID int, retailerID int, message string) (*models.CatalogAccess, error)
+	GetAccessByRetailer(retailerID int) ([]models.CatalogAccess, error)
+	GetAccessByWholesaler(wholesalerID int, status models.CatalogAccessStatus) ([]models.CatalogAccess, error)
+	SetStatus(wholesalerID int, retailerID int, from []models.CatalogAccessStatus, to models.CatalogAccessStatus) (*models.CatalogAccess, error)
+	CanViewWholesaler(retailerID int, wholesalerID int) (bool, error)
+	CanViewProduct(retailerID int, productID int) (bool, error)
+}
+
+type CatalogAccessRepo struct {
+	DB *sql.DB
+}
+
+func NewCatalogAccessRepo(db *sql.DB) *CatalogAccessRepo {
+	return &CatalogAccessRepo{DB: db}
+}
+
+const catalogAccessColumns = `a.id, a.wholesaler_id, COALESCE(w.business_name, ''), a.retailer_id, COALESCE(r.business_name, ''),
+		a.status, a.message, a.created_at, a.updated_at`
+
+const catalogAccessFrom = `catalog_access a
+		JOIN wholesalers w ON w.id = a.wholesaler_id
+		JOIN retailers r ON r.id = a.retailer_id`
+
+func scanCatalogAccess(scan func(dest ...any) error) (*models.CatalogAccess, error) {
+	var access models.CatalogAccess
+	err := scan(&access.Id, &access.WholesalerId, &access.WholesalerName, &access.RetailerId, &access.RetailerName,
+		&access.Status, &access.Message, &access.CreatedAt, &access.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &access, nil
+}
+
+// RequestAccess records a retailer's request to see a wholesaler's catalog. A retailer that was
+// rejected or revoked may ask again, which makes the request pending once more; one that is
+// pending or approved already gets ErrCatalogAccessExists.
+func (repo *CatalogAccessRepo) RequestAccess(wholesalerID int, retailerID int, message string) (*models.CatalogAccess, error) {
+	query := `
+		INSERT INTO catalog_access (wholesaler_id, retailer_id, message)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (wholesaler_id, retailer_id) DO UPDATE
+		SET status = 'pending', message = EXCLUDED.message, updated_at = NOW()
+		WHERE catalog_access.status IN ('rejected', 'revoked')
+		RETURNING id`
+
+	var id int
+	err := repo.DB.QueryRow(query, wholesalerID, retailerID, message).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCatalogAccessExists
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" && strings.HasSuffix(pqErr.Constraint, "_wholesaler_id_fkey") {
+			return nil, ErrWholesalerNotFound
+		}
+		return nil, err
+	}
+	return repo.getAccess(`a.id = $1`, id)
+}
+
+func (repo *CatalogAccessRepo) getAccess(condition string, args ...any) (*models.CatalogAccess, error) {
+	row := repo.DB.QueryRow(`SELECT `+catalogAccessColumns+` FROM `+catalogAccessFrom+` WHERE `+condition, args...)
+	access, err := scanCatalogAccess(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCatalogAccessNotFound
+	}
+	return access, err
+}
+
+func (repo *CatalogAccessRepo) listAccess(condition string, args ...any) ([]models.CatalogAccess, error) {
+	rows, err := repo.DB.Query(`SELECT `+catalogAccessColumns+` FROM `+catalogAccessFrom+` WHERE `+condition+` ORDER BY a.updated_at DESC, a.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.CatalogAccess{}
+	for rows.Next() {
+		access, err := scanCatalogAccess(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *access)
+	}
+	return list, rows.Err()
+}
+
+// GetAccessByRetailer returns every catalog the retailer has asked to see, most recent first.
+func (repo *CatalogAccessRepo) GetAccessByRetailer(retailerID int) ([]models.CatalogAccess, error) {
+	return repo.listAccess(`a.retailer_id = $1`, retailerID)
+}
+
+// GetAccessByWholesaler returns the retailers that have asked to see the wholesaler's catalog,
+// most recent first, optionally only those with the given status.
+func (repo *CatalogAccessRepo) GetAccessByWholesaler(wholesalerID int, status models.CatalogAccessStatus) ([]models.CatalogAccess, error) {
+	if status == "" {
+		return repo.listAccess(`a.wholesaler_id = $1`, wholesalerID)
+	}
+	return repo.listAccess(`a.wholesaler_id = $1 AND a.status = $2`, wholesalerID, status)
+}
+
+// SetStatus moves a retailer's access to the wholesaler's catalog to status to, provided it is
+// in one of the statuses from. It returns ErrCatalogAccessNotFound if there is no such request
+// and ErrInvalidAccessChange if it is in another status.
+func (repo *CatalogAccessRepo) SetStatus(wholesalerID int, retailerID int, from []models.CatalogAccessStatus, to models.CatalogAccessStatus) (*models.CatalogAccess, error) {
+	statuses := make([]string, len(from))
+	for i, status := range from {
+		statuses[i] = string(status)
+	}
+
+	result, err := repo.DB.Exec(`
+		UPDATE catalog_access SET status = $1, updated_at = NOW()
+		WHERE wholesaler_id = $2 AND retailer_id = $3 AND status = ANY($4)
+	`, to, wholesalerID, retailerID, pq.Array(statuses))
+	if err != nil {
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := repo.getAccess(`a.wholesaler_id = $1 AND a.retailer_id = $2`, wholesalerID, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrInvalidAccessChange
+	}
+	return access, nil
+}
+
+// CanViewWholesaler reports whether the retailer may see and order from the wholesaler's catalog.
+func (repo *CatalogAccessRepo) CanViewWholesaler(retailerID int, wholesalerID int) (bool, error) {
+	var visible bool
+	err := repo.DB.QueryRow(`SELECT `+catalogVisibleTo("$2", "$1"), retailerID, wholesalerID).Scan(&visible)
+	return visible, err
+}
+
+// CanViewProduct reports whether the retailer may see and order the product. It returns
+// ErrWholesalerProductNotFound if there is no such product.
+func (repo *CatalogAccessRepo) CanViewProduct(retailerID int, productID int) (bool, error) {
+	var visible bool
+	err := repo.DB.QueryRow(`SELECT `+catalogVisibleTo("p.wholesaler_id", "$1")+` FROM wholesaler_products p WHERE p.id = $2`, retailerID, productID).Scan(&visible)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrWholesalerProductNotFound
+	}
+	return visible, err
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+type ICategoriesRepo interface {
+	GetShopCategories() ([]models.Category, error)
+	GetWholesaleCategories(viewerID int) ([]models.Category, error)
+	GetCategoryBySlug(slug string) (*models.Category, error)
+}
+
+type CategoriesRepo struct {
+	DB *sql.DB
+}
+
+func NewCategoriesRepo(db *sql.DB) *CategoriesRepo {
+	return &CategoriesRepo{DB: db}
+}
+
+// GetShopCategories returns every category with the number of retailer products assigned
+// directly to it, parents before their children.
+func (repo *CategoriesRepo) GetShopCategories() ([]models.Category, error) {
+	return repo.getCategories(retailerCatalog, 0)
+}
+
+// GetWholesaleCategories is the wholesaler_products counterpart of GetShopCategories. Only the
+// products viewerID can see are counted.
+func (repo *CategoriesRepo) GetWholesaleCategories(viewerID int) ([]models.Category, error) {
+	return repo.getCategories(wholesalerCatalog, viewerID)
+}
+
+func (repo *CategoriesRepo) getCategories(catalog productCatalog, viewerID int) ([]models.Category, error) {
+	join := "p.category_id = c.id"
+	var args []any
+	if catalog.visibleTo != nil {
+		join += " AND " + catalog.visibleTo("$1")
+		args = append(args, viewerID)
+	}
+	query := fmt.Sprintf(`
+		SELECT c.id, COALESCE(c.parent_id, 0), c.name, c.slug, COUNT(p.id)
+		FROM categories c
+		LEFT JOIN %s p ON %s
+		GROUP BY c.id
+		ORDER BY c.parent_id NULLS FIRST, c.name
+	`, catalog.table, join)
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.Id, &category.ParentId, &category.Name, &category.Slug, &category.ProductCount); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (repo *CategoriesRepo) GetCategoryBySlug(slug string) (*models.Category, error) {
+	query := `SELECT id, COALESCE(parent_id, 0), name, slug FROM categories WHERE slug = $1`
+
+	var category models.Category
+	err := repo.DB.QueryRow(query, slug).Scan(&category.Id, &category.ParentId, &category.Name, &category.Slug)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// categoryError turns a product write rejected by the category_id foreign key into
+// ErrCategoryNotFound and returns any other error unchanged.
+func categoryError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" && strings.HasSuffix(pqErr.Constraint, "_category_id_fkey") {
+		return ErrCategoryNotFound
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrStockBelowReserved = errors.New("adjustment would leave less stock than pending checkouts hold")
+
+type IInventoryRepo interface {
+	GetLedger(productID int, sellerID int, limit int) (*models.StockLedger, error)
+	AdjustStock(productID int, sellerID int, variantID int, quantity int, note string) (*models.InventoryMovement, error)
+}
+
+// InventoryRepo reads the stock ledger of one catalog's products and posts manual adjustments
+// to it. Reads and writes only succeed for products that belong to the given seller.
+type InventoryRepo struct {
+	DB      *sql.DB
+	catalog productCatalog
+}
+
+func NewRetailerInventoryRepo(db *sql.DB) *InventoryRepo {
+	return &InventoryRepo{DB: db, catalog: retailerCatalog}
+}
+
+func NewWholesalerInventoryRepo(db *sql.DB) *InventoryRepo {
+	return &InventoryRepo{DB: db, catalog: wholesalerCatalog}
+}
+
+// GetLedger returns the product's latest limit movements and compares the stock of the product
+// and of each of its variants with the sum of all their movements.
+func (repo *InventoryRepo) GetLedger(productID int, sellerID int, limit int) (*models.StockLedger, error) {
+	ownerQuery := fmt.Sprintf(`SELECT COALESCE(stock_qty, 0) FROM %s WHERE id = $1 AND %s = $2`, repo.catalog.table, repo.catalog.sellerColumn)
+	var productStock int
+	if err := repo.DB.QueryRow(ownerQuery, productID, sellerID).Scan(&productStock); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.catalog.notFound
+		}
+		return nil, err
+	}
+
+	ledger := &models.StockLedger{ProductId: productID, Balances: []models.StockBalance{}, Movements: []models.InventoryMovement{}}
+
+	balancesQuery := fmt.Sprintf(`
+		SELECT 0, $3::int, COALESCE((
+			SELECT SUM(quantity) FROM inventory_movements
+			WHERE product_type = $1 AND product_id = $2 AND variant_id IS NULL
+		), 0)
+		UNION ALL
+		SELECT v.id, v.stock_qty, COALESCE((
+			SELECT SUM(m.quantity) FROM inventory_movements m
+			WHERE m.product_type = $1 AND m.product_id = $2 AND m.variant_id = v.id
+		), 0)
+		FROM %s v
+		WHERE v.product_id = $2
+	`, repo.catalog.variants)
+	rows, err := repo.DB.Query(balancesQuery, repo.catalog.table, productID, productStock)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var balance models.StockBalance
+		if err := rows.Scan(&balance.VariantId, &balance.StockQty, &balance.LedgerQty); err != nil {
+			return nil, err
+		}
+		balance.Reconciled = balance.StockQty == balance.LedgerQty
+		ledger.Balances = append(ledger.Balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	movementsQuery := `
+		SELECT id, product_id, COALESCE(variant_id, 0), quantity, stock_after, reason, note, COALESCE(order_id, 0), created_at
+		FROM inventory_movements
+		WHERE product_type = $1 AND product_id = $2
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	movementRows, err := repo.DB.Query(movementsQuery, repo.catalog.table, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer movementRows.Close()
+	for movementRows.Next() {
+		var m models.InventoryMovement
+		err := movementRows.Scan(&m.Id, &m.ProductId, &m.VariantId, &m.Quantity, &m.StockAfter, &m.Reason, &m.Note, &m.OrderId, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ledger.Movements = append(ledger.Movements, m)
+	}
+	return ledger, movementRows.Err()
+}
+
+// AdjustStock adds quantity units, or removes them when it is negative, to the stock of the
+// product or of its variant variantID, and records the change as a manual adjustment. Stock
+// held for pending checkouts cannot be adjusted away.
+func (repo *InventoryRepo) AdjustStock(productID int, sellerID int, variantID int, quantity int, note string) (*models.InventoryMovement, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ownerQuery := fmt.Sprintf(`SELECT 1 FROM %s WHERE id = $1 AND %s = $2 FOR UPDATE`, repo.catalog.table, repo.catalog.sellerColumn)
+	var owned int
+	if err := tx.QueryRow(ownerQuery, productID, sellerID).Scan(&owned); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.catalog.notFound
+		}
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET stock_qty = COALESCE(stock_qty, 0) + $1, updated_at = NOW()
+		WHERE id = $2 AND COALESCE(stock_qty, 0) + $1 >= reserved_qty
+		RETURNING stock_qty
+	`, repo.catalog.table)
+	args := []any{quantity, productID}
+	if variantID != 0 {
+		query = fmt.Sprintf(`
+			UPDATE %s SET stock_qty = stock_qty + $1, updated_at = NOW()
+			WHERE id = $2 AND product_id = $3 AND stock_qty + $1 >= reserved_qty
+			RETURNING stock_qty
+		`, repo.catalog.variants)
+		args = []any{quantity, variantID, productID}
+	}
+
+	movement := models.InventoryMovement{ProductId: productID, VariantId: variantID, Quantity: quantity, Reason: models.MovementAdjustment, Note: note}
+	if err := tx.QueryRow(query, args...).Scan(&movement.StockAfter); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		// Either the variant is not the product's or the adjustment went too far
+		if variantID == 0 {
+			return nil, ErrStockBelowReserved
+		}
+		var exists bool
+		existsQuery := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1 AND product_id = $2)`, repo.catalog.variants)
+		if err := tx.QueryRow(existsQuery, variantID, productID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrVariantNotFound
+		}
+		return nil, ErrStockBelowReserved
+	}
+
+	if err := recordMovement(tx, repo.catalog.table, &movement); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// recordMovement adds a movement of a product of productsTable to the ledger, in the transaction
+// that changed its stock, and fills in its ID and time. Movements of no units are skipped.
+func recordMovement(tx *sql.Tx, productsTable string, movement *models.InventoryMovement) error {
+	if movement.Quantity == 0 {
+		return nil
+	}
+	query := `
+		INSERT INTO inventory_movements (product_type, product_id, variant_id, quantity, stock_after, reason, note, order_id)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6, $7, NULLIF($8, 0))
+		RETURNING id, created_at
+	`
+	err := tx.QueryRow(query, productsTable, movement.ProductId, movement.VariantId, movement.Quantity, movement.StockAfter,
+		movement.Reason, movement.Note, movement.OrderId).Scan(&movement.Id, &movement.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	return nil
+}
+
+// withMovements turns update, an UPDATE of stock returning the columns product_id, variant_id,
+// quantity and stock_after for every row it changed, into a statement that also records those
+// changes in the ledger. orderID is the SQL for the order the movements belong to.
+func withMovements(update string, productsTable string, reason models.MovementReason, orderID string) string {
+	return fmt.Sprintf(`
+		WITH moved (product_id, variant_id, quantity, stock_after) AS (%s)
+		INSERT INTO inventory_movements (product_type, product_id, variant_id, quantity, stock_after, reason, order_id)
+		SELECT '%s', product_id, variant_id, quantity, stock_after, '%s', %s
+		FROM moved
+		WHERE quantity <> 0
+	`, update, productsTable, reason, orderID)
+}
+
+// lockStock returns the stock of the row id of table, locking it until tx ends so a write that
+// replaces the stock can record how much it changed by.
+func lockStock(tx *sql.Tx, table string, id int) (int, error) {
+	var stock int
+	query := fmt.Sprintf(`SELECT COALESCE(stock_qty, 0) FROM %s WHERE id = $1 FOR UPDATE`, table)
+	if err := tx.QueryRow(query, id).Scan(&stock); err != nil {
+		return 0, err
+	}
+	return stock, nil
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInventoryRepo_AdjustStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRetailerInventoryRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT 1 FROM retailer_products WHERE id = \\$1 AND retailer_id = \\$2 FOR UPDATE").
+		WithArgs(3, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery("UPDATE retailer_products SET stock_qty").
+		WithArgs(-2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"stock_qty"}).AddRow(8))
+	mock.ExpectQuery("INSERT INTO inventory_movements").
+		WithArgs("retailer_products", 3, 0, -2, 8, models.MovementAdjustment, "Damaged in storage", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(41, "2026-10-17T10:00:00Z"))
+	mock.ExpectCommit()
+
+	movement, err := repo.AdjustStock(3, 7, 0, -2, "Damaged in storage")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if movement.Id != 41 || movement.StockAfter != 8 || movement.Reason != models.MovementAdjustment {
+		t.Errorf("Unexpected movement: %+v", movement)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestInventoryRepo_AdjustStock_BelowReserved(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewWholesalerInventoryRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT 1 FROM wholesaler_products").
+		WithArgs(3, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery("UPDATE wholesaler_product_variants SET stock_qty").
+		WithArgs(-20, 9, 3).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(9, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err = repo.AdjustStock(3, 7, 9, -20, "Recount")
+	if !errors.Is(err, ErrStockBelowReserved) {
+		t.Errorf("Expected ErrStockBelowReserved, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
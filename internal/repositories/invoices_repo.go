@@ -0,0 +1,416 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCreditTermsNotFound   = errors.New("retailer has no credit terms with this wholesaler")
+	ErrCreditCurrency        = errors.New("order is not in the currency of the credit terms")
+	ErrInvoiceNotFound       = errors.New("invoice not found")
+	ErrInvoiceNotPayable     = errors.New("invoice is already paid or void")
+	ErrPaymentExceedsBalance = errors.New("payment exceeds the invoice balance")
+)
+
+// CreditLimitError is returned when an order on account would take a retailer past the credit
+// limit a wholesaler granted it. Nothing is ordered when it is returned.
+type CreditLimitError struct {
+	WholesalerId int          `json:"wholesaler_id"`
+	Available    models.Money `json:"available"`
+	Requested    models.Money `json:"requested"`
+}
+
+func (e *CreditLimitError) Error() string {
+	return fmt.Sprintf("order of %s exceeds the %s of credit available with wholesaler %d", e.Requested, e.Available, e.WholesalerId)
+}
+
+type IInvoicesRepo interface {
+	SetCreditTerms(wholesalerID int, retailerID int, creditLimit models.Money, termsDays int) (*models.CreditTerms, error)
+	GetCreditTerms(wholesalerID int, retailerID int) (*models.CreditTerms, error)
+	GetCreditTermsByWholesaler(wholesalerID int) ([]models.CreditTerms, error)
+	GetCreditTermsByRetailer(retailerID int) ([]models.CreditTerms, error)
+	DeleteCreditTerms(wholesalerID int, retailerID int) error
+	CreateInvoicedOrders(orders []*models.RetailerOrder) ([]models.Invoice, error)
+	MarkOverdueInvoices() error
+	GetInvoice(id int) (*models.Invoice, error)
+	GetInvoicesByWholesaler(wholesalerID int, status models.InvoiceStatus) ([]models.Invoice, error)
+	GetInvoicesByRetailer(retailerID int, status models.InvoiceStatus) ([]models.Invoice, error)
+	RecordPayment(invoiceID int, amount models.Money, method string, reference string, receivedAt time.Time) (*models.Invoice, error)
+}
+
+type InvoicesRepo struct {
+	DB *sql.DB
+}
+
+func NewInvoicesRepo(db *sql.DB) *InvoicesRepo {
+	return &InvoicesRepo{DB: db}
+}
+
+// outstandingBalance sums what a retailer owes a wholesaler on invoices that are still due
+const outstandingBalance = `COALESCE((
+			SELECT SUM(i.amount - i.amount_paid) FROM invoices i
+			WHERE i.wholesaler_id = %s AND i.retailer_id = %s AND i.status IN ('open', 'overdue')
+		), 0)`
+
+var creditTermsColumns = `t.id, t.wholesaler_id, COALESCE(w.business_name, ''), t.retailer_id, COALESCE(r.business_name, ''),
+		t.credit_limit, t.currency, t.terms_days, ` + fmt.Sprintf(outstandingBalance, "t.wholesaler_id", "t.retailer_id") + `,
+		t.created_at, t.updated_at`
+
+const creditTermsFrom = `credit_terms t
+		JOIN wholesalers w ON w.id = t.wholesaler_id
+		JOIN retailers r ON r.id = t.retailer_id`
+
+func scanCreditTerms(scan func(dest ...any) error) (*models.CreditTerms, error) {
+	var terms models.CreditTerms
+	err := scan(&terms.Id, &terms.WholesalerId, &terms.WholesalerName, &terms.RetailerId, &terms.RetailerName,
+		&terms.CreditLimit, &terms.Currency, &terms.TermsDays, &terms.Outstanding, &terms.CreatedAt, &terms.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &terms, nil
+}
+
+// SetCreditTerms grants a retailer credit with a wholesaler, or changes the terms it already has.
+// The limit is in the wholesaler's currency. Lowering it below the outstanding balance does not
+// affect existing invoices but blocks new orders on account until enough is paid.
+func (repo *InvoicesRepo) SetCreditTerms(wholesalerID int, retailerID int, creditLimit models.Money, termsDays int) (*models.CreditTerms, error) {
+	query := `
+		INSERT INTO credit_terms (wholesaler_id, retailer_id, credit_limit, currency, terms_days)
+		SELECT w.id, $2, $3, w.currency, $4 FROM wholesalers w WHERE w.id = $1
+		ON CONFLICT (wholesaler_id, retailer_id) DO UPDATE
+		SET credit_limit = EXCLUDED.credit_limit, terms_days = EXCLUDED.terms_days, updated_at = NOW()
+		RETURNING id`
+
+	var id int
+	err := repo.DB.QueryRow(query, wholesalerID, retailerID, creditLimit, termsDays).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWholesalerNotFound
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" && strings.HasSuffix(pqErr.Constraint, "_retailer_id_fkey") {
+			return nil, ErrRetailerNotFound
+		}
+		return nil, err
+	}
+	return repo.getCreditTerms(`t.id = $1`, id)
+}
+
+// GetCreditTerms returns the terms the wholesaler granted the retailer, or ErrCreditTermsNotFound.
+func (repo *InvoicesRepo) GetCreditTerms(wholesalerID int, retailerID int) (*models.CreditTerms, error) {
+	return repo.getCreditTerms(`t.wholesaler_id = $1 AND t.retailer_id = $2`, wholesalerID, retailerID)
+}
+
+func (repo *InvoicesRepo) getCreditTerms(condition string, args ...any) (*models.CreditTerms, error) {
+	row := repo.DB.QueryRow(`SELECT `+creditTermsColumns+` FROM `+creditTermsFrom+` WHERE `+condition, args...)
+	terms, err := scanCreditTerms(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCreditTermsNotFound
+	}
+	return terms, err
+}
+
+func (repo *InvoicesRepo) listCreditTerms(condition string, args ...any) ([]models.CreditTerms, error) {
+	rows, err := repo.DB.Query(`SELECT `+creditTermsColumns+` FROM `+creditTermsFrom+` WHERE `+condition+` ORDER BY t.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.CreditTerms{}
+	for rows.Next() {
+		terms, err := scanCreditTerms(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *terms)
+	}
+	return list, rows.Err()
+}
+
+// GetCreditTermsByWholesaler returns every retailer the wholesaler has granted credit to.
+func (repo *InvoicesRepo) GetCreditTermsByWholesaler(wholesalerID int) ([]models.CreditTerms, error) {
+	return repo.listCreditTerms(`t.wholesaler_id = $1`, wholesalerID)
+}
+
+// GetCreditTermsByRetailer returns every wholesaler the retailer may order from on account.
+func (repo *InvoicesRepo) GetCreditTermsByRetailer(retailerID int) ([]models.CreditTerms, error) {
+	return repo.listCreditTerms(`t.retailer_id = $1`, retailerID)
+}
+
+// DeleteCreditTerms withdraws a retailer's credit. Invoices already issued stay due.
+func (repo *InvoicesRepo) DeleteCreditTerms(wholesalerID int, retailerID int) error {
+	result, err := repo.DB.Exec(`DELETE FROM credit_terms WHERE wholesaler_id = $1 AND retailer_id = $2`, wholesalerID, retailerID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCreditTermsNotFound
+	}
+	return nil
+}
+
+// CreateInvoicedOrders places wholesale orders on account: each is checked against the credit
+// its wholesaler granted the retailer, inserted, confirmed with its stock taken off the shelf,
+// and invoiced with a due date from the terms. Either every order is placed or none is.
+func (repo *InvoicesRepo) CreateInvoicedOrders(orders []*models.RetailerOrder) ([]models.Invoice, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the terms keeps two checkouts from spending the same credit
+	termsQuery := `
+		SELECT credit_limit, currency, terms_days, ` + fmt.Sprintf(outstandingBalance, "$1", "$2") + `
+		FROM credit_terms
+		WHERE wholesaler_id = $1 AND retailer_id = $2
+		FOR UPDATE`
+
+	termsDays := make([]int, len(orders))
+	for i, order := range orders {
+		var terms models.CreditTerms
+		err := tx.QueryRow(termsQuery, order.WholesalerId, order.RetailerId).Scan(&terms.CreditLimit, &terms.Currency, &terms.TermsDays, &terms.Outstanding)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrCreditTermsNotFound
+			}
+			return nil, err
+		}
+		if terms.Currency != order.Currency {
+			return nil, ErrCreditCurrency
+		}
+		if order.TotalPrice > terms.Available() {
+			return nil, &CreditLimitError{WholesalerId: order.WholesalerId, Available: terms.Available(), Requested: order.TotalPrice}
+		}
+		termsDays[i] = terms.TermsDays
+	}
+
+	var lines []stockLine
+	for _, order := range orders {
+		order.Status = models.OrderStatusPending
+		orderLines, err := insertRetailerOrder(tx, order)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, orderLines...)
+	}
+	if err := reserveStock(tx, retailerOrdersTable, lines); err != nil {
+		return nil, err
+	}
+
+	invoiceQuery := `
+		INSERT INTO invoices (order_id, wholesaler_id, retailer_id, amount, currency, due_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(days => $6))
+		RETURNING id`
+
+	ids := make([]int, len(orders))
+	for i, order := range orders {
+		actor := models.OrderActor{Type: models.OrderActorRetailer, Id: order.RetailerId}
+		if _, err := tx.Exec(`UPDATE wholesaler_orders SET status = $1, updated_at = NOW() WHERE id = $2`, models.OrderStatusConfirmed, order.Id); err != nil {
+			return nil, err
+		}
+		if err := applyStockTransition(tx, retailerOrdersTable, order.Id, models.OrderStatusPending, models.OrderStatusConfirmed); err != nil {
+			return nil, err
+		}
+		if err := insertStatusHistory(tx, retailerOrdersTable, order.Id, models.OrderStatusPending, models.OrderStatusConfirmed, actor); err != nil {
+			return nil, err
+		}
+		order.Status = models.OrderStatusConfirmed
+
+		if err := tx.QueryRow(invoiceQuery, order.Id, order.WholesalerId, order.RetailerId, order.TotalPrice, order.Currency, termsDays[i]).Scan(&ids[i]); err != nil {
+			return nil, fmt.Errorf("failed to insert invoice: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	invoices := make([]models.Invoice, 0, len(ids))
+	for _, id := range ids {
+		invoice, err := repo.getInvoice(`i.id = $1`, id)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *invoice)
+	}
+	return invoices, nil
+}
+
+// voidOrderInvoice voids the invoice of a wholesale order that is being cancelled, so it no
+// longer counts against the retailer's credit. Anything already paid on it has to be returned
+// outside the platform.
+func voidOrderInvoice(tx *sql.Tx, orderID int) error {
+	_, err := tx.Exec(`
+		UPDATE invoices SET status = 'void', updated_at = NOW()
+		WHERE order_id = $1 AND status IN ('open', 'overdue')
+	`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to void invoice: %w", err)
+	}
+	return nil
+}
+
+// MarkOverdueInvoices moves open invoices whose due date has passed to overdue.
+func (repo *InvoicesRepo) MarkOverdueInvoices() error {
+	_, err := repo.DB.Exec(`UPDATE invoices SET status = 'overdue', updated_at = NOW() WHERE status = 'open' AND due_at < NOW()`)
+	return err
+}
+
+const invoiceColumns = `i.id, i.order_id, i.wholesaler_id, COALESCE(w.business_name, ''), i.retailer_id, COALESCE(r.business_name, ''),
+		i.amount, i.amount_paid, i.currency, i.status, i.due_at, i.paid_at, i.created_at, i.updated_at`
+
+const invoiceFrom = `invoices i
+		JOIN wholesalers w ON w.id = i.wholesaler_id
+		JOIN retailers r ON r.id = i.retailer_id`
+
+func scanInvoice(scan func(dest ...any) error) (*models.Invoice, error) {
+	var invoice models.Invoice
+	var paidAt sql.NullString
+	err := scan(&invoice.Id, &invoice.OrderId, &invoice.WholesalerId, &invoice.WholesalerName, &invoice.RetailerId, &invoice.RetailerName,
+		&invoice.Amount, &invoice.AmountPaid, &invoice.Currency, &invoice.Status, &invoice.DueAt, &paidAt, &invoice.CreatedAt, &invoice.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	invoice.PaidAt = paidAt.String
+	invoice.Balance = invoice.Amount - invoice.AmountPaid
+	return &invoice, nil
+}
+
+// GetInvoice returns an invoice with the payments recorded against it.
+func (repo *InvoicesRepo) GetInvoice(id int) (*models.Invoice, error) {
+	invoice, err := repo.getInvoice(`i.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.DB.Query(`
+		SELECT id, invoice_id, amount, method, reference, received_at, created_at
+		FROM invoice_payments
+		WHERE invoice_id = $1
+		ORDER BY received_at, id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.InvoicePayment
+		if err := rows.Scan(&p.Id, &p.InvoiceId, &p.Amount, &p.Method, &p.Reference, &p.ReceivedAt, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		invoice.Payments = append(invoice.Payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (repo *InvoicesRepo) getInvoice(condition string, args ...any) (*models.Invoice, error) {
+	row := repo.DB.QueryRow(`SELECT `+invoiceColumns+` FROM `+invoiceFrom+` WHERE `+condition, args...)
+	invoice, err := scanInvoice(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvoiceNotFound
+	}
+	return invoice, err
+}
+
+func (repo *InvoicesRepo) listInvoices(condition string, args ...any) ([]models.Invoice, error) {
+	rows, err := repo.DB.Query(`SELECT `+invoiceColumns+` FROM `+invoiceFrom+` WHERE `+condition+` ORDER BY i.due_at, i.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *invoice)
+	}
+	return list, rows.Err()
+}
+
+// GetInvoicesByWholesaler returns the wholesaler's invoices, soonest due first, optionally only
+// those with the given status.
+func (repo *InvoicesRepo) GetInvoicesByWholesaler(wholesalerID int, status models.InvoiceStatus) ([]models.Invoice, error) {
+	if status == "" {
+		return repo.listInvoices(`i.wholesaler_id = $1`, wholesalerID)
+	}
+	return repo.listInvoices(`i.wholesaler_id = $1 AND i.status = $2`, wholesalerID, status)
+}
+
+// GetInvoicesByRetailer is the retailer counterpart of GetInvoicesByWholesaler.
+func (repo *InvoicesRepo) GetInvoicesByRetailer(retailerID int, status models.InvoiceStatus) ([]models.Invoice, error) {
+	if status == "" {
+		return repo.listInvoices(`i.retailer_id = $1`, retailerID)
+	}
+	return repo.listInvoices(`i.retailer_id = $1 AND i.status = $2`, retailerID, status)
+}
+
+// RecordPayment records money received against an open or overdue invoice. The invoice is paid
+// once its balance reaches 0; a payment larger than the balance is refused.
+func (repo *InvoicesRepo) RecordPayment(invoiceID int, amount models.Money, method string, reference string, receivedAt time.Time) (*models.Invoice, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var total, paid models.Money
+	var status models.InvoiceStatus
+	err = tx.QueryRow(`SELECT amount, amount_paid, status FROM invoices WHERE id = $1 FOR UPDATE`, invoiceID).Scan(&total, &paid, &status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvoiceNotFound
+		}
+		return nil, err
+	}
+	if status != models.InvoiceOpen && status != models.InvoiceOverdue {
+		return nil, ErrInvoiceNotPayable
+	}
+	if amount > total-paid {
+		return nil, ErrPaymentExceedsBalance
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO invoice_payments (invoice_id, amount, method, reference, received_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, invoiceID, amount, method, reference, receivedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert payment: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE invoices SET
+			amount_paid = amount_paid + $1,
+			status = CASE WHEN amount_paid + $1 = amount THEN 'paid' ELSE status END,
+			paid_at = CASE WHEN amount_paid + $1 = amount THEN NOW() ELSE paid_at END,
+			updated_at = NOW()
+		WHERE id = $2
+	`, amount, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetInvoice(invoiceID)
+}
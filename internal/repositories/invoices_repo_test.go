@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInvoicesRepo_CreateInvoicedOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewInvoicesRepo(db)
+	termsColumns := []string{"credit_limit", "currency", "terms_days", "outstanding"}
+
+	t.Run("order beyond the credit left is refused", func(t *testing.T) {
+		order := &models.RetailerOrder{WholesalerId: 7, RetailerId: 3, TotalPrice: 40000, Currency: "usd"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT credit_limit, currency, terms_days").
+			WithArgs(7, 3).
+			WillReturnRows(sqlmock.NewRows(termsColumns).AddRow(100000, "usd", 30, 75000))
+		mock.ExpectRollback()
+
+		_, err := repo.CreateInvoicedOrders([]*models.RetailerOrder{order})
+		var creditErr *CreditLimitError
+		if !errors.As(err, &creditErr) {
+			t.Fatalf("Expected CreditLimitError, got %v", err)
+		}
+		if creditErr.Available != 25000 || creditErr.Requested != 40000 || creditErr.WholesalerId != 7 {
+			t.Errorf("Unexpected credit error: %+v", creditErr)
+		}
+	})
+
+	t.Run("no terms with the wholesaler", func(t *testing.T) {
+		order := &models.RetailerOrder{WholesalerId: 8, RetailerId: 3, TotalPrice: 1000, Currency: "usd"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT credit_limit, currency, terms_days").
+			WithArgs(8, 3).
+			WillReturnRows(sqlmock.NewRows(termsColumns))
+		mock.ExpectRollback()
+
+		_, err := repo.CreateInvoicedOrders([]*models.RetailerOrder{order})
+		if !errors.Is(err, ErrCreditTermsNotFound) {
+			t.Errorf("Expected ErrCreditTermsNotFound, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestInvoicesRepo_RecordPayment_ExceedsBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewInvoicesRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT amount, amount_paid, status FROM invoices").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "amount_paid", "status"}).AddRow(5000, 4000, "overdue"))
+	mock.ExpectRollback()
+
+	_, err = repo.RecordPayment(5, 2000, "bank transfer", "", time.Now())
+	if !errors.Is(err, ErrPaymentExceedsBalance) {
+		t.Errorf("Expected ErrPaymentExceedsBalance, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -3,21 +3,43 @@ package repositories
 import (
 	"Obsonarium-backend/internal/models"
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderStatusConflict = errors.New("order status changed concurrently")
+)
+
+const (
+	consumerOrdersTable = "retailer_orders"
+	retailerOrdersTable = "wholesaler_orders"
 )
 
 type IOrdersRepo interface {
-	CreateConsumerOrder(order *models.ConsumerOrder) error
-	CreateRetailerOrder(order *models.RetailerOrder) error
+	CreateConsumerOrders(orders []*models.ConsumerOrder) error
+	CreateRetailerOrders(orders []*models.RetailerOrder) error
 	GetConsumerOrderBySessionID(sessionID string) (*models.ConsumerOrder, error)
 	GetRetailerOrderBySessionID(sessionID string) (*models.RetailerOrder, error)
-	UpdateConsumerOrderStatus(sessionID string, status models.OrderStatus) error
-	UpdateRetailerOrderStatus(sessionID string, status models.OrderStatus) error
+	GetConsumerOrdersBySessionID(sessionID string) ([]models.ConsumerOrder, error)
+	GetRetailerOrdersBySessionID(sessionID string) ([]models.RetailerOrder, error)
+	UpdateConsumerOrderStatus(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error
+	UpdateRetailerOrderStatus(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error
 	UpdateConsumerOrderStripeSession(orderID int, sessionID string) error
+	UpdateRetailerOrderStripeSession(orderID int, sessionID string) error
+	SetSessionPaymentIntent(sessionID string, paymentIntentID string) error
+	GetSessionIDByPaymentIntent(paymentIntentID string) (string, error)
 	GetConsumerOrdersByUserID(userID int) ([]models.ConsumerOrder, error)
 	GetConsumerOrdersByRetailerID(retailerID int) ([]models.ConsumerOrder, error)
 	GetRetailerOrdersByRetailerID(retailerID int) ([]models.RetailerOrder, error)
 	GetRetailerOrdersByWholesalerID(wholesalerID int) ([]models.RetailerOrder, error)
+	GetConsumerOrderByID(orderID int) (*models.ConsumerOrder, error)
+	GetRetailerOrderByID(orderID int) (*models.RetailerOrder, error)
+	TransitionConsumerOrderStatus(orderID int, from, to models.OrderStatus, actor models.OrderActor) error
+	TransitionRetailerOrderStatus(orderID int, from, to models.OrderStatus, actor models.OrderActor) error
 }
 
 type OrdersRepo struct {
@@ -28,7 +50,9 @@ func NewOrdersRepo(db *sql.DB) *OrdersRepo {
 	return &OrdersRepo{db: db}
 }
 
-func (r *OrdersRepo) CreateConsumerOrder(order *models.ConsumerOrder) error {
+// CreateConsumerOrders inserts one order per retailer and reserves their stock in a single
+// transaction, so a consumer checkout either reserves everything or nothing.
+func (r *OrdersRepo) CreateConsumerOrders(orders []*models.ConsumerOrder) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -36,18 +60,13 @@ func (r *OrdersRepo) CreateConsumerOrder(order *models.ConsumerOrder) error {
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO retailer_orders (retailer_id, user_id, total_price, status, stripe_session_id)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO retailer_orders (retailer_id, user_id, address_id, total_price, currency, status, stripe_session_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at
 	`
-	err = tx.QueryRow(query, order.RetailerId, order.UserId, order.TotalPrice, order.Status, order.StripeSessionId).Scan(&order.Id, &order.CreatedAt, &order.UpdatedAt)
-	if err != nil {
-		return fmt.Errorf("failed to insert order: %w", err)
-	}
-
 	itemQuery := `
-		INSERT INTO retailer_order_items (order_id, product_id, quantity, price)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO retailer_order_items (order_id, product_id, variant_id, quantity, price)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5)
 	`
 	stmt, err := tx.Prepare(itemQuery)
 	if err != nil {
@@ -55,62 +74,93 @@ func (r *OrdersRepo) CreateConsumerOrder(order *models.ConsumerOrder) error {
 	}
 	defer stmt.Close()
 
-	for _, item := range order.Items {
-		_, err = stmt.Exec(order.Id, item.ProductId, item.Quantity, item.Price)
+	var lines []stockLine
+	for _, order := range orders {
+		err = tx.QueryRow(query, order.RetailerId, order.UserId, order.AddressId, order.TotalPrice, order.Currency, order.Status, order.StripeSessionId).Scan(&order.Id, &order.CreatedAt, &order.UpdatedAt)
 		if err != nil {
-			return fmt.Errorf("failed to insert order item: %w", err)
+			return fmt.Errorf("failed to insert order: %w", err)
+		}
+
+		for _, item := range order.Items {
+			_, err = stmt.Exec(order.Id, item.ProductId, item.VariantId, item.Quantity, item.Price)
+			if err != nil {
+				return fmt.Errorf("failed to insert order item: %w", err)
+			}
+			lines = append(lines, stockLine{productID: item.ProductId, variantID: item.VariantId, quantity: item.Quantity})
 		}
 	}
 
+	// Reserve stock in the same transaction so the orders only exist if their stock does
+	if err := reserveStock(tx, consumerOrdersTable, lines); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-func (r *OrdersRepo) CreateRetailerOrder(order *models.RetailerOrder) error {
+// CreateRetailerOrders inserts one order per wholesaler and reserves their stock in a single
+// transaction, so a retailer checkout either reserves everything or nothing.
+func (r *OrdersRepo) CreateRetailerOrders(orders []*models.RetailerOrder) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	var lines []stockLine
+	for _, order := range orders {
+		orderLines, err := insertRetailerOrder(tx, order)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, orderLines...)
+	}
+
+	if err := reserveStock(tx, retailerOrdersTable, lines); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertRetailerOrder inserts a wholesaler_orders row and its items, and returns the stock the
+// caller has to reserve for it.
+func insertRetailerOrder(tx *sql.Tx, order *models.RetailerOrder) ([]stockLine, error) {
 	query := `
-		INSERT INTO wholesaler_orders (wholesaler_id, retailer_id, total_price, status, stripe_session_id)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO wholesaler_orders (wholesaler_id, retailer_id, total_price, currency, status, stripe_session_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at, updated_at
 	`
-	err = tx.QueryRow(query, order.WholesalerId, order.RetailerId, order.TotalPrice, order.Status, order.StripeSessionId).Scan(&order.Id, &order.CreatedAt, &order.UpdatedAt)
-	if err != nil {
-		return fmt.Errorf("failed to insert order: %w", err)
-	}
-
 	itemQuery := `
-		INSERT INTO wholesaler_order_items (order_id, product_id, quantity, price)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO wholesaler_order_items (order_id, product_id, variant_id, quantity, price)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5)
 	`
-	stmt, err := tx.Prepare(itemQuery)
+
+	err := tx.QueryRow(query, order.WholesalerId, order.RetailerId, order.TotalPrice, order.Currency, order.Status, order.StripeSessionId).Scan(&order.Id, &order.CreatedAt, &order.UpdatedAt)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to insert order: %w", err)
 	}
-	defer stmt.Close()
 
+	lines := make([]stockLine, 0, len(order.Items))
 	for _, item := range order.Items {
-		_, err = stmt.Exec(order.Id, item.ProductId, item.Quantity, item.Price)
+		_, err = tx.Exec(itemQuery, order.Id, item.ProductId, item.VariantId, item.Quantity, item.Price)
 		if err != nil {
-			return fmt.Errorf("failed to insert order item: %w", err)
+			return nil, fmt.Errorf("failed to insert order item: %w", err)
 		}
+		lines = append(lines, stockLine{productID: item.ProductId, variantID: item.VariantId, quantity: item.Quantity})
 	}
-
-	return tx.Commit()
+	return lines, nil
 }
 
 func (r *OrdersRepo) GetConsumerOrderBySessionID(sessionID string) (*models.ConsumerOrder, error) {
 	query := `
-		SELECT id, retailer_id, user_id, total_price, status, stripe_session_id, created_at, updated_at
+		SELECT id, retailer_id, user_id, total_price, currency, status, stripe_session_id, created_at, updated_at
 		FROM retailer_orders
 		WHERE stripe_session_id = $1
 	`
 	var order models.ConsumerOrder
 	err := r.db.QueryRow(query, sessionID).Scan(
-		&order.Id, &order.RetailerId, &order.UserId, &order.TotalPrice, &order.Status, &order.StripeSessionId, &order.CreatedAt, &order.UpdatedAt,
+		&order.Id, &order.RetailerId, &order.UserId, &order.TotalPrice, &order.Currency, &order.Status, &order.StripeSessionId, &order.CreatedAt, &order.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -120,13 +170,13 @@ func (r *OrdersRepo) GetConsumerOrderBySessionID(sessionID string) (*models.Cons
 
 func (r *OrdersRepo) GetRetailerOrderBySessionID(sessionID string) (*models.RetailerOrder, error) {
 	query := `
-		SELECT id, wholesaler_id, retailer_id, total_price, status, stripe_session_id, created_at, updated_at
+		SELECT id, wholesaler_id, retailer_id, total_price, currency, status, stripe_session_id, created_at, updated_at
 		FROM wholesaler_orders
 		WHERE stripe_session_id = $1
 	`
 	var order models.RetailerOrder
 	err := r.db.QueryRow(query, sessionID).Scan(
-		&order.Id, &order.WholesalerId, &order.RetailerId, &order.TotalPrice, &order.Status, &order.StripeSessionId, &order.CreatedAt, &order.UpdatedAt,
+		&order.Id, &order.WholesalerId, &order.RetailerId, &order.TotalPrice, &order.Currency, &order.Status, &order.StripeSessionId, &order.CreatedAt, &order.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -134,16 +184,200 @@ func (r *OrdersRepo) GetRetailerOrderBySessionID(sessionID string) (*models.Reta
 	return &order, nil
 }
 
-func (r *OrdersRepo) UpdateConsumerOrderStatus(sessionID string, status models.OrderStatus) error {
-	query := `UPDATE retailer_orders SET status = $1, updated_at = NOW() WHERE stripe_session_id = $2`
-	_, err := r.db.Exec(query, status, sessionID)
-	return err
+// GetConsumerOrdersBySessionID returns every consumer order paid for by a checkout session, with
+// their items.
+func (r *OrdersRepo) GetConsumerOrdersBySessionID(sessionID string) ([]models.ConsumerOrder, error) {
+	query := `
+		SELECT id, retailer_id, user_id, total_price, currency, status, stripe_session_id, created_at, updated_at
+		FROM retailer_orders
+		WHERE stripe_session_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.ConsumerOrder
+	for rows.Next() {
+		var o models.ConsumerOrder
+		if err := rows.Scan(&o.Id, &o.RetailerId, &o.UserId, &o.TotalPrice, &o.Currency, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadConsumerOrderItems(orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
 }
 
-func (r *OrdersRepo) UpdateRetailerOrderStatus(sessionID string, status models.OrderStatus) error {
-	query := `UPDATE wholesaler_orders SET status = $1, updated_at = NOW() WHERE stripe_session_id = $2`
-	_, err := r.db.Exec(query, status, sessionID)
-	return err
+// GetRetailerOrdersBySessionID is the wholesaler_orders counterpart of GetConsumerOrdersBySessionID.
+func (r *OrdersRepo) GetRetailerOrdersBySessionID(sessionID string) ([]models.RetailerOrder, error) {
+	query := `
+		SELECT id, wholesaler_id, retailer_id, total_price, currency, status, stripe_session_id, created_at, updated_at
+		FROM wholesaler_orders
+		WHERE stripe_session_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.RetailerOrder
+	for rows.Next() {
+		var o models.RetailerOrder
+		if err := rows.Scan(&o.Id, &o.WholesalerId, &o.RetailerId, &o.TotalPrice, &o.Currency, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadRetailerOrderItems(orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// UpdateConsumerOrderStatus moves every order of a checkout session that is currently in one of
+// the from statuses to the new status, adjusting product stock and recording each change in
+// order_status_history.
+func (r *OrdersRepo) UpdateConsumerOrderStatus(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+	return r.updateStatusBySession(consumerOrdersTable, sessionID, from, to, actor)
+}
+
+// UpdateRetailerOrderStatus is the wholesaler_orders counterpart of UpdateConsumerOrderStatus.
+func (r *OrdersRepo) UpdateRetailerOrderStatus(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+	return r.updateStatusBySession(retailerOrdersTable, sessionID, from, to, actor)
+}
+
+// TransitionConsumerOrderStatus moves a single order from one status to another. It fails with
+// ErrOrderStatusConflict if the order is no longer in the from status.
+func (r *OrdersRepo) TransitionConsumerOrderStatus(orderID int, from, to models.OrderStatus, actor models.OrderActor) error {
+	return r.transitionStatus(consumerOrdersTable, orderID, from, to, actor)
+}
+
+// TransitionRetailerOrderStatus is the wholesaler_orders counterpart of TransitionConsumerOrderStatus.
+func (r *OrdersRepo) TransitionRetailerOrderStatus(orderID int, from, to models.OrderStatus, actor models.OrderActor) error {
+	return r.transitionStatus(retailerOrdersTable, orderID, from, to, actor)
+}
+
+func (r *OrdersRepo) updateStatusBySession(table string, sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	fromStatuses := make([]string, len(from))
+	for i, status := range from {
+		fromStatuses[i] = string(status)
+	}
+
+	query := fmt.Sprintf(`
+		WITH prev AS (
+			SELECT id, status FROM %[1]s
+			WHERE stripe_session_id = $2 AND status = ANY($3)
+			FOR UPDATE
+		)
+		UPDATE %[1]s o SET status = $1, updated_at = NOW()
+		FROM prev
+		WHERE o.id = prev.id
+		RETURNING o.id, prev.status
+	`, table)
+	rows, err := tx.Query(query, to, sessionID, pq.Array(fromStatuses))
+	if err != nil {
+		return err
+	}
+
+	type change struct {
+		orderID int
+		from    models.OrderStatus
+	}
+	var changes []change
+	for rows.Next() {
+		var c change
+		if err := rows.Scan(&c.orderID, &c.from); err != nil {
+			rows.Close()
+			return err
+		}
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		if err := applyStockTransition(tx, table, c.orderID, c.from, to); err != nil {
+			return err
+		}
+		if err := insertStatusHistory(tx, table, c.orderID, c.from, to, actor); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *OrdersRepo) transitionStatus(table string, orderID int, from, to models.OrderStatus, actor models.OrderActor) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`, table)
+	result, err := tx.Exec(query, to, orderID, from)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrOrderStatusConflict
+	}
+
+	if err := applyStockTransition(tx, table, orderID, from, to); err != nil {
+		return err
+	}
+	if table == retailerOrdersTable && to == models.OrderStatusCancelled {
+		if err := voidOrderInvoice(tx, orderID); err != nil {
+			return err
+		}
+	}
+	if table == retailerOrdersTable && to == models.OrderStatusDelivered {
+		if err := restockLinkedProducts(tx, orderID); err != nil {
+			return err
+		}
+	}
+	if err := insertStatusHistory(tx, table, orderID, from, to, actor); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertStatusHistory(tx *sql.Tx, table string, orderID int, from, to models.OrderStatus, actor models.OrderActor) error {
+	query := `
+		INSERT INTO order_status_history (order_type, order_id, from_status, to_status, actor_type, actor_id)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))
+	`
+	if _, err := tx.Exec(query, table, orderID, from, to, actor.Type, actor.Id); err != nil {
+		return fmt.Errorf("failed to record status history: %w", err)
+	}
+	return nil
 }
 
 func (r *OrdersRepo) UpdateConsumerOrderStripeSession(orderID int, sessionID string) error {
@@ -152,9 +386,53 @@ func (r *OrdersRepo) UpdateConsumerOrderStripeSession(orderID int, sessionID str
 	return err
 }
 
+func (r *OrdersRepo) UpdateRetailerOrderStripeSession(orderID int, sessionID string) error {
+	query := `UPDATE wholesaler_orders SET stripe_session_id = $1, updated_at = NOW() WHERE id = $2`
+	_, err := r.db.Exec(query, sessionID, orderID)
+	return err
+}
+
+// SetSessionPaymentIntent stores the payment intent that paid a checkout session on every
+// consumer and retailer order created for that session.
+func (r *OrdersRepo) SetSessionPaymentIntent(sessionID string, paymentIntentID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{consumerOrdersTable, retailerOrdersTable} {
+		query := fmt.Sprintf(`UPDATE %s SET stripe_payment_intent_id = $1, updated_at = NOW() WHERE stripe_session_id = $2`, table)
+		if _, err := tx.Exec(query, paymentIntentID, sessionID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSessionIDByPaymentIntent finds the checkout session a payment intent belongs to.
+func (r *OrdersRepo) GetSessionIDByPaymentIntent(paymentIntentID string) (string, error) {
+	query := `
+		SELECT stripe_session_id FROM retailer_orders WHERE stripe_payment_intent_id = $1
+		UNION
+		SELECT stripe_session_id FROM wholesaler_orders WHERE stripe_payment_intent_id = $1
+		LIMIT 1
+	`
+	var sessionID string
+	err := r.db.QueryRow(query, paymentIntentID).Scan(&sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrOrderNotFound
+		}
+		return "", err
+	}
+	return sessionID, nil
+}
+
 func (r *OrdersRepo) GetConsumerOrdersByUserID(userID int) ([]models.ConsumerOrder, error) {
 	query := `
-		SELECT id, retailer_id, user_id, total_price, status, stripe_session_id, created_at, updated_at
+		SELECT id, retailer_id, user_id, total_price, currency, status, stripe_session_id, created_at, updated_at
 		FROM retailer_orders
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -168,17 +446,23 @@ func (r *OrdersRepo) GetConsumerOrdersByUserID(userID int) ([]models.ConsumerOrd
 	var orders []models.ConsumerOrder
 	for rows.Next() {
 		var o models.ConsumerOrder
-		if err := rows.Scan(&o.Id, &o.RetailerId, &o.UserId, &o.TotalPrice, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		if err := rows.Scan(&o.Id, &o.RetailerId, &o.UserId, &o.TotalPrice, &o.Currency, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, o)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadConsumerOrderItems(orders); err != nil {
+		return nil, err
+	}
 	return orders, nil
 }
 
 func (r *OrdersRepo) GetConsumerOrdersByRetailerID(retailerID int) ([]models.ConsumerOrder, error) {
 	query := `
-		SELECT id, retailer_id, user_id, total_price, status, stripe_session_id, created_at, updated_at
+		SELECT id, retailer_id, user_id, total_price, currency, status, stripe_session_id, created_at, updated_at
 		FROM retailer_orders
 		WHERE retailer_id = $1
 		ORDER BY created_at DESC
@@ -192,17 +476,23 @@ func (r *OrdersRepo) GetConsumerOrdersByRetailerID(retailerID int) ([]models.Con
 	var orders []models.ConsumerOrder
 	for rows.Next() {
 		var o models.ConsumerOrder
-		if err := rows.Scan(&o.Id, &o.RetailerId, &o.UserId, &o.TotalPrice, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		if err := rows.Scan(&o.Id, &o.RetailerId, &o.UserId, &o.TotalPrice, &o.Currency, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, o)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadConsumerOrderItems(orders); err != nil {
+		return nil, err
+	}
 	return orders, nil
 }
 
 func (r *OrdersRepo) GetRetailerOrdersByRetailerID(retailerID int) ([]models.RetailerOrder, error) {
 	query := `
-		SELECT id, wholesaler_id, retailer_id, total_price, status, stripe_session_id, created_at, updated_at
+		SELECT id, wholesaler_id, retailer_id, total_price, currency, status, stripe_session_id, created_at, updated_at
 		FROM wholesaler_orders
 		WHERE retailer_id = $1
 		ORDER BY created_at DESC
@@ -216,17 +506,23 @@ func (r *OrdersRepo) GetRetailerOrdersByRetailerID(retailerID int) ([]models.Ret
 	var orders []models.RetailerOrder
 	for rows.Next() {
 		var o models.RetailerOrder
-		if err := rows.Scan(&o.Id, &o.WholesalerId, &o.RetailerId, &o.TotalPrice, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		if err := rows.Scan(&o.Id, &o.WholesalerId, &o.RetailerId, &o.TotalPrice, &o.Currency, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, o)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadRetailerOrderItems(orders); err != nil {
+		return nil, err
+	}
 	return orders, nil
 }
 
 func (r *OrdersRepo) GetRetailerOrdersByWholesalerID(wholesalerID int) ([]models.RetailerOrder, error) {
 	query := `
-		SELECT id, wholesaler_id, retailer_id, total_price, status, stripe_session_id, created_at, updated_at
+		SELECT id, wholesaler_id, retailer_id, total_price, currency, status, stripe_session_id, created_at, updated_at
 		FROM wholesaler_orders
 		WHERE wholesaler_id = $1
 		ORDER BY created_at DESC
@@ -240,10 +536,160 @@ func (r *OrdersRepo) GetRetailerOrdersByWholesalerID(wholesalerID int) ([]models
 	var orders []models.RetailerOrder
 	for rows.Next() {
 		var o models.RetailerOrder
-		if err := rows.Scan(&o.Id, &o.WholesalerId, &o.RetailerId, &o.TotalPrice, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		if err := rows.Scan(&o.Id, &o.WholesalerId, &o.RetailerId, &o.TotalPrice, &o.Currency, &o.Status, &o.StripeSessionId, &o.CreatedAt, &o.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, o)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadRetailerOrderItems(orders); err != nil {
+		return nil, err
+	}
 	return orders, nil
 }
+
+func (r *OrdersRepo) GetConsumerOrderByID(orderID int) (*models.ConsumerOrder, error) {
+	query := `
+		SELECT id, retailer_id, user_id, total_price, currency, status, stripe_session_id, created_at, updated_at
+		FROM retailer_orders
+		WHERE id = $1
+	`
+	var order models.ConsumerOrder
+	err := r.db.QueryRow(query, orderID).Scan(
+		&order.Id, &order.RetailerId, &order.UserId, &order.TotalPrice, &order.Currency, &order.Status, &order.StripeSessionId, &order.CreatedAt, &order.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	orders := []models.ConsumerOrder{order}
+	if err := r.loadConsumerOrderItems(orders); err != nil {
+		return nil, err
+	}
+	return &orders[0], nil
+}
+
+func (r *OrdersRepo) GetRetailerOrderByID(orderID int) (*models.RetailerOrder, error) {
+	query := `
+		SELECT id, wholesaler_id, retailer_id, total_price, currency, status, stripe_session_id, created_at, updated_at
+		FROM wholesaler_orders
+		WHERE id = $1
+	`
+	var order models.RetailerOrder
+	err := r.db.QueryRow(query, orderID).Scan(
+		&order.Id, &order.WholesalerId, &order.RetailerId, &order.TotalPrice, &order.Currency, &order.Status, &order.StripeSessionId, &order.CreatedAt, &order.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	orders := []models.RetailerOrder{order}
+	if err := r.loadRetailerOrderItems(orders); err != nil {
+		return nil, err
+	}
+	return &orders[0], nil
+}
+
+// loadConsumerOrderItems fills in Items for each order, joined to the retailer product and
+// variant that was bought, using a single query for the whole batch.
+func (r *OrdersRepo) loadConsumerOrderItems(orders []models.ConsumerOrder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(orders))
+	byID := make(map[int]int, len(orders))
+	for i, o := range orders {
+		ids[i] = int64(o.Id)
+		byID[o.Id] = i
+	}
+
+	query := `
+		SELECT i.id, i.order_id, i.product_id, COALESCE(i.variant_id, 0), i.quantity, i.price, i.refunded_qty,
+			   p.id, p.retailer_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description,
+			   v.id, v.sku, v.options, v.price, v.stock_qty, v.image_url
+		FROM retailer_order_items i
+		JOIN retailer_products p ON p.id = i.product_id
+		LEFT JOIN retailer_product_variants v ON v.id = i.variant_id
+		WHERE i.order_id = ANY($1)
+		ORDER BY i.id
+	`
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.ConsumerOrderItem
+		var product models.RetailerProduct
+		var variant lineVariant
+		if err := rows.Scan(append([]any{
+			&item.Id, &item.OrderId, &item.ProductId, &item.VariantId, &item.Quantity, &item.Price, &item.RefundedQty,
+			&product.Id, &product.Retailer_id, &product.Name, &product.Price, &product.Currency, &product.Stock_qty, &product.Image_url, &product.Description,
+		}, variant.dest()...)...); err != nil {
+			return err
+		}
+		item.Product = &product
+		item.Variant = variant.variant(product.Id, product.Currency)
+		o := &orders[byID[item.OrderId]]
+		o.Items = append(o.Items, item)
+	}
+	return rows.Err()
+}
+
+// loadRetailerOrderItems fills in Items for each order, joined to the wholesaler product and
+// variant that was bought, using a single query for the whole batch.
+func (r *OrdersRepo) loadRetailerOrderItems(orders []models.RetailerOrder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(orders))
+	byID := make(map[int]int, len(orders))
+	for i, o := range orders {
+		ids[i] = int64(o.Id)
+		byID[o.Id] = i
+	}
+
+	query := `
+		SELECT i.id, i.order_id, i.product_id, COALESCE(i.variant_id, 0), i.quantity, i.price, i.refunded_qty,
+			   p.id, p.wholesaler_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description,
+			   v.id, v.sku, v.options, v.price, v.stock_qty, v.image_url
+		FROM wholesaler_order_items i
+		JOIN wholesaler_products p ON p.id = i.product_id
+		LEFT JOIN wholesaler_product_variants v ON v.id = i.variant_id
+		WHERE i.order_id = ANY($1)
+		ORDER BY i.id
+	`
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.RetailerOrderItem
+		var product models.WholesalerProduct
+		var variant lineVariant
+		if err := rows.Scan(append([]any{
+			&item.Id, &item.OrderId, &item.ProductId, &item.VariantId, &item.Quantity, &item.Price, &item.RefundedQty,
+			&product.Id, &product.Wholesaler_id, &product.Name, &product.Price, &product.Currency, &product.Stock_qty, &product.Image_url, &product.Description,
+		}, variant.dest()...)...); err != nil {
+			return err
+		}
+		item.Product = &product
+		item.Variant = variant.variant(product.Id, product.Currency)
+		o := &orders[byID[item.OrderId]]
+		o.Items = append(o.Items, item)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,240 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOrdersRepo_CreateConsumerOrders_InsufficientStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrdersRepo(db)
+
+	order := &models.ConsumerOrder{
+		RetailerId: 1,
+		UserId:     2,
+		AddressId:  3,
+		TotalPrice: 3000,
+		Currency:   "inr",
+		Status:     models.OrderStatusPending,
+		Items: []models.ConsumerOrderItem{
+			{ProductId: 10, Quantity: 1, Price: 1000},
+			{ProductId: 11, Quantity: 4, Price: 500},
+			{ProductId: 12, VariantId: 5, Quantity: 2, Price: 250},
+		},
+	}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare("INSERT INTO retailer_order_items")
+	mock.ExpectQuery("INSERT INTO retailer_orders").
+		WithArgs(1, 2, 3, models.Money(3000), "inr", models.OrderStatusPending, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(100, "now", "now"))
+	prep.ExpectExec().WithArgs(100, 10, 0, 1, models.Money(1000)).WillReturnResult(sqlmock.NewResult(1, 1))
+	prep.ExpectExec().WithArgs(100, 11, 0, 4, models.Money(500)).WillReturnResult(sqlmock.NewResult(2, 1))
+	prep.ExpectExec().WithArgs(100, 12, 5, 2, models.Money(250)).WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("UPDATE retailer_products p SET reserved_qty").
+		WithArgs(1, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE retailer_products p SET reserved_qty").
+		WithArgs(4, 11).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT name, '', COALESCE").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "sku", "available", "has_variants"}).AddRow("Tea", "", 2, false))
+	mock.ExpectExec("UPDATE retailer_product_variants SET reserved_qty").
+		WithArgs(2, 5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT p.name, v.sku").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "sku", "available", "has_variants"}).AddRow("Mug", "MUG-BLUE", 1, false))
+	mock.ExpectRollback()
+
+	err = repo.CreateConsumerOrders([]*models.ConsumerOrder{order})
+
+	var stockErr *InsufficientStockError
+	if !errors.As(err, &stockErr) {
+		t.Fatalf("Expected InsufficientStockError, got %v", err)
+	}
+	if len(stockErr.Items) != 2 {
+		t.Fatalf("Expected 2 shortages, got %d", len(stockErr.Items))
+	}
+	if got := stockErr.Items[0]; got.ProductId != 11 || got.Requested != 4 || got.Available != 2 || got.Name != "Tea" {
+		t.Errorf("Unexpected shortage: %+v", got)
+	}
+	if got := stockErr.Items[1]; got.ProductId != 12 || got.VariantId != 5 || got.Sku != "MUG-BLUE" || got.Available != 1 {
+		t.Errorf("Unexpected variant shortage: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestOrdersRepo_CreateConsumerOrders_StaleLineWithoutVariant(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrdersRepo(db)
+
+	// The shirt was put in the cart before the retailer gave it sizes
+	order := &models.ConsumerOrder{
+		RetailerId: 1,
+		UserId:     2,
+		AddressId:  3,
+		TotalPrice: 1000,
+		Currency:   "inr",
+		Status:     models.OrderStatusPending,
+		Items:      []models.ConsumerOrderItem{{ProductId: 10, Quantity: 1, Price: 1000}},
+	}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare("INSERT INTO retailer_order_items")
+	mock.ExpectQuery("INSERT INTO retailer_orders").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(100, "now", "now"))
+	prep.ExpectExec().WithArgs(100, 10, 0, 1, models.Money(1000)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE retailer_products p SET reserved_qty .* AND NOT EXISTS \\(SELECT 1 FROM retailer_product_variants v WHERE v.product_id = p.id\\)").
+		WithArgs(1, 10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT name, '', COALESCE").
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "sku", "available", "has_variants"}).AddRow("Shirt", "", 50, true))
+	mock.ExpectRollback()
+
+	err = repo.CreateConsumerOrders([]*models.ConsumerOrder{order})
+	if !errors.Is(err, ErrVariantRequired) {
+		t.Fatalf("Expected ErrVariantRequired, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestOrdersRepo_TransitionConsumerOrderStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrdersRepo(db)
+	actor := models.OrderActor{Type: models.OrderActorRetailer, Id: 3}
+
+	t.Run("paid to cancelled restocks and records history", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE retailer_orders SET status").
+			WithArgs(models.OrderStatusCancelled, 7, models.OrderStatusPaid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE retailer_products p SET stock_qty = COALESCE\\(p.stock_qty, 0\\) \\+ i.quantity - i.restocked_qty").
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("UPDATE retailer_product_variants p SET stock_qty = COALESCE\\(p.stock_qty, 0\\) \\+ i.quantity - i.restocked_qty").
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE retailer_order_items SET restocked_qty = quantity").
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO order_status_history").
+			WithArgs("retailer_orders", 7, models.OrderStatusPaid, models.OrderStatusCancelled, actor.Type, 3).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		if err := repo.TransitionConsumerOrderStatus(7, models.OrderStatusPaid, models.OrderStatusCancelled, actor); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("status changed underneath", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE retailer_orders SET status").
+			WithArgs(models.OrderStatusShipped, 7, models.OrderStatusPaid).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.TransitionConsumerOrderStatus(7, models.OrderStatusPaid, models.OrderStatusShipped, actor)
+		if !errors.Is(err, ErrOrderStatusConflict) {
+			t.Errorf("Expected ErrOrderStatusConflict, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestOrdersRepo_TransitionRetailerOrderStatus_VoidsInvoice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrdersRepo(db)
+	actor := models.OrderActor{Type: models.OrderActorWholesaler, Id: 4}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE wholesaler_orders SET status").
+		WithArgs(models.OrderStatusCancelled, 9, models.OrderStatusConfirmed).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE wholesaler_products p SET stock_qty = COALESCE\\(p.stock_qty, 0\\) \\+ i.quantity - i.restocked_qty").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE wholesaler_product_variants p SET stock_qty = COALESCE\\(p.stock_qty, 0\\) \\+ i.quantity - i.restocked_qty").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE wholesaler_order_items SET restocked_qty = quantity").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE invoices SET status = 'void'").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO order_status_history").
+		WithArgs("wholesaler_orders", 9, models.OrderStatusConfirmed, models.OrderStatusCancelled, actor.Type, 4).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.TransitionRetailerOrderStatus(9, models.OrderStatusConfirmed, models.OrderStatusCancelled, actor); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestOrdersRepo_TransitionRetailerOrderStatus_DeliveredRestocksLinkedProducts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrdersRepo(db)
+	actor := models.OrderActor{Type: models.OrderActorWholesaler, Id: 4}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE wholesaler_orders SET status").
+		WithArgs(models.OrderStatusDelivered, 9, models.OrderStatusShipped).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE retailer_products rp\\s+SET stock_qty = COALESCE\\(rp.stock_qty, 0\\) \\+ d.quantity").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO order_status_history").
+		WithArgs("wholesaler_orders", 9, models.OrderStatusShipped, models.OrderStatusDelivered, actor.Type, 4).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.TransitionRetailerOrderStatus(9, models.OrderStatusShipped, models.OrderStatusDelivered, actor); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
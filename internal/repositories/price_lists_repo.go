@@ -0,0 +1,403 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPriceListNotFound     = errors.New("price list not found")
+	ErrRetailerGroupNotFound = errors.New("retailer group not found")
+	ErrDuplicatePriceList    = errors.New("a price list with this name already exists")
+	ErrDuplicateGroup        = errors.New("a retailer group with this name already exists")
+)
+
+type IPriceListsRepo interface {
+	GetPriceLists(wholesalerID int) ([]models.PriceList, error)
+	GetPriceList(id int, wholesalerID int) (*models.PriceList, error)
+	CreatePriceList(list *models.PriceList) (*models.PriceList, error)
+	UpdatePriceList(list *models.PriceList) (*models.PriceList, error)
+	DeletePriceList(id int, wholesalerID int) error
+	GetRetailerGroups(wholesalerID int) ([]models.RetailerGroup, error)
+	GetRetailerGroup(id int, wholesalerID int) (*models.RetailerGroup, error)
+	CreateRetailerGroup(group *models.RetailerGroup) (*models.RetailerGroup, error)
+	UpdateRetailerGroup(group *models.RetailerGroup) (*models.RetailerGroup, error)
+	DeleteRetailerGroup(id int, wholesalerID int) error
+	GetPriceRules(retailerID int, productIDs []int) ([]models.PriceRule, error)
+}
+
+// PriceListsRepo stores the price lists and retailer groups of wholesalers. Every read and write
+// is scoped to the wholesaler that owns them.
+type PriceListsRepo struct {
+	DB *sql.DB
+}
+
+func NewPriceListsRepo(db *sql.DB) *PriceListsRepo {
+	return &PriceListsRepo{DB: db}
+}
+
+const priceListColumns = `l.id, l.wholesaler_id, l.name, COALESCE(l.discount_percent, 0),
+		COALESCE((
+			SELECT json_agg(json_build_object(
+				'product_id', i.product_id, 'price', COALESCE(i.price, 0), 'discount_percent', COALESCE(i.discount_percent, 0)
+			) ORDER BY i.product_id)
+			FROM price_list_items i
+			WHERE i.price_list_id = l.id
+		), '[]'),
+		ARRAY(SELECT retailer_id FROM price_list_retailers WHERE price_list_id = l.id ORDER BY retailer_id),
+		ARRAY(SELECT group_id FROM price_list_groups WHERE price_list_id = l.id ORDER BY group_id)`
+
+func scanPriceList(scan func(dest ...any) error) (*models.PriceList, error) {
+	var list models.PriceList
+	var retailerIDs, groupIDs pq.Int64Array
+	if err := scan(&list.Id, &list.WholesalerId, &list.Name, &list.DiscountPercent, &list.Items, &retailerIDs, &groupIDs); err != nil {
+		return nil, err
+	}
+	list.RetailerIds, list.GroupIds = ints(retailerIDs), ints(groupIDs)
+	return &list, nil
+}
+
+func (repo *PriceListsRepo) GetPriceLists(wholesalerID int) ([]models.PriceList, error) {
+	rows, err := repo.DB.Query(`SELECT `+priceListColumns+` FROM price_lists l WHERE l.wholesaler_id = $1 ORDER BY l.name`, wholesalerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []models.PriceList{}
+	for rows.Next() {
+		list, err := scanPriceList(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, *list)
+	}
+	return lists, rows.Err()
+}
+
+func (repo *PriceListsRepo) GetPriceList(id int, wholesalerID int) (*models.PriceList, error) {
+	row := repo.DB.QueryRow(`SELECT `+priceListColumns+` FROM price_lists l WHERE l.id = $1 AND l.wholesaler_id = $2`, id, wholesalerID)
+	list, err := scanPriceList(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPriceListNotFound
+	}
+	return list, err
+}
+
+// CreatePriceList saves a new price list along with its items and assignments.
+func (repo *PriceListsRepo) CreatePriceList(list *models.PriceList) (*models.PriceList, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO price_lists (wholesaler_id, name, discount_percent)
+		VALUES ($1, $2, NULLIF($3::numeric, 0))
+		RETURNING id
+	`, list.WholesalerId, list.Name, list.DiscountPercent).Scan(&id)
+	if err != nil {
+		return nil, priceListError(err)
+	}
+
+	if err := writePriceListEntries(tx, id, list); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetPriceList(id, list.WholesalerId)
+}
+
+// UpdatePriceList replaces the name, discount, items and assignments of a price list.
+func (repo *PriceListsRepo) UpdatePriceList(list *models.PriceList) (*models.PriceList, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE price_lists
+		SET name = $1, discount_percent = NULLIF($2::numeric, 0), updated_at = NOW()
+		WHERE id = $3 AND wholesaler_id = $4
+	`, list.Name, list.DiscountPercent, list.Id, list.WholesalerId)
+	if err != nil {
+		return nil, priceListError(err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrPriceListNotFound
+	}
+
+	for _, table := range []string{"price_list_items", "price_list_retailers", "price_list_groups"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE price_list_id = $1`, list.Id); err != nil {
+			return nil, err
+		}
+	}
+	if err := writePriceListEntries(tx, list.Id, list); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetPriceList(list.Id, list.WholesalerId)
+}
+
+// writePriceListEntries saves the items and assignments of a price list. Items may only name the
+// wholesaler's own products and assignments only its own groups.
+func writePriceListEntries(tx *sql.Tx, listID int, list *models.PriceList) error {
+	for _, item := range list.Items {
+		result, err := tx.Exec(`
+			INSERT INTO price_list_items (price_list_id, product_id, price, discount_percent)
+			SELECT $1, id, NULLIF($3::bigint, 0), NULLIF($4::numeric, 0)
+			FROM wholesaler_products
+			WHERE id = $2 AND wholesaler_id = $5
+		`, listID, item.ProductId, item.Price, item.DiscountPercent, list.WholesalerId)
+		if err := requireInserted(result, err, ErrWholesalerProductNotFound); err != nil {
+			return err
+		}
+	}
+
+	for _, retailerID := range list.RetailerIds {
+		_, err := tx.Exec(`INSERT INTO price_list_retailers (price_list_id, retailer_id) VALUES ($1, $2)`, listID, retailerID)
+		if err != nil {
+			return priceListError(err)
+		}
+	}
+
+	for _, groupID := range list.GroupIds {
+		result, err := tx.Exec(`
+			INSERT INTO price_list_groups (price_list_id, group_id)
+			SELECT $1, id FROM retailer_groups WHERE id = $2 AND wholesaler_id = $3
+		`, listID, groupID, list.WholesalerId)
+		if err := requireInserted(result, err, ErrRetailerGroupNotFound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *PriceListsRepo) DeletePriceList(id int, wholesalerID int) error {
+	result, err := repo.DB.Exec(`DELETE FROM price_lists WHERE id = $1 AND wholesaler_id = $2`, id, wholesalerID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrPriceListNotFound
+	}
+	return nil
+}
+
+const retailerGroupColumns = `g.id, g.wholesaler_id, g.name,
+		ARRAY(SELECT retailer_id FROM retailer_group_members WHERE group_id = g.id ORDER BY retailer_id)`
+
+func scanRetailerGroup(scan func(dest ...any) error) (*models.RetailerGroup, error) {
+	var group models.RetailerGroup
+	var retailerIDs pq.Int64Array
+	if err := scan(&group.Id, &group.WholesalerId, &group.Name, &retailerIDs); err != nil {
+		return nil, err
+	}
+	group.RetailerIds = ints(retailerIDs)
+	return &group, nil
+}
+
+func (repo *PriceListsRepo) GetRetailerGroups(wholesalerID int) ([]models.RetailerGroup, error) {
+	rows, err := repo.DB.Query(`SELECT `+retailerGroupColumns+` FROM retailer_groups g WHERE g.wholesaler_id = $1 ORDER BY g.name`, wholesalerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.RetailerGroup{}
+	for rows.Next() {
+		group, err := scanRetailerGroup(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, *group)
+	}
+	return groups, rows.Err()
+}
+
+func (repo *PriceListsRepo) GetRetailerGroup(id int, wholesalerID int) (*models.RetailerGroup, error) {
+	row := repo.DB.QueryRow(`SELECT `+retailerGroupColumns+` FROM retailer_groups g WHERE g.id = $1 AND g.wholesaler_id = $2`, id, wholesalerID)
+	group, err := scanRetailerGroup(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRetailerGroupNotFound
+	}
+	return group, err
+}
+
+func (repo *PriceListsRepo) CreateRetailerGroup(group *models.RetailerGroup) (*models.RetailerGroup, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`INSERT INTO retailer_groups (wholesaler_id, name) VALUES ($1, $2) RETURNING id`, group.WholesalerId, group.Name).Scan(&id)
+	if err != nil {
+		return nil, priceListError(err)
+	}
+
+	if err := writeGroupMembers(tx, id, group.RetailerIds); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetRetailerGroup(id, group.WholesalerId)
+}
+
+// UpdateRetailerGroup renames a group and replaces its members.
+func (repo *PriceListsRepo) UpdateRetailerGroup(group *models.RetailerGroup) (*models.RetailerGroup, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE retailer_groups SET name = $1, updated_at = NOW()
+		WHERE id = $2 AND wholesaler_id = $3
+	`, group.Name, group.Id, group.WholesalerId)
+	if err != nil {
+		return nil, priceListError(err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrRetailerGroupNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM retailer_group_members WHERE group_id = $1`, group.Id); err != nil {
+		return nil, err
+	}
+	if err := writeGroupMembers(tx, group.Id, group.RetailerIds); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetRetailerGroup(group.Id, group.WholesalerId)
+}
+
+func writeGroupMembers(tx *sql.Tx, groupID int, retailerIDs []int) error {
+	for _, retailerID := range retailerIDs {
+		_, err := tx.Exec(`INSERT INTO retailer_group_members (group_id, retailer_id) VALUES ($1, $2)`, groupID, retailerID)
+		if err != nil {
+			return priceListError(err)
+		}
+	}
+	return nil
+}
+
+// DeleteRetailerGroup deletes a group. The price lists assigned to it stop applying to its
+// members, unless they are assigned to them some other way.
+func (repo *PriceListsRepo) DeleteRetailerGroup(id int, wholesalerID int) error {
+	result, err := repo.DB.Exec(`DELETE FROM retailer_groups WHERE id = $1 AND wholesaler_id = $2`, id, wholesalerID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrRetailerGroupNotFound
+	}
+	return nil
+}
+
+// GetPriceRules returns what every price list that applies to the retailer, directly or through
+// one of its groups, says about each of the given products. Products a list says nothing about
+// are left out.
+func (repo *PriceListsRepo) GetPriceRules(retailerID int, productIDs []int) ([]models.PriceRule, error) {
+	ids := make([]int64, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = int64(id)
+	}
+
+	query := `
+		SELECT l.id, l.name, p.id, COALESCE(i.price, 0), COALESCE(i.discount_percent, l.discount_percent, 0)
+		FROM price_lists l
+		JOIN wholesaler_products p ON p.wholesaler_id = l.wholesaler_id
+		LEFT JOIN price_list_items i ON i.price_list_id = l.id AND i.product_id = p.id
+		WHERE p.id = ANY($2)
+		  AND (i.product_id IS NOT NULL OR l.discount_percent IS NOT NULL)
+		  AND (
+			EXISTS (SELECT 1 FROM price_list_retailers r WHERE r.price_list_id = l.id AND r.retailer_id = $1)
+			OR EXISTS (
+				SELECT 1 FROM price_list_groups g
+				JOIN retailer_group_members m ON m.group_id = g.group_id
+				WHERE g.price_list_id = l.id AND m.retailer_id = $1
+			)
+		  )
+		ORDER BY l.id, p.id`
+
+	rows, err := repo.DB.Query(query, retailerID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.PriceRule
+	for rows.Next() {
+		var rule models.PriceRule
+		if err := rows.Scan(&rule.PriceListId, &rule.PriceList, &rule.ProductId, &rule.Price, &rule.DiscountPercent); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// requireInserted turns an INSERT ... SELECT that matched no row into notFound.
+func requireInserted(result sql.Result, err error, notFound error) error {
+	if err != nil {
+		return priceListError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// priceListError maps the constraint violations a price list or group write can hit to their
+// sentinel errors and returns any other error unchanged.
+func priceListError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch {
+	case pqErr.Code == "23505" && pqErr.Constraint == "price_lists_wholesaler_id_name_key":
+		return ErrDuplicatePriceList
+	case pqErr.Code == "23505" && pqErr.Constraint == "retailer_groups_wholesaler_id_name_key":
+		return ErrDuplicateGroup
+	case pqErr.Code == "23503" && strings.HasSuffix(pqErr.Constraint, "_retailer_id_fkey"):
+		return ErrRetailerNotFound
+	}
+	return err
+}
+
+func ints(values pq.Int64Array) []int {
+	out := make([]int, len(values))
+	for i, v := range values {
+		out[i] = int(v)
+	}
+	return out
+}
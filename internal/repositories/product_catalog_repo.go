@@ -0,0 +1,214 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// CatalogUpsert is the outcome of upserting one row of a catalog import.
+type CatalogUpsert struct {
+	ProductId int // 0 for a row that would create a product but was not written
+	Created   bool
+	Err       error // ErrCategoryNotFound, or nil if the row can be written
+}
+
+type IProductCatalogRepo interface {
+	UpsertProducts(sellerID int, rows []models.CatalogRow, commit bool) ([]CatalogUpsert, bool, error)
+	ExportProducts(sellerID int, fn func(models.CatalogRow) error) error
+}
+
+// ProductCatalogRepo reads and writes a seller's whole catalog at once, matching products on
+// the seller's own SKUs.
+type ProductCatalogRepo struct {
+	DB      *sql.DB
+	catalog productCatalog
+}
+
+func NewRetailerCatalogRepo(db *sql.DB) *ProductCatalogRepo {
+	return &ProductCatalogRepo{DB: db, catalog: retailerCatalog}
+}
+
+func NewWholesalerCatalogRepo(db *sql.DB) *ProductCatalogRepo {
+	return &ProductCatalogRepo{DB: db, catalog: wholesalerCatalog}
+}
+
+// UpsertProducts creates or updates one product per row, matched on the seller's SKU. Every row
+// is checked first and the results say what each row would do. The rows are only written, in a
+// single transaction, when commit is set and every row passed; the second return value reports
+// whether they were.
+func (repo *ProductCatalogRepo) UpsertProducts(sellerID int, rows []models.CatalogRow, commit bool) ([]CatalogUpsert, bool, error) {
+	existing, err := repo.productIDsBySKU(sellerID, rows)
+	if err != nil {
+		return nil, false, err
+	}
+	categories, err := knownCategories(repo.DB, rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	results := make([]CatalogUpsert, len(rows))
+	ok := true
+	for i, row := range rows {
+		if row.CategoryID != 0 && !categories[row.CategoryID] {
+			results[i].Err = ErrCategoryNotFound
+			ok = false
+			continue
+		}
+		results[i].ProductId, results[i].Created = existing[row.Sku], existing[row.Sku] == 0
+	}
+	if !commit || !ok {
+		return results, false, nil
+	}
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// xmax is only set on a row version that replaced another, so it is 0 for an insert
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s, sku, name, price, currency, stock_qty, image_url, description, category_id)
+		VALUES ($1, $2, $3, $4, (SELECT currency FROM %[3]s WHERE id = $1), $5, $6, $7, NULLIF($8, 0))
+		ON CONFLICT (%[2]s, sku) DO UPDATE
+		SET name = EXCLUDED.name,
+		    price = EXCLUDED.price,
+		    stock_qty = EXCLUDED.stock_qty,
+		    image_url = EXCLUDED.image_url,
+		    description = EXCLUDED.description,
+		    category_id = EXCLUDED.category_id,
+		    updated_at = NOW()
+		RETURNING id, xmax = 0
+	`, repo.catalog.table, repo.catalog.sellerColumn, repo.catalog.sellers))
+	if err != nil {
+		return nil, false, err
+	}
+	defer stmt.Close()
+
+	for i, row := range rows {
+		// The stock a row replaces is read first so the ledger can record the difference
+		previousStock := 0
+		if results[i].ProductId != 0 {
+			if previousStock, err = lockStock(tx, repo.catalog.table, results[i].ProductId); err != nil {
+				return nil, false, err
+			}
+		}
+
+		err := stmt.QueryRow(sellerID, row.Sku, row.Name, row.Price, row.StockQty, row.ImageURL, row.Description, row.CategoryID).
+			Scan(&results[i].ProductId, &results[i].Created)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to upsert product %q: %w", row.Sku, productWriteError(err))
+		}
+
+		movement := models.InventoryMovement{ProductId: results[i].ProductId, Quantity: row.StockQty - previousStock, StockAfter: row.StockQty, Reason: models.MovementImport}
+		if err := recordMovement(tx, repo.catalog.table, &movement); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
+
+// ExportProducts calls fn with each of the seller's products in the order they were created,
+// reading them from the database as it goes. It stops at the first error fn returns.
+func (repo *ProductCatalogRepo) ExportProducts(sellerID int, fn func(models.CatalogRow) error) error {
+	query := fmt.Sprintf(`
+		SELECT COALESCE(sku, ''), name, price, COALESCE(stock_qty, 0), image_url, description, COALESCE(category_id, 0)
+		FROM %s
+		WHERE %s = $1
+		ORDER BY id
+	`, repo.catalog.table, repo.catalog.sellerColumn)
+
+	rows, err := repo.DB.Query(query, sellerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.CatalogRow
+		if err := rows.Scan(&row.Sku, &row.Name, &row.Price, &row.StockQty, &row.ImageURL, &row.Description, &row.CategoryID); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// productIDsBySKU returns the IDs of the seller's products that have one of the rows' SKUs.
+func (repo *ProductCatalogRepo) productIDsBySKU(sellerID int, rows []models.CatalogRow) (map[string]int, error) {
+	skus := make([]string, len(rows))
+	for i, row := range rows {
+		skus[i] = row.Sku
+	}
+
+	query := fmt.Sprintf(`SELECT sku, id FROM %s WHERE %s = $1 AND sku = ANY($2)`, repo.catalog.table, repo.catalog.sellerColumn)
+	result, err := repo.DB.Query(query, sellerID, pq.Array(skus))
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	ids := make(map[string]int)
+	for result.Next() {
+		var sku string
+		var id int
+		if err := result.Scan(&sku, &id); err != nil {
+			return nil, err
+		}
+		ids[sku] = id
+	}
+	return ids, result.Err()
+}
+
+// knownCategories returns which of the categories the rows refer to exist.
+func knownCategories(db *sql.DB, rows []models.CatalogRow) (map[int]bool, error) {
+	var ids []int64
+	for _, row := range rows {
+		if row.CategoryID != 0 {
+			ids = append(ids, int64(row.CategoryID))
+		}
+	}
+	known := make(map[int]bool)
+	if len(ids) == 0 {
+		return known, nil
+	}
+
+	result, err := db.Query(`SELECT id FROM categories WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var id int
+		if err := result.Scan(&id); err != nil {
+			return nil, err
+		}
+		known[id] = true
+	}
+	return known, result.Err()
+}
+
+// productWriteError maps the constraint violations a product insert or update can hit to their
+// sentinel errors and returns any other error unchanged.
+func productWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.HasSuffix(pqErr.Constraint, "_sku_key") {
+		return ErrDuplicateSKU
+	}
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.HasSuffix(pqErr.Constraint, "_source_product_id_key") {
+		return ErrSourceAlreadyLinked
+	}
+	return categoryError(err)
+}
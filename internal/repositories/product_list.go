@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrUnsupportedSort   = errors.New("sort is not supported for this catalog")
+	ErrUnsupportedFilter = errors.New("filter is not supported for this catalog")
+)
+
+// productCatalog describes a products table that can be listed page by page, and the tables
+// holding its option types and variants.
+type productCatalog struct {
+	table        string
+	sellerColumn string
+	sellers      string // table sellerColumn references
+	options      string
+	variants     string
+	// notFound is the error returned when a product of this catalog does not exist
+	notFound error
+	// ratings joins the review totals of product p as pr, or is "" if the catalog has no reviews
+	ratings string
+	// visibleTo returns an SQL condition for whether product p is shown to the retailer whose ID
+	// is the given placeholder, or is nil if the whole catalog is public
+	visibleTo func(viewer string) string
+}
+
+var (
+	retailerCatalog = productCatalog{
+		table:        "retailer_products",
+		sellerColumn: "retailer_id",
+		sellers:      "retailers",
+		options:      "retailer_product_options",
+		variants:     "retailer_product_variants",
+		notFound:     ErrProductNotFound,
+		ratings:      "LEFT JOIN product_ratings pr ON pr.product_id = p.id",
+	}
+	wholesalerCatalog = productCatalog{
+		table:        "wholesaler_products",
+		sellerColumn: "wholesaler_id",
+		sellers:      "wholesalers",
+		options:      "wholesaler_product_options",
+		variants:     "wholesaler_product_variants",
+		notFound:     ErrWholesalerProductNotFound,
+		visibleTo: func(viewer string) string {
+			return catalogVisibleTo("p.wholesaler_id", viewer)
+		},
+	}
+)
+
+// catalogVisibleTo is an SQL condition for whether the catalog of the wholesaler whose ID is the
+// expression wholesalerID is shown to the retailer whose ID is viewer: it is either public or the
+// retailer's access to it has been approved. A viewer of 0 is an anonymous visitor.
+func catalogVisibleTo(wholesalerID, viewer string) string {
+	return fmt.Sprintf(`(NOT EXISTS (SELECT 1 FROM wholesalers w WHERE w.id = %[1]s AND w.private_catalog)
+		OR EXISTS (SELECT 1 FROM catalog_access a WHERE a.wholesaler_id = %[1]s AND a.retailer_id = %[2]s AND a.status = 'approved'))`, wholesalerID, viewer)
+}
+
+// averageRating is the average rating of product p in a catalog that joins its ratings, 0 when it
+// has no reviews.
+const averageRating = "COALESCE(pr.average_rating, 0)"
+
+// ratingColumns are the columns of the rating summary of product p, in the order ratingDest lists
+// them, in a catalog that joins its ratings.
+const ratingColumns = averageRating + `, COALESCE(pr.review_count, 0), COALESCE(pr.rating_1, 0), COALESCE(pr.rating_2, 0),
+		COALESCE(pr.rating_3, 0), COALESCE(pr.rating_4, 0), COALESCE(pr.rating_5, 0)`
+
+// ratingDest returns the scan destinations for ratingColumns.
+func ratingDest(rating *models.RatingSummary) []any {
+	return []any{&rating.Average, &rating.Count, &rating.Histogram[0], &rating.Histogram[1],
+		&rating.Histogram[2], &rating.Histogram[3], &rating.Histogram[4]}
+}
+
+// productOrder is the keyset a listing is ordered by: key, then the product ID to break ties.
+type productOrder struct {
+	key  string
+	cast string // type the key is cast back to when read from a cursor
+	desc bool
+}
+
+func (c productCatalog) order(sort models.ProductSort) (productOrder, error) {
+	switch sort {
+	case models.ProductSortPriceAsc:
+		return productOrder{key: "p.price", cast: "bigint"}, nil
+	case models.ProductSortPriceDesc:
+		return productOrder{key: "p.price", cast: "bigint", desc: true}, nil
+	case models.ProductSortRating:
+		if c.ratings == "" {
+			return productOrder{}, ErrUnsupportedSort
+		}
+		return productOrder{key: averageRating, cast: "numeric", desc: true}, nil
+	default:
+		return productOrder{key: "p.created_at", cast: "timestamptz", desc: true}, nil
+	}
+}
+
+// productCursor is the position after the last product of a page: the sort it was taken under,
+// that product's sort key as text, and its ID.
+type productCursor struct {
+	Sort models.ProductSort `json:"s"`
+	Key  string             `json:"k"`
+	Id   int                `json:"id"`
+}
+
+func encodeProductCursor(cursor productCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProductCursor(s string, sort models.ProductSort) (productCursor, error) {
+	var cursor productCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.Id <= 0 {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// productListQuery is one page of a listing and the count of everything the filter matches.
+type productListQuery struct {
+	page      string
+	pageArgs  []any
+	count     string
+	countArgs []any
+	sort      models.ProductSort
+	limit     int
+}
+
+// listQuery builds the queries for a page of the catalog. The page query selects columns
+// followed by the sort key as text, and fetches one row more than the limit so the caller can
+// tell whether there is a next page.
+func (c productCatalog) listQuery(columns string, filter models.ProductFilter) (*productListQuery, error) {
+	if filter.Limit <= 0 {
+		return nil, errors.New("product page limit must be positive")
+	}
+	order, err := c.order(filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []string
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Query != "" {
+		conditions = append(conditions, "to_tsvector('simple', p.name || ' ' || coalesce(p.description, '')) @@ plainto_tsquery('simple', "+arg(filter.Query)+")")
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "p.price >= "+arg(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "p.price <= "+arg(*filter.MaxPrice))
+	}
+	if filter.InStock {
+		// A product with variants is sold only through them, so its own stock does not count
+		conditions = append(conditions, fmt.Sprintf(`CASE WHEN EXISTS (SELECT 1 FROM %[1]s v WHERE v.product_id = p.id)
+			THEN EXISTS (SELECT 1 FROM %[1]s v WHERE v.product_id = p.id AND v.stock_qty - v.reserved_qty > 0)
+			ELSE COALESCE(p.stock_qty, 0) - p.reserved_qty > 0 END`, c.variants))
+	}
+	if filter.MinRating != 0 {
+		if c.ratings == "" {
+			return nil, ErrUnsupportedFilter
+		}
+		conditions = append(conditions, averageRating+" >= "+arg(filter.MinRating))
+	}
+	if filter.SellerID != 0 {
+		conditions = append(conditions, fmt.Sprintf("p.%s = %s", c.sellerColumn, arg(filter.SellerID)))
+	}
+	if filter.CategoryID != 0 {
+		conditions = append(conditions, "p.category_id IN ("+categorySubtree(arg(filter.CategoryID))+")")
+	}
+	if c.visibleTo != nil {
+		conditions = append(conditions, c.visibleTo(arg(filter.ViewerID)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	// Counting only needs the ratings to filter by them
+	countJoin := ""
+	if filter.MinRating != 0 {
+		countJoin = c.ratings
+	}
+	q := &productListQuery{
+		count:     fmt.Sprintf(`SELECT COUNT(*) FROM %s p %s %s`, c.table, countJoin, where),
+		countArgs: append([]any(nil), args...),
+		sort:      filter.Sort,
+		limit:     filter.Limit,
+	}
+
+	direction, comparison := "ASC", ">"
+	if order.desc {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		cursor, err := decodeProductCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return nil, err
+		}
+		keyset := fmt.Sprintf("(%s, p.id) %s (%s::%s, %s)", order.key, comparison, arg(cursor.Key), order.cast, arg(cursor.Id))
+		conditions = append(conditions, keyset)
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	q.page = fmt.Sprintf(`
+		SELECT %s, (%s)::text
+		FROM %s p %s
+		%s
+		ORDER BY %s %s, p.id %s
+		LIMIT %s
+	`, columns, order.key, c.table, c.ratings, where, order.key, direction, direction, arg(filter.Limit+1))
+	q.pageArgs = args
+
+	return q, nil
+}
+
+// nextCursor returns the cursor after the last product kept on the page, given the sort key and
+// ID of each row fetched, or "" when the page is the last one.
+func (q *productListQuery) nextCursor(keys []string, ids []int) string {
+	if len(ids) <= q.limit {
+		return ""
+	}
+	last := q.limit - 1
+	return encodeProductCursor(productCursor{Sort: q.sort, Key: keys[last], Id: ids[last]})
+}
+
+// categorySubtree is a query for the IDs of the category with the given ID and all of its descendants.
+func categorySubtree(id string) string {
+	return `WITH RECURSIVE subtree AS (
+		SELECT id FROM categories WHERE id = ` + id + `
+		UNION ALL
+		SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+	) SELECT id FROM subtree`
+}
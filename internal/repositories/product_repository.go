@@ -6,12 +6,16 @@ import (
 	"errors"
 )
 
+var ErrSourceAlreadyLinked = errors.New("another product is already linked to this wholesale product")
+
 type IProductRepository interface {
 	GetProductsByRetailerID(retailerID int) ([]models.RetailerProduct, error)
 	GetProductByIDForRetailer(productID int, retailerID int) (*models.RetailerProduct, error)
 	CreateProduct(product *models.RetailerProduct) (*models.RetailerProduct, error)
 	UpdateProduct(product *models.RetailerProduct) (*models.RetailerProduct, error)
 	DeleteProduct(productID int, retailerID int) error
+	SetSourceProduct(productID int, retailerID int, sourceProductID int) (*models.RetailerProduct, error)
+	SetReorderThreshold(productID int, retailerID int, threshold int) (*models.RetailerProduct, error)
 }
 
 type ProductRepository struct {
@@ -24,7 +28,7 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 
 func (repo *ProductRepository) GetProductsByRetailerID(retailerID int) ([]models.RetailerProduct, error) {
 	query := `
-		SELECT id, retailer_id, name, price, stock_qty, image_url, description
+		SELECT id, retailer_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0), COALESCE(sku, ''), COALESCE(source_product_id, 0), reorder_threshold
 		FROM retailer_products
 		WHERE retailer_id = $1
 		ORDER BY updated_at DESC
@@ -45,9 +49,14 @@ func (repo *ProductRepository) GetProductsByRetailerID(retailerID int) ([]models
 			&product.Retailer_id,
 			&product.Name,
 			&product.Price,
+			&product.Currency,
 			&product.Stock_qty,
 			&product.Image_url,
 			&product.Description,
+			&product.CategoryId,
+			&product.Sku,
+			&product.SourceProductId,
+			&product.ReorderThreshold,
 		)
 		if err != nil {
 			return nil, err
@@ -64,7 +73,7 @@ func (repo *ProductRepository) GetProductsByRetailerID(retailerID int) ([]models
 
 func (repo *ProductRepository) GetProductByIDForRetailer(productID int, retailerID int) (*models.RetailerProduct, error) {
 	query := `
-		SELECT id, retailer_id, name, price, stock_qty, image_url, description
+		SELECT id, retailer_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0), COALESCE(sku, ''), COALESCE(source_product_id, 0), reorder_threshold
 		FROM retailer_products
 		WHERE id = $1 AND retailer_id = $2
 	`
@@ -75,9 +84,14 @@ func (repo *ProductRepository) GetProductByIDForRetailer(productID int, retailer
 		&product.Retailer_id,
 		&product.Name,
 		&product.Price,
+		&product.Currency,
 		&product.Stock_qty,
 		&product.Image_url,
 		&product.Description,
+		&product.CategoryId,
+		&product.Sku,
+		&product.SourceProductId,
+		&product.ReorderThreshold,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -89,14 +103,21 @@ func (repo *ProductRepository) GetProductByIDForRetailer(productID int, retailer
 	return &product, nil
 }
 
+// CreateProduct inserts a product and records its initial stock, if any, as an adjustment.
 func (repo *ProductRepository) CreateProduct(product *models.RetailerProduct) (*models.RetailerProduct, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return &models.RetailerProduct{}, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO retailer_products (retailer_id, name, price, stock_qty, image_url, description)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, retailer_id, name, price, stock_qty, image_url, description
+		INSERT INTO retailer_products (retailer_id, name, price, currency, stock_qty, image_url, description, category_id, sku, source_product_id)
+		VALUES ($1, $2, $3, (SELECT currency FROM retailers WHERE id = $1), $4, $5, $6, NULLIF($7, 0), NULLIF($8, ''), NULLIF($9, 0))
+		RETURNING id, retailer_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0), COALESCE(sku, ''), COALESCE(source_product_id, 0), reorder_threshold
 	`
 
-	err := repo.DB.QueryRow(
+	err = tx.QueryRow(
 		query,
 		product.Retailer_id,
 		product.Name,
@@ -104,23 +125,55 @@ func (repo *ProductRepository) CreateProduct(product *models.RetailerProduct) (*
 		product.Stock_qty,
 		product.Image_url,
 		product.Description,
+		product.CategoryId,
+		product.Sku,
+		product.SourceProductId,
 	).Scan(
 		&product.Id,
 		&product.Retailer_id,
 		&product.Name,
 		&product.Price,
+		&product.Currency,
 		&product.Stock_qty,
 		&product.Image_url,
 		&product.Description,
+		&product.CategoryId,
+		&product.Sku,
+		&product.SourceProductId,
+		&product.ReorderThreshold,
 	)
 	if err != nil {
+		return &models.RetailerProduct{}, productWriteError(err)
+	}
+
+	movement := models.InventoryMovement{ProductId: product.Id, Quantity: product.Stock_qty, StockAfter: product.Stock_qty, Reason: models.MovementAdjustment, Note: "Initial stock"}
+	if err := recordMovement(tx, "retailer_products", &movement); err != nil {
+		return &models.RetailerProduct{}, err
+	}
+	if err := tx.Commit(); err != nil {
 		return &models.RetailerProduct{}, err
 	}
 
 	return product, nil
 }
 
+// UpdateProduct replaces a product's details. A change of stock_qty is recorded as an
+// adjustment.
 func (repo *ProductRepository) UpdateProduct(product *models.RetailerProduct) (*models.RetailerProduct, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return &models.RetailerProduct{}, err
+	}
+	defer tx.Rollback()
+
+	previousStock, err := lockStock(tx, "retailer_products", product.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &models.RetailerProduct{}, ErrProductNotFound
+		}
+		return &models.RetailerProduct{}, err
+	}
+
 	query := `
 		UPDATE retailer_products
 		SET name = $1,
@@ -128,12 +181,14 @@ func (repo *ProductRepository) UpdateProduct(product *models.RetailerProduct) (*
 		    stock_qty = $3,
 		    image_url = $4,
 		    description = $5,
+		    category_id = NULLIF($8, 0),
+		    sku = NULLIF($9, ''),
 		    updated_at = NOW()
 		WHERE id = $6 AND retailer_id = $7
-		RETURNING id, retailer_id, name, price, stock_qty, image_url, description
+		RETURNING id, retailer_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0), COALESCE(sku, ''), COALESCE(source_product_id, 0), reorder_threshold
 	`
 
-	err := repo.DB.QueryRow(
+	err = tx.QueryRow(
 		query,
 		product.Name,
 		product.Price,
@@ -142,19 +197,34 @@ func (repo *ProductRepository) UpdateProduct(product *models.RetailerProduct) (*
 		product.Description,
 		product.Id,
 		product.Retailer_id,
+		product.CategoryId,
+		product.Sku,
 	).Scan(
 		&product.Id,
 		&product.Retailer_id,
 		&product.Name,
 		&product.Price,
+		&product.Currency,
 		&product.Stock_qty,
 		&product.Image_url,
 		&product.Description,
+		&product.CategoryId,
+		&product.Sku,
+		&product.SourceProductId,
+		&product.ReorderThreshold,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return &models.RetailerProduct{}, ErrProductNotFound
 		}
+		return &models.RetailerProduct{}, productWriteError(err)
+	}
+
+	movement := models.InventoryMovement{ProductId: product.Id, Quantity: product.Stock_qty - previousStock, StockAfter: product.Stock_qty, Reason: models.MovementAdjustment, Note: "Product edited"}
+	if err := recordMovement(tx, "retailer_products", &movement); err != nil {
+		return &models.RetailerProduct{}, err
+	}
+	if err := tx.Commit(); err != nil {
 		return &models.RetailerProduct{}, err
 	}
 
@@ -183,3 +253,51 @@ func (repo *ProductRepository) DeleteProduct(productID int, retailerID int) erro
 	return nil
 }
 
+// SetSourceProduct links a retailer product to the wholesaler product it is bought as, or
+// unlinks it when sourceProductID is 0. It returns ErrSourceAlreadyLinked if another of the
+// retailer's products is linked to the same source.
+func (repo *ProductRepository) SetSourceProduct(productID int, retailerID int, sourceProductID int) (*models.RetailerProduct, error) {
+	query := `
+		UPDATE retailer_products
+		SET source_product_id = NULLIF($1, 0), updated_at = NOW()
+		WHERE id = $2 AND retailer_id = $3
+	`
+	result, err := repo.DB.Exec(query, sourceProductID, productID, retailerID)
+	if err != nil {
+		return nil, productWriteError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrProductNotFound
+	}
+
+	return repo.GetProductByIDForRetailer(productID, retailerID)
+}
+
+// SetReorderThreshold sets the available stock below which the retailer is alerted about the
+// product. Changing it clears any alert already sent, so the new threshold is checked afresh.
+func (repo *ProductRepository) SetReorderThreshold(productID int, retailerID int, threshold int) (*models.RetailerProduct, error) {
+	query := `
+		UPDATE retailer_products
+		SET reorder_threshold = $1, low_stock_alerted_at = NULL, updated_at = NOW()
+		WHERE id = $2 AND retailer_id = $3
+	`
+	result, err := repo.DB.Exec(query, threshold, productID, retailerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrProductNotFound
+	}
+
+	return repo.GetProductByIDForRetailer(productID, retailerID)
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestProductRepository_GetProductsByRetailerID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductRepository(db)
+	rows := sqlmock.NewRows([]string{"id", "retailer_id", "name", "price", "currency", "stock_qty", "image_url", "description", "category_id", "sku", "source_product_id", "reorder_threshold"}).
+		AddRow(2, 1, "Binoculars", 4999, "inr", 4, "https://example.com/img.jpg", "", 0, "BIN-10", 0, 5).
+		AddRow(1, 1, "Star map", 999, "inr", 0, "https://example.com/img.jpg", "", 3, "", 40, 0)
+	mock.ExpectQuery("FROM retailer_products").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	products, err := repo.GetProductsByRetailerID(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("Expected 2 products, got %d", len(products))
+	}
+	if products[0].Sku != "BIN-10" || products[1].Sku != "" || products[1].CategoryId != 3 || products[1].SourceProductId != 40 || products[0].ReorderThreshold != 5 {
+		t.Errorf("Unexpected products %+v", products)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Mock expectations were not met: %v", err)
+	}
+}
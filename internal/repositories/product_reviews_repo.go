@@ -3,14 +3,44 @@ package repositories
 import (
 	"Obsonarium-backend/internal/models"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrReviewNotFound    = errors.New("review not found")
+	ErrDuplicateReview   = errors.New("user has already reviewed this product")
+	ErrOwnReview         = errors.New("users cannot report or vote on their own review")
+	ErrAlreadyReported   = errors.New("user has already reported this review")
+	ErrReviewNotReported = errors.New("review has no open reports")
+	ErrVoteNotFound      = errors.New("user has not voted on this review")
+	ErrTooManyImages     = errors.New("a review can have at most 4 images")
+	ErrImageNotFound     = errors.New("review image not found")
 )
 
-var ErrReviewNotFound = errors.New("review not found")
+// maxReviewImages is how many photos a review can have.
+const maxReviewImages = 4
 
 type IProductReviewsRepo interface {
-	GetReviewsByProductID(productID int) ([]models.ProductReview, error)
+	GetReviewsByProductID(productID int, filter models.ReviewFilter) ([]models.ProductReview, models.PageInfo, error)
+	HasPurchased(userID int, productID int) (bool, error)
 	CreateReview(review *models.ProductReview) (*models.ProductReview, error)
+	UpdateReview(review *models.ProductReview) (*models.ProductReview, error)
+	DeleteReview(reviewID int, productID int, userID int) ([]string, error)
+	ReportReview(report *models.ReviewReport, productID int) (*models.ReviewReport, error)
+	GetModerationQueue(retailerID int) ([]models.ReportedReview, error)
+	SetReviewHidden(reviewID int, retailerID int, hidden bool) (*models.ProductReview, error)
+	DeleteReportedReview(reviewID int, retailerID int) ([]string, error)
+	SetSellerReply(reviewID int, retailerID int, reply string) (*models.ProductReview, error)
+	VoteReview(reviewID int, productID int, userID int, helpful bool) (*models.ProductReview, error)
+	RemoveVote(reviewID int, productID int, userID int) (*models.ProductReview, error)
+	AddReviewImage(reviewID int, productID int, userID int, url string) (*models.ReviewImage, error)
+	DeleteReviewImage(imageID int, reviewID int, productID int, userID int) (string, error)
 }
 
 type ProductReviewsRepo struct {
@@ -21,75 +51,754 @@ func NewProductReviewsRepo(db *sql.DB) *ProductReviewsRepo {
 	return &ProductReviewsRepo{DB: db}
 }
 
-func (repo *ProductReviewsRepo) GetReviewsByProductID(productID int) ([]models.ProductReview, error) {
-	query := `
-		SELECT r.id, r.product_id, r.user_id, COALESCE(u.name, 'Anonymous') as reviewer_name, r.rating, r.comment, r.created_at, r.updated_at
+// purchasedStatuses are the statuses of a consumer order that has been paid for, whether or not
+// it has been shipped or delivered yet. Buying a product in one lets the customer review it.
+const purchasedStatuses = `('paid', 'shipped', 'delivered')`
+
+// verifiedPurchase is whether the author of review r has paid for the product it reviews.
+const verifiedPurchase = `EXISTS (
+				SELECT 1 FROM retailer_order_items i
+				JOIN retailer_orders o ON o.id = i.order_id
+				WHERE i.product_id = r.product_id AND o.user_id = r.user_id AND o.status IN ` + purchasedStatuses + `
+			)`
+
+const reviewColumns = `r.id, r.product_id, r.user_id, COALESCE(u.name, 'Anonymous') as reviewer_name, r.rating, r.comment,
+			` + verifiedPurchase + ` AS verified_purchase,
+			r.seller_reply, r.seller_replied_at, r.helpful_count, r.unhelpful_count, COALESCE((
+				SELECT json_agg(json_build_object('id', i.id, 'url', i.url) ORDER BY i.id)
+				FROM review_images i WHERE i.review_id = r.id
+			), '[]'), r.created_at, r.updated_at`
+
+// reviewOrder is the keyset a review feed is ordered by: key, then the review ID to break ties.
+type reviewOrder struct {
+	key  string
+	cast string // type the key is cast back to when read from a cursor
+	desc bool
+}
+
+var reviewOrders = map[models.ReviewSort]reviewOrder{
+	models.ReviewSortNewest:  {key: "r.created_at", cast: "timestamptz", desc: true},
+	models.ReviewSortHelpful: {key: "r.helpful_count", cast: "int", desc: true},
+	models.ReviewSortHighest: {key: "r.rating", cast: "int", desc: true},
+	models.ReviewSortLowest:  {key: "r.rating", cast: "int"},
+}
+
+// reviewCursor is the position after the last review of a page: the sort it was taken under,
+// that review's sort key as text, and its ID.
+type reviewCursor struct {
+	Sort models.ReviewSort `json:"s"`
+	Key  string            `json:"k"`
+	Id   int               `json:"id"`
+}
+
+func decodeReviewCursor(s string, sort models.ReviewSort) (reviewCursor, error) {
+	var cursor reviewCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.Id <= 0 {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// GetReviewsByProductID returns one page of the product's visible reviews matching filter, along
+// with the cursor of the next page and the number of matching reviews.
+func (repo *ProductReviewsRepo) GetReviewsByProductID(productID int, filter models.ReviewFilter) ([]models.ProductReview, models.PageInfo, error) {
+	order, ok := reviewOrders[filter.Sort]
+	if !ok || filter.Limit <= 0 {
+		return nil, models.PageInfo{}, errors.New("review feed needs a sort and a positive limit")
+	}
+
+	conditions := []string{"r.product_id = $1", "r.hidden_at IS NULL"}
+	args := []any{productID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if filter.Rating != 0 {
+		conditions = append(conditions, "r.rating = "+arg(filter.Rating))
+	}
+	if filter.VerifiedOnly {
+		conditions = append(conditions, verifiedPurchase)
+	}
+
+	var page models.PageInfo
+	countQuery := `SELECT COUNT(*) FROM product_reviews r WHERE ` + strings.Join(conditions, " AND ")
+	if err := repo.DB.QueryRow(countQuery, args...).Scan(&page.Total); err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if order.desc {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		cursor, err := decodeReviewCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, r.id) %s (%s::%s, %s)", order.key, comparison, arg(cursor.Key), order.cast, arg(cursor.Id)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, (%s)::text
 		FROM product_reviews r
 		LEFT JOIN users u ON u.id = r.user_id
-		WHERE r.product_id = $1
-		ORDER BY r.created_at DESC
+		WHERE %s
+		ORDER BY %s %s, r.id %s
+		LIMIT %s
+	`, reviewColumns, order.key, strings.Join(conditions, " AND "), order.key, direction, direction, arg(filter.Limit+1))
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	reviews := []models.ProductReview{}
+	var keys []string
+	for rows.Next() {
+		var key string
+		review, err := scanReview(func(dest ...any) error {
+			return rows.Scan(append(dest, &key)...)
+		})
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		reviews = append(reviews, *review)
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	if len(reviews) > filter.Limit {
+		last := filter.Limit - 1
+		data, _ := json.Marshal(reviewCursor{Sort: filter.Sort, Key: keys[last], Id: reviews[last].Id})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+		reviews = reviews[:filter.Limit]
+	}
+
+	return reviews, page, nil
+}
+
+// HasPurchased reports whether the user has a paid, shipped or delivered order for the product.
+func (repo *ProductReviewsRepo) HasPurchased(userID int, productID int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM retailer_order_items i
+			JOIN retailer_orders o ON o.id = i.order_id
+			WHERE i.product_id = $1 AND o.user_id = $2 AND o.status IN ` + purchasedStatuses + `
+		)
 	`
 
-	rows, err := repo.DB.Query(query, productID)
+	var purchased bool
+	if err := repo.DB.QueryRow(query, productID, userID).Scan(&purchased); err != nil {
+		return false, err
+	}
+	return purchased, nil
+}
+
+// CreateReview adds the user's review of the product and counts it in the product's ratings. A
+// user who has already reviewed the product gets ErrDuplicateReview.
+func (repo *ProductReviewsRepo) CreateReview(review *models.ProductReview) (*models.ProductReview, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO product_reviews (product_id, user_id, rating, comment)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	var id int
+	err = tx.QueryRow(query, review.Product_id, review.User_id, review.Rating, review.Comment).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "product_reviews_product_id_user_id_key" {
+			return nil, ErrDuplicateReview
+		}
+		return nil, err
+	}
+
+	if err := adjustRatings(tx, review.Product_id, 0, review.Rating); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.getReview(id)
+}
+
+// UpdateReview replaces the rating and comment of a review. Only its author can change it; for
+// anyone else, or a review of another product, it returns ErrReviewNotFound.
+func (repo *ProductReviewsRepo) UpdateReview(review *models.ProductReview) (*models.ProductReview, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var oldRating int
+	var hidden bool
+	err = tx.QueryRow(`SELECT rating, hidden_at IS NOT NULL FROM product_reviews WHERE id = $1 AND product_id = $2 AND user_id = $3 FOR UPDATE`,
+		review.Id, review.Product_id, review.User_id).Scan(&oldRating, &hidden)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
 
-	var reviews []models.ProductReview
+	_, err = tx.Exec(`UPDATE product_reviews SET rating = $1, comment = $2, updated_at = NOW() WHERE id = $3`,
+		review.Rating, review.Comment, review.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A hidden review is not counted in the ratings, before or after the edit
+	if !hidden {
+		if err := adjustRatings(tx, review.Product_id, oldRating, review.Rating); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.getReview(review.Id)
+}
+
+// DeleteReview removes a review of the product written by the user and takes it out of the
+// product's ratings, unless it was hidden and so not counted in them. It returns the URLs of the
+// review's photos, which are deleted along with it.
+func (repo *ProductReviewsRepo) DeleteReview(reviewID int, productID int, userID int) ([]string, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rating int
+	err = tx.QueryRow(`SELECT `+countedRating+` FROM product_reviews WHERE id = $1 AND product_id = $2 AND user_id = $3 FOR UPDATE`,
+		reviewID, productID, userID).Scan(&rating)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+
+	images, err := deleteReviewImages(tx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM product_reviews WHERE id = $1`, reviewID); err != nil {
+		return nil, err
+	}
+	if err := adjustRatings(tx, productID, rating, 0); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// ReportReview files the user's report of a visible review of the product. Reporting a review
+// again is only accepted once the retailer has dealt with the earlier report.
+func (repo *ProductReviewsRepo) ReportReview(report *models.ReviewReport, productID int) (*models.ReviewReport, error) {
+	var authorID int
+	err := repo.DB.QueryRow(`SELECT user_id FROM product_reviews WHERE id = $1 AND product_id = $2 AND hidden_at IS NULL`,
+		report.Review_id, productID).Scan(&authorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	if authorID == report.User_id {
+		return nil, ErrOwnReview
+	}
+
+	query := `
+		INSERT INTO review_reports (review_id, user_id, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (review_id, user_id) DO UPDATE
+		SET reason = EXCLUDED.reason, resolution = NULL, created_at = NOW(), resolved_at = NULL
+		WHERE review_reports.resolved_at IS NOT NULL
+		RETURNING id, review_id, user_id, reason, created_at
+	`
+	var created models.ReviewReport
+	err = repo.DB.QueryRow(query, report.Review_id, report.User_id, report.Reason).
+		Scan(&created.Id, &created.Review_id, &created.User_id, &created.Reason, &created.Created_at)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAlreadyReported
+		}
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetModerationQueue returns the reviews of the retailer's products that have open reports or
+// have been hidden, most reported first, each with its open reports.
+func (repo *ProductReviewsRepo) GetModerationQueue(retailerID int) ([]models.ReportedReview, error) {
+	query := `
+		SELECT ` + reviewColumns + `, p.name, r.hidden_at IS NOT NULL
+		FROM product_reviews r
+		JOIN retailer_products p ON p.id = r.product_id
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE p.retailer_id = $1 AND (r.hidden_at IS NOT NULL OR EXISTS (
+			SELECT 1 FROM review_reports rr WHERE rr.review_id = r.id AND rr.resolved_at IS NULL
+		))
+		ORDER BY (SELECT COUNT(*) FROM review_reports rr WHERE rr.review_id = r.id AND rr.resolved_at IS NULL) DESC, r.id DESC
+	`
+	rows, err := repo.DB.Query(query, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	queue := []models.ReportedReview{}
+	index := map[int]int{}
+	var reviewIDs []int
 	for rows.Next() {
-		var review models.ProductReview
-		err := rows.Scan(
-			&review.Id,
-			&review.Product_id,
-			&review.User_id,
-			&review.Reviewer_name,
-			&review.Rating,
-			&review.Comment,
-			&review.Created_at,
-			&review.Updated_at,
-		)
+		var item models.ReportedReview
+		review, err := scanReview(func(dest ...any) error {
+			return rows.Scan(append(dest, &item.Product_name, &item.Hidden)...)
+		})
 		if err != nil {
 			return nil, err
 		}
-		reviews = append(reviews, review)
+		item.ProductReview = *review
+		item.Reports = []models.ReviewReport{}
+		index[review.Id] = len(queue)
+		queue = append(queue, item)
+		reviewIDs = append(reviewIDs, review.Id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(queue) == 0 {
+		return queue, nil
 	}
 
-	if err = rows.Err(); err != nil {
+	reportRows, err := repo.DB.Query(`
+		SELECT id, review_id, user_id, reason, created_at
+		FROM review_reports
+		WHERE review_id = ANY($1) AND resolved_at IS NULL
+		ORDER BY id
+	`, pq.Array(reviewIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer reportRows.Close()
+	for reportRows.Next() {
+		var report models.ReviewReport
+		if err := reportRows.Scan(&report.Id, &report.Review_id, &report.User_id, &report.Reason, &report.Created_at); err != nil {
+			return nil, err
+		}
+		item := &queue[index[report.Review_id]]
+		item.Reports = append(item.Reports, report)
+	}
+	return queue, reportRows.Err()
+}
+
+// SetReviewHidden hides a reported review of one of the retailer's products, or restores a
+// hidden or reported one, and closes its open reports either way. Hiding takes the review out of
+// the product's listing and ratings; restoring puts it back. Reviews nobody has reported cannot
+// be hidden, so that retailers cannot bury criticism on their own.
+func (repo *ProductReviewsRepo) SetReviewHidden(reviewID int, retailerID int, hidden bool) (*models.ProductReview, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	review, err := lockModeratedReview(tx, reviewID, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	if !review.reported && (hidden || !review.hidden) {
+		return nil, ErrReviewNotReported
+	}
+
+	resolution := "dismissed"
+	if hidden {
+		resolution = "hidden"
+	}
+	_, err = tx.Exec(`UPDATE review_reports SET resolution = $1, resolved_at = NOW() WHERE review_id = $2 AND resolved_at IS NULL`,
+		resolution, reviewID)
+	if err != nil {
 		return nil, err
 	}
 
-	return reviews, nil
+	if hidden != review.hidden {
+		_, err = tx.Exec(`UPDATE product_reviews SET hidden_at = CASE WHEN $1 THEN NOW() END WHERE id = $2`, hidden, reviewID)
+		if err != nil {
+			return nil, err
+		}
+		removed, added := review.rating, 0
+		if !hidden {
+			removed, added = 0, review.rating
+		}
+		if err := adjustRatings(tx, review.productID, removed, added); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.getReview(reviewID)
 }
 
-func (repo *ProductReviewsRepo) CreateReview(review *models.ProductReview) (*models.ProductReview, error) {
+// DeleteReportedReview removes a review of one of the retailer's products that has open reports
+// or has been hidden, returning the URLs of its photos as DeleteReview does.
+func (repo *ProductReviewsRepo) DeleteReportedReview(reviewID int, retailerID int) ([]string, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	review, err := lockModeratedReview(tx, reviewID, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	if !review.reported && !review.hidden {
+		return nil, ErrReviewNotReported
+	}
+
+	images, err := deleteReviewImages(tx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM product_reviews WHERE id = $1`, reviewID); err != nil {
+		return nil, err
+	}
+	if !review.hidden {
+		if err := adjustRatings(tx, review.productID, review.rating, 0); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// moderatedReview is what moderating a review needs to know about it.
+type moderatedReview struct {
+	productID int
+	rating    int
+	hidden    bool
+	reported  bool // has open reports
+}
+
+// lockModeratedReview locks a review of one of the retailer's products for moderation.
+func lockModeratedReview(tx *sql.Tx, reviewID int, retailerID int) (*moderatedReview, error) {
 	query := `
-		INSERT INTO product_reviews (product_id, user_id, rating, comment)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, product_id, user_id, rating, comment, created_at, updated_at
+		SELECT r.product_id, r.rating, r.hidden_at IS NOT NULL,
+			EXISTS (SELECT 1 FROM review_reports rr WHERE rr.review_id = r.id AND rr.resolved_at IS NULL)
+		FROM product_reviews r
+		JOIN retailer_products p ON p.id = r.product_id
+		WHERE r.id = $1 AND p.retailer_id = $2
+		FOR UPDATE OF r
+	`
+	var review moderatedReview
+	err := tx.QueryRow(query, reviewID, retailerID).Scan(&review.productID, &review.rating, &review.hidden, &review.reported)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// SetSellerReply posts the retailer's public reply to a review of one of its products, replacing
+// any earlier reply. An empty reply removes it.
+func (repo *ProductReviewsRepo) SetSellerReply(reviewID int, retailerID int, reply string) (*models.ProductReview, error) {
+	query := `
+		UPDATE product_reviews r
+		SET seller_reply = NULLIF($1, ''), seller_replied_at = CASE WHEN $1 = '' THEN NULL ELSE NOW() END
+		FROM retailer_products p
+		WHERE p.id = r.product_id AND r.id = $2 AND p.retailer_id = $3
+		RETURNING r.id
 	`
+	var id int
+	if err := repo.DB.QueryRow(query, reply, reviewID, retailerID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return repo.getReview(id)
+}
+
+// VoteReview records whether the user found a visible review of the product helpful, replacing
+// any earlier vote of theirs on it.
+func (repo *ProductReviewsRepo) VoteReview(reviewID int, productID int, userID int, helpful bool) (*models.ProductReview, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockVotedReview(tx, reviewID, productID, userID); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO review_votes (review_id, user_id, helpful) VALUES ($1, $2, $3)
+		ON CONFLICT (review_id, user_id) DO UPDATE SET helpful = EXCLUDED.helpful, created_at = NOW()
+	`, reviewID, userID, helpful)
+	if err != nil {
+		return nil, err
+	}
+	if err := refreshVoteCounts(tx, reviewID); err != nil {
+		return nil, err
+	}
 
-	var createdReview models.ProductReview
-	err := repo.DB.QueryRow(
-		query,
-		review.Product_id,
-		review.User_id,
-		review.Rating,
-		review.Comment,
-	).Scan(
-		&createdReview.Id,
-		&createdReview.Product_id,
-		&createdReview.User_id,
-		&createdReview.Rating,
-		&createdReview.Comment,
-		&createdReview.Created_at,
-		&createdReview.Updated_at,
-	)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.getReview(reviewID)
+}
+
+// RemoveVote takes back the user's vote on a visible review of the product.
+func (repo *ProductReviewsRepo) RemoveVote(reviewID int, productID int, userID int) (*models.ProductReview, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockVotedReview(tx, reviewID, productID, userID); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM review_votes WHERE review_id = $1 AND user_id = $2`, reviewID, userID)
 	if err != nil {
 		return nil, err
 	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrVoteNotFound
+	}
+	if err := refreshVoteCounts(tx, reviewID); err != nil {
+		return nil, err
+	}
 
-	return &createdReview, nil
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.getReview(reviewID)
 }
 
+// lockVotedReview locks a visible review of the product while the user's vote on it changes,
+// and refuses votes on the user's own review.
+func lockVotedReview(tx *sql.Tx, reviewID int, productID int, userID int) error {
+	var authorID int
+	err := tx.QueryRow(`SELECT user_id FROM product_reviews WHERE id = $1 AND product_id = $2 AND hidden_at IS NULL FOR UPDATE`,
+		reviewID, productID).Scan(&authorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrReviewNotFound
+		}
+		return err
+	}
+	if authorID == userID {
+		return ErrOwnReview
+	}
+	return nil
+}
+
+// refreshVoteCounts recounts the votes on a review that lockVotedReview has locked.
+func refreshVoteCounts(tx *sql.Tx, reviewID int) error {
+	_, err := tx.Exec(`
+		UPDATE product_reviews SET
+			helpful_count = (SELECT COUNT(*) FROM review_votes WHERE review_id = $1 AND helpful),
+			unhelpful_count = (SELECT COUNT(*) FROM review_votes WHERE review_id = $1 AND NOT helpful)
+		WHERE id = $1
+	`, reviewID)
+	if err != nil {
+		return fmt.Errorf("failed to count review votes: %w", err)
+	}
+	return nil
+}
+
+// AddReviewImage attaches an uploaded photo to a review of the product written by the user. It
+// returns ErrReviewNotFound for anyone else's review and ErrTooManyImages once the review has
+// maxReviewImages photos.
+func (repo *ProductReviewsRepo) AddReviewImage(reviewID int, productID int, userID int, url string) (*models.ReviewImage, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM review_images i WHERE i.review_id = r.id)
+		FROM product_reviews r
+		WHERE r.id = $1 AND r.product_id = $2 AND r.user_id = $3
+		FOR UPDATE
+	`, reviewID, productID, userID).Scan(&count)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	if count >= maxReviewImages {
+		return nil, ErrTooManyImages
+	}
+
+	image := models.ReviewImage{Url: url}
+	err = tx.QueryRow(`INSERT INTO review_images (review_id, url) VALUES ($1, $2) RETURNING id`, reviewID, url).Scan(&image.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// DeleteReviewImage removes a photo from a review of the product written by the user and
+// returns its URL so the file can be deleted too.
+func (repo *ProductReviewsRepo) DeleteReviewImage(imageID int, reviewID int, productID int, userID int) (string, error) {
+	query := `
+		DELETE FROM review_images i
+		USING product_reviews r
+		WHERE i.id = $1 AND i.review_id = $2 AND r.id = i.review_id AND r.product_id = $3 AND r.user_id = $4
+		RETURNING i.url
+	`
+
+	var url string
+	if err := repo.DB.QueryRow(query, imageID, reviewID, productID, userID).Scan(&url); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrImageNotFound
+		}
+		return "", err
+	}
+	return url, nil
+}
+
+// deleteReviewImages removes the photos of a review that is about to be deleted and returns
+// their URLs.
+func deleteReviewImages(tx *sql.Tx, reviewID int) ([]string, error) {
+	rows, err := tx.Query(`DELETE FROM review_images WHERE review_id = $1 RETURNING url`, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
+
+// countedRating is the rating a review contributes to its product's ratings: none, 0, while it
+// is hidden.
+const countedRating = `CASE WHEN hidden_at IS NULL THEN rating ELSE 0 END`
+
+// adjustRatings moves a review of the product in its rating totals from the rating removed to
+// the rating added, where 0 stands for no review: 0 to 4 counts a new 4-star review, 4 to 0
+// takes it out again.
+func adjustRatings(tx *sql.Tx, productID int, removed int, added int) error {
+	if removed == added {
+		return nil
+	}
+
+	delta := func(rating int) int {
+		d := 0
+		if rating == added {
+			d++
+		}
+		if rating == removed {
+			d--
+		}
+		return d
+	}
+	count := 0
+	if added != 0 {
+		count++
+	}
+	if removed != 0 {
+		count--
+	}
+
+	_, err := tx.Exec(`INSERT INTO product_ratings (product_id) VALUES ($1) ON CONFLICT (product_id) DO NOTHING`, productID)
+	if err != nil {
+		return fmt.Errorf("failed to update product ratings: %w", err)
+	}
+
+	query := `
+		UPDATE product_ratings SET
+			review_count = review_count + $2,
+			rating_total = rating_total + $3,
+			rating_1 = rating_1 + $4,
+			rating_2 = rating_2 + $5,
+			rating_3 = rating_3 + $6,
+			rating_4 = rating_4 + $7,
+			rating_5 = rating_5 + $8
+		WHERE product_id = $1
+	`
+	_, err = tx.Exec(query, productID, count, added-removed, delta(1), delta(2), delta(3), delta(4), delta(5))
+	if err != nil {
+		return fmt.Errorf("failed to update product ratings: %w", err)
+	}
+	return nil
+}
+
+func (repo *ProductReviewsRepo) getReview(id int) (*models.ProductReview, error) {
+	query := `
+		SELECT ` + reviewColumns + `
+		FROM product_reviews r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.id = $1
+	`
+
+	review, err := scanReview(repo.DB.QueryRow(query, id).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return review, nil
+}
+
+func scanReview(scan func(dest ...any) error) (*models.ProductReview, error) {
+	var review models.ProductReview
+	var reply, repliedAt sql.NullString
+	err := scan(&review.Id, &review.Product_id, &review.User_id, &review.Reviewer_name, &review.Rating, &review.Comment,
+		&review.Verified_purchase, &reply, &repliedAt, &review.Helpful_count, &review.Unhelpful_count, &review.Images, &review.Created_at, &review.Updated_at)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Valid {
+		review.Seller_reply = &reply.String
+		review.Seller_replied_at = &repliedAt.String
+	}
+	return &review, nil
+}
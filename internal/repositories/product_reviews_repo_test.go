@@ -0,0 +1,430 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+var reviewColumnNames = []string{"id", "product_id", "user_id", "reviewer_name", "rating", "comment", "verified_purchase",
+	"seller_reply", "seller_replied_at", "helpful_count", "unhelpful_count", "images", "created_at", "updated_at"}
+
+func TestProductReviewsRepo_CreateReview_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductReviewsRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO product_reviews").
+		WithArgs(1, 2, 5, "Great").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "product_reviews_product_id_user_id_key"})
+	mock.ExpectRollback()
+
+	_, err = repo.CreateReview(&models.ProductReview{Product_id: 1, User_id: 2, Rating: 5, Comment: "Great"})
+	if !errors.Is(err, ErrDuplicateReview) {
+		t.Errorf("Expected ErrDuplicateReview, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProductReviewsRepo_GetReviewsByProductID(t *testing.T) {
+	pageColumns := append(append([]string(nil), reviewColumnNames...), "key")
+
+	t.Run("verified purchase", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Failed to create mock: %v", err)
+		}
+		defer db.Close()
+
+		repo := NewProductReviewsRepo(db)
+
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM product_reviews r WHERE r.product_id = \\$1 AND r.hidden_at IS NULL").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("AS verified_purchase.*WHERE r.product_id = \\$1 AND r.hidden_at IS NULL\\s+ORDER BY r.created_at DESC, r.id DESC").
+			WithArgs(1, 21).
+			WillReturnRows(sqlmock.NewRows(pageColumns).
+				AddRow(9, 1, 2, "Ada", 5, "Great", true, "Thank you!", "2026-10-02", 3, 1, `[{"id": 4, "url": "/api/uploads/reviews/1.jpg"}]`, "2026-10-01", "2026-10-01", "2026-10-01").
+				AddRow(8, 1, 3, "Anonymous", 2, "Meh", false, nil, nil, 0, 0, "[]", "2026-09-01", "2026-09-01", "2026-09-01"))
+
+		reviews, page, err := repo.GetReviewsByProductID(1, models.ReviewFilter{Sort: models.ReviewSortNewest, Limit: 20})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(reviews) != 2 || !reviews[0].Verified_purchase || reviews[1].Verified_purchase {
+			t.Errorf("Unexpected reviews: %+v", reviews)
+		}
+		if reviews[0].Seller_reply == nil || *reviews[0].Seller_reply != "Thank you!" || reviews[1].Seller_reply != nil {
+			t.Errorf("Unexpected seller replies: %+v", reviews)
+		}
+		if reviews[0].Helpful_count != 3 || reviews[0].Unhelpful_count != 1 {
+			t.Errorf("Unexpected vote counts: %+v", reviews[0])
+		}
+		if len(reviews[0].Images) != 1 || reviews[0].Images[0].Id != 4 || len(reviews[1].Images) != 0 {
+			t.Errorf("Unexpected images: %+v", reviews)
+		}
+		if page.NextCursor != "" || page.Total != 2 {
+			t.Errorf("Unexpected page: %+v", page)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("most helpful pages", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Failed to create mock: %v", err)
+		}
+		defer db.Close()
+
+		repo := NewProductReviewsRepo(db)
+		filter := models.ReviewFilter{Sort: models.ReviewSortHelpful, Rating: 5, VerifiedOnly: true, Limit: 1}
+
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM product_reviews r WHERE .* AND r.rating = \\$2 AND EXISTS").
+			WithArgs(1, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("ORDER BY r.helpful_count DESC, r.id DESC").
+			WithArgs(1, 5, 2).
+			WillReturnRows(sqlmock.NewRows(pageColumns).
+				AddRow(9, 1, 2, "Ada", 5, "Great", true, nil, nil, 4, 0, "[]", "2026-10-01", "2026-10-01", "4").
+				AddRow(7, 1, 4, "Bo", 5, "Love it", true, nil, nil, 4, 2, "[]", "2026-09-01", "2026-09-01", "4"))
+
+		reviews, page, err := repo.GetReviewsByProductID(1, filter)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(reviews) != 1 || reviews[0].Id != 9 || page.NextCursor == "" || page.Total != 3 {
+			t.Fatalf("Unexpected first page: %+v %+v", reviews, page)
+		}
+
+		// The next page continues after review 9 among reviews with as many helpful votes
+		filter.Cursor = page.NextCursor
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\)").
+			WithArgs(1, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("\\(r.helpful_count, r.id\\) < \\(\\$3::int, \\$4\\)").
+			WithArgs(1, 5, "4", 9, 2).
+			WillReturnRows(sqlmock.NewRows(pageColumns))
+
+		if _, _, err := repo.GetReviewsByProductID(1, filter); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// A cursor is only good for the sort it was taken under
+		filter.Sort = models.ReviewSortNewest
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\)").
+			WithArgs(1, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		if _, _, err := repo.GetReviewsByProductID(1, filter); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor, got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestProductReviewsRepo_UpdateReview_MovesRating(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductReviewsRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rating, hidden_at IS NOT NULL FROM product_reviews WHERE id = \\$1 AND product_id = \\$2 AND user_id = \\$3 FOR UPDATE").
+		WithArgs(9, 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"rating", "hidden"}).AddRow(2, false))
+	mock.ExpectExec("UPDATE product_reviews SET rating").
+		WithArgs(5, "Better than I thought", 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO product_ratings \\(product_id\\) VALUES \\(\\$1\\) ON CONFLICT").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// Same review count, 3 more stars, one fewer 2-star and one more 5-star review
+	mock.ExpectExec("UPDATE product_ratings SET").
+		WithArgs(1, 0, 3, 0, -1, 0, 0, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("AS verified_purchase").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(reviewColumnNames).
+			AddRow(9, 1, 2, "Ada", 5, "Better than I thought", true, nil, nil, 0, 0, "[]", "2026-10-01", "2026-10-17"))
+
+	review, err := repo.UpdateReview(&models.ProductReview{Id: 9, Product_id: 1, User_id: 2, Rating: 5, Comment: "Better than I thought"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if review.Rating != 5 {
+		t.Errorf("Expected the new rating, got %d", review.Rating)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProductReviewsRepo_DeleteReview_NotAuthor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductReviewsRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT CASE WHEN hidden_at IS NULL THEN rating ELSE 0 END FROM product_reviews WHERE id = \\$1 AND product_id = \\$2 AND user_id = \\$3 FOR UPDATE").
+		WithArgs(9, 1, 3).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if _, err := repo.DeleteReview(9, 1, 3); !errors.Is(err, ErrReviewNotFound) {
+		t.Errorf("Expected ErrReviewNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProductReviewsRepo_DeleteReview_ReturnsImages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductReviewsRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT CASE WHEN hidden_at IS NULL THEN rating ELSE 0 END FROM product_reviews").
+		WithArgs(9, 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"rating"}).AddRow(4))
+	mock.ExpectQuery("DELETE FROM review_images WHERE review_id = \\$1 RETURNING url").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"url"}).AddRow("/api/uploads/reviews/1.jpg").AddRow("/api/uploads/reviews/2.jpg"))
+	mock.ExpectExec("DELETE FROM product_reviews WHERE id = \\$1").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO product_ratings").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE product_ratings SET").
+		WithArgs(1, -1, -4, 0, 0, 0, -1, 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	images, err := repo.DeleteReview(9, 1, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(images) != 2 || images[0] != "/api/uploads/reviews/1.jpg" {
+		t.Errorf("Unexpected images: %v", images)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProductReviewsRepo_AddReviewImage_TooMany(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductReviewsRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\(SELECT COUNT\\(\\*\\) FROM review_images i WHERE i.review_id = r.id\\)\\s+FROM product_reviews r\\s+WHERE r.id = \\$1 AND r.product_id = \\$2 AND r.user_id = \\$3\\s+FOR UPDATE").
+		WithArgs(9, 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(maxReviewImages))
+	mock.ExpectRollback()
+
+	if _, err := repo.AddReviewImage(9, 1, 2, "/api/uploads/reviews/5.jpg"); !errors.Is(err, ErrTooManyImages) {
+		t.Errorf("Expected ErrTooManyImages, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProductReviewsRepo_SetReviewHidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductReviewsRepo(db)
+	moderated := []string{"product_id", "rating", "hidden", "reported"}
+
+	t.Run("hiding a reported review takes it out of the ratings", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("FROM product_reviews r JOIN retailer_products p .* FOR UPDATE OF r").
+			WithArgs(9, 4).
+			WillReturnRows(sqlmock.NewRows(moderated).AddRow(1, 2, false, true))
+		mock.ExpectExec("UPDATE review_reports SET resolution").
+			WithArgs("hidden", 9).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("UPDATE product_reviews SET hidden_at").
+			WithArgs(true, 9).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO product_ratings").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE product_ratings SET").
+			WithArgs(1, -1, -2, 0, -1, 0, 0, 0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("AS verified_purchase").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows(reviewColumnNames).
+				AddRow(9, 1, 2, "Ada", 2, "Spam spam spam", false, nil, nil, 0, 0, "[]", "2026-10-01", "2026-10-01"))
+
+		if _, err := repo.SetReviewHidden(9, 4, true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("unreported reviews cannot be hidden", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE OF r").
+			WithArgs(9, 4).
+			WillReturnRows(sqlmock.NewRows(moderated).AddRow(1, 2, false, false))
+		mock.ExpectRollback()
+
+		if _, err := repo.SetReviewHidden(9, 4, true); !errors.Is(err, ErrReviewNotReported) {
+			t.Errorf("Expected ErrReviewNotReported, got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestProductReviewsRepo_ReportReview_OwnReview(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductReviewsRepo(db)
+
+	mock.ExpectQuery("SELECT user_id FROM product_reviews").
+		WithArgs(9, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+
+	_, err = repo.ReportReview(&models.ReviewReport{Review_id: 9, User_id: 2, Reason: "Fake"}, 1)
+	if !errors.Is(err, ErrOwnReview) {
+		t.Errorf("Expected ErrOwnReview, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProductReviewsRepo_VoteReview(t *testing.T) {
+	t.Run("replaces earlier vote", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Failed to create mock: %v", err)
+		}
+		defer db.Close()
+
+		repo := NewProductReviewsRepo(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id FROM product_reviews WHERE id = \\$1 AND product_id = \\$2 AND hidden_at IS NULL FOR UPDATE").
+			WithArgs(9, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+		mock.ExpectExec("INSERT INTO review_votes .* ON CONFLICT \\(review_id, user_id\\) DO UPDATE").
+			WithArgs(9, 3, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE product_reviews SET\\s+helpful_count").
+			WithArgs(9).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("AS verified_purchase").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows(reviewColumnNames).
+				AddRow(9, 1, 2, "Ada", 5, "Great", true, nil, nil, 0, 1, "[]", "2026-10-01", "2026-10-01"))
+
+		review, err := repo.VoteReview(9, 1, 3, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if review.Unhelpful_count != 1 {
+			t.Errorf("Expected the recounted votes, got %+v", review)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("own review", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Failed to create mock: %v", err)
+		}
+		defer db.Close()
+
+		repo := NewProductReviewsRepo(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id FROM product_reviews").
+			WithArgs(9, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+		mock.ExpectRollback()
+
+		if _, err := repo.VoteReview(9, 1, 2, true); !errors.Is(err, ErrOwnReview) {
+			t.Errorf("Expected ErrOwnReview, got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestProductReviewsRepo_RemoveVote_NotVoted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProductReviewsRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT user_id FROM product_reviews").
+		WithArgs(9, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+	mock.ExpectExec("DELETE FROM review_votes").
+		WithArgs(9, 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if _, err := repo.RemoveVote(9, 1, 3); !errors.Is(err, ErrVoteNotFound) {
+		t.Errorf("Expected ErrVoteNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrVariantNotFound  = errors.New("variant not found")
+	ErrVariantRequired  = errors.New("product is sold in variants, a variant must be chosen")
+	ErrDuplicateSKU     = errors.New("sku is already in use in the seller's catalog")
+	ErrDuplicateVariant = errors.New("product already has a variant with these options")
+	ErrVariantOrdered   = errors.New("variant has been ordered and cannot be deleted")
+	ErrVariantsExist    = errors.New("option types cannot change while the product has variants")
+)
+
+type IProductVariantsRepo interface {
+	GetOptions(productID int) ([]string, error)
+	SetOptions(productID int, sellerID int, names []string) error
+	GetVariants(productID int) ([]models.ProductVariant, error)
+	CreateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error)
+	UpdateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error)
+	DeleteVariant(productID int, variantID int, sellerID int) error
+}
+
+// ProductVariantsRepo manages the option types and variants of one catalog's products. Writes
+// only succeed for products that belong to the given seller.
+type ProductVariantsRepo struct {
+	DB      *sql.DB
+	catalog productCatalog
+}
+
+func NewRetailerVariantsRepo(db *sql.DB) *ProductVariantsRepo {
+	return &ProductVariantsRepo{DB: db, catalog: retailerCatalog}
+}
+
+func NewWholesalerVariantsRepo(db *sql.DB) *ProductVariantsRepo {
+	return &ProductVariantsRepo{DB: db, catalog: wholesalerCatalog}
+}
+
+// GetOptions returns the names of a product's option types in display order.
+func (repo *ProductVariantsRepo) GetOptions(productID int) ([]string, error) {
+	query := fmt.Sprintf(`SELECT name FROM %s WHERE product_id = $1 ORDER BY position`, repo.catalog.options)
+	rows, err := repo.DB.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// SetOptions replaces a product's option types. Once the product has variants the option types
+// can only be reordered, since every variant has a value for each of them.
+func (repo *ProductVariantsRepo) SetOptions(productID int, sellerID int, names []string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var hasVariants bool
+	query := fmt.Sprintf(`
+		SELECT EXISTS (SELECT 1 FROM %s WHERE product_id = p.id)
+		FROM %s p
+		WHERE p.id = $1 AND p.%s = $2
+		FOR UPDATE
+	`, repo.catalog.variants, repo.catalog.table, repo.catalog.sellerColumn)
+	if err := tx.QueryRow(query, productID, sellerID).Scan(&hasVariants); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.catalog.notFound
+		}
+		return err
+	}
+
+	if hasVariants {
+		rows, err := tx.Query(fmt.Sprintf(`SELECT name FROM %s WHERE product_id = $1`, repo.catalog.options), productID)
+		if err != nil {
+			return err
+		}
+		current := make(map[string]bool)
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return err
+			}
+			current[name] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(current) != len(names) {
+			return ErrVariantsExist
+		}
+		for _, name := range names {
+			if !current[name] {
+				return ErrVariantsExist
+			}
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE product_id = $1`, repo.catalog.options), productID); err != nil {
+		return err
+	}
+	insert := fmt.Sprintf(`INSERT INTO %s (product_id, name, position) VALUES ($1, $2, $3)`, repo.catalog.options)
+	for position, name := range names {
+		if _, err := tx.Exec(insert, productID, name, position); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetVariants returns every variant of a product in the order they were added.
+func (repo *ProductVariantsRepo) GetVariants(productID int) ([]models.ProductVariant, error) {
+	query := fmt.Sprintf(`
+		SELECT v.id, v.product_id, v.sku, v.options, v.price, p.currency, v.stock_qty, COALESCE(v.image_url, '')
+		FROM %s v
+		JOIN %s p ON p.id = v.product_id
+		WHERE v.product_id = $1
+		ORDER BY v.id
+	`, repo.catalog.variants, repo.catalog.table)
+	rows, err := repo.DB.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []models.ProductVariant{}
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := rows.Scan(&v.Id, &v.ProductId, &v.Sku, &v.Options, &v.Price, &v.Currency, &v.Stock_qty, &v.Image_url); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+// CreateVariant adds a variant to one of the seller's products. Its SKU must not be used by any
+// other variant of the seller's, or it returns ErrDuplicateSKU.
+func (repo *ProductVariantsRepo) CreateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	query := fmt.Sprintf(`
+		WITH product AS (
+			SELECT id, currency FROM %[1]s WHERE id = $1 AND %[2]s = $2
+		)
+		INSERT INTO %[3]s (product_id, %[2]s, sku, options, price, stock_qty, image_url)
+		SELECT id, $2::int, $3::text, $4::jsonb, $5::bigint, $6::int, NULLIF($7::text, '') FROM product
+		RETURNING id, product_id, sku, options, price, (SELECT currency FROM product), stock_qty, COALESCE(image_url, '')
+	`, repo.catalog.table, repo.catalog.sellerColumn, repo.catalog.variants)
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var created models.ProductVariant
+	err = tx.QueryRow(query, variant.ProductId, sellerID, variant.Sku, variant.Options, variant.Price, variant.Stock_qty, variant.Image_url).Scan(
+		&created.Id, &created.ProductId, &created.Sku, &created.Options, &created.Price, &created.Currency, &created.Stock_qty, &created.Image_url,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.catalog.notFound
+		}
+		return nil, variantError(err)
+	}
+
+	movement := models.InventoryMovement{ProductId: created.ProductId, VariantId: created.Id, Quantity: created.Stock_qty, StockAfter: created.Stock_qty, Reason: models.MovementAdjustment, Note: "Initial stock"}
+	if err := recordMovement(tx, repo.catalog.table, &movement); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (repo *ProductVariantsRepo) UpdateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s v
+		SET sku = $1, options = $2, price = $3, stock_qty = $4, image_url = NULLIF($5, ''), updated_at = NOW()
+		FROM %s p
+		WHERE v.id = $6 AND v.product_id = $7 AND p.id = v.product_id AND p.%s = $8
+		RETURNING v.id, v.product_id, v.sku, v.options, v.price, p.currency, v.stock_qty, COALESCE(v.image_url, '')
+	`, repo.catalog.variants, repo.catalog.table, repo.catalog.sellerColumn)
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	previousStock, err := lockStock(tx, repo.catalog.variants, variant.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	var updated models.ProductVariant
+	err = tx.QueryRow(query, variant.Sku, variant.Options, variant.Price, variant.Stock_qty, variant.Image_url, variant.Id, variant.ProductId, sellerID).Scan(
+		&updated.Id, &updated.ProductId, &updated.Sku, &updated.Options, &updated.Price, &updated.Currency, &updated.Stock_qty, &updated.Image_url,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, variantError(err)
+	}
+
+	movement := models.InventoryMovement{ProductId: updated.ProductId, VariantId: updated.Id, Quantity: updated.Stock_qty - previousStock, StockAfter: updated.Stock_qty, Reason: models.MovementAdjustment, Note: "Variant edited"}
+	if err := recordMovement(tx, repo.catalog.table, &movement); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (repo *ProductVariantsRepo) DeleteVariant(productID int, variantID int, sellerID int) error {
+	query := fmt.Sprintf(`
+		DELETE FROM %s v
+		USING %s p
+		WHERE v.id = $1 AND v.product_id = $2 AND p.id = v.product_id AND p.%s = $3
+	`, repo.catalog.variants, repo.catalog.table, repo.catalog.sellerColumn)
+
+	result, err := repo.DB.Exec(query, variantID, productID, sellerID)
+	if err != nil {
+		return variantError(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+// variantError maps the constraint violations a variant write can hit to their sentinel errors
+// and returns any other error unchanged.
+func variantError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch {
+	case pqErr.Code == "23505" && strings.HasSuffix(pqErr.Constraint, "_sku_key"):
+		return ErrDuplicateSKU
+	case pqErr.Code == "23505" && strings.HasSuffix(pqErr.Constraint, "_product_id_options_key"):
+		return ErrDuplicateVariant
+	case pqErr.Code == "23503" && strings.HasSuffix(pqErr.Constraint, "_variant_id_fkey"):
+		return ErrVariantOrdered
+	}
+	return err
+}
+
+// lineVariant scans the variant of a cart or order line, selected from a LEFT JOIN as
+// v.id, v.sku, v.options, v.price, v.stock_qty, v.image_url. The columns are all NULL when the
+// line has no variant.
+type lineVariant struct {
+	id       sql.NullInt64
+	sku      sql.NullString
+	options  models.VariantOptions
+	price    sql.NullInt64
+	stockQty sql.NullInt64
+	imageURL sql.NullString
+}
+
+func (v *lineVariant) dest() []any {
+	return []any{&v.id, &v.sku, &v.options, &v.price, &v.stockQty, &v.imageURL}
+}
+
+// variant returns the scanned variant, or nil if the line has none.
+func (v *lineVariant) variant(productID int, currency string) *models.ProductVariant {
+	if !v.id.Valid {
+		return nil
+	}
+	return &models.ProductVariant{
+		Id:        int(v.id.Int64),
+		ProductId: productID,
+		Sku:       v.sku.String,
+		Options:   v.options,
+		Price:     models.Money(v.price.Int64),
+		Currency:  currency,
+		Stock_qty: int(v.stockQty.Int64),
+		Image_url: v.imageURL.String,
+	}
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestProductVariantsRepo_CreateVariant_DuplicateSKU(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRetailerVariantsRepo(db)
+	variant := &models.ProductVariant{ProductId: 10, Sku: "SHIRT-M", Options: models.VariantOptions{"size": "M"}, Price: 1500, Stock_qty: 3}
+
+	// The variant is stored against its seller, whose other variants the SKU must not clash with
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO retailer_product_variants \\(product_id, retailer_id, sku,").
+		WithArgs(10, 7, "SHIRT-M", sqlmock.AnyArg(), models.Money(1500), 3, "").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "retailer_product_variants_retailer_id_sku_key"})
+	mock.ExpectRollback()
+
+	if _, err := repo.CreateVariant(7, variant); !errors.Is(err, ErrDuplicateSKU) {
+		t.Errorf("Expected ErrDuplicateSKU, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
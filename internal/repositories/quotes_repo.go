@@ -0,0 +1,391 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrQuoteNotFound       = errors.New("quote not found")
+	ErrQuoteStatusConflict = errors.New("quote cannot be changed in its current status")
+	ErrQuoteExpired        = errors.New("quote offer has expired")
+)
+
+type IQuotesRepo interface {
+	CreateQuote(wholesalerID int, retailerID int, items []models.QuoteItem, note string) (*models.Quote, error)
+	GetQuote(id int) (*models.Quote, error)
+	GetQuotesByRetailer(retailerID int) ([]models.Quote, error)
+	GetQuotesByWholesaler(wholesalerID int) ([]models.Quote, error)
+	ReviseQuote(id int, author models.OrderActorType, from []models.QuoteStatus, to models.QuoteStatus, items []models.QuoteItem, note string, expiresAt *time.Time) (*models.Quote, error)
+	DeclineQuote(id int) (*models.Quote, error)
+	AcceptQuote(id int) (*models.RetailerOrder, error)
+}
+
+type QuotesRepo struct {
+	DB *sql.DB
+}
+
+func NewQuotesRepo(db *sql.DB) *QuotesRepo {
+	return &QuotesRepo{DB: db}
+}
+
+// quoteColumns selects a quote along with its current items, named after their products. An
+// offer whose expiry has passed reads as expired.
+const quoteColumns = `q.id, q.wholesaler_id, COALESCE(w.business_name, ''), q.retailer_id, COALESCE(r.business_name, ''),
+		CASE WHEN q.status = 'offered' AND q.expires_at < NOW() THEN 'expired' ELSE q.status END,
+		q.currency, q.expires_at, COALESCE(q.order_id, 0), COALESCE((
+			SELECT json_agg(json_build_object(
+				'product_id', i.product_id, 'variant_id', COALESCE(i.variant_id, 0), 'name', p.name,
+				'sku', COALESCE(v.sku, ''), 'quantity', i.quantity, 'price', i.price
+			) ORDER BY i.id)
+			FROM quote_items i
+			JOIN wholesaler_products p ON p.id = i.product_id
+			LEFT JOIN wholesaler_product_variants v ON v.id = i.variant_id
+			WHERE i.quote_id = q.id
+		), '[]'), q.created_at, q.updated_at`
+
+const quoteFrom = `quotes q
+		JOIN wholesalers w ON w.id = q.wholesaler_id
+		JOIN retailers r ON r.id = q.retailer_id`
+
+func scanQuote(scan func(dest ...any) error) (*models.Quote, error) {
+	var quote models.Quote
+	var expiresAt sql.NullString
+	err := scan(&quote.Id, &quote.WholesalerId, &quote.WholesalerName, &quote.RetailerId, &quote.RetailerName,
+		&quote.Status, &quote.Currency, &expiresAt, &quote.OrderId, &quote.Items, &quote.CreatedAt, &quote.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	quote.ExpiresAt = expiresAt.String
+	quote.Total = quote.Items.Total()
+	return &quote, nil
+}
+
+// CreateQuote records a retailer's request for a quote. Every item must be a product of the
+// wholesaler that the retailer may see.
+func (repo *QuotesRepo) CreateQuote(wholesalerID int, retailerID int, items []models.QuoteItem, note string) (*models.Quote, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var currency string
+	if err := tx.QueryRow(`SELECT currency FROM wholesalers WHERE id = $1`, wholesalerID).Scan(&currency); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWholesalerNotFound
+		}
+		return nil, err
+	}
+
+	var id int
+	err = tx.QueryRow(
+		`INSERT INTO quotes (wholesaler_id, retailer_id, currency) VALUES ($1, $2, $3) RETURNING id`,
+		wholesalerID, retailerID, currency,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setQuoteItems(tx, id, wholesalerID, retailerID, items); err != nil {
+		return nil, err
+	}
+	if err := addQuoteRevision(tx, id, models.OrderActorRetailer, note, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.GetQuote(id)
+}
+
+// GetQuote returns a quote along with its revisions.
+func (repo *QuotesRepo) GetQuote(id int) (*models.Quote, error) {
+	row := repo.DB.QueryRow(`SELECT `+quoteColumns+` FROM `+quoteFrom+` WHERE q.id = $1`, id)
+	quote, err := scanQuote(row.Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := repo.DB.Query(`
+		SELECT id, author, items, note, expires_at, created_at
+		FROM quote_revisions
+		WHERE quote_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quote.Revisions = []models.QuoteRevision{}
+	for rows.Next() {
+		var revision models.QuoteRevision
+		var expiresAt sql.NullString
+		if err := rows.Scan(&revision.Id, &revision.Author, &revision.Items, &revision.Note, &expiresAt, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+		revision.ExpiresAt = expiresAt.String
+		quote.Revisions = append(quote.Revisions, revision)
+	}
+	return quote, rows.Err()
+}
+
+// GetQuotesByRetailer returns the retailer's quotes, most recently changed first.
+func (repo *QuotesRepo) GetQuotesByRetailer(retailerID int) ([]models.Quote, error) {
+	return repo.listQuotes(`q.retailer_id = $1`, retailerID)
+}
+
+// GetQuotesByWholesaler returns the quotes asked of the wholesaler, most recently changed first.
+func (repo *QuotesRepo) GetQuotesByWholesaler(wholesalerID int) ([]models.Quote, error) {
+	return repo.listQuotes(`q.wholesaler_id = $1`, wholesalerID)
+}
+
+func (repo *QuotesRepo) listQuotes(condition string, args ...any) ([]models.Quote, error) {
+	rows, err := repo.DB.Query(`SELECT `+quoteColumns+` FROM `+quoteFrom+` WHERE `+condition+` ORDER BY q.updated_at DESC, q.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quotes := []models.Quote{}
+	for rows.Next() {
+		quote, err := scanQuote(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, *quote)
+	}
+	return quotes, rows.Err()
+}
+
+// ReviseQuote puts new terms on the table: the quote's items are replaced, it moves to status to
+// and the terms are added to its revisions. The quote must be in one of the from statuses. The
+// items of a retailer's revision must be visible to the retailer.
+func (repo *QuotesRepo) ReviseQuote(id int, author models.OrderActorType, from []models.QuoteStatus, to models.QuoteStatus, items []models.QuoteItem, note string, expiresAt *time.Time) (*models.Quote, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	quote, err := lockQuote(tx, id, from)
+	if err != nil {
+		return nil, err
+	}
+
+	viewerID := 0
+	if author == models.OrderActorRetailer {
+		viewerID = quote.RetailerId
+	}
+	if _, err := tx.Exec(`DELETE FROM quote_items WHERE quote_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if err := setQuoteItems(tx, id, quote.WholesalerId, viewerID, items); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE quotes SET status = $1, expires_at = $2, updated_at = NOW() WHERE id = $3`, to, expiresAt, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := addQuoteRevision(tx, id, author, note, expiresAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.GetQuote(id)
+}
+
+// DeclineQuote ends a negotiation that has not been accepted yet.
+func (repo *QuotesRepo) DeclineQuote(id int) (*models.Quote, error) {
+	result, err := repo.DB.Exec(`
+		UPDATE quotes SET status = 'declined', updated_at = NOW()
+		WHERE id = $1 AND status IN ('requested', 'offered', 'countered')
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := repo.GetQuote(id)
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrQuoteStatusConflict
+	}
+	return quote, nil
+}
+
+// AcceptQuote places a pending wholesale order for the offer on the table and reserves its stock.
+// An offer can be accepted until it expires. If the checkout for the order is abandoned, which
+// fails the order, the offer can be accepted again.
+func (repo *QuotesRepo) AcceptQuote(id int) (*models.RetailerOrder, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status models.QuoteStatus
+	var expired bool
+	var orderStatus sql.NullString
+	order := &models.RetailerOrder{Status: models.OrderStatusPending}
+	err = tx.QueryRow(`
+		SELECT q.status, COALESCE(q.expires_at < NOW(), false), o.status, q.wholesaler_id, q.retailer_id, q.currency
+		FROM quotes q
+		LEFT JOIN wholesaler_orders o ON o.id = q.order_id
+		WHERE q.id = $1
+		FOR UPDATE OF q
+	`, id).Scan(&status, &expired, &orderStatus, &order.WholesalerId, &order.RetailerId, &order.Currency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, err
+	}
+	abandoned := status == models.QuoteAccepted && orderStatus.String == string(models.OrderStatusFailed)
+	if status != models.QuoteOffered && !abandoned {
+		return nil, ErrQuoteStatusConflict
+	}
+	if expired {
+		return nil, ErrQuoteExpired
+	}
+
+	rows, err := tx.Query(`
+		SELECT i.product_id, COALESCE(i.variant_id, 0), i.quantity, i.price, p.name, COALESCE(v.sku, '')
+		FROM quote_items i
+		JOIN wholesaler_products p ON p.id = i.product_id
+		LEFT JOIN wholesaler_product_variants v ON v.id = i.variant_id
+		WHERE i.quote_id = $1
+		ORDER BY i.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var item models.RetailerOrderItem
+		var name, sku string
+		if err := rows.Scan(&item.ProductId, &item.VariantId, &item.Quantity, &item.Price, &name, &sku); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		item.Product = &models.WholesalerProduct{Id: item.ProductId, Name: name, Currency: order.Currency}
+		if item.VariantId != 0 {
+			item.Variant = &models.ProductVariant{Id: item.VariantId, ProductId: item.ProductId, Sku: sku}
+		}
+		order.TotalPrice += item.Price.Times(item.Quantity)
+		order.Items = append(order.Items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lines, err := insertRetailerOrder(tx, order)
+	if err != nil {
+		return nil, err
+	}
+	if err := reserveStock(tx, retailerOrdersTable, lines); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE quotes SET status = 'accepted', order_id = $1, updated_at = NOW() WHERE id = $2`, order.Id, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return order, tx.Commit()
+}
+
+// lockQuote locks a quote for the rest of the transaction, provided it is in one of the given
+// statuses.
+func lockQuote(tx *sql.Tx, id int, statuses []models.QuoteStatus) (*models.Quote, error) {
+	var quote models.Quote
+	err := tx.QueryRow(`SELECT id, wholesaler_id, retailer_id, status FROM quotes WHERE id = $1 FOR UPDATE`, id).Scan(
+		&quote.Id, &quote.WholesalerId, &quote.RetailerId, &quote.Status,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, err
+	}
+	for _, status := range statuses {
+		if quote.Status == status {
+			return &quote, nil
+		}
+	}
+	return nil, ErrQuoteStatusConflict
+}
+
+// setQuoteItems adds the items to a quote. Each must be a product of the wholesaler, with a
+// variant of it if it has any; when viewerID is not 0 the product must also be visible to that
+// retailer.
+func setQuoteItems(tx *sql.Tx, quoteID int, wholesalerID int, viewerID int, items []models.QuoteItem) error {
+	productQuery := `
+		SELECT EXISTS (SELECT 1 FROM wholesaler_product_variants WHERE product_id = p.id)
+		FROM wholesaler_products p
+		WHERE p.id = $1 AND p.wholesaler_id = $2 AND ($3 = 0 OR ` + catalogVisibleTo("p.wholesaler_id", "$3") + `)`
+
+	for _, item := range items {
+		var hasVariants bool
+		if err := tx.QueryRow(productQuery, item.ProductId, wholesalerID, viewerID).Scan(&hasVariants); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrWholesalerProductNotFound
+			}
+			return err
+		}
+
+		if item.VariantId == 0 && hasVariants {
+			return ErrVariantRequired
+		}
+		if item.VariantId != 0 {
+			var exists bool
+			err := tx.QueryRow(
+				`SELECT EXISTS (SELECT 1 FROM wholesaler_product_variants WHERE id = $1 AND product_id = $2)`,
+				item.VariantId, item.ProductId,
+			).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return ErrVariantNotFound
+			}
+		}
+
+		_, err := tx.Exec(
+			`INSERT INTO quote_items (quote_id, product_id, variant_id, quantity, price) VALUES ($1, $2, NULLIF($3, 0), $4, $5)`,
+			quoteID, item.ProductId, item.VariantId, item.Quantity, item.Price,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addQuoteRevision records the quote's current items as terms put forward by author.
+func addQuoteRevision(tx *sql.Tx, quoteID int, author models.OrderActorType, note string, expiresAt *time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO quote_revisions (quote_id, author, note, expires_at, items)
+		SELECT $1::int, $2::text, $3::text, $4::timestamptz, COALESCE(json_agg(json_build_object(
+			'product_id', product_id, 'variant_id', COALESCE(variant_id, 0), 'quantity', quantity, 'price', price
+		) ORDER BY id), '[]')::jsonb
+		FROM quote_items
+		WHERE quote_id = $1
+	`, quoteID, author, note, expiresAt)
+	return err
+}
@@ -0,0 +1,311 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrRefundNotFound        = errors.New("refund not found")
+	ErrRefundExceedsQuantity = errors.New("refund quantity exceeds the unrefunded quantity of the order item")
+	ErrOrderNotPaid          = errors.New("order has no captured payment to refund")
+)
+
+type IRefundsRepo interface {
+	CreateConsumerRefund(refund *models.Refund) error
+	CreateRetailerRefund(refund *models.Refund) error
+	CompleteRefund(refundID int, stripeRefundID string) error
+	FailRefund(refundID int) error
+	GetConsumerOrderRefunds(orderID int) ([]models.Refund, error)
+	GetRetailerOrderRefunds(orderID int) ([]models.Refund, error)
+}
+
+type RefundsRepo struct {
+	db *sql.DB
+}
+
+func NewRefundsRepo(db *sql.DB) *RefundsRepo {
+	return &RefundsRepo{db: db}
+}
+
+// CreateConsumerRefund records a pending refund of a consumer order and marks its item
+// quantities as refunded, so that a concurrent refund cannot cover the same units.
+func (r *RefundsRepo) CreateConsumerRefund(refund *models.Refund) error {
+	return r.createRefund(consumerOrdersTable, refund)
+}
+
+// CreateRetailerRefund is the wholesaler_orders counterpart of CreateConsumerRefund.
+func (r *RefundsRepo) CreateRetailerRefund(refund *models.Refund) error {
+	return r.createRefund(retailerOrdersTable, refund)
+}
+
+// createRefund prices each refunded line from the order item, so refund.Amount and the item
+// amounts are filled in from the database rather than trusted from the caller. It also sets
+// refund.PaymentIntentId to the payment the money goes back to.
+func (r *RefundsRepo) createRefund(ordersTable string, refund *models.Refund) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var paymentIntentID sql.NullString
+	query := fmt.Sprintf(`SELECT stripe_payment_intent_id, currency FROM %s WHERE id = $1 FOR UPDATE`, ordersTable)
+	if err := tx.QueryRow(query, refund.OrderId).Scan(&paymentIntentID, &refund.Currency); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOrderNotFound
+		}
+		return err
+	}
+	if !paymentIntentID.Valid || paymentIntentID.String == "" {
+		return ErrOrderNotPaid
+	}
+	refund.PaymentIntentId = paymentIntentID.String
+
+	itemsQuery := fmt.Sprintf(`
+		UPDATE %s
+		SET refunded_qty = refunded_qty + $1
+		WHERE id = $2 AND order_id = $3 AND refunded_qty + $1 <= quantity
+		RETURNING price
+	`, stockTables[ordersTable].items)
+
+	refund.Amount = 0
+	for i := range refund.Items {
+		item := &refund.Items[i]
+		var price models.Money
+		err := tx.QueryRow(itemsQuery, item.Quantity, item.OrderItemId, refund.OrderId).Scan(&price)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRefundExceedsQuantity
+			}
+			return err
+		}
+		item.Amount = price.Times(item.Quantity)
+		refund.Amount += item.Amount
+	}
+	refund.Status = models.RefundStatusPending
+
+	insertRefund := `
+		INSERT INTO refunds (order_type, order_id, amount, reason, restock, status, actor_type, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0))
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(insertRefund, ordersTable, refund.OrderId, refund.Amount, refund.Reason, refund.Restock, refund.Status, refund.Actor.Type, refund.Actor.Id).Scan(&refund.Id, &refund.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO refund_items (refund_id, order_item_id, quantity, amount) VALUES ($1, $2, $3, $4)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, item := range refund.Items {
+		if _, err := stmt.Exec(refund.Id, item.OrderItemId, item.Quantity, item.Amount); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CompleteRefund marks a pending refund as paid out. If asked to, it puts the refunded units
+// back into stock, unless the order has been cancelled since, which restocked them already. Once
+// every line of the order has been refunded it moves the order to refunded.
+func (r *RefundsRepo) CompleteRefund(refundID int, stripeRefundID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ordersTable string
+	var orderID int
+	var restock bool
+	var actor models.OrderActor
+	var actorID sql.NullInt64
+	query := `
+		UPDATE refunds SET status = $1, stripe_refund_id = $2
+		WHERE id = $3 AND status = $4
+		RETURNING order_type, order_id, restock, actor_type, actor_id
+	`
+	err = tx.QueryRow(query, models.RefundStatusSucceeded, stripeRefundID, refundID, models.RefundStatusPending).Scan(&ordersTable, &orderID, &restock, &actor.Type, &actorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRefundNotFound
+		}
+		return err
+	}
+	actor.Id = int(actorID.Int64)
+
+	tables, ok := stockTables[ordersTable]
+	if !ok {
+		return fmt.Errorf("refund %d has unknown order type %q", refundID, ordersTable)
+	}
+
+	// Locking the order serialises the refund with a cancellation, which restocks the same lines
+	var from models.OrderStatus
+	statusQuery := fmt.Sprintf(`SELECT status FROM %s WHERE id = $1 FOR UPDATE`, ordersTable)
+	if err := tx.QueryRow(statusQuery, orderID).Scan(&from); err != nil {
+		return err
+	}
+
+	// A cancelled order had all of its units put back when it was cancelled
+	if restock && from != models.OrderStatusCancelled {
+		// Lines that name a variant go back to the variant's stock, the rest to the product's
+		restockProducts := withMovements(fmt.Sprintf(`
+			UPDATE %s p
+			SET stock_qty = COALESCE(p.stock_qty, 0) + ri.quantity
+			FROM refund_items ri
+			JOIN %s i ON i.id = ri.order_item_id
+			WHERE ri.refund_id = $1 AND i.variant_id IS NULL AND p.id = i.product_id
+			RETURNING p.id, NULL::int, ri.quantity, p.stock_qty
+		`, tables.products, tables.items), tables.products, models.MovementRefundRestock, "(SELECT order_id FROM refunds WHERE id = $1)")
+		restockVariants := withMovements(fmt.Sprintf(`
+			UPDATE %s v
+			SET stock_qty = v.stock_qty + ri.quantity
+			FROM refund_items ri
+			JOIN %s i ON i.id = ri.order_item_id
+			WHERE ri.refund_id = $1 AND v.id = i.variant_id
+			RETURNING v.product_id, v.id, ri.quantity, v.stock_qty
+		`, tables.variants, tables.items), tables.products, models.MovementRefundRestock, "(SELECT order_id FROM refunds WHERE id = $1)")
+		// Remember which units are back on the shelf so a later cancellation skips them
+		markRestocked := fmt.Sprintf(`
+			UPDATE %s i
+			SET restocked_qty = i.restocked_qty + ri.quantity
+			FROM refund_items ri
+			WHERE ri.refund_id = $1 AND i.id = ri.order_item_id
+		`, tables.items)
+		for _, query := range []string{restockProducts, restockVariants, markRestocked} {
+			if _, err := tx.Exec(query, refundID); err != nil {
+				return fmt.Errorf("failed to restock refunded items: %w", err)
+			}
+		}
+	}
+
+	var fullyRefunded bool
+	remainingQuery := fmt.Sprintf(`SELECT NOT EXISTS (SELECT 1 FROM %s WHERE order_id = $1 AND refunded_qty < quantity)`, tables.items)
+	if err := tx.QueryRow(remainingQuery, orderID).Scan(&fullyRefunded); err != nil {
+		return err
+	}
+
+	if fullyRefunded && from != models.OrderStatusRefunded {
+		updateQuery := fmt.Sprintf(`UPDATE %s SET status = $1, updated_at = NOW() WHERE id = $2`, ordersTable)
+		if _, err := tx.Exec(updateQuery, models.OrderStatusRefunded, orderID); err != nil {
+			return err
+		}
+		if err := insertStatusHistory(tx, ordersTable, orderID, from, models.OrderStatusRefunded, actor); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FailRefund marks a pending refund as failed and makes its units refundable again.
+func (r *RefundsRepo) FailRefund(refundID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ordersTable string
+	query := `UPDATE refunds SET status = $1 WHERE id = $2 AND status = $3 RETURNING order_type`
+	err = tx.QueryRow(query, models.RefundStatusFailed, refundID, models.RefundStatusPending).Scan(&ordersTable)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRefundNotFound
+		}
+		return err
+	}
+
+	tables, ok := stockTables[ordersTable]
+	if !ok {
+		return fmt.Errorf("refund %d has unknown order type %q", refundID, ordersTable)
+	}
+
+	releaseQuery := fmt.Sprintf(`
+		UPDATE %s i
+		SET refunded_qty = i.refunded_qty - ri.quantity
+		FROM refund_items ri
+		WHERE ri.refund_id = $1 AND i.id = ri.order_item_id
+	`, tables.items)
+	if _, err := tx.Exec(releaseQuery, refundID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *RefundsRepo) GetConsumerOrderRefunds(orderID int) ([]models.Refund, error) {
+	return r.getOrderRefunds(consumerOrdersTable, orderID)
+}
+
+func (r *RefundsRepo) GetRetailerOrderRefunds(orderID int) ([]models.Refund, error) {
+	return r.getOrderRefunds(retailerOrdersTable, orderID)
+}
+
+func (r *RefundsRepo) getOrderRefunds(ordersTable string, orderID int) ([]models.Refund, error) {
+	query := fmt.Sprintf(`
+		SELECT r.id, r.order_id, r.amount, o.currency, COALESCE(r.reason, ''), r.restock, r.status, COALESCE(r.stripe_refund_id, ''),
+			   r.actor_type, COALESCE(r.actor_id, 0), r.created_at
+		FROM refunds r
+		JOIN %s o ON o.id = r.order_id
+		WHERE r.order_type = $1 AND r.order_id = $2
+		ORDER BY r.created_at DESC, r.id DESC
+	`, ordersTable)
+	rows, err := r.db.Query(query, ordersTable, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []models.Refund{}
+	byID := make(map[int]int)
+	for rows.Next() {
+		var refund models.Refund
+		if err := rows.Scan(
+			&refund.Id, &refund.OrderId, &refund.Amount, &refund.Currency, &refund.Reason, &refund.Restock, &refund.Status, &refund.StripeRefundId,
+			&refund.Actor.Type, &refund.Actor.Id, &refund.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		refund.Items = []models.RefundItem{}
+		byID[refund.Id] = len(refunds)
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(refunds) == 0 {
+		return refunds, nil
+	}
+
+	itemsQuery := `
+		SELECT ri.refund_id, ri.order_item_id, ri.quantity, ri.amount
+		FROM refund_items ri
+		JOIN refunds r ON r.id = ri.refund_id
+		WHERE r.order_type = $1 AND r.order_id = $2
+		ORDER BY ri.id
+	`
+	itemRows, err := r.db.Query(itemsQuery, ordersTable, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var refundID int
+		var item models.RefundItem
+		if err := itemRows.Scan(&refundID, &item.OrderItemId, &item.Quantity, &item.Amount); err != nil {
+			return nil, err
+		}
+		refund := &refunds[byID[refundID]]
+		refund.Items = append(refund.Items, item)
+	}
+	return refunds, itemRows.Err()
+}
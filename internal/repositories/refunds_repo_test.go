@@ -0,0 +1,229 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRefundsRepo_CreateConsumerRefund(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRefundsRepo(db)
+
+	refund := &models.Refund{
+		OrderId: 100,
+		Reason:  "damaged",
+		Restock: true,
+		Actor:   models.OrderActor{Type: models.OrderActorRetailer, Id: 1},
+		Items: []models.RefundItem{
+			{OrderItemId: 10, Quantity: 2},
+			{OrderItemId: 11, Quantity: 1},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT stripe_payment_intent_id, currency FROM retailer_orders").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"stripe_payment_intent_id", "currency"}).AddRow("pi_123", "inr"))
+	mock.ExpectQuery("UPDATE retailer_order_items").
+		WithArgs(2, 10, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(335))
+	mock.ExpectQuery("UPDATE retailer_order_items").
+		WithArgs(1, 11, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(1000))
+	mock.ExpectQuery("INSERT INTO refunds").
+		WithArgs("retailer_orders", 100, models.Money(1670), "damaged", true, models.RefundStatusPending, models.OrderActorRetailer, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, "now"))
+	prep := mock.ExpectPrepare("INSERT INTO refund_items")
+	prep.ExpectExec().WithArgs(7, 10, 2, models.Money(670)).WillReturnResult(sqlmock.NewResult(1, 1))
+	prep.ExpectExec().WithArgs(7, 11, 1, models.Money(1000)).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	if err := repo.CreateConsumerRefund(refund); err != nil {
+		t.Fatalf("CreateConsumerRefund returned error: %v", err)
+	}
+	if refund.Id != 7 || refund.PaymentIntentId != "pi_123" || refund.Amount != 1670 || refund.Currency != "inr" {
+		t.Errorf("Unexpected refund: %+v", refund)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRefundsRepo_CreateConsumerRefund_ExceedsQuantity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRefundsRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT stripe_payment_intent_id, currency FROM retailer_orders").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"stripe_payment_intent_id", "currency"}).AddRow("pi_123", "inr"))
+	mock.ExpectQuery("UPDATE retailer_order_items").
+		WithArgs(5, 10, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"price"}))
+	mock.ExpectRollback()
+
+	err = repo.CreateConsumerRefund(&models.Refund{
+		OrderId: 100,
+		Items:   []models.RefundItem{{OrderItemId: 10, Quantity: 5}},
+	})
+	if !errors.Is(err, ErrRefundExceedsQuantity) {
+		t.Fatalf("Expected ErrRefundExceedsQuantity, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRefundsRepo_CompleteRefund_FullRefund(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRefundsRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE refunds SET status").
+		WithArgs(models.RefundStatusSucceeded, "re_123", 7, models.RefundStatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"order_type", "order_id", "restock", "actor_type", "actor_id"}).
+			AddRow("wholesaler_orders", 100, true, "wholesaler", 3))
+	mock.ExpectQuery("SELECT status FROM wholesaler_orders").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("delivered"))
+	mock.ExpectExec("UPDATE wholesaler_products p").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE wholesaler_product_variants v").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE wholesaler_order_items i SET restocked_qty = i.restocked_qty \\+ ri.quantity").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT NOT EXISTS").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"fully_refunded"}).AddRow(true))
+	mock.ExpectExec("UPDATE wholesaler_orders SET status").
+		WithArgs(models.OrderStatusRefunded, 100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO order_status_history").
+		WithArgs("wholesaler_orders", 100, models.OrderStatusDelivered, models.OrderStatusRefunded, models.OrderActorWholesaler, 3).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.CompleteRefund(7, "re_123"); err != nil {
+		t.Fatalf("CompleteRefund returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRefundsRepo_RefundThenCancelRestocksOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	refunds := NewRefundsRepo(db)
+	orders := NewOrdersRepo(db)
+	retailer := models.OrderActor{Type: models.OrderActorRetailer, Id: 3}
+
+	// Two of the three units bought are refunded and put back on the shelf
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE refunds SET status").
+		WithArgs(models.RefundStatusSucceeded, "re_1", 5, models.RefundStatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"order_type", "order_id", "restock", "actor_type", "actor_id"}).
+			AddRow("retailer_orders", 100, true, "retailer", 3))
+	mock.ExpectQuery("SELECT status FROM retailer_orders").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("paid"))
+	mock.ExpectExec("UPDATE retailer_products p SET stock_qty = COALESCE\\(p.stock_qty, 0\\) \\+ ri.quantity").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE retailer_product_variants v SET stock_qty = v.stock_qty \\+ ri.quantity").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE retailer_order_items i SET restocked_qty = i.restocked_qty \\+ ri.quantity").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT NOT EXISTS").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"fully_refunded"}).AddRow(false))
+	mock.ExpectCommit()
+
+	// Cancelling the order then puts back only the unit that is still out
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE retailer_orders SET status").
+		WithArgs(models.OrderStatusCancelled, 100, models.OrderStatusPaid).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE retailer_products p SET stock_qty = COALESCE\\(p.stock_qty, 0\\) \\+ i.quantity - i.restocked_qty .* RETURNING p.id, NULL::int, i.quantity - i.restocked_qty, p.stock_qty").
+		WithArgs(100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE retailer_product_variants p SET stock_qty = COALESCE\\(p.stock_qty, 0\\) \\+ i.quantity - i.restocked_qty").
+		WithArgs(100).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE retailer_order_items SET restocked_qty = quantity").
+		WithArgs(100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO order_status_history").
+		WithArgs("retailer_orders", 100, models.OrderStatusPaid, models.OrderStatusCancelled, retailer.Type, 3).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := refunds.CompleteRefund(5, "re_1"); err != nil {
+		t.Fatalf("CompleteRefund returned error: %v", err)
+	}
+	if err := orders.TransitionConsumerOrderStatus(100, models.OrderStatusPaid, models.OrderStatusCancelled, retailer); err != nil {
+		t.Fatalf("TransitionConsumerOrderStatus returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRefundsRepo_CompleteRefund_CancelledOrderIsNotRestocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRefundsRepo(db)
+
+	// The refund was requested with restocking, but the order was cancelled before it was paid
+	// out and the cancellation already put every unit back
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE refunds SET status").
+		WithArgs(models.RefundStatusSucceeded, "re_2", 6, models.RefundStatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"order_type", "order_id", "restock", "actor_type", "actor_id"}).
+			AddRow("retailer_orders", 100, true, "retailer", 3))
+	mock.ExpectQuery("SELECT status FROM retailer_orders").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("cancelled"))
+	mock.ExpectQuery("SELECT NOT EXISTS").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"fully_refunded"}).AddRow(false))
+	mock.ExpectCommit()
+
+	if err := repo.CompleteRefund(6, "re_2"); err != nil {
+		t.Fatalf("CompleteRefund returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -9,8 +9,7 @@ import (
 var ErrProductNotFound = errors.New("product not found")
 
 type IRetailerProductsRepo interface {
-	GetProducts() ([]models.RetailerProduct, error)
-	SearchProducts(keyword string) ([]models.RetailerProduct, error)
+	ListProducts(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error)
 	GetProduct(id int) (*models.RetailerProduct, error)
 	GetProductsByRetailerID(retailerID int) ([]models.RetailerProduct, error)
 }
@@ -23,108 +22,89 @@ func NewRetailerProductsRepo(db *sql.DB) *RetailerProductsRepo {
 	return &RetailerProductsRepo{DB: db}
 }
 
-func (repo *RetailerProductsRepo) GetProducts() ([]models.RetailerProduct, error) {
-	query := `
-		SELECT id, retailer_id, name, price, stock_qty, image_url, description
-		FROM retailer_products
-		ORDER BY updated_at DESC
-		LIMIT 9
-	`
+// ListProducts returns one page of the catalog matching filter, along with the cursor of the
+// next page and the number of matching products.
+func (repo *RetailerProductsRepo) ListProducts(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+	q, err := retailerCatalog.listQuery("p.id, p.retailer_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description, COALESCE(p.category_id, 0), "+ratingColumns, filter)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	var page models.PageInfo
+	if err := repo.DB.QueryRow(q.count, q.countArgs...).Scan(&page.Total); err != nil {
+		return nil, models.PageInfo{}, err
+	}
 
-	rows, err := repo.DB.Query(query)
+	rows, err := repo.DB.Query(q.page, q.pageArgs...)
 	if err != nil {
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
 	defer rows.Close()
 
-	var products []models.RetailerProduct
+	products := []models.RetailerProduct{}
+	var keys []string
+	var ids []int
 
 	for rows.Next() {
-		var product models.RetailerProduct
-		err := rows.Scan(
+		product := models.RetailerProduct{Rating: &models.RatingSummary{}}
+		var key string
+		dest := []any{
 			&product.Id,
 			&product.Retailer_id,
 			&product.Name,
 			&product.Price,
+			&product.Currency,
 			&product.Stock_qty,
 			&product.Image_url,
 			&product.Description,
-		)
+			&product.CategoryId,
+		}
+		dest = append(dest, ratingDest(product.Rating)...)
+		err := rows.Scan(append(dest, &key)...)
 		if err != nil {
-			return nil, err
+			return nil, models.PageInfo{}, err
 		}
 		products = append(products, product)
+		keys = append(keys, key)
+		ids = append(ids, product.Id)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return products, nil
-}
-
-func (repo *RetailerProductsRepo) SearchProducts(keyword string) ([]models.RetailerProduct, error) {
-	query := `
-        SELECT id, retailer_id, name, price, stock_qty, image_url, description
-        FROM retailer_products
-        WHERE
-            to_tsvector('simple', name || ' ' || coalesce(description, ''))
-            @@ plainto_tsquery('simple', $1)
-        ORDER BY updated_at DESC
-        LIMIT 50;
-    `
-
-	rows, err := repo.DB.Query(query, keyword)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var products []models.RetailerProduct
-
-	for rows.Next() {
-		var p models.RetailerProduct
-		err := rows.Scan(
-			&p.Id,
-			&p.Retailer_id,
-			&p.Name,
-			&p.Price,
-			&p.Stock_qty,
-			&p.Image_url,
-			&p.Description,
-		)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, p)
+		return nil, models.PageInfo{}, err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	page.NextCursor = q.nextCursor(keys, ids)
+	if len(products) > q.limit {
+		products = products[:q.limit]
 	}
 
-	return products, nil
+	return products, page, nil
 }
 
 func (repo *RetailerProductsRepo) GetProduct(id int) (*models.RetailerProduct, error) {
 	query := `
-		SELECT id, retailer_id, name, price, stock_qty, image_url, description
-		FROM retailer_products
-		WHERE id = $1`
+		SELECT p.id, p.retailer_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description, COALESCE(p.category_id, 0),
+			` + ratingColumns + `
+		FROM retailer_products p
+		` + retailerCatalog.ratings + `
+		WHERE p.id = $1`
 
-	var product models.RetailerProduct
+	product := models.RetailerProduct{Rating: &models.RatingSummary{}}
 
 	row := repo.DB.QueryRow(query, id)
 
-	err := row.Scan(
+	dest := []any{
 		&product.Id,
 		&product.Retailer_id,
 		&product.Name,
 		&product.Price,
+		&product.Currency,
 		&product.Stock_qty,
 		&product.Image_url,
 		&product.Description,
-	)
+		&product.CategoryId,
+	}
+	err := row.Scan(append(dest, ratingDest(product.Rating)...)...)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -138,7 +118,7 @@ func (repo *RetailerProductsRepo) GetProduct(id int) (*models.RetailerProduct, e
 
 func (repo *RetailerProductsRepo) GetProductsByRetailerID(retailerID int) ([]models.RetailerProduct, error) {
 	query := `
-		SELECT id, retailer_id, name, price, stock_qty, image_url, description
+		SELECT id, retailer_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0)
 		FROM retailer_products
 		WHERE retailer_id = $1
 		ORDER BY updated_at DESC
@@ -159,9 +139,11 @@ func (repo *RetailerProductsRepo) GetProductsByRetailerID(retailerID int) ([]mod
 			&product.Retailer_id,
 			&product.Name,
 			&product.Price,
+			&product.Currency,
 			&product.Stock_qty,
 			&product.Image_url,
 			&product.Description,
+			&product.CategoryId,
 		)
 		if err != nil {
 			return nil, err
@@ -1,7 +1,9 @@
 package repositories
 
 import (
+	"Obsonarium-backend/internal/models"
 	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -20,7 +22,7 @@ func TestNewRetailerProductsRepo(t *testing.T) {
 	}
 }
 
-func TestRetailerProductsRepo_GetProducts(t *testing.T) {
+func TestRetailerProductsRepo_ListProducts(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("Failed to create mock: %v", err)
@@ -28,40 +30,127 @@ func TestRetailerProductsRepo_GetProducts(t *testing.T) {
 	defer db.Close()
 
 	repo := NewRetailerProductsRepo(db)
-
-	t.Run("successful retrieval", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "retailer_id", "name", "price", "stock_qty", "image_url", "description"}).
-			AddRow(1, 1, "Test Product", 99.99, 10, "https://example.com/img.jpg", "Test description")
-		mock.ExpectQuery("SELECT id, retailer_id, name, price, stock_qty, image_url, description").
+	columns := []string{"id", "retailer_id", "name", "price", "currency", "stock_qty", "image_url", "description", "category_id",
+		"average_rating", "review_count", "rating_1", "rating_2", "rating_3", "rating_4", "rating_5", "sort_key"}
+
+	var cursor string
+	t.Run("first page", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM retailer_products p").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		rows := sqlmock.NewRows(columns).
+			AddRow(3, 1, "Telescope", 9999, "inr", 10, "https://example.com/img.jpg", "A great telescope", 7, "4.50", 2, 0, 0, 0, 1, 1, "2024-05-03 10:00:00+00").
+			AddRow(2, 1, "Binoculars", 4999, "inr", 4, "https://example.com/img.jpg", "", 0, "0", 0, 0, 0, 0, 0, 0, "2024-05-02 10:00:00+00").
+			AddRow(1, 2, "Star map", 999, "inr", 0, "https://example.com/img.jpg", "", 0, "0", 0, 0, 0, 0, 0, 0, "2024-05-01 10:00:00+00")
+		mock.ExpectQuery("ORDER BY p.created_at DESC, p.id DESC").
+			WithArgs(3).
 			WillReturnRows(rows)
 
-		products, err := repo.GetProducts()
+		products, page, err := repo.ListProducts(models.ProductFilter{Sort: models.ProductSortNewest, Limit: 2})
 		if err != nil {
-			t.Errorf("Unexpected error: %v", err)
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(products) != 2 || products[1].Name != "Binoculars" {
+			t.Errorf("Expected the first two products, got %+v", products)
 		}
-		if len(products) != 1 {
-			t.Errorf("Expected 1 product, got %d", len(products))
+		if rating := products[0].Rating; rating.Average != 4.5 || rating.Count != 2 || rating.Histogram != [5]int{0, 0, 0, 1, 1} {
+			t.Errorf("Unexpected rating %+v", rating)
 		}
-		if products[0].Name != "Test Product" {
-			t.Errorf("Expected product name 'Test Product', got %s", products[0].Name)
+		if page.Total != 3 || page.NextCursor == "" {
+			t.Errorf("Unexpected page info %+v", page)
 		}
+		cursor = page.NextCursor
 
 		if err := mock.ExpectationsWereMet(); err != nil {
 			t.Errorf("Mock expectations were not met: %v", err)
 		}
 	})
 
-	t.Run("empty result", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "retailer_id", "name", "price", "stock_qty", "image_url", "description"})
-		mock.ExpectQuery("SELECT id, retailer_id, name, price, stock_qty, image_url, description").
-			WillReturnRows(rows)
+	t.Run("filtered page after the cursor", func(t *testing.T) {
+		minPrice := models.Money(500)
+		filter := models.ProductFilter{
+			Query:    "telescope",
+			Sort:     models.ProductSortNewest,
+			MinPrice: &minPrice,
+			InStock:  true,
+			SellerID: 1,
+			Cursor:   cursor,
+			Limit:    2,
+		}
+
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM retailer_products p WHERE .*plainto_tsquery.*p.price >= \\$2 AND .*reserved_qty > 0 END AND p.retailer_id = \\$3").
+			WithArgs("telescope", minPrice, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("\\(p.created_at, p.id\\) < \\(\\$4::timestamptz, \\$5\\)").
+			WithArgs("telescope", minPrice, 1, "2024-05-02 10:00:00+00", 2, 3).
+			WillReturnRows(sqlmock.NewRows(columns))
 
-		products, err := repo.GetProducts()
+		products, page, err := repo.ListProducts(filter)
 		if err != nil {
-			t.Errorf("Unexpected error: %v", err)
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(products) != 0 || page.NextCursor != "" || page.Total != 1 {
+			t.Errorf("Expected an empty last page, got %+v %+v", products, page)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Mock expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("category includes its descendants", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM retailer_products p WHERE p.category_id IN \\(WITH RECURSIVE subtree .*WHERE id = \\$1").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("JOIN subtree s ON c.parent_id = s.id").
+			WithArgs(1, 3).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(3, 1, "Telescope", 9999, "inr", 10, "https://example.com/img.jpg", "A great telescope", 7, "0", 0, 0, 0, 0, 0, 0, "2024-05-03 10:00:00+00"))
+
+		products, _, err := repo.ListProducts(models.ProductFilter{Sort: models.ProductSortNewest, CategoryID: 1, Limit: 2})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(products) != 1 || products[0].CategoryId != 7 {
+			t.Errorf("Expected the product in the subcategory, got %+v", products)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Mock expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("cursor from another sort", func(t *testing.T) {
+		_, _, err := repo.ListProducts(models.ProductFilter{Sort: models.ProductSortPriceAsc, Cursor: cursor, Limit: 2})
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor, got %v", err)
+		}
+	})
+
+	t.Run("minimum rating", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM retailer_products p LEFT JOIN product_ratings pr ON pr.product_id = p.id WHERE COALESCE\\(pr.average_rating, 0\\) >= \\$1").
+			WithArgs(4.0).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("ORDER BY COALESCE\\(pr.average_rating, 0\\) DESC, p.id DESC").
+			WithArgs(4.0, 3).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, _, err := repo.ListProducts(models.ProductFilter{Sort: models.ProductSortRating, MinRating: 4, Limit: 2})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Mock expectations were not met: %v", err)
 		}
-		if len(products) != 0 {
-			t.Errorf("Expected 0 products, got %d", len(products))
+	})
+
+	t.Run("wholesale catalog has no ratings", func(t *testing.T) {
+		_, _, err := NewWholesalerProductRepository(db).ListProducts(models.ProductFilter{Sort: models.ProductSortRating, Limit: 2})
+		if !errors.Is(err, ErrUnsupportedSort) {
+			t.Errorf("Expected ErrUnsupportedSort, got %v", err)
+		}
+		_, _, err = NewWholesalerProductRepository(db).ListProducts(models.ProductFilter{Sort: models.ProductSortNewest, MinRating: 4, Limit: 2})
+		if !errors.Is(err, ErrUnsupportedFilter) {
+			t.Errorf("Expected ErrUnsupportedFilter, got %v", err)
 		}
 	})
 }
@@ -76,9 +165,10 @@ func TestRetailerProductsRepo_GetProduct(t *testing.T) {
 	repo := NewRetailerProductsRepo(db)
 
 	t.Run("successful retrieval", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "retailer_id", "name", "price", "stock_qty", "image_url", "description"}).
-			AddRow(1, 1, "Test Product", 99.99, 10, "https://example.com/img.jpg", "Test description")
-		mock.ExpectQuery("SELECT id, retailer_id, name, price, stock_qty, image_url, description").
+		rows := sqlmock.NewRows([]string{"id", "retailer_id", "name", "price", "currency", "stock_qty", "image_url", "description", "category_id",
+			"average_rating", "review_count", "rating_1", "rating_2", "rating_3", "rating_4", "rating_5"}).
+			AddRow(1, 1, "Test Product", 9999, "inr", 10, "https://example.com/img.jpg", "Test description", 0, "3.00", 1, 0, 0, 1, 0, 0)
+		mock.ExpectQuery("SELECT p.id, p.retailer_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description.*LEFT JOIN product_ratings pr").
 			WithArgs(1).
 			WillReturnRows(rows)
 
@@ -92,10 +182,13 @@ func TestRetailerProductsRepo_GetProduct(t *testing.T) {
 		if product.Name != "Test Product" {
 			t.Errorf("Expected product name 'Test Product', got %s", product.Name)
 		}
+		if product.Rating.Average != 3 || product.Rating.Count != 1 {
+			t.Errorf("Unexpected rating %+v", product.Rating)
+		}
 	})
 
 	t.Run("product not found", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id, retailer_id, name, price, stock_qty, image_url, description").
+		mock.ExpectQuery("SELECT p.id, p.retailer_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description").
 			WithArgs(999).
 			WillReturnError(sql.ErrNoRows)
 
@@ -112,32 +205,6 @@ func TestRetailerProductsRepo_GetProduct(t *testing.T) {
 	})
 }
 
-func TestRetailerProductsRepo_SearchProducts(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("Failed to create mock: %v", err)
-	}
-	defer db.Close()
-
-	repo := NewRetailerProductsRepo(db)
-
-	t.Run("successful search", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "retailer_id", "name", "price", "stock_qty", "image_url", "description"}).
-			AddRow(1, 1, "Telescope", 99.99, 10, "https://example.com/img.jpg", "A great telescope")
-		mock.ExpectQuery("SELECT id, retailer_id, name, price, stock_qty, image_url, description").
-			WithArgs("telescope").
-			WillReturnRows(rows)
-
-		products, err := repo.SearchProducts("telescope")
-		if err != nil {
-			t.Errorf("Unexpected error: %v", err)
-		}
-		if len(products) != 1 {
-			t.Errorf("Expected 1 product, got %d", len(products))
-		}
-	})
-}
-
 func IsError(err error, target error) bool {
 	return err != nil && err.Error() == target.Error()
 }
@@ -10,9 +10,9 @@ var ErrRetailerCartItemNotFound = errors.New("retailer cart item not found")
 
 type IRetailerCartRepo interface {
 	GetCartItemsByRetailerID(retailerID int) ([]models.RetailerCartItem, error)
-	AddCartItem(retailerID int, productID int, quantity int) (int, error)
-	RemoveCartItem(retailerID int, productID int) error
-	DecreaseCartItem(retailerID int, productID int) (int, error)
+	AddCartItem(retailerID int, productID int, variantID int, quantity int) (int, error)
+	RemoveCartItem(retailerID int, productID int, variantID int) error
+	DecreaseCartItem(retailerID int, productID int, variantID int) (int, error)
 	GetCartNumber(retailerID int) (int, error)
 }
 
@@ -26,10 +26,13 @@ func NewRetailerCartRepo(db *sql.DB) *RetailerCartRepo {
 
 func (repo *RetailerCartRepo) GetCartItemsByRetailerID(retailerID int) ([]models.RetailerCartItem, error) {
 	query := `
-		SELECT c.id, c.retailer_id, c.product_id, c.quantity,
-			   p.id, p.wholesaler_id, p.name, p.price, p.stock_qty, p.image_url, p.description
+		SELECT c.id, c.retailer_id, c.product_id, COALESCE(c.variant_id, 0), c.quantity,
+			   p.id, p.wholesaler_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description,
+			   ` + wholesaleTermsColumns + `,
+			   v.id, v.sku, v.options, v.price, v.stock_qty, v.image_url
 		FROM retailer_cart_items c
 		JOIN wholesaler_products p ON p.id = c.product_id
+		LEFT JOIN wholesaler_product_variants v ON v.id = c.variant_id
 		WHERE c.retailer_id = $1
 		ORDER BY c.id`
 
@@ -43,23 +46,29 @@ func (repo *RetailerCartRepo) GetCartItemsByRetailerID(retailerID int) ([]models
 
 	for rows.Next() {
 		var item models.RetailerCartItem
-		// scan cart item fields then product fields
-		err := rows.Scan(
+		var variant lineVariant
+		// scan cart item fields, then product fields, then the variant's
+		err := rows.Scan(append([]any{
 			&item.Id,
 			&item.Retailer_id,
 			&item.Product_id,
+			&item.Variant_id,
 			&item.Quantity,
 			&item.Product.Id,
 			&item.Product.Wholesaler_id,
 			&item.Product.Name,
 			&item.Product.Price,
+			&item.Product.Currency,
 			&item.Product.Stock_qty,
 			&item.Product.Image_url,
 			&item.Product.Description,
-		)
+			&item.Product.MinOrderQty,
+			&item.Product.PriceTiers,
+		}, variant.dest()...)...)
 		if err != nil {
 			return nil, err
 		}
+		item.Variant = variant.variant(item.Product.Id, item.Product.Currency)
 		cartItems = append(cartItems, item)
 	}
 
@@ -70,32 +79,44 @@ func (repo *RetailerCartRepo) GetCartItemsByRetailerID(retailerID int) ([]models
 	return cartItems, nil
 }
 
-func (repo *RetailerCartRepo) AddCartItem(retailerID int, productID int, quantity int) (int, error) {
+// AddCartItem adds quantity units of a product to the cart. variantID is 0 for a product
+// without variants and must name one of its variants otherwise.
+func (repo *RetailerCartRepo) AddCartItem(retailerID int, productID int, variantID int, quantity int) (int, error) {
 	query := `
-		INSERT INTO retailer_cart_items (retailer_id, product_id, quantity)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (retailer_id, product_id) DO UPDATE
+		INSERT INTO retailer_cart_items (retailer_id, product_id, variant_id, quantity)
+		SELECT $1::int, $2::int, NULLIF($3::int, 0), $4::int
+		WHERE CASE WHEN $3 = 0
+			THEN NOT EXISTS (SELECT 1 FROM wholesaler_product_variants WHERE product_id = $2)
+			ELSE EXISTS (SELECT 1 FROM wholesaler_product_variants WHERE id = $3 AND product_id = $2)
+		END
+		ON CONFLICT (retailer_id, product_id, (COALESCE(variant_id, 0))) DO UPDATE
 		SET quantity = retailer_cart_items.quantity + EXCLUDED.quantity
 		RETURNING quantity
 		`
 
 	var newQuantity int
-	err := repo.DB.QueryRow(query, retailerID, productID, quantity).Scan(&newQuantity)
+	err := repo.DB.QueryRow(query, retailerID, productID, variantID, quantity).Scan(&newQuantity)
+	if errors.Is(err, sql.ErrNoRows) {
+		if variantID == 0 {
+			return 0, ErrVariantRequired
+		}
+		return 0, ErrVariantNotFound
+	}
 
 	return newQuantity, err
 }
 
-func (repo *RetailerCartRepo) DecreaseCartItem(retailerID int, productID int) (int, error) {
+func (repo *RetailerCartRepo) DecreaseCartItem(retailerID int, productID int, variantID int) (int, error) {
 	// First decrease quantity
 	query := `
 		UPDATE retailer_cart_items
 		SET quantity = quantity - 1
-		WHERE retailer_id = $1 AND product_id = $2
+		WHERE retailer_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3
 		RETURNING quantity
 	`
 
 	var newQty int
-	err := repo.DB.QueryRow(query, retailerID, productID).Scan(&newQty)
+	err := repo.DB.QueryRow(query, retailerID, productID, variantID).Scan(&newQty)
 
 	if err == sql.ErrNoRows {
 		// no cart item found
@@ -109,8 +130,8 @@ func (repo *RetailerCartRepo) DecreaseCartItem(retailerID int, productID int) (i
 	// If quantity is now 0 → delete the row
 	if newQty <= 0 {
 		_, err := repo.DB.Exec(
-			`DELETE FROM retailer_cart_items WHERE retailer_id = $1 AND product_id = $2`,
-			retailerID, productID,
+			`DELETE FROM retailer_cart_items WHERE retailer_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3`,
+			retailerID, productID, variantID,
 		)
 		return newQty, err
 	}
@@ -118,12 +139,12 @@ func (repo *RetailerCartRepo) DecreaseCartItem(retailerID int, productID int) (i
 	return newQty, nil
 }
 
-func (repo *RetailerCartRepo) RemoveCartItem(retailerID int, productID int) error {
+func (repo *RetailerCartRepo) RemoveCartItem(retailerID int, productID int, variantID int) error {
 	query := `
 		DELETE FROM retailer_cart_items
-		WHERE retailer_id = $1 AND product_id = $2`
+		WHERE retailer_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3`
 
-	result, err := repo.DB.Exec(query, retailerID, productID)
+	result, err := repo.DB.Exec(query, retailerID, productID, variantID)
 	if err != nil {
 		return err
 	}
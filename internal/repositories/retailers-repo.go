@@ -6,7 +6,13 @@ import (
 	"errors"
 )
 
-var ErrRetailerNotFound = errors.New("retailer not found")
+var (
+	ErrRetailerNotFound = errors.New("retailer not found")
+	// ErrCurrencyInUse is returned when a seller asks to change currency after amounts have been
+	// priced in the current one. Amounts are stored without a conversion rate, so changing the
+	// currency would silently change what they are worth.
+	ErrCurrencyInUse = errors.New("currency cannot be changed once products or orders are priced in it")
+)
 
 type IRetailersRepo interface {
 	GetRetailerByID(id int) (*models.Retailer, error)
@@ -25,7 +31,7 @@ func NewRetailersRepo(db *sql.DB) *RetailersRepo {
 
 func (repo *RetailersRepo) GetRetailerByID(id int) (*models.Retailer, error) {
 	query := `
-		SELECT id, name, business_name, email, phone, address
+		SELECT id, name, business_name, email, phone, address, currency
 		FROM retailers
 		WHERE id = $1`
 
@@ -41,6 +47,7 @@ func (repo *RetailersRepo) GetRetailerByID(id int) (*models.Retailer, error) {
 		&retailer.Email,
 		&retailer.Phone,
 		&retailer.Address,
+		&retailer.Currency,
 	)
 
 	if businessName.Valid {
@@ -64,7 +71,7 @@ func (repo *RetailersRepo) UpsertRetailer(retailer *models.Retailer) error {
         ON CONFLICT (email) DO UPDATE
         SET 
             name = EXCLUDED.name
-        RETURNING id, email, name, business_name, phone, address
+        RETURNING id, email, name, business_name, phone, address, currency
     `
 
 	// Note: Phone, Address, and BusinessName are not updated here as they come from onboarding/profile update
@@ -85,6 +92,7 @@ func (repo *RetailersRepo) UpsertRetailer(retailer *models.Retailer) error {
 		&businessName,
 		&phone,
 		&address,
+		&retailer.Currency,
 	)
 
 	if businessName.Valid {
@@ -102,7 +110,7 @@ func (repo *RetailersRepo) UpsertRetailer(retailer *models.Retailer) error {
 
 func (repo *RetailersRepo) GetRetailerByEmail(email string) (*models.Retailer, error) {
 	query := `
-		SELECT id, name, business_name, email, phone, address
+		SELECT id, name, business_name, email, phone, address, currency
 		FROM retailers
 		WHERE email = $1`
 
@@ -120,6 +128,7 @@ func (repo *RetailersRepo) GetRetailerByEmail(email string) (*models.Retailer, e
 		&retailer.Email,
 		&phone,
 		&address,
+		&retailer.Currency,
 	)
 
 	if businessName.Valid {
@@ -142,22 +151,53 @@ func (repo *RetailersRepo) GetRetailerByEmail(email string) (*models.Retailer, e
 	return &retailer, nil
 }
 
+// UpdateRetailer saves the profile fields. An empty Currency keeps the current one. The currency
+// can only be changed while the retailer has no products or orders; otherwise it returns
+// ErrCurrencyInUse.
 func (repo *RetailersRepo) UpdateRetailer(retailer *models.Retailer) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if retailer.Currency != "" {
+		var inUse bool
+		lockQuery := `
+			SELECT r.currency <> $1 AND (
+				EXISTS (SELECT 1 FROM retailer_products WHERE retailer_id = r.id) OR
+				EXISTS (SELECT 1 FROM retailer_orders WHERE retailer_id = r.id)
+			)
+			FROM retailers r
+			WHERE r.email = $2
+			FOR UPDATE`
+		if err := tx.QueryRow(lockQuery, retailer.Currency, retailer.Email).Scan(&inUse); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRetailerNotFound
+			}
+			return err
+		}
+		if inUse {
+			return ErrCurrencyInUse
+		}
+	}
+
 	query := `
 		UPDATE retailers
-		SET business_name = $1, phone = $2, address = $3
-		WHERE email = $4
-		RETURNING id`
+		SET business_name = $1, phone = $2, address = $3, currency = COALESCE(NULLIF($4, ''), currency)
+		WHERE email = $5
+		RETURNING id, currency`
 
 	// Note: name is not updated here - it only comes from Google OAuth during login
 
-	err := repo.DB.QueryRow(
+	err = tx.QueryRow(
 		query,
 		retailer.BusinessName,
 		retailer.Phone,
 		retailer.Address,
+		retailer.Currency,
 		retailer.Email,
-	).Scan(&retailer.Id)
+	).Scan(&retailer.Id, &retailer.Currency)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -166,5 +206,5 @@ func (repo *RetailersRepo) UpdateRetailer(retailer *models.Retailer) error {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
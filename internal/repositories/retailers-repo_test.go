@@ -3,6 +3,7 @@ package repositories
 import (
 	"Obsonarium-backend/internal/models"
 	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -41,18 +42,19 @@ func TestRetailersRepo_GetRetailerByID(t *testing.T) {
 			name: "successful retrieval",
 			id:   1,
 			setupMock: func() {
-				rows := sqlmock.NewRows([]string{"id", "name", "business_name", "email", "phone", "address"}).
-					AddRow(1, "Test Retailer", nil, "test@retailer.com", "+1234567890", "123 Test St")
-				mock.ExpectQuery("SELECT id, name, business_name, email, phone, address").
+				rows := sqlmock.NewRows([]string{"id", "name", "business_name", "email", "phone", "address", "currency"}).
+					AddRow(1, "Test Retailer", nil, "test@retailer.com", "+1234567890", "123 Test St", "inr")
+				mock.ExpectQuery("SELECT id, name, business_name, email, phone, address, currency").
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
 			expectedRetailer: &models.Retailer{
-				Id:      1,
-				Name:    "Test Retailer",
-				Email:   "test@retailer.com",
-				Phone:   "+1234567890",
-				Address: "123 Test St",
+				Id:       1,
+				Name:     "Test Retailer",
+				Email:    "test@retailer.com",
+				Phone:    "+1234567890",
+				Address:  "123 Test St",
+				Currency: "inr",
 			},
 			expectedError: nil,
 		},
@@ -60,7 +62,7 @@ func TestRetailersRepo_GetRetailerByID(t *testing.T) {
 			name: "retailer not found",
 			id:   999,
 			setupMock: func() {
-				mock.ExpectQuery("SELECT id, name, business_name, email, phone, address").
+				mock.ExpectQuery("SELECT id, name, business_name, email, phone, address, currency").
 					WithArgs(999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -96,3 +98,49 @@ func TestRetailersRepo_GetRetailerByID(t *testing.T) {
 	}
 }
 
+
+func TestRetailersRepo_UpdateRetailer_Currency(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRetailersRepo(db)
+
+	t.Run("rejected once products or orders are priced in it", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT r.currency <> \\$1 AND .* FROM retailers r WHERE r.email = \\$2 FOR UPDATE").
+			WithArgs("usd", "shop@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"in_use"}).AddRow(true))
+		mock.ExpectRollback()
+
+		retailer := &models.Retailer{Email: "shop@example.com", BusinessName: "Shop", Phone: "123", Address: "1 Road", Currency: "usd"}
+		if err := repo.UpdateRetailer(retailer); !errors.Is(err, ErrCurrencyInUse) {
+			t.Errorf("Expected ErrCurrencyInUse, got %v", err)
+		}
+	})
+
+	t.Run("changed while nothing is priced in it", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT r.currency <> \\$1").
+			WithArgs("usd", "shop@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"in_use"}).AddRow(false))
+		mock.ExpectQuery("UPDATE retailers").
+			WithArgs("Shop", "123", "1 Road", "usd", "shop@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "currency"}).AddRow(4, "usd"))
+		mock.ExpectCommit()
+
+		retailer := &models.Retailer{Email: "shop@example.com", BusinessName: "Shop", Phone: "123", Address: "1 Road", Currency: "usd"}
+		if err := repo.UpdateRetailer(retailer); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if retailer.Id != 4 || retailer.Currency != "usd" {
+			t.Errorf("Unexpected retailer %+v", retailer)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// InsufficientStockError is returned when a checkout cannot reserve every line it asks for.
+// Nothing is reserved when it is returned.
+type InsufficientStockError struct {
+	Items []models.StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	parts := make([]string, len(e.Items))
+	for i, item := range e.Items {
+		parts[i] = fmt.Sprintf("%s: requested %d, available %d", item.Name, item.Requested, item.Available)
+	}
+	return "insufficient stock for " + strings.Join(parts, "; ")
+}
+
+// stockTables maps an orders table to the tables holding its line items and the products and
+// variants they reference.
+var stockTables = map[string]struct{ items, products, variants string }{
+	consumerOrdersTable: {items: "retailer_order_items", products: "retailer_products", variants: "retailer_product_variants"},
+	retailerOrdersTable: {items: "wholesaler_order_items", products: "wholesaler_products", variants: "wholesaler_product_variants"},
+}
+
+// stockLine is a quantity of a product to reserve. Stock is held on the variant when the line
+// names one and on the product otherwise.
+type stockLine struct {
+	productID int
+	variantID int
+	quantity  int
+}
+
+// reserveStock holds quantity units of each line for a pending checkout. Every line is attempted
+// so the caller gets the full list of shortages, but the caller must roll back if any are reported.
+// A line without a variant for a product that has since been given variants, left in a cart from
+// before, fails the checkout with ErrVariantRequired: such a product is sold only through them.
+func reserveStock(tx *sql.Tx, ordersTable string, lines []stockLine) error {
+	tables := stockTables[ordersTable]
+	reserveProduct := fmt.Sprintf(`
+		UPDATE %[1]s p SET reserved_qty = reserved_qty + $1
+		WHERE id = $2 AND COALESCE(stock_qty, 0) - reserved_qty >= $1
+			AND NOT EXISTS (SELECT 1 FROM %[2]s v WHERE v.product_id = p.id)
+	`, tables.products, tables.variants)
+	reserveVariant := fmt.Sprintf(`
+		UPDATE %s SET reserved_qty = reserved_qty + $1
+		WHERE id = $2 AND stock_qty - reserved_qty >= $1
+	`, tables.variants)
+	productAvailable := fmt.Sprintf(`
+		SELECT name, '', COALESCE(stock_qty, 0) - reserved_qty, EXISTS (SELECT 1 FROM %s v WHERE v.product_id = p.id)
+		FROM %s p WHERE id = $1
+	`, tables.variants, tables.products)
+	variantAvailable := fmt.Sprintf(`
+		SELECT p.name, v.sku, v.stock_qty - v.reserved_qty, false
+		FROM %s v JOIN %s p ON p.id = v.product_id
+		WHERE v.id = $1
+	`, tables.variants, tables.products)
+
+	var shortages []models.StockShortage
+	for _, line := range lines {
+		reserve, available, id := reserveProduct, productAvailable, line.productID
+		if line.variantID != 0 {
+			reserve, available, id = reserveVariant, variantAvailable, line.variantID
+		}
+
+		result, err := tx.Exec(reserve, line.quantity, id)
+		if err != nil {
+			return fmt.Errorf("failed to reserve stock: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected > 0 {
+			continue
+		}
+
+		shortage := models.StockShortage{ProductId: line.productID, VariantId: line.variantID, Requested: line.quantity}
+		var hasVariants bool
+		if err := tx.QueryRow(available, id).Scan(&shortage.Name, &shortage.Sku, &shortage.Available, &hasVariants); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if hasVariants {
+			return fmt.Errorf("%s: %w", shortage.Name, ErrVariantRequired)
+		}
+		if shortage.Available < 0 {
+			shortage.Available = 0
+		}
+		shortages = append(shortages, shortage)
+	}
+
+	if len(shortages) > 0 {
+		return &InsufficientStockError{Items: shortages}
+	}
+	return nil
+}
+
+// applyStockTransition keeps product and variant stock in step with an order's status change,
+// recording every change of stock_qty in the ledger:
+//   - pending -> paid/confirmed turns the reservation into a permanent decrement, a sale
+//   - pending -> failed/cancelled releases the reservation
+//   - paid/confirmed -> cancelled puts the sold units back on the shelf, less those a refund
+//     already restocked, and marks every unit of the order as restocked
+func applyStockTransition(tx *sql.Tx, ordersTable string, orderID int, from, to models.OrderStatus) error {
+	var set, change string
+	var reason models.MovementReason
+	switch {
+	case from == models.OrderStatusPending && (to == models.OrderStatusPaid || to == models.OrderStatusConfirmed):
+		set = "stock_qty = COALESCE(p.stock_qty, 0) - i.quantity, reserved_qty = p.reserved_qty - i.quantity"
+		change, reason = "-i.quantity", models.MovementSale
+	case from == models.OrderStatusPending && (to == models.OrderStatusFailed || to == models.OrderStatusCancelled):
+		set = "reserved_qty = p.reserved_qty - i.quantity"
+	case (from == models.OrderStatusPaid || from == models.OrderStatusConfirmed) && to == models.OrderStatusCancelled:
+		// Units already put back by a restocking refund must not be put back again
+		set = "stock_qty = COALESCE(p.stock_qty, 0) + i.quantity - i.restocked_qty"
+		change, reason = "i.quantity - i.restocked_qty", models.MovementCancellation
+	default:
+		return nil
+	}
+
+	tables := stockTables[ordersTable]
+	productQuery := fmt.Sprintf(`
+		UPDATE %s p SET %s
+		FROM %s i
+		WHERE i.order_id = $1 AND i.variant_id IS NULL AND p.id = i.product_id
+	`, tables.products, set, tables.items)
+	variantQuery := fmt.Sprintf(`
+		UPDATE %s p SET %s
+		FROM %s i
+		WHERE i.order_id = $1 AND p.id = i.variant_id
+	`, tables.variants, set, tables.items)
+	if reason != "" {
+		productQuery = withMovements(productQuery+"RETURNING p.id, NULL::int, "+change+", p.stock_qty", tables.products, reason, "$1::int")
+		variantQuery = withMovements(variantQuery+"RETURNING p.product_id, p.id, "+change+", p.stock_qty", tables.products, reason, "$1::int")
+	}
+	for _, query := range []string{productQuery, variantQuery} {
+		if _, err := tx.Exec(query, orderID); err != nil {
+			return fmt.Errorf("failed to update stock: %w", err)
+		}
+	}
+	if reason == models.MovementCancellation {
+		restocked := fmt.Sprintf(`UPDATE %s SET restocked_qty = quantity WHERE order_id = $1`, tables.items)
+		if _, err := tx.Exec(restocked, orderID); err != nil {
+			return fmt.Errorf("failed to update stock: %w", err)
+		}
+	}
+	return nil
+}
+
+// restockLinkedProducts adds the units of a delivered wholesale order to the ordering retailer's
+// products linked to its lines, less any units refunded before delivery. Lines of every variant
+// of a source product count towards the one retailer product; retailer products that have
+// variants of their own hold stock per variant and are left alone.
+func restockLinkedProducts(tx *sql.Tx, orderID int) error {
+	query := withMovements(`
+		UPDATE retailer_products rp
+		SET stock_qty = COALESCE(rp.stock_qty, 0) + d.quantity, updated_at = NOW()
+		FROM (
+			SELECT o.retailer_id, i.product_id, SUM(i.quantity - i.refunded_qty) AS quantity
+			FROM wholesaler_order_items i
+			JOIN wholesaler_orders o ON o.id = i.order_id
+			WHERE i.order_id = $1
+			GROUP BY o.retailer_id, i.product_id
+		) d
+		WHERE rp.retailer_id = d.retailer_id AND rp.source_product_id = d.product_id AND d.quantity > 0
+			AND NOT EXISTS (SELECT 1 FROM retailer_product_variants v WHERE v.product_id = rp.id)
+		RETURNING rp.id, NULL::int, d.quantity::int, rp.stock_qty
+	`, "retailer_products", models.MovementWholesaleReceipt, "$1::int")
+	_, err := tx.Exec(query, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to restock linked products: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type IStockAlertsRepo interface {
+	ClearRecoveredAlerts() error
+	GetLowStockProducts() ([]models.LowStockProduct, error)
+	MarkLowStockAlerted(productIDs []int) error
+	GetReorderCandidates(retailerID int, salesWindowDays int) ([]models.ReorderCandidate, error)
+}
+
+type StockAlertsRepo struct {
+	DB *sql.DB
+}
+
+func NewStockAlertsRepo(db *sql.DB) *StockAlertsRepo {
+	return &StockAlertsRepo{DB: db}
+}
+
+// retailerAvailableStock is the units of the retailer product p that can still be sold: the
+// stock of its variants if it has any, else its own, less what pending checkouts hold.
+const retailerAvailableStock = `COALESCE(
+			(SELECT SUM(v.stock_qty - v.reserved_qty) FROM retailer_product_variants v WHERE v.product_id = p.id),
+			COALESCE(p.stock_qty, 0) - p.reserved_qty
+		)`
+
+// ClearRecoveredAlerts forgets the alerts of products that are back at or above their reorder
+// threshold, so they are reported again the next time they run low.
+func (repo *StockAlertsRepo) ClearRecoveredAlerts() error {
+	_, err := repo.DB.Exec(`
+		UPDATE retailer_products p SET low_stock_alerted_at = NULL
+		WHERE p.low_stock_alerted_at IS NOT NULL AND ` + retailerAvailableStock + ` >= p.reorder_threshold
+	`)
+	return err
+}
+
+// GetLowStockProducts returns the products below their reorder threshold that their retailer
+// has not been alerted about yet, grouped by retailer.
+func (repo *StockAlertsRepo) GetLowStockProducts() ([]models.LowStockProduct, error) {
+	rows, err := repo.DB.Query(`
+		SELECT p.retailer_id, r.email, p.id, p.name, COALESCE(p.sku, ''), ` + retailerAvailableStock + `, p.reorder_threshold
+		FROM retailer_products p
+		JOIN retailers r ON r.id = p.retailer_id
+		WHERE p.reorder_threshold > 0 AND p.low_stock_alerted_at IS NULL
+			AND ` + retailerAvailableStock + ` < p.reorder_threshold
+		ORDER BY p.retailer_id, p.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.LowStockProduct
+	for rows.Next() {
+		var product models.LowStockProduct
+		err := rows.Scan(&product.RetailerId, &product.RetailerEmail, &product.ProductId, &product.Name,
+			&product.Sku, &product.Available, &product.ReorderThreshold)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+// MarkLowStockAlerted records that the retailers of the given products have been alerted about
+// them.
+func (repo *StockAlertsRepo) MarkLowStockAlerted(productIDs []int) error {
+	_, err := repo.DB.Exec(`UPDATE retailer_products SET low_stock_alerted_at = NOW() WHERE id = ANY($1)`, pq.Array(productIDs))
+	return err
+}
+
+// GetReorderCandidates returns the retailer's products that are linked to a wholesale source.
+// Sales count the units of paid, shipped and delivered consumer orders placed in the last
+// salesWindowDays days; incoming stock counts the units of wholesale orders the retailer has
+// placed for the source that are paid or confirmed but not delivered yet. Refunded units are
+// left out of both.
+func (repo *StockAlertsRepo) GetReorderCandidates(retailerID int, salesWindowDays int) ([]models.ReorderCandidate, error) {
+	query := `
+		SELECT p.id, p.name, p.source_product_id, p.reorder_threshold, ` + retailerAvailableStock + `,
+			COALESCE((
+				SELECT SUM(i.quantity - i.refunded_qty)
+				FROM retailer_order_items i
+				JOIN retailer_orders o ON o.id = i.order_id
+				WHERE i.product_id = p.id AND o.status IN ('paid', 'shipped', 'delivered')
+					AND o.created_at >= NOW() - make_interval(days => $2)
+			), 0),
+			COALESCE((
+				SELECT SUM(i.quantity - i.refunded_qty)
+				FROM wholesaler_order_items i
+				JOIN wholesaler_orders o ON o.id = i.order_id
+				WHERE i.product_id = p.source_product_id AND o.retailer_id = p.retailer_id
+					AND o.status IN ('paid', 'confirmed', 'shipped')
+			), 0),
+			EXISTS (SELECT 1 FROM wholesaler_product_variants v WHERE v.product_id = p.source_product_id)
+		FROM retailer_products p
+		WHERE p.retailer_id = $1 AND p.source_product_id IS NOT NULL
+		ORDER BY p.name
+	`
+	rows, err := repo.DB.Query(query, retailerID, salesWindowDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []models.ReorderCandidate
+	for rows.Next() {
+		candidate := models.ReorderCandidate{SalesWindowDays: salesWindowDays}
+		err := rows.Scan(&candidate.ProductId, &candidate.Name, &candidate.SourceProductId, &candidate.ReorderThreshold,
+			&candidate.Available, &candidate.SoldQty, &candidate.IncomingQty, &candidate.SourceHasVariants)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
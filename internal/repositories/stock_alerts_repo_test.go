@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStockAlertsRepo_GetReorderCandidates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewStockAlertsRepo(db)
+
+	mock.ExpectQuery("SELECT p.id, p.name, p.source_product_id, p.reorder_threshold").
+		WithArgs(5, 30).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "source_product_id", "reorder_threshold", "available", "sold", "incoming", "source_has_variants"}).
+			AddRow(1, "Eyepiece", 21, 5, 2, 12, 4, true))
+
+	candidates, err := repo.GetReorderCandidates(5, 30)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("Expected 1 candidate, got %d", len(candidates))
+	}
+	c := candidates[0]
+	if c.SourceProductId != 21 || c.SoldQty != 12 || c.IncomingQty != 4 || c.SalesWindowDays != 30 || !c.SourceHasVariants {
+		t.Errorf("Unexpected candidate: %+v", c)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
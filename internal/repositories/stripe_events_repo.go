@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// EventClaim is the outcome of claiming a webhook event for processing.
+type EventClaim int
+
+const (
+	// EventInProgress means another delivery is still processing the event.
+	EventInProgress EventClaim = iota
+	// EventClaimed means this delivery is the one that processes the event.
+	EventClaimed
+	// EventProcessed means an earlier delivery already processed the event.
+	EventProcessed
+)
+
+type IStripeEventsRepo interface {
+	ClaimEvent(eventID string, eventType string) (EventClaim, error)
+	CompleteEvent(eventID string) error
+	ReleaseEvent(eventID string) error
+}
+
+type StripeEventsRepo struct {
+	DB *sql.DB
+}
+
+func NewStripeEventsRepo(db *sql.DB) *StripeEventsRepo {
+	return &StripeEventsRepo{DB: db}
+}
+
+// ClaimEvent records that a webhook event is being processed. The claim holds until
+// CompleteEvent or ReleaseEvent is called; a claim left behind by a process that died while
+// holding it can be taken over once it is ten minutes old.
+func (repo *StripeEventsRepo) ClaimEvent(eventID string, eventType string) (EventClaim, error) {
+	query := `
+		INSERT INTO stripe_events (id, type, status, claimed_at)
+		VALUES ($1, $2, 'processing', NOW())
+		ON CONFLICT (id) DO UPDATE SET claimed_at = NOW()
+		WHERE stripe_events.status = 'processing' AND stripe_events.claimed_at < NOW() - INTERVAL '10 minutes'
+		RETURNING id
+	`
+	var claimedID string
+	err := repo.DB.QueryRow(query, eventID, eventType).Scan(&claimedID)
+	if err == nil {
+		return EventClaimed, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return EventInProgress, err
+	}
+
+	var status string
+	if err := repo.DB.QueryRow(`SELECT status FROM stripe_events WHERE id = $1`, eventID).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Released between the two statements; the next delivery claims it
+			return EventInProgress, nil
+		}
+		return EventInProgress, err
+	}
+	if status == "processed" {
+		return EventProcessed, nil
+	}
+	return EventInProgress, nil
+}
+
+// CompleteEvent marks a claimed event as processed, so later deliveries of it are skipped.
+func (repo *StripeEventsRepo) CompleteEvent(eventID string) error {
+	_, err := repo.DB.Exec(`UPDATE stripe_events SET status = 'processed', processed_at = NOW() WHERE id = $1`, eventID)
+	return err
+}
+
+// ReleaseEvent forgets a claimed event so that Stripe's next delivery processes it again.
+func (repo *StripeEventsRepo) ReleaseEvent(eventID string) error {
+	_, err := repo.DB.Exec(`DELETE FROM stripe_events WHERE id = $1 AND status = 'processing'`, eventID)
+	return err
+}
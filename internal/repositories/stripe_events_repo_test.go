@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStripeEventsRepo_ClaimEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		row    *sqlmock.Rows
+		status *sqlmock.Rows
+		want   EventClaim
+	}{
+		{
+			name: "new event",
+			row:  sqlmock.NewRows([]string{"id"}).AddRow("evt_1"),
+			want: EventClaimed,
+		},
+		{
+			name:   "still processing",
+			row:    sqlmock.NewRows([]string{"id"}),
+			status: sqlmock.NewRows([]string{"status"}).AddRow("processing"),
+			want:   EventInProgress,
+		},
+		{
+			name:   "already processed",
+			row:    sqlmock.NewRows([]string{"id"}),
+			status: sqlmock.NewRows([]string{"status"}).AddRow("processed"),
+			want:   EventProcessed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create mock: %v", err)
+			}
+			defer db.Close()
+
+			repo := NewStripeEventsRepo(db)
+
+			mock.ExpectQuery("INSERT INTO stripe_events .* ON CONFLICT \\(id\\) DO UPDATE SET claimed_at = NOW\\(\\) WHERE stripe_events.status = 'processing'").
+				WithArgs("evt_1", "checkout.session.completed").
+				WillReturnRows(tt.row)
+			if tt.status != nil {
+				mock.ExpectQuery("SELECT status FROM stripe_events WHERE id = \\$1").
+					WithArgs("evt_1").
+					WillReturnRows(tt.status)
+			}
+
+			got, err := repo.ClaimEvent("evt_1", "checkout.session.completed")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected claim %v, got %v", tt.want, got)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestStripeEventsRepo_ClaimEvent_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewStripeEventsRepo(db)
+
+	mock.ExpectQuery("INSERT INTO stripe_events").
+		WillReturnError(sql.ErrConnDone)
+
+	if _, err := repo.ClaimEvent("evt_1", "checkout.session.completed"); err != sql.ErrConnDone {
+		t.Errorf("Expected sql.ErrConnDone, got %v", err)
+	}
+}
@@ -9,16 +9,24 @@ import (
 var ErrWholesalerProductNotFound = errors.New("wholesaler product not found")
 
 type IWholesalerProductRepository interface {
-	GetProducts() ([]models.WholesalerProduct, error)
-	SearchProducts(keyword string) ([]models.WholesalerProduct, error)
-	GetProduct(id int) (*models.WholesalerProduct, error)
+	ListProducts(filter models.ProductFilter) ([]models.WholesalerProduct, models.PageInfo, error)
+	GetProduct(id int, viewerID int) (*models.WholesalerProduct, error)
 	GetProductsByWholesalerID(wholesalerID int) ([]models.WholesalerProduct, error)
 	GetProductByIDForWholesaler(productID int, wholesalerID int) (*models.WholesalerProduct, error)
 	CreateProduct(product *models.WholesalerProduct) (*models.WholesalerProduct, error)
 	UpdateProduct(product *models.WholesalerProduct) (*models.WholesalerProduct, error)
 	DeleteProduct(productID int, wholesalerID int) error
+	SetPricing(productID int, wholesalerID int, minOrderQty int, tiers []models.PriceTier) error
 }
 
+// wholesaleTermsColumns selects a product's minimum order quantity and its quantity breaks from
+// a query whose products table is named p.
+const wholesaleTermsColumns = `p.min_order_qty, COALESCE((
+			SELECT json_agg(json_build_object('min_qty', t.min_qty, 'price', t.price) ORDER BY t.min_qty)
+			FROM wholesaler_product_price_tiers t
+			WHERE t.product_id = p.id
+		), '[]')`
+
 type WholesalerProductRepository struct {
 	DB *sql.DB
 }
@@ -27,105 +35,89 @@ func NewWholesalerProductRepository(db *sql.DB) *WholesalerProductRepository {
 	return &WholesalerProductRepository{DB: db}
 }
 
-func (repo *WholesalerProductRepository) GetProducts() ([]models.WholesalerProduct, error) {
-	query := `
-		SELECT id, wholesaler_id, name, price, stock_qty, image_url, description
-		FROM wholesaler_products
-		ORDER BY updated_at DESC
-		LIMIT 9
-	`
-
-	rows, err := repo.DB.Query(query)
+// ListProducts returns one page of the catalog matching filter, along with the cursor of the
+// next page and the number of matching products.
+func (repo *WholesalerProductRepository) ListProducts(filter models.ProductFilter) ([]models.WholesalerProduct, models.PageInfo, error) {
+	q, err := wholesalerCatalog.listQuery("p.id, p.wholesaler_id, p.name, p.price, p.currency, p.stock_qty, p.image_url, p.description, COALESCE(p.category_id, 0), "+wholesaleTermsColumns, filter)
 	if err != nil {
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
-	defer rows.Close()
-
-	var products []models.WholesalerProduct
 
-	for rows.Next() {
-		var product models.WholesalerProduct
-		err := rows.Scan(
-			&product.Id,
-			&product.Wholesaler_id,
-			&product.Name,
-			&product.Price,
-			&product.Stock_qty,
-			&product.Image_url,
-			&product.Description,
-		)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
+	var page models.PageInfo
+	if err := repo.DB.QueryRow(q.count, q.countArgs...).Scan(&page.Total); err != nil {
+		return nil, models.PageInfo{}, err
 	}
 
-	return products, nil
-}
-
-func (repo *WholesalerProductRepository) SearchProducts(keyword string) ([]models.WholesalerProduct, error) {
-	query := `
-        SELECT id, wholesaler_id, name, price, stock_qty, image_url, description
-        FROM wholesaler_products
-        WHERE
-            to_tsvector('simple', name || ' ' || coalesce(description, ''))
-            @@ plainto_tsquery('simple', $1)
-        ORDER BY updated_at DESC
-        LIMIT 50;
-    `
-
-	rows, err := repo.DB.Query(query, keyword)
+	rows, err := repo.DB.Query(q.page, q.pageArgs...)
 	if err != nil {
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
 	defer rows.Close()
 
-	var products []models.WholesalerProduct
+	products := []models.WholesalerProduct{}
+	var keys []string
+	var ids []int
 
 	for rows.Next() {
 		var product models.WholesalerProduct
+		var key string
 		err := rows.Scan(
 			&product.Id,
 			&product.Wholesaler_id,
 			&product.Name,
 			&product.Price,
+			&product.Currency,
 			&product.Stock_qty,
 			&product.Image_url,
 			&product.Description,
+			&product.CategoryId,
+			&product.MinOrderQty,
+			&product.PriceTiers,
+			&key,
 		)
 		if err != nil {
-			return nil, err
+			return nil, models.PageInfo{}, err
 		}
 		products = append(products, product)
+		keys = append(keys, key)
+		ids = append(ids, product.Id)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, models.PageInfo{}, err
 	}
 
-	return products, nil
+	page.NextCursor = q.nextCursor(keys, ids)
+	if len(products) > q.limit {
+		products = products[:q.limit]
+	}
+
+	return products, page, nil
 }
 
-func (repo *WholesalerProductRepository) GetProduct(id int) (*models.WholesalerProduct, error) {
+// GetProduct returns a product of the public catalog, or of a private one viewerID has been
+// approved for. Any other product is reported as not found.
+func (repo *WholesalerProductRepository) GetProduct(id int, viewerID int) (*models.WholesalerProduct, error) {
 	query := `
-		SELECT id, wholesaler_id, name, price, stock_qty, image_url, description
-		FROM wholesaler_products
-		WHERE id = $1
+		SELECT id, wholesaler_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0),
+		       ` + wholesaleTermsColumns + `
+		FROM wholesaler_products p
+		WHERE id = $1 AND ` + catalogVisibleTo("p.wholesaler_id", "$2") + `
 	`
 
 	var product models.WholesalerProduct
-	err := repo.DB.QueryRow(query, id).Scan(
+	err := repo.DB.QueryRow(query, id, viewerID).Scan(
 		&product.Id,
 		&product.Wholesaler_id,
 		&product.Name,
 		&product.Price,
+		&product.Currency,
 		&product.Stock_qty,
 		&product.Image_url,
 		&product.Description,
+		&product.CategoryId,
+		&product.MinOrderQty,
+		&product.PriceTiers,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -139,8 +131,9 @@ func (repo *WholesalerProductRepository) GetProduct(id int) (*models.WholesalerP
 
 func (repo *WholesalerProductRepository) GetProductsByWholesalerID(wholesalerID int) ([]models.WholesalerProduct, error) {
 	query := `
-		SELECT id, wholesaler_id, name, price, stock_qty, image_url, description
-		FROM wholesaler_products
+		SELECT id, wholesaler_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0), COALESCE(sku, ''),
+		       ` + wholesaleTermsColumns + `
+		FROM wholesaler_products p
 		WHERE wholesaler_id = $1
 		ORDER BY updated_at DESC
 	`
@@ -160,9 +153,14 @@ func (repo *WholesalerProductRepository) GetProductsByWholesalerID(wholesalerID
 			&product.Wholesaler_id,
 			&product.Name,
 			&product.Price,
+			&product.Currency,
 			&product.Stock_qty,
 			&product.Image_url,
 			&product.Description,
+			&product.CategoryId,
+			&product.Sku,
+			&product.MinOrderQty,
+			&product.PriceTiers,
 		)
 		if err != nil {
 			return nil, err
@@ -179,8 +177,9 @@ func (repo *WholesalerProductRepository) GetProductsByWholesalerID(wholesalerID
 
 func (repo *WholesalerProductRepository) GetProductByIDForWholesaler(productID int, wholesalerID int) (*models.WholesalerProduct, error) {
 	query := `
-		SELECT id, wholesaler_id, name, price, stock_qty, image_url, description
-		FROM wholesaler_products
+		SELECT id, wholesaler_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0), COALESCE(sku, ''),
+		       ` + wholesaleTermsColumns + `
+		FROM wholesaler_products p
 		WHERE id = $1 AND wholesaler_id = $2
 	`
 
@@ -190,9 +189,14 @@ func (repo *WholesalerProductRepository) GetProductByIDForWholesaler(productID i
 		&product.Wholesaler_id,
 		&product.Name,
 		&product.Price,
+		&product.Currency,
 		&product.Stock_qty,
 		&product.Image_url,
 		&product.Description,
+		&product.CategoryId,
+		&product.Sku,
+		&product.MinOrderQty,
+		&product.PriceTiers,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -204,14 +208,22 @@ func (repo *WholesalerProductRepository) GetProductByIDForWholesaler(productID i
 	return &product, nil
 }
 
+// CreateProduct inserts a product and records its initial stock, if any, as an adjustment.
 func (repo *WholesalerProductRepository) CreateProduct(product *models.WholesalerProduct) (*models.WholesalerProduct, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return &models.WholesalerProduct{}, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO wholesaler_products (wholesaler_id, name, price, stock_qty, image_url, description)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, wholesaler_id, name, price, stock_qty, image_url, description
+		INSERT INTO wholesaler_products AS p (wholesaler_id, name, price, currency, stock_qty, image_url, description, category_id, sku)
+		VALUES ($1, $2, $3, (SELECT currency FROM wholesalers WHERE id = $1), $4, $5, $6, NULLIF($7, 0), NULLIF($8, ''))
+		RETURNING id, wholesaler_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0), COALESCE(sku, ''),
+		          ` + wholesaleTermsColumns + `
 	`
 
-	err := repo.DB.QueryRow(
+	err = tx.QueryRow(
 		query,
 		product.Wholesaler_id,
 		product.Name,
@@ -219,36 +231,70 @@ func (repo *WholesalerProductRepository) CreateProduct(product *models.Wholesale
 		product.Stock_qty,
 		product.Image_url,
 		product.Description,
+		product.CategoryId,
+		product.Sku,
 	).Scan(
 		&product.Id,
 		&product.Wholesaler_id,
 		&product.Name,
 		&product.Price,
+		&product.Currency,
 		&product.Stock_qty,
 		&product.Image_url,
 		&product.Description,
+		&product.CategoryId,
+		&product.Sku,
+		&product.MinOrderQty,
+		&product.PriceTiers,
 	)
 	if err != nil {
+		return &models.WholesalerProduct{}, productWriteError(err)
+	}
+
+	movement := models.InventoryMovement{ProductId: product.Id, Quantity: product.Stock_qty, StockAfter: product.Stock_qty, Reason: models.MovementAdjustment, Note: "Initial stock"}
+	if err := recordMovement(tx, "wholesaler_products", &movement); err != nil {
+		return &models.WholesalerProduct{}, err
+	}
+	if err := tx.Commit(); err != nil {
 		return &models.WholesalerProduct{}, err
 	}
 
 	return product, nil
 }
 
+// UpdateProduct replaces a product's details. A change of stock_qty is recorded as an
+// adjustment.
 func (repo *WholesalerProductRepository) UpdateProduct(product *models.WholesalerProduct) (*models.WholesalerProduct, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return &models.WholesalerProduct{}, err
+	}
+	defer tx.Rollback()
+
+	previousStock, err := lockStock(tx, "wholesaler_products", product.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &models.WholesalerProduct{}, ErrWholesalerProductNotFound
+		}
+		return &models.WholesalerProduct{}, err
+	}
+
 	query := `
-		UPDATE wholesaler_products
+		UPDATE wholesaler_products p
 		SET name = $1,
 		    price = $2,
 		    stock_qty = $3,
 		    image_url = $4,
 		    description = $5,
+		    category_id = NULLIF($8, 0),
+		    sku = NULLIF($9, ''),
 		    updated_at = NOW()
 		WHERE id = $6 AND wholesaler_id = $7
-		RETURNING id, wholesaler_id, name, price, stock_qty, image_url, description
+		RETURNING id, wholesaler_id, name, price, currency, stock_qty, image_url, description, COALESCE(category_id, 0), COALESCE(sku, ''),
+		          ` + wholesaleTermsColumns + `
 	`
 
-	err := repo.DB.QueryRow(
+	err = tx.QueryRow(
 		query,
 		product.Name,
 		product.Price,
@@ -257,19 +303,34 @@ func (repo *WholesalerProductRepository) UpdateProduct(product *models.Wholesale
 		product.Description,
 		product.Id,
 		product.Wholesaler_id,
+		product.CategoryId,
+		product.Sku,
 	).Scan(
 		&product.Id,
 		&product.Wholesaler_id,
 		&product.Name,
 		&product.Price,
+		&product.Currency,
 		&product.Stock_qty,
 		&product.Image_url,
 		&product.Description,
+		&product.CategoryId,
+		&product.Sku,
+		&product.MinOrderQty,
+		&product.PriceTiers,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return &models.WholesalerProduct{}, ErrWholesalerProductNotFound
 		}
+		return &models.WholesalerProduct{}, productWriteError(err)
+	}
+
+	movement := models.InventoryMovement{ProductId: product.Id, Quantity: product.Stock_qty - previousStock, StockAfter: product.Stock_qty, Reason: models.MovementAdjustment, Note: "Product edited"}
+	if err := recordMovement(tx, "wholesaler_products", &movement); err != nil {
+		return &models.WholesalerProduct{}, err
+	}
+	if err := tx.Commit(); err != nil {
 		return &models.WholesalerProduct{}, err
 	}
 
@@ -297,3 +358,46 @@ func (repo *WholesalerProductRepository) DeleteProduct(productID int, wholesaler
 
 	return nil
 }
+
+// SetPricing replaces a product's minimum order quantity and quantity breaks.
+func (repo *WholesalerProductRepository) SetPricing(productID int, wholesalerID int, minOrderQty int, tiers []models.PriceTier) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`UPDATE wholesaler_products SET min_order_qty = $1, updated_at = NOW() WHERE id = $2 AND wholesaler_id = $3`,
+		minOrderQty,
+		productID,
+		wholesalerID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrWholesalerProductNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM wholesaler_product_price_tiers WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+	for _, tier := range tiers {
+		_, err := tx.Exec(
+			`INSERT INTO wholesaler_product_price_tiers (product_id, min_qty, price) VALUES ($1, $2, $3)`,
+			productID,
+			tier.MinQty,
+			tier.Price,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
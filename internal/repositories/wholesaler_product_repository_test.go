@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWholesalerProductRepository_GetProductsByWholesalerID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewWholesalerProductRepository(db)
+	rows := sqlmock.NewRows([]string{"id", "wholesaler_id", "name", "price", "currency", "stock_qty", "image_url", "description", "category_id", "sku", "min_order_qty", "price_tiers"}).
+		AddRow(2, 1, "Binoculars", 4999, "inr", 4, "https://example.com/img.jpg", "", 0, "BIN-10", 10, `[{"min_qty": 50, "price": 4500}]`).
+		AddRow(1, 1, "Star map", 999, "inr", 0, "https://example.com/img.jpg", "", 3, "", 1, nil)
+	mock.ExpectQuery("FROM wholesaler_products").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	products, err := repo.GetProductsByWholesalerID(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("Expected 2 products, got %d", len(products))
+	}
+	if products[0].Sku != "BIN-10" || products[1].Sku != "" || products[1].CategoryId != 3 {
+		t.Errorf("Unexpected products %+v", products)
+	}
+	if products[0].MinOrderQty != 10 || len(products[0].PriceTiers) != 1 || products[0].PriceTiers[0].Price != 4500 {
+		t.Errorf("Expected the wholesale terms to be read, got %+v", products[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Mock expectations were not met: %v", err)
+	}
+}
@@ -25,7 +25,7 @@ func NewWholesalersRepo(db *sql.DB) *WholesalersRepo {
 
 func (repo *WholesalersRepo) GetWholesalerByID(id int) (*models.Wholesaler, error) {
 	query := `
-		SELECT id, name, business_name, email, phone, address
+		SELECT id, name, business_name, email, phone, address, currency, min_order_value, private_catalog
 		FROM wholesalers
 		WHERE id = $1`
 
@@ -41,6 +41,9 @@ func (repo *WholesalersRepo) GetWholesalerByID(id int) (*models.Wholesaler, erro
 		&wholesaler.Email,
 		&wholesaler.Phone,
 		&wholesaler.Address,
+		&wholesaler.Currency,
+		&wholesaler.MinOrderValue,
+		&wholesaler.PrivateCatalog,
 	)
 
 	if businessName.Valid {
@@ -64,7 +67,7 @@ func (repo *WholesalersRepo) UpsertWholesaler(wholesaler *models.Wholesaler) err
         ON CONFLICT (email) DO UPDATE
         SET 
             name = EXCLUDED.name
-        RETURNING id, email, name, business_name, phone, address
+        RETURNING id, email, name, business_name, phone, address, currency, min_order_value, private_catalog
     `
 
 	// Note: Phone, Address, and BusinessName are not updated here as they come from onboarding/profile update
@@ -85,6 +88,9 @@ func (repo *WholesalersRepo) UpsertWholesaler(wholesaler *models.Wholesaler) err
 		&businessName,
 		&phone,
 		&address,
+		&wholesaler.Currency,
+		&wholesaler.MinOrderValue,
+		&wholesaler.PrivateCatalog,
 	)
 
 	if businessName.Valid {
@@ -102,7 +108,7 @@ func (repo *WholesalersRepo) UpsertWholesaler(wholesaler *models.Wholesaler) err
 
 func (repo *WholesalersRepo) GetWholesalerByEmail(email string) (*models.Wholesaler, error) {
 	query := `
-		SELECT id, name, business_name, email, phone, address
+		SELECT id, name, business_name, email, phone, address, currency, min_order_value, private_catalog
 		FROM wholesalers
 		WHERE email = $1`
 
@@ -120,6 +126,9 @@ func (repo *WholesalersRepo) GetWholesalerByEmail(email string) (*models.Wholesa
 		&wholesaler.Email,
 		&phone,
 		&address,
+		&wholesaler.Currency,
+		&wholesaler.MinOrderValue,
+		&wholesaler.PrivateCatalog,
 	)
 
 	if businessName.Valid {
@@ -142,22 +151,61 @@ func (repo *WholesalersRepo) GetWholesalerByEmail(email string) (*models.Wholesa
 	return &wholesaler, nil
 }
 
+// UpdateWholesaler saves the profile fields. An empty Currency keeps the current one. The
+// currency can only be changed while nothing is priced in it: no products, orders, quotes, price
+// lists or credit terms, and no minimum order value carried over from before. Otherwise it
+// returns ErrCurrencyInUse.
 func (repo *WholesalersRepo) UpdateWholesaler(wholesaler *models.Wholesaler) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if wholesaler.Currency != "" {
+		var inUse bool
+		lockQuery := `
+			SELECT w.currency <> $1 AND (
+				(w.min_order_value > 0 AND w.min_order_value = $3) OR
+				EXISTS (SELECT 1 FROM wholesaler_products WHERE wholesaler_id = w.id) OR
+				EXISTS (SELECT 1 FROM wholesaler_orders WHERE wholesaler_id = w.id) OR
+				EXISTS (SELECT 1 FROM quotes WHERE wholesaler_id = w.id) OR
+				EXISTS (SELECT 1 FROM price_lists WHERE wholesaler_id = w.id) OR
+				EXISTS (SELECT 1 FROM credit_terms WHERE wholesaler_id = w.id)
+			)
+			FROM wholesalers w
+			WHERE w.email = $2
+			FOR UPDATE`
+		if err := tx.QueryRow(lockQuery, wholesaler.Currency, wholesaler.Email, wholesaler.MinOrderValue).Scan(&inUse); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrWholesalerNotFound
+			}
+			return err
+		}
+		if inUse {
+			return ErrCurrencyInUse
+		}
+	}
+
 	query := `
 		UPDATE wholesalers
-		SET business_name = $1, phone = $2, address = $3
-		WHERE email = $4
-		RETURNING id`
+		SET business_name = $1, phone = $2, address = $3, currency = COALESCE(NULLIF($4, ''), currency),
+		    min_order_value = $6, private_catalog = $7
+		WHERE email = $5
+		RETURNING id, currency`
 
 	// Note: name is not updated here - it only comes from Google OAuth during login
 
-	err := repo.DB.QueryRow(
+	err = tx.QueryRow(
 		query,
 		wholesaler.BusinessName,
 		wholesaler.Phone,
 		wholesaler.Address,
+		wholesaler.Currency,
 		wholesaler.Email,
-	).Scan(&wholesaler.Id)
+		wholesaler.MinOrderValue,
+		wholesaler.PrivateCatalog,
+	).Scan(&wholesaler.Id, &wholesaler.Currency)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -166,6 +214,5 @@ func (repo *WholesalersRepo) UpdateWholesaler(wholesaler *models.Wholesaler) err
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
-
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWholesalersRepo_UpdateWholesaler_CurrencyInUse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewWholesalersRepo(db)
+
+	// A minimum order value kept from before counts as priced in the old currency
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT w.currency <> \\$1 AND \\(\\s*\\(w.min_order_value > 0 AND w.min_order_value = \\$3\\) OR .*EXISTS \\(SELECT 1 FROM credit_terms WHERE wholesaler_id = w.id\\)").
+		WithArgs("eur", "depot@example.com", models.Money(50000)).
+		WillReturnRows(sqlmock.NewRows([]string{"in_use"}).AddRow(true))
+	mock.ExpectRollback()
+
+	wholesaler := &models.Wholesaler{Email: "depot@example.com", BusinessName: "Depot", Phone: "123", Address: "1 Road", Currency: "eur", MinOrderValue: 50000}
+	if err := repo.UpdateWholesaler(wholesaler); !errors.Is(err, ErrCurrencyInUse) {
+		t.Errorf("Expected ErrCurrencyInUse, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -45,10 +45,15 @@ func (m *MockUsersRepo) UpsertUser(user *models.User) error {
 
 // MockWholesalersRepo is a mock implementation of IWholesalersRepo
 type MockWholesalersRepo struct {
-	UpsertWholesalerFunc func(wholesaler *models.Wholesaler) error
+	UpsertWholesalerFunc     func(wholesaler *models.Wholesaler) error
+	GetWholesalerByEmailFunc func(email string) (*models.Wholesaler, error)
+	GetWholesalerByIDFunc    func(id int) (*models.Wholesaler, error)
 }
 
 func (m *MockWholesalersRepo) GetWholesalerByID(id int) (*models.Wholesaler, error) {
+	if m.GetWholesalerByIDFunc != nil {
+		return m.GetWholesalerByIDFunc(id)
+	}
 	return nil, errors.New("not implemented")
 }
 
@@ -60,6 +65,9 @@ func (m *MockWholesalersRepo) UpsertWholesaler(wholesaler *models.Wholesaler) er
 }
 
 func (m *MockWholesalersRepo) GetWholesalerByEmail(email string) (*models.Wholesaler, error) {
+	if m.GetWholesalerByEmailFunc != nil {
+		return m.GetWholesalerByEmailFunc(email)
+	}
 	return nil, errors.New("not implemented")
 }
 
@@ -40,7 +40,7 @@ func (s *CartService) GetCartItemsByUserID(userID int) ([]models.CartItem, error
 	return cartItems, nil
 }
 
-func (s *CartService) AddCartItem(email string, productID int, quantity int) (int, error) {
+func (s *CartService) AddCartItem(email string, productID int, variantID int, quantity int) (int, error) {
 	user, err := s.usersRepo.GetUserByEmail(email)
 	if err != nil {
 		return 0, fmt.Errorf("service error fetching user: %w", err)
@@ -48,26 +48,29 @@ func (s *CartService) AddCartItem(email string, productID int, quantity int) (in
 
 	var newQuantity int
 	if quantity == 1 {
-		newQuantity, err = s.cartRepo.AddCartItem(user.Id, productID, quantity)
+		newQuantity, err = s.cartRepo.AddCartItem(user.Id, productID, variantID, quantity)
 	}
 
 	if quantity == -1 {
-		newQuantity, err = s.cartRepo.DecreaseCartItem(user.Id, productID)
+		newQuantity, err = s.cartRepo.DecreaseCartItem(user.Id, productID, variantID)
 	}
 	if err != nil {
+		if err == repositories.ErrVariantRequired || err == repositories.ErrVariantNotFound {
+			return 0, err
+		}
 		return 0, fmt.Errorf("service error adding cart item: %w", err)
 	}
 
 	return newQuantity, nil
 }
 
-func (s *CartService) RemoveCartItem(email string, productID int) error {
+func (s *CartService) RemoveCartItem(email string, productID int, variantID int) error {
 	user, err := s.usersRepo.GetUserByEmail(email)
 	if err != nil {
 		return fmt.Errorf("service error fetching user: %w", err)
 	}
 
-	err = s.cartRepo.RemoveCartItem(user.Id, productID)
+	err = s.cartRepo.RemoveCartItem(user.Id, productID, variantID)
 	if err != nil {
 		if err == repositories.ErrCartItemNotFound {
 			return err
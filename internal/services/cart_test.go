@@ -10,9 +10,9 @@ import (
 // MockCartRepo is a mock implementation of ICartRepo
 type MockCartRepo struct {
 	GetCartItemsByUserIDFunc func(userID int) ([]models.CartItem, error)
-	AddCartItemFunc          func(userID int, productID int, quantity int) (int, error)
-	RemoveCartItemFunc       func(userID int, productID int) error
-	DecreaseCartItemFunc     func(userID int, productID int) (int, error)
+	AddCartItemFunc          func(userID int, productID int, variantID int, quantity int) (int, error)
+	RemoveCartItemFunc       func(userID int, productID int, variantID int) error
+	DecreaseCartItemFunc     func(userID int, productID int, variantID int) (int, error)
 	GetCartNumberFunc        func(userID int) (int, error)
 }
 
@@ -23,23 +23,23 @@ func (m *MockCartRepo) GetCartItemsByUserID(userID int) ([]models.CartItem, erro
 	return nil, errors.New("not implemented")
 }
 
-func (m *MockCartRepo) AddCartItem(userID int, productID int, quantity int) (int, error) {
+func (m *MockCartRepo) AddCartItem(userID int, productID int, variantID int, quantity int) (int, error) {
 	if m.AddCartItemFunc != nil {
-		return m.AddCartItemFunc(userID, productID, quantity)
+		return m.AddCartItemFunc(userID, productID, variantID, quantity)
 	}
 	return 0, errors.New("not implemented")
 }
 
-func (m *MockCartRepo) RemoveCartItem(userID int, productID int) error {
+func (m *MockCartRepo) RemoveCartItem(userID int, productID int, variantID int) error {
 	if m.RemoveCartItemFunc != nil {
-		return m.RemoveCartItemFunc(userID, productID)
+		return m.RemoveCartItemFunc(userID, productID, variantID)
 	}
 	return errors.New("not implemented")
 }
 
-func (m *MockCartRepo) DecreaseCartItem(userID int, productID int) (int, error) {
+func (m *MockCartRepo) DecreaseCartItem(userID int, productID int, variantID int) (int, error) {
 	if m.DecreaseCartItemFunc != nil {
-		return m.DecreaseCartItemFunc(userID, productID)
+		return m.DecreaseCartItemFunc(userID, productID, variantID)
 	}
 	return 0, errors.New("not implemented")
 }
@@ -154,7 +154,7 @@ func TestCartService_AddCartItem(t *testing.T) {
 					},
 				}
 				mockCartRepo := &MockCartRepo{
-					AddCartItemFunc: func(userID int, productID int, quantity int) (int, error) {
+					AddCartItemFunc: func(userID int, productID int, variantID int, quantity int) (int, error) {
 						return 1, nil
 					},
 				}
@@ -175,7 +175,7 @@ func TestCartService_AddCartItem(t *testing.T) {
 					},
 				}
 				mockCartRepo := &MockCartRepo{
-					DecreaseCartItemFunc: func(userID int, productID int) (int, error) {
+					DecreaseCartItemFunc: func(userID int, productID int, variantID int) (int, error) {
 						return 0, nil
 					},
 				}
@@ -191,7 +191,7 @@ func TestCartService_AddCartItem(t *testing.T) {
 			mockCartRepo, mockUsersRepo := tt.setupMocks()
 			service := NewCartService(mockCartRepo, mockUsersRepo)
 
-			qty, err := service.AddCartItem(tt.email, tt.productID, tt.quantity)
+			qty, err := service.AddCartItem(tt.email, tt.productID, 0, tt.quantity)
 
 			if tt.expectedError {
 				if err == nil {
@@ -218,13 +218,13 @@ func TestCartService_RemoveCartItem(t *testing.T) {
 
 	t.Run("successful removal", func(t *testing.T) {
 		mockCartRepo := &MockCartRepo{
-			RemoveCartItemFunc: func(userID int, productID int) error {
+			RemoveCartItemFunc: func(userID int, productID int, variantID int) error {
 				return nil
 			},
 		}
 
 		service := NewCartService(mockCartRepo, mockUsersRepo)
-		err := service.RemoveCartItem("test@example.com", 1)
+		err := service.RemoveCartItem("test@example.com", 1, 0)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -232,13 +232,13 @@ func TestCartService_RemoveCartItem(t *testing.T) {
 
 	t.Run("cart item not found", func(t *testing.T) {
 		mockCartRepo := &MockCartRepo{
-			RemoveCartItemFunc: func(userID int, productID int) error {
+			RemoveCartItemFunc: func(userID int, productID int, variantID int) error {
 				return repositories.ErrCartItemNotFound
 			},
 		}
 
 		service := NewCartService(mockCartRepo, mockUsersRepo)
-		err := service.RemoveCartItem("test@example.com", 1)
+		err := service.RemoveCartItem("test@example.com", 1, 0)
 		if err == nil {
 			t.Fatal("Expected error, got nil")
 		}
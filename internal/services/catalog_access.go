@@ -0,0 +1,114 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+)
+
+// CatalogAccessService runs the approval workflow for private wholesale catalogs: retailers ask
+// to see a catalog and its wholesaler approves, rejects or later revokes their access.
+type CatalogAccessService struct {
+	accessRepo      repositories.ICatalogAccessRepo
+	retailersRepo   repositories.IRetailersRepo
+	wholesalersRepo repositories.IWholesalersRepo
+}
+
+func NewCatalogAccessService(accessRepo repositories.ICatalogAccessRepo, retailersRepo repositories.IRetailersRepo, wholesalersRepo repositories.IWholesalersRepo) *CatalogAccessService {
+	return &CatalogAccessService{
+		accessRepo:      accessRepo,
+		retailersRepo:   retailersRepo,
+		wholesalersRepo: wholesalersRepo,
+	}
+}
+
+// isCatalogAccessError reports whether err is one the handlers report to the user as is.
+func isCatalogAccessError(err error) bool {
+	return errors.Is(err, repositories.ErrCatalogAccessNotFound) ||
+		errors.Is(err, repositories.ErrCatalogAccessExists) ||
+		errors.Is(err, repositories.ErrInvalidAccessChange) ||
+		errors.Is(err, repositories.ErrWholesalerNotFound)
+}
+
+// ViewerID returns the id the catalog queries filter by for the retailer with the given email,
+// or 0 for an empty email, i.e. an anonymous visitor.
+func (s *CatalogAccessService) ViewerID(email string) (int, error) {
+	if email == "" {
+		return 0, nil
+	}
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return 0, fmt.Errorf("service error fetching retailer: %w", err)
+	}
+	return retailer.Id, nil
+}
+
+// RequestAccess asks the wholesaler to let the retailer see its catalog.
+func (s *CatalogAccessService) RequestAccess(email string, wholesalerID int, message string) (*models.CatalogAccess, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching retailer: %w", err)
+	}
+
+	access, err := s.accessRepo.RequestAccess(wholesalerID, retailer.Id, message)
+	if err != nil {
+		if isCatalogAccessError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error requesting catalog access: %w", err)
+	}
+	return access, nil
+}
+
+// GetRetailerAccess lists the catalogs the retailer has asked to see.
+func (s *CatalogAccessService) GetRetailerAccess(email string) ([]models.CatalogAccess, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching retailer: %w", err)
+	}
+
+	list, err := s.accessRepo.GetAccessByRetailer(retailer.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching catalog access: %w", err)
+	}
+	return list, nil
+}
+
+// GetWholesalerAccess lists the retailers that have asked to see the wholesaler's catalog,
+// optionally only those with the given status.
+func (s *CatalogAccessService) GetWholesalerAccess(wholesalerID int, status models.CatalogAccessStatus) ([]models.CatalogAccess, error) {
+	list, err := s.accessRepo.GetAccessByWholesaler(wholesalerID, status)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching catalog access: %w", err)
+	}
+	return list, nil
+}
+
+// Approve grants the retailer access to the wholesaler's catalog. A rejected or revoked
+// retailer can be approved without asking again.
+func (s *CatalogAccessService) Approve(wholesalerID int, retailerID int) (*models.CatalogAccess, error) {
+	return s.setStatus(wholesalerID, retailerID, models.CatalogAccessApproved,
+		models.CatalogAccessPending, models.CatalogAccessRejected, models.CatalogAccessRevoked)
+}
+
+// Reject turns down a pending request.
+func (s *CatalogAccessService) Reject(wholesalerID int, retailerID int) (*models.CatalogAccess, error) {
+	return s.setStatus(wholesalerID, retailerID, models.CatalogAccessRejected, models.CatalogAccessPending)
+}
+
+// Revoke withdraws access the retailer was approved for.
+func (s *CatalogAccessService) Revoke(wholesalerID int, retailerID int) (*models.CatalogAccess, error) {
+	return s.setStatus(wholesalerID, retailerID, models.CatalogAccessRevoked, models.CatalogAccessApproved)
+}
+
+func (s *CatalogAccessService) setStatus(wholesalerID int, retailerID int, to models.CatalogAccessStatus, from ...models.CatalogAccessStatus) (*models.CatalogAccess, error) {
+	access, err := s.accessRepo.SetStatus(wholesalerID, retailerID, from, to)
+	if err != nil {
+		if isCatalogAccessError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error updating catalog access: %w", err)
+	}
+	return access, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// MockCatalogAccessRepo is a mock implementation of ICatalogAccessRepo
+type MockCatalogAccessRepo struct {
+	RequestAccessFunc         func(wholesalerID int, retailerID int, message string) (*models.CatalogAccess, error)
+	GetAccessByRetailerFunc   func(retailerID int) ([]models.CatalogAccess, error)
+	GetAccessByWholesalerFunc func(wholesalerID int, status models.CatalogAccessStatus) ([]models.CatalogAccess, error)
+	SetStatusFunc             func(wholesalerID int, retailerID int, from []models.CatalogAccessStatus, to models.CatalogAccessStatus) (*models.CatalogAccess, error)
+	CanViewWholesalerFunc     func(retailerID int, wholesalerID int) (bool, error)
+	CanViewProductFunc        func(retailerID int, productID int) (bool, error)
+}
+
+func (m *MockCatalogAccessRepo) RequestAccess(wholesalerID int, retailerID int, message string) (*models.CatalogAccess, error) {
+	if m.RequestAccessFunc != nil {
+		return m.RequestAccessFunc(wholesalerID, retailerID, message)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockCatalogAccessRepo) GetAccessByRetailer(retailerID int) ([]models.CatalogAccess, error) {
+	if m.GetAccessByRetailerFunc != nil {
+		return m.GetAccessByRetailerFunc(retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockCatalogAccessRepo) GetAccessByWholesaler(wholesalerID int, status models.CatalogAccessStatus) ([]models.CatalogAccess, error) {
+	if m.GetAccessByWholesalerFunc != nil {
+		return m.GetAccessByWholesalerFunc(wholesalerID, status)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockCatalogAccessRepo) SetStatus(wholesalerID int, retailerID int, from []models.CatalogAccessStatus, to models.CatalogAccessStatus) (*models.CatalogAccess, error) {
+	if m.SetStatusFunc != nil {
+		return m.SetStatusFunc(wholesalerID, retailerID, from, to)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockCatalogAccessRepo) CanViewWholesaler(retailerID int, wholesalerID int) (bool, error) {
+	if m.CanViewWholesalerFunc != nil {
+		return m.CanViewWholesalerFunc(retailerID, wholesalerID)
+	}
+	return false, errors.New("not implemented")
+}
+
+func (m *MockCatalogAccessRepo) CanViewProduct(retailerID int, productID int) (bool, error) {
+	if m.CanViewProductFunc != nil {
+		return m.CanViewProductFunc(retailerID, productID)
+	}
+	return false, errors.New("not implemented")
+}
+
+func TestCatalogAccessService_ViewerID(t *testing.T) {
+	service := NewCatalogAccessService(&MockCatalogAccessRepo{}, &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 4, Email: email}, nil
+		},
+	}, &MockWholesalersRepo{})
+
+	if id, err := service.ViewerID(""); err != nil || id != 0 {
+		t.Errorf("Expected anonymous viewer 0, got %d, %v", id, err)
+	}
+	if id, err := service.ViewerID("shop@example.com"); err != nil || id != 4 {
+		t.Errorf("Expected viewer 4, got %d, %v", id, err)
+	}
+}
+
+func TestCatalogAccessService_Transitions(t *testing.T) {
+	var gotFrom []models.CatalogAccessStatus
+	var gotTo models.CatalogAccessStatus
+	repo := &MockCatalogAccessRepo{
+		SetStatusFunc: func(wholesalerID int, retailerID int, from []models.CatalogAccessStatus, to models.CatalogAccessStatus) (*models.CatalogAccess, error) {
+			gotFrom, gotTo = from, to
+			return &models.CatalogAccess{WholesalerId: wholesalerID, RetailerId: retailerID, Status: to}, nil
+		},
+	}
+	service := NewCatalogAccessService(repo, &MockRetailersRepo{}, &MockWholesalersRepo{})
+
+	tests := []struct {
+		name string
+		set  func(wholesalerID int, retailerID int) (*models.CatalogAccess, error)
+		from []models.CatalogAccessStatus
+		to   models.CatalogAccessStatus
+	}{
+		{"approve", service.Approve, []models.CatalogAccessStatus{models.CatalogAccessPending, models.CatalogAccessRejected, models.CatalogAccessRevoked}, models.CatalogAccessApproved},
+		{"reject", service.Reject, []models.CatalogAccessStatus{models.CatalogAccessPending}, models.CatalogAccessRejected},
+		{"revoke", service.Revoke, []models.CatalogAccessStatus{models.CatalogAccessApproved}, models.CatalogAccessRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access, err := tt.set(2, 5)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if access.Status != tt.to || gotTo != tt.to || !reflect.DeepEqual(gotFrom, tt.from) {
+				t.Errorf("Expected %v -> %s, got %v -> %s", tt.from, tt.to, gotFrom, gotTo)
+			}
+		})
+	}
+
+	t.Run("invalid change passes through", func(t *testing.T) {
+		repo.SetStatusFunc = func(wholesalerID int, retailerID int, from []models.CatalogAccessStatus, to models.CatalogAccessStatus) (*models.CatalogAccess, error) {
+			return nil, repositories.ErrInvalidAccessChange
+		}
+		if _, err := service.Revoke(2, 5); err != repositories.ErrInvalidAccessChange {
+			t.Errorf("Expected ErrInvalidAccessChange, got %v", err)
+		}
+	})
+}
+
+func TestRetailerCartService_AddCartItem_PrivateCatalog(t *testing.T) {
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 4, Email: email}, nil
+		},
+	}
+	accessRepo := &MockCatalogAccessRepo{
+		CanViewProductFunc: func(retailerID int, productID int) (bool, error) {
+			return false, nil
+		},
+	}
+	// The cart repo must not be reached
+	service := NewRetailerCartService(&MockRetailerCartRepo{}, retailersRepo, &MockWholesalersRepo{}, &MockPriceListsRepo{}, accessRepo)
+
+	if _, err := service.AddCartItem("shop@example.com", 1, 0, 5); err != repositories.ErrCatalogAccessDenied {
+		t.Errorf("Expected ErrCatalogAccessDenied, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"fmt"
+)
+
+type CategoriesService struct {
+	categoriesRepo repositories.ICategoriesRepo
+}
+
+func NewCategoriesService(categoriesRepo repositories.ICategoriesRepo) *CategoriesService {
+	return &CategoriesService{
+		categoriesRepo: categoriesRepo,
+	}
+}
+
+// GetShopCategoryTree returns the root categories with their children nested, each counting
+// the retailer products in it and in everything below it.
+func (s *CategoriesService) GetShopCategoryTree() ([]*models.Category, error) {
+	categories, err := s.categoriesRepo.GetShopCategories()
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching shop categories: %w", err)
+	}
+	return buildCategoryTree(categories), nil
+}
+
+// GetWholesaleCategoryTree is the wholesale catalog counterpart of GetShopCategoryTree. Only
+// products the retailer viewerID may see are counted; 0 is an anonymous visitor.
+func (s *CategoriesService) GetWholesaleCategoryTree(viewerID int) ([]*models.Category, error) {
+	categories, err := s.categoriesRepo.GetWholesaleCategories(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching wholesale categories: %w", err)
+	}
+	return buildCategoryTree(categories), nil
+}
+
+func (s *CategoriesService) GetCategoryBySlug(slug string) (*models.Category, error) {
+	category, err := s.categoriesRepo.GetCategoryBySlug(slug)
+	if err != nil {
+		if err == repositories.ErrCategoryNotFound {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching category: %w", err)
+	}
+	return category, nil
+}
+
+// buildCategoryTree nests a flat list of categories under their parents and adds each
+// category's product count to all of its ancestors.
+func buildCategoryTree(categories []models.Category) []*models.Category {
+	byID := make(map[int]*models.Category, len(categories))
+	for i := range categories {
+		byID[categories[i].Id] = &categories[i]
+	}
+
+	roots := []*models.Category{}
+	for i := range categories {
+		category := &categories[i]
+		parent, ok := byID[category.ParentId]
+		if !ok {
+			roots = append(roots, category)
+			continue
+		}
+		parent.Children = append(parent.Children, category)
+	}
+
+	var rollUp func(category *models.Category) int
+	rollUp = func(category *models.Category) int {
+		for _, child := range category.Children {
+			category.ProductCount += rollUp(child)
+		}
+		return category.ProductCount
+	}
+	for _, root := range roots {
+		rollUp(root)
+	}
+	return roots
+}
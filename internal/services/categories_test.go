@@ -0,0 +1,102 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"testing"
+)
+
+// MockCategoriesRepo is a mock implementation of ICategoriesRepo
+type MockCategoriesRepo struct {
+	GetShopCategoriesFunc      func() ([]models.Category, error)
+	GetWholesaleCategoriesFunc func(viewerID int) ([]models.Category, error)
+	GetCategoryBySlugFunc      func(slug string) (*models.Category, error)
+}
+
+func (m *MockCategoriesRepo) GetShopCategories() ([]models.Category, error) {
+	if m.GetShopCategoriesFunc != nil {
+		return m.GetShopCategoriesFunc()
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockCategoriesRepo) GetWholesaleCategories(viewerID int) ([]models.Category, error) {
+	if m.GetWholesaleCategoriesFunc != nil {
+		return m.GetWholesaleCategoriesFunc(viewerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockCategoriesRepo) GetCategoryBySlug(slug string) (*models.Category, error) {
+	if m.GetCategoryBySlugFunc != nil {
+		return m.GetCategoryBySlugFunc(slug)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func TestCategoriesService_GetShopCategoryTree(t *testing.T) {
+	mockRepo := &MockCategoriesRepo{
+		GetShopCategoriesFunc: func() ([]models.Category, error) {
+			return []models.Category{
+				{Id: 1, Name: "Telescopes", Slug: "telescopes", ProductCount: 1},
+				{Id: 2, Name: "Books & Charts", Slug: "books-charts", ProductCount: 4},
+				{Id: 3, ParentId: 1, Name: "Refractors", Slug: "refractors", ProductCount: 2},
+				{Id: 4, ParentId: 1, Name: "Reflectors", Slug: "reflectors", ProductCount: 3},
+				{Id: 5, ParentId: 4, Name: "Dobsonians", Slug: "dobsonians", ProductCount: 5},
+			}, nil
+		},
+	}
+	service := NewCategoriesService(mockRepo)
+
+	tree, err := service.GetShopCategoryTree()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tree) != 2 {
+		t.Fatalf("Expected 2 root categories, got %d", len(tree))
+	}
+
+	telescopes := tree[0]
+	if telescopes.ProductCount != 11 {
+		t.Errorf("Expected telescopes to count 11 products, got %d", telescopes.ProductCount)
+	}
+	if len(telescopes.Children) != 2 {
+		t.Fatalf("Expected telescopes to have 2 children, got %d", len(telescopes.Children))
+	}
+	if reflectors := telescopes.Children[1]; reflectors.ProductCount != 8 || len(reflectors.Children) != 1 {
+		t.Errorf("Expected reflectors to count 8 products with 1 child, got %+v", reflectors)
+	}
+	if tree[1].ProductCount != 4 || tree[1].Children != nil {
+		t.Errorf("Expected books to count 4 products with no children, got %+v", tree[1])
+	}
+}
+
+func TestCategoriesService_GetCategoryBySlug(t *testing.T) {
+	t.Run("not found is passed through", func(t *testing.T) {
+		service := NewCategoriesService(&MockCategoriesRepo{
+			GetCategoryBySlugFunc: func(slug string) (*models.Category, error) {
+				return nil, repositories.ErrCategoryNotFound
+			},
+		})
+
+		_, err := service.GetCategoryBySlug("comets")
+		if err != repositories.ErrCategoryNotFound {
+			t.Errorf("Expected ErrCategoryNotFound, got %v", err)
+		}
+	})
+
+	t.Run("repository error is wrapped", func(t *testing.T) {
+		repoErr := errors.New("database error")
+		service := NewCategoriesService(&MockCategoriesRepo{
+			GetCategoryBySlugFunc: func(slug string) (*models.Category, error) {
+				return nil, repoErr
+			},
+		})
+
+		_, err := service.GetCategoryBySlug("refractors")
+		if !errors.Is(err, repoErr) || err == repoErr {
+			t.Errorf("Expected a wrapped repository error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrFakeSessionNotFound = errors.New("fake checkout session not found")
+	ErrFakeSessionClosed   = errors.New("fake checkout session is no longer open")
+)
+
+type FakeSessionStatus string
+
+const (
+	FakeSessionOpen     FakeSessionStatus = "open"
+	FakeSessionComplete FakeSessionStatus = "complete"
+	FakeSessionExpired  FakeSessionStatus = "expired"
+)
+
+// FakeCheckoutSession is a checkout session held in memory by FakePaymentGateway.
+type FakeCheckoutSession struct {
+	CheckoutSessionRequest
+	ID              string            `json:"id"`
+	URL             string            `json:"url"`
+	Status          FakeSessionStatus `json:"status"`
+	AmountTotal     int64             `json:"amount_total"`
+	AmountRefunded  int64             `json:"amount_refunded"`
+	PaymentIntentID string            `json:"payment_intent_id,omitempty"`
+}
+
+// FakePaymentGateway is an in-process PaymentGateway that never touches the network. Its
+// session URLs point at the dev payment endpoints of this API, and sessions are paid or expired
+// by calling CompleteSession or ExpireSession, which return the event a real provider would send.
+type FakePaymentGateway struct {
+	mu       sync.Mutex
+	baseURL  string
+	nextID   int
+	sessions map[string]*FakeCheckoutSession
+}
+
+// NewFakePaymentGateway creates a fake gateway whose session URLs start with baseURL,
+// e.g. "http://localhost:8000".
+func NewFakePaymentGateway(baseURL string) *FakePaymentGateway {
+	return &FakePaymentGateway{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		sessions: make(map[string]*FakeCheckoutSession),
+	}
+}
+
+func (g *FakePaymentGateway) newID(prefix string) string {
+	g.nextID++
+	return fmt.Sprintf("%s_fake_%d", prefix, g.nextID)
+}
+
+func (g *FakePaymentGateway) CreateCheckoutSession(req CheckoutSessionRequest) (*CheckoutSession, error) {
+	if len(req.LineItems) == 0 {
+		return nil, errors.New("checkout session needs at least one line item")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	sess := &FakeCheckoutSession{
+		CheckoutSessionRequest: req,
+		ID:                     g.newID("cs"),
+		Status:                 FakeSessionOpen,
+	}
+	sess.URL = g.baseURL + "/api/dev/payments/sessions/" + sess.ID
+	for _, item := range req.LineItems {
+		sess.AmountTotal += item.UnitAmount * item.Quantity
+	}
+	g.sessions[sess.ID] = sess
+
+	return &CheckoutSession{ID: sess.ID, URL: sess.URL}, nil
+}
+
+// ParseWebhookEvent accepts an unsigned JSON PaymentEvent, so events can also be posted to the
+// webhook endpoint by hand during development.
+func (g *FakePaymentGateway) ParseWebhookEvent(payload []byte, header string) (*PaymentEvent, error) {
+	var event PaymentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fake payment event: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, errors.New("fake payment event needs an id and a type")
+	}
+	return &event, nil
+}
+
+// RefundPayment refunds part or all of a completed fake session.
+func (g *FakePaymentGateway) RefundPayment(paymentIntentID string, amount int64, metadata map[string]string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, sess := range g.sessions {
+		if sess.PaymentIntentID != paymentIntentID || sess.Status != FakeSessionComplete {
+			continue
+		}
+		if amount <= 0 || sess.AmountRefunded+amount > sess.AmountTotal {
+			return "", fmt.Errorf("refund of %d exceeds the %d left on %s", amount, sess.AmountTotal-sess.AmountRefunded, paymentIntentID)
+		}
+		sess.AmountRefunded += amount
+		return g.newID("re"), nil
+	}
+	return "", fmt.Errorf("no paid fake session for payment intent %s", paymentIntentID)
+}
+
+// GetSession returns a copy of a fake session.
+func (g *FakePaymentGateway) GetSession(sessionID string) (*FakeCheckoutSession, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	sess, ok := g.sessions[sessionID]
+	if !ok {
+		return nil, ErrFakeSessionNotFound
+	}
+	copied := *sess
+	return &copied, nil
+}
+
+// CompleteSession pays an open session and returns the resulting checkout paid event.
+func (g *FakePaymentGateway) CompleteSession(sessionID string) (*PaymentEvent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	sess, err := g.openSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	sess.Status = FakeSessionComplete
+	sess.PaymentIntentID = g.newID("pi")
+
+	return &PaymentEvent{
+		ID:              g.newID("evt"),
+		Type:            PaymentEventCheckoutPaid,
+		SessionID:       sess.ID,
+		PaymentIntentID: sess.PaymentIntentID,
+	}, nil
+}
+
+// ExpireSession abandons an open session and returns the resulting checkout expired event.
+func (g *FakePaymentGateway) ExpireSession(sessionID string) (*PaymentEvent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	sess, err := g.openSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	sess.Status = FakeSessionExpired
+
+	return &PaymentEvent{
+		ID:        g.newID("evt"),
+		Type:      PaymentEventCheckoutExpired,
+		SessionID: sess.ID,
+	}, nil
+}
+
+func (g *FakePaymentGateway) openSession(sessionID string) (*FakeCheckoutSession, error) {
+	sess, ok := g.sessions[sessionID]
+	if !ok {
+		return nil, ErrFakeSessionNotFound
+	}
+	if sess.Status != FakeSessionOpen {
+		return nil, ErrFakeSessionClosed
+	}
+	return sess, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultLedgerLimit = 100
+	maxLedgerLimit     = 500
+	maxAdjustmentNote  = 500
+)
+
+var ErrInvalidAdjustment = errors.New("an adjustment needs a non-zero quantity and a note of at most 500 characters")
+
+// InventoryService reads and adjusts the stock ledger of one catalog, so the shop and the
+// wholesale catalog each get their own instance.
+type InventoryService struct {
+	inventoryRepo repositories.IInventoryRepo
+}
+
+func NewInventoryService(inventoryRepo repositories.IInventoryRepo) *InventoryService {
+	return &InventoryService{
+		inventoryRepo: inventoryRepo,
+	}
+}
+
+// GetLedger returns the latest limit stock movements of one of the seller's products, 100 when
+// limit is 0 and never more than 500.
+func (s *InventoryService) GetLedger(productID int, sellerID int, limit int) (*models.StockLedger, error) {
+	if limit <= 0 {
+		limit = defaultLedgerLimit
+	}
+	limit = min(limit, maxLedgerLimit)
+
+	ledger, err := s.inventoryRepo.GetLedger(productID, sellerID, limit)
+	if err != nil {
+		if isInventoryError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching stock ledger: %w", err)
+	}
+	return ledger, nil
+}
+
+// AdjustStock posts a manual correction of quantity units to the stock of one of the seller's
+// products, or of its variant variantID. The note is required so the ledger can say why the
+// count changed, e.g. "damaged in storage".
+func (s *InventoryService) AdjustStock(productID int, sellerID int, variantID int, quantity int, note string) (*models.InventoryMovement, error) {
+	note = strings.TrimSpace(note)
+	if quantity == 0 || note == "" || len(note) > maxAdjustmentNote {
+		return nil, ErrInvalidAdjustment
+	}
+
+	movement, err := s.inventoryRepo.AdjustStock(productID, sellerID, variantID, quantity, note)
+	if err != nil {
+		if isInventoryError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error adjusting stock: %w", err)
+	}
+	return movement, nil
+}
+
+func isInventoryError(err error) bool {
+	return errors.Is(err, repositories.ErrProductNotFound) ||
+		errors.Is(err, repositories.ErrWholesalerProductNotFound) ||
+		errors.Is(err, repositories.ErrVariantNotFound) ||
+		errors.Is(err, repositories.ErrStockBelowReserved)
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// MockInventoryRepo is a mock implementation of IInventoryRepo
+type MockInventoryRepo struct {
+	GetLedgerFunc   func(productID int, sellerID int, limit int) (*models.StockLedger, error)
+	AdjustStockFunc func(productID int, sellerID int, variantID int, quantity int, note string) (*models.InventoryMovement, error)
+}
+
+func (m *MockInventoryRepo) GetLedger(productID int, sellerID int, limit int) (*models.StockLedger, error) {
+	if m.GetLedgerFunc != nil {
+		return m.GetLedgerFunc(productID, sellerID, limit)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInventoryRepo) AdjustStock(productID int, sellerID int, variantID int, quantity int, note string) (*models.InventoryMovement, error) {
+	if m.AdjustStockFunc != nil {
+		return m.AdjustStockFunc(productID, sellerID, variantID, quantity, note)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func TestInventoryService_GetLedger_Limit(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		expected int
+	}{
+		{name: "default", limit: 0, expected: defaultLedgerLimit},
+		{name: "within bounds", limit: 20, expected: 20},
+		{name: "capped", limit: 10000, expected: maxLedgerLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockInventoryRepo{
+				GetLedgerFunc: func(productID int, sellerID int, limit int) (*models.StockLedger, error) {
+					if limit != tt.expected {
+						t.Errorf("Expected limit %d, got %d", tt.expected, limit)
+					}
+					return &models.StockLedger{ProductId: productID}, nil
+				},
+			}
+			if _, err := NewInventoryService(repo).GetLedger(1, 2, tt.limit); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestInventoryService_AdjustStock(t *testing.T) {
+	t.Run("invalid adjustments", func(t *testing.T) {
+		service := NewInventoryService(&MockInventoryRepo{})
+		for _, tc := range []struct {
+			quantity int
+			note     string
+		}{
+			{0, "Recount"},
+			{3, "   "},
+			{3, strings.Repeat("a", maxAdjustmentNote+1)},
+		} {
+			if _, err := service.AdjustStock(1, 2, 0, tc.quantity, tc.note); !errors.Is(err, ErrInvalidAdjustment) {
+				t.Errorf("Expected ErrInvalidAdjustment for %d %q, got %v", tc.quantity, tc.note, err)
+			}
+		}
+	})
+
+	t.Run("trims the note", func(t *testing.T) {
+		repo := &MockInventoryRepo{
+			AdjustStockFunc: func(productID int, sellerID int, variantID int, quantity int, note string) (*models.InventoryMovement, error) {
+				return &models.InventoryMovement{ProductId: productID, Quantity: quantity, Reason: models.MovementAdjustment, Note: note}, nil
+			},
+		}
+		movement, err := NewInventoryService(repo).AdjustStock(1, 2, 0, -2, " Damaged in storage ")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if movement.Note != "Damaged in storage" {
+			t.Errorf("Expected a trimmed note, got %q", movement.Note)
+		}
+	})
+
+	t.Run("passes repository errors through", func(t *testing.T) {
+		repo := &MockInventoryRepo{
+			AdjustStockFunc: func(productID int, sellerID int, variantID int, quantity int, note string) (*models.InventoryMovement, error) {
+				return nil, repositories.ErrStockBelowReserved
+			},
+		}
+		if _, err := NewInventoryService(repo).AdjustStock(1, 2, 0, -50, "Recount"); !errors.Is(err, repositories.ErrStockBelowReserved) {
+			t.Errorf("Expected ErrStockBelowReserved, got %v", err)
+		}
+	})
+}
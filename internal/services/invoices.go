@@ -0,0 +1,233 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidCreditTerms = errors.New("invalid credit terms")
+	ErrInvalidPayment     = errors.New("invalid payment")
+	ErrInvoiceForbidden   = errors.New("invoice belongs to another account")
+)
+
+// InvoicesService manages trade credit: the terms wholesalers grant retailers, the invoices for
+// orders placed on account, and the payments wholesalers receive for them outside the platform.
+// Invoices past their due date are marked overdue whenever invoices are read.
+type InvoicesService struct {
+	invoicesRepo    repositories.IInvoicesRepo
+	retailersRepo   repositories.IRetailersRepo
+	wholesalersRepo repositories.IWholesalersRepo
+}
+
+func NewInvoicesService(invoicesRepo repositories.IInvoicesRepo, retailersRepo repositories.IRetailersRepo, wholesalersRepo repositories.IWholesalersRepo) *InvoicesService {
+	return &InvoicesService{
+		invoicesRepo:    invoicesRepo,
+		retailersRepo:   retailersRepo,
+		wholesalersRepo: wholesalersRepo,
+	}
+}
+
+// isInvoiceError reports whether err is one the handlers report to the user as is.
+func isInvoiceError(err error) bool {
+	return errors.Is(err, repositories.ErrCreditTermsNotFound) ||
+		errors.Is(err, repositories.ErrInvoiceNotFound) ||
+		errors.Is(err, repositories.ErrInvoiceNotPayable) ||
+		errors.Is(err, repositories.ErrPaymentExceedsBalance) ||
+		errors.Is(err, repositories.ErrRetailerNotFound) ||
+		errors.Is(err, repositories.ErrWholesalerNotFound)
+}
+
+// SetCreditTermsByEmail grants the retailer credit with the wholesaler with the given email, or
+// changes the terms it has. termsDays is the number of days an invoice is due after its order,
+// e.g. 30 for Net 30.
+func (s *InvoicesService) SetCreditTermsByEmail(email string, retailerID int, creditLimit models.Money, termsDays int) (*models.CreditTerms, error) {
+	if creditLimit < 0 {
+		return nil, fmt.Errorf("%w: credit limit cannot be negative", ErrInvalidCreditTerms)
+	}
+	if termsDays <= 0 {
+		return nil, fmt.Errorf("%w: terms_days must be positive", ErrInvalidCreditTerms)
+	}
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	terms, err := s.invoicesRepo.SetCreditTerms(wholesaler.Id, retailerID, creditLimit, termsDays)
+	if err != nil {
+		if isInvoiceError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error setting credit terms: %w", err)
+	}
+	return terms, nil
+}
+
+// DeleteCreditTermsByEmail withdraws the retailer's credit with the wholesaler with the given
+// email. Its invoices stay due.
+func (s *InvoicesService) DeleteCreditTermsByEmail(email string, retailerID int) error {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	if err := s.invoicesRepo.DeleteCreditTerms(wholesaler.Id, retailerID); err != nil {
+		if isInvoiceError(err) {
+			return err
+		}
+		return fmt.Errorf("service error deleting credit terms: %w", err)
+	}
+	return nil
+}
+
+// GetWholesalerCreditTermsByEmail lists the retailers the wholesaler with the given email has
+// granted credit to, with what each owes.
+func (s *InvoicesService) GetWholesalerCreditTermsByEmail(email string) ([]models.CreditTerms, error) {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	terms, err := s.invoicesRepo.GetCreditTermsByWholesaler(wholesaler.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching credit terms: %w", err)
+	}
+	return terms, nil
+}
+
+// GetRetailerCreditTermsByEmail lists the wholesalers the retailer with the given email may
+// order from on account.
+func (s *InvoicesService) GetRetailerCreditTermsByEmail(email string) ([]models.CreditTerms, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	terms, err := s.invoicesRepo.GetCreditTermsByRetailer(retailer.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching credit terms: %w", err)
+	}
+	return terms, nil
+}
+
+// GetWholesalerInvoicesByEmail lists the invoices issued by the wholesaler with the given email,
+// optionally only those with the given status.
+func (s *InvoicesService) GetWholesalerInvoicesByEmail(email string, status models.InvoiceStatus) ([]models.Invoice, error) {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+	if err := s.markOverdue(); err != nil {
+		return nil, err
+	}
+
+	invoices, err := s.invoicesRepo.GetInvoicesByWholesaler(wholesaler.Id, status)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching invoices: %w", err)
+	}
+	return invoices, nil
+}
+
+// GetWholesalerInvoiceByEmail returns an invoice issued by the wholesaler with the given email,
+// with its payments.
+func (s *InvoicesService) GetWholesalerInvoiceByEmail(email string, invoiceID int) (*models.Invoice, error) {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	invoice, err := s.getInvoice(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.WholesalerId != wholesaler.Id {
+		return nil, ErrInvoiceForbidden
+	}
+	return invoice, nil
+}
+
+// GetRetailerInvoicesByEmail lists the invoices owed by the retailer with the given email,
+// optionally only those with the given status.
+func (s *InvoicesService) GetRetailerInvoicesByEmail(email string, status models.InvoiceStatus) ([]models.Invoice, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+	if err := s.markOverdue(); err != nil {
+		return nil, err
+	}
+
+	invoices, err := s.invoicesRepo.GetInvoicesByRetailer(retailer.Id, status)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching invoices: %w", err)
+	}
+	return invoices, nil
+}
+
+// GetRetailerInvoiceByEmail returns an invoice owed by the retailer with the given email, with
+// its payments.
+func (s *InvoicesService) GetRetailerInvoiceByEmail(email string, invoiceID int) (*models.Invoice, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	invoice, err := s.getInvoice(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.RetailerId != retailer.Id {
+		return nil, ErrInvoiceForbidden
+	}
+	return invoice, nil
+}
+
+// RecordPaymentByEmail records a payment the wholesaler with the given email received for one
+// of its invoices. A zero receivedAt means the payment arrived now.
+func (s *InvoicesService) RecordPaymentByEmail(email string, invoiceID int, amount models.Money, method string, reference string, receivedAt time.Time) (*models.Invoice, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidPayment)
+	}
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	} else if receivedAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: received_at cannot be in the future", ErrInvalidPayment)
+	}
+	if _, err := s.GetWholesalerInvoiceByEmail(email, invoiceID); err != nil {
+		return nil, err
+	}
+
+	invoice, err := s.invoicesRepo.RecordPayment(invoiceID, amount, method, reference, receivedAt)
+	if err != nil {
+		if isInvoiceError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error recording payment: %w", err)
+	}
+	return invoice, nil
+}
+
+func (s *InvoicesService) getInvoice(invoiceID int) (*models.Invoice, error) {
+	if err := s.markOverdue(); err != nil {
+		return nil, err
+	}
+
+	invoice, err := s.invoicesRepo.GetInvoice(invoiceID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvoiceNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching invoice: %w", err)
+	}
+	return invoice, nil
+}
+
+func (s *InvoicesService) markOverdue() error {
+	if err := s.invoicesRepo.MarkOverdueInvoices(); err != nil {
+		return fmt.Errorf("service error marking overdue invoices: %w", err)
+	}
+	return nil
+}
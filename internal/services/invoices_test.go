@@ -0,0 +1,312 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"testing"
+	"time"
+)
+
+// MockInvoicesRepo is a mock implementation of IInvoicesRepo
+type MockInvoicesRepo struct {
+	SetCreditTermsFunc             func(wholesalerID int, retailerID int, creditLimit models.Money, termsDays int) (*models.CreditTerms, error)
+	GetCreditTermsFunc             func(wholesalerID int, retailerID int) (*models.CreditTerms, error)
+	GetCreditTermsByWholesalerFunc func(wholesalerID int) ([]models.CreditTerms, error)
+	GetCreditTermsByRetailerFunc   func(retailerID int) ([]models.CreditTerms, error)
+	DeleteCreditTermsFunc          func(wholesalerID int, retailerID int) error
+	CreateInvoicedOrdersFunc       func(orders []*models.RetailerOrder) ([]models.Invoice, error)
+	MarkOverdueInvoicesFunc        func() error
+	GetInvoiceFunc                 func(id int) (*models.Invoice, error)
+	GetInvoicesByWholesalerFunc    func(wholesalerID int, status models.InvoiceStatus) ([]models.Invoice, error)
+	GetInvoicesByRetailerFunc      func(retailerID int, status models.InvoiceStatus) ([]models.Invoice, error)
+	RecordPaymentFunc              func(invoiceID int, amount models.Money, method string, reference string, receivedAt time.Time) (*models.Invoice, error)
+}
+
+func (m *MockInvoicesRepo) SetCreditTerms(wholesalerID int, retailerID int, creditLimit models.Money, termsDays int) (*models.CreditTerms, error) {
+	if m.SetCreditTermsFunc != nil {
+		return m.SetCreditTermsFunc(wholesalerID, retailerID, creditLimit, termsDays)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) GetCreditTerms(wholesalerID int, retailerID int) (*models.CreditTerms, error) {
+	if m.GetCreditTermsFunc != nil {
+		return m.GetCreditTermsFunc(wholesalerID, retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) GetCreditTermsByWholesaler(wholesalerID int) ([]models.CreditTerms, error) {
+	if m.GetCreditTermsByWholesalerFunc != nil {
+		return m.GetCreditTermsByWholesalerFunc(wholesalerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) GetCreditTermsByRetailer(retailerID int) ([]models.CreditTerms, error) {
+	if m.GetCreditTermsByRetailerFunc != nil {
+		return m.GetCreditTermsByRetailerFunc(retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) DeleteCreditTerms(wholesalerID int, retailerID int) error {
+	if m.DeleteCreditTermsFunc != nil {
+		return m.DeleteCreditTermsFunc(wholesalerID, retailerID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) CreateInvoicedOrders(orders []*models.RetailerOrder) ([]models.Invoice, error) {
+	if m.CreateInvoicedOrdersFunc != nil {
+		return m.CreateInvoicedOrdersFunc(orders)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) MarkOverdueInvoices() error {
+	if m.MarkOverdueInvoicesFunc != nil {
+		return m.MarkOverdueInvoicesFunc()
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) GetInvoice(id int) (*models.Invoice, error) {
+	if m.GetInvoiceFunc != nil {
+		return m.GetInvoiceFunc(id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) GetInvoicesByWholesaler(wholesalerID int, status models.InvoiceStatus) ([]models.Invoice, error) {
+	if m.GetInvoicesByWholesalerFunc != nil {
+		return m.GetInvoicesByWholesalerFunc(wholesalerID, status)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) GetInvoicesByRetailer(retailerID int, status models.InvoiceStatus) ([]models.Invoice, error) {
+	if m.GetInvoicesByRetailerFunc != nil {
+		return m.GetInvoicesByRetailerFunc(retailerID, status)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockInvoicesRepo) RecordPayment(invoiceID int, amount models.Money, method string, reference string, receivedAt time.Time) (*models.Invoice, error) {
+	if m.RecordPaymentFunc != nil {
+		return m.RecordPaymentFunc(invoiceID, amount, method, reference, receivedAt)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func newInvoicesTestService(repo *MockInvoicesRepo) *InvoicesService {
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 3, Email: email}, nil
+		},
+	}
+	wholesalersRepo := &MockWholesalersRepo{
+		GetWholesalerByEmailFunc: func(email string) (*models.Wholesaler, error) {
+			return &models.Wholesaler{Id: 7, Email: email}, nil
+		},
+	}
+	if repo.MarkOverdueInvoicesFunc == nil {
+		repo.MarkOverdueInvoicesFunc = func() error { return nil }
+	}
+	if repo.GetInvoiceFunc == nil {
+		repo.GetInvoiceFunc = func(id int) (*models.Invoice, error) {
+			return &models.Invoice{Id: id, WholesalerId: 7, RetailerId: 3, Amount: 5000, Balance: 5000, Status: models.InvoiceOpen}, nil
+		}
+	}
+	return NewInvoicesService(repo, retailersRepo, wholesalersRepo)
+}
+
+func TestInvoicesService_Validation(t *testing.T) {
+	// The repo must not be reached with invalid terms or payments
+	service := newInvoicesTestService(&MockInvoicesRepo{})
+
+	tests := []struct {
+		name     string
+		call     func() error
+		expected error
+	}{
+		{"negative credit limit", func() error {
+			_, err := service.SetCreditTermsByEmail("depot@example.com", 3, -100, 30)
+			return err
+		}, ErrInvalidCreditTerms},
+		{"no payment terms", func() error {
+			_, err := service.SetCreditTermsByEmail("depot@example.com", 3, 100000, 0)
+			return err
+		}, ErrInvalidCreditTerms},
+		{"zero payment", func() error {
+			_, err := service.RecordPaymentByEmail("depot@example.com", 1, 0, "bank transfer", "", time.Time{})
+			return err
+		}, ErrInvalidPayment},
+		{"payment received in the future", func() error {
+			_, err := service.RecordPaymentByEmail("depot@example.com", 1, 1000, "bank transfer", "", time.Now().Add(time.Hour))
+			return err
+		}, ErrInvalidPayment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestInvoicesService_RecordPayment(t *testing.T) {
+	t.Run("defaults the receipt time to now", func(t *testing.T) {
+		var gotReceivedAt time.Time
+		service := newInvoicesTestService(&MockInvoicesRepo{
+			RecordPaymentFunc: func(invoiceID int, amount models.Money, method string, reference string, receivedAt time.Time) (*models.Invoice, error) {
+				gotReceivedAt = receivedAt
+				return &models.Invoice{Id: invoiceID, Amount: 5000, AmountPaid: amount, Balance: 5000 - amount, Status: models.InvoiceOpen}, nil
+			},
+		})
+
+		invoice, err := service.RecordPaymentByEmail("depot@example.com", 1, 2000, "cheque", "CHQ-118", time.Time{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if invoice.Balance != 3000 {
+			t.Errorf("Expected a balance of 3000, got %d", invoice.Balance)
+		}
+		if time.Since(gotReceivedAt) > time.Minute {
+			t.Errorf("Expected the payment to be received now, got %v", gotReceivedAt)
+		}
+	})
+
+	t.Run("another wholesaler's invoice", func(t *testing.T) {
+		service := newInvoicesTestService(&MockInvoicesRepo{
+			GetInvoiceFunc: func(id int) (*models.Invoice, error) {
+				return &models.Invoice{Id: id, WholesalerId: 8, RetailerId: 3}, nil
+			},
+		})
+
+		_, err := service.RecordPaymentByEmail("depot@example.com", 1, 2000, "cheque", "", time.Time{})
+		if err != ErrInvoiceForbidden {
+			t.Errorf("Expected ErrInvoiceForbidden, got %v", err)
+		}
+	})
+
+	t.Run("overpayment is passed through", func(t *testing.T) {
+		service := newInvoicesTestService(&MockInvoicesRepo{
+			RecordPaymentFunc: func(invoiceID int, amount models.Money, method string, reference string, receivedAt time.Time) (*models.Invoice, error) {
+				return nil, repositories.ErrPaymentExceedsBalance
+			},
+		})
+
+		_, err := service.RecordPaymentByEmail("depot@example.com", 1, 9000, "cheque", "", time.Time{})
+		if err != repositories.ErrPaymentExceedsBalance {
+			t.Errorf("Expected ErrPaymentExceedsBalance, got %v", err)
+		}
+	})
+}
+
+func TestInvoicesService_MarksOverdueBeforeListing(t *testing.T) {
+	var marked bool
+	service := newInvoicesTestService(&MockInvoicesRepo{
+		MarkOverdueInvoicesFunc: func() error {
+			marked = true
+			return nil
+		},
+		GetInvoicesByRetailerFunc: func(retailerID int, status models.InvoiceStatus) ([]models.Invoice, error) {
+			if !marked {
+				t.Error("Expected overdue invoices to be marked before they are listed")
+			}
+			return []models.Invoice{}, nil
+		},
+	})
+
+	if _, err := service.GetRetailerInvoicesByEmail("shop@example.com", models.InvoiceOverdue); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestOrdersService_CreateRetailerInvoiceCheckout(t *testing.T) {
+	newService := func(invoicesRepo *MockInvoicesRepo) *OrdersService {
+		service := NewOrdersService(&MockOrdersRepo{}, CartService{}, RetailerCartService{}, nil, nil, &MockUsersRepo{}, &MockRetailersRepo{}, &MockWholesalersRepo{}, nil, nil, invoicesRepo)
+		service.retailerCartService = *NewRetailerCartService(&MockRetailerCartRepo{
+			GetCartItemsByRetailerIDFunc: func(retailerID int) ([]models.RetailerCartItem, error) {
+				return []models.RetailerCartItem{
+					{Product_id: 1, Quantity: 4, Product: models.WholesalerProduct{Id: 1, Wholesaler_id: 7, Name: "Eyepiece", Price: 1000, Currency: "usd"}},
+					{Product_id: 2, Quantity: 1, Product: models.WholesalerProduct{Id: 2, Wholesaler_id: 8, Name: "Star chart", Price: 500, Currency: "usd"}},
+				}, nil
+			},
+		}, &MockRetailersRepo{}, &MockWholesalersRepo{
+			GetWholesalerByIDFunc: func(id int) (*models.Wholesaler, error) {
+				return &models.Wholesaler{Id: id}, nil
+			},
+		}, &MockPriceListsRepo{GetPriceRulesFunc: noPriceRules}, &MockCatalogAccessRepo{
+			CanViewWholesalerFunc: func(retailerID int, wholesalerID int) (bool, error) {
+				return true, nil
+			},
+		})
+		return service
+	}
+
+	t.Run("places one order per wholesaler on account", func(t *testing.T) {
+		var gotOrders []*models.RetailerOrder
+		service := newService(&MockInvoicesRepo{
+			CreateInvoicedOrdersFunc: func(orders []*models.RetailerOrder) ([]models.Invoice, error) {
+				gotOrders = orders
+				invoices := make([]models.Invoice, len(orders))
+				for i, order := range orders {
+					order.Status = models.OrderStatusConfirmed
+					invoices[i] = models.Invoice{Id: i + 1, OrderId: order.Id, Amount: order.TotalPrice}
+				}
+				return invoices, nil
+			},
+		})
+
+		checkout, err := service.CreateRetailerInvoiceCheckout(3)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(gotOrders) != 2 || len(checkout.Invoices) != 2 {
+			t.Fatalf("Expected 2 orders and 2 invoices, got %d and %d", len(gotOrders), len(checkout.Invoices))
+		}
+		for _, order := range gotOrders {
+			if order.RetailerId != 3 {
+				t.Errorf("Expected the order to be for retailer 3, got %d", order.RetailerId)
+			}
+			if order.WholesalerId == 7 && order.TotalPrice != 4000 {
+				t.Errorf("Expected wholesaler 7's order to total 4000, got %d", order.TotalPrice)
+			}
+		}
+	})
+
+	t.Run("credit limit is passed through", func(t *testing.T) {
+		creditErr := &repositories.CreditLimitError{WholesalerId: 7, Available: 1500, Requested: 4000}
+		service := newService(&MockInvoicesRepo{
+			CreateInvoicedOrdersFunc: func(orders []*models.RetailerOrder) ([]models.Invoice, error) {
+				return nil, creditErr
+			},
+		})
+
+		_, err := service.CreateRetailerInvoiceCheckout(3)
+		var gotErr *repositories.CreditLimitError
+		if !errors.As(err, &gotErr) || gotErr.Available != 1500 {
+			t.Errorf("Expected the CreditLimitError, got %v", err)
+		}
+	})
+}
+
+func TestCreditTerms_Available(t *testing.T) {
+	tests := []struct {
+		limit, outstanding, expected models.Money
+	}{
+		{limit: 100000, outstanding: 0, expected: 100000},
+		{limit: 100000, outstanding: 25000, expected: 75000},
+		{limit: 100000, outstanding: 120000, expected: 0},
+	}
+	for _, tt := range tests {
+		terms := models.CreditTerms{CreditLimit: tt.limit, Outstanding: tt.outstanding}
+		if got := terms.Available(); got != tt.expected {
+			t.Errorf("Available() with limit %d and %d outstanding = %d, expected %d", tt.limit, tt.outstanding, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	ErrOrderNotRefundable = errors.New("order cannot be refunded in its current status")
+	ErrNothingToRefund    = errors.New("order has nothing left to refund")
+	ErrInvalidRefund      = errors.New("invalid refund request")
+	ErrRefundFailed       = errors.New("payment provider rejected the refund")
+)
+
+// cancelledBeforePaymentReason is recorded on the refunds issued for orders cancelled while their
+// checkout was still open.
+const cancelledBeforePaymentReason = "order was cancelled before its payment completed"
+
+// refundableStatuses are the statuses in which money has been taken for an order. A cancelled
+// order may have been paid before it was cancelled; if it was not, it has no payment to refund.
+var refundableStatuses = map[models.OrderStatus]bool{
+	models.OrderStatusPaid:      true,
+	models.OrderStatusShipped:   true,
+	models.OrderStatusDelivered: true,
+	models.OrderStatusCancelled: true,
+}
+
+// RefundRetailerSaleByEmail refunds items of a consumer order received by the retailer with the
+// given email. An empty lines slice refunds everything not refunded yet. Restocking is skipped for
+// cancelled orders, whose stock was already returned when they were cancelled.
+func (s *OrdersService) RefundRetailerSaleByEmail(email string, orderID int, lines []models.RefundItem, restock bool, reason string) (*models.Refund, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	order, err := s.getConsumerOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.RetailerId != retailer.Id {
+		return nil, ErrOrderForbidden
+	}
+	if !refundableStatuses[order.Status] {
+		return nil, ErrOrderNotRefundable
+	}
+
+	remaining := make([]orderLineBalance, len(order.Items))
+	for i, item := range order.Items {
+		remaining[i] = orderLineBalance{itemID: item.Id, quantity: item.Quantity - item.RefundedQty}
+	}
+	items, err := resolveRefundItems(lines, remaining)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &models.Refund{
+		OrderId: order.Id,
+		Reason:  reason,
+		Restock: restock && order.Status != models.OrderStatusCancelled,
+		Actor:   models.OrderActor{Type: models.OrderActorRetailer, Id: retailer.Id},
+		Items:   items,
+	}
+	if err := s.refundsRepo.CreateConsumerRefund(refund); err != nil {
+		return nil, refundRepoError(err)
+	}
+
+	return s.payOutRefund(refund)
+}
+
+// RefundWholesalerOrderByEmail is the wholesaler_orders counterpart of RefundRetailerSaleByEmail.
+func (s *OrdersService) RefundWholesalerOrderByEmail(email string, orderID int, lines []models.RefundItem, restock bool, reason string) (*models.Refund, error) {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	order, err := s.getRetailerOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.WholesalerId != wholesaler.Id {
+		return nil, ErrOrderForbidden
+	}
+	if !refundableStatuses[order.Status] {
+		return nil, ErrOrderNotRefundable
+	}
+
+	remaining := make([]orderLineBalance, len(order.Items))
+	for i, item := range order.Items {
+		remaining[i] = orderLineBalance{itemID: item.Id, quantity: item.Quantity - item.RefundedQty}
+	}
+	items, err := resolveRefundItems(lines, remaining)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &models.Refund{
+		OrderId: order.Id,
+		Reason:  reason,
+		Restock: restock && order.Status != models.OrderStatusCancelled,
+		Actor:   models.OrderActor{Type: models.OrderActorWholesaler, Id: wholesaler.Id},
+		Items:   items,
+	}
+	if err := s.refundsRepo.CreateRetailerRefund(refund); err != nil {
+		return nil, refundRepoError(err)
+	}
+
+	return s.payOutRefund(refund)
+}
+
+// GetRetailerSaleRefundsByEmail lists the refunds of a consumer order received by the retailer
+// with the given email.
+func (s *OrdersService) GetRetailerSaleRefundsByEmail(email string, orderID int) ([]models.Refund, error) {
+	if _, err := s.GetRetailerSaleByEmail(email, orderID); err != nil {
+		return nil, err
+	}
+
+	refunds, err := s.refundsRepo.GetConsumerOrderRefunds(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// GetWholesalerOrderRefundsByEmail lists the refunds of a wholesale order received by the
+// wholesaler with the given email.
+func (s *OrdersService) GetWholesalerOrderRefundsByEmail(email string, orderID int) ([]models.Refund, error) {
+	if _, err := s.GetWholesalerOrderByEmail(email, orderID); err != nil {
+		return nil, err
+	}
+
+	refunds, err := s.refundsRepo.GetRetailerOrderRefunds(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// refundCancelledSessionOrders pays back the orders of a just-paid checkout session that their
+// seller cancelled while they were still pending. Cancelling an unpaid order takes no money
+// back, but the customer's payment still covered it. Units already being refunded are skipped,
+// so a retried payment event does not refund an order twice.
+func (s *OrdersService) refundCancelledSessionOrders(sessionID string) error {
+	systemActor := models.OrderActor{Type: models.OrderActorSystem}
+
+	consumerOrders, err := s.ordersRepo.GetConsumerOrdersBySessionID(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get consumer orders of session: %w", err)
+	}
+	for _, order := range consumerOrders {
+		if order.Status != models.OrderStatusCancelled {
+			continue
+		}
+		remaining := make([]orderLineBalance, len(order.Items))
+		for i, item := range order.Items {
+			remaining[i] = orderLineBalance{itemID: item.Id, quantity: item.Quantity - item.RefundedQty}
+		}
+		items, err := resolveRefundItems(nil, remaining)
+		if errors.Is(err, ErrNothingToRefund) {
+			continue
+		}
+		refund := &models.Refund{OrderId: order.Id, Reason: cancelledBeforePaymentReason, Actor: systemActor, Items: items}
+		if err := s.refundsRepo.CreateConsumerRefund(refund); err != nil {
+			return refundRepoError(err)
+		}
+		if _, err := s.payOutRefund(refund); err != nil {
+			return err
+		}
+	}
+
+	// Retailer orders exist when a retailer bought from wholesalers in this session
+	retailerOrders, err := s.ordersRepo.GetRetailerOrdersBySessionID(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get retailer orders of session: %w", err)
+	}
+	for _, order := range retailerOrders {
+		if order.Status != models.OrderStatusCancelled {
+			continue
+		}
+		remaining := make([]orderLineBalance, len(order.Items))
+		for i, item := range order.Items {
+			remaining[i] = orderLineBalance{itemID: item.Id, quantity: item.Quantity - item.RefundedQty}
+		}
+		items, err := resolveRefundItems(nil, remaining)
+		if errors.Is(err, ErrNothingToRefund) {
+			continue
+		}
+		refund := &models.Refund{OrderId: order.Id, Reason: cancelledBeforePaymentReason, Actor: systemActor, Items: items}
+		if err := s.refundsRepo.CreateRetailerRefund(refund); err != nil {
+			return refundRepoError(err)
+		}
+		if _, err := s.payOutRefund(refund); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// payOutRefund sends a recorded pending refund to the payment provider and settles it. If the
+// provider rejects it the refund is marked failed, which makes its units refundable again.
+func (s *OrdersService) payOutRefund(refund *models.Refund) (*models.Refund, error) {
+	metadata := map[string]string{
+		"refund_id": strconv.Itoa(refund.Id),
+		"order_id":  strconv.Itoa(refund.OrderId),
+	}
+	providerRefundID, err := s.paymentGateway.RefundPayment(refund.PaymentIntentId, int64(refund.Amount), metadata)
+	if err != nil {
+		if failErr := s.refundsRepo.FailRefund(refund.Id); failErr != nil {
+			fmt.Printf("failed to release refund %d: %v\n", refund.Id, failErr)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrRefundFailed, err)
+	}
+
+	if err := s.refundsRepo.CompleteRefund(refund.Id, providerRefundID); err != nil {
+		return nil, fmt.Errorf("refund %s was issued but could not be recorded: %w", providerRefundID, err)
+	}
+
+	refund.Status = models.RefundStatusSucceeded
+	refund.StripeRefundId = providerRefundID
+	return refund, nil
+}
+
+// orderLineBalance is how many units of an order item can still be refunded.
+type orderLineBalance struct {
+	itemID   int
+	quantity int
+}
+
+// resolveRefundItems validates the requested refund lines against what is left to refund on the
+// order, merging repeated lines for the same item. With no lines requested, it refunds every unit
+// still outstanding.
+func resolveRefundItems(lines []models.RefundItem, remaining []orderLineBalance) ([]models.RefundItem, error) {
+	if len(lines) == 0 {
+		var items []models.RefundItem
+		for _, line := range remaining {
+			if line.quantity > 0 {
+				items = append(items, models.RefundItem{OrderItemId: line.itemID, Quantity: line.quantity})
+			}
+		}
+		if len(items) == 0 {
+			return nil, ErrNothingToRefund
+		}
+		return items, nil
+	}
+
+	available := make(map[int]int, len(remaining))
+	for _, line := range remaining {
+		available[line.itemID] = line.quantity
+	}
+
+	var items []models.RefundItem
+	position := make(map[int]int)
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for item %d must be positive", ErrInvalidRefund, line.OrderItemId)
+		}
+		if _, ok := available[line.OrderItemId]; !ok {
+			return nil, fmt.Errorf("%w: item %d is not part of this order", ErrInvalidRefund, line.OrderItemId)
+		}
+		if i, ok := position[line.OrderItemId]; ok {
+			items[i].Quantity += line.Quantity
+			continue
+		}
+		position[line.OrderItemId] = len(items)
+		items = append(items, models.RefundItem{OrderItemId: line.OrderItemId, Quantity: line.Quantity})
+	}
+
+	for _, item := range items {
+		if item.Quantity > available[item.OrderItemId] {
+			return nil, repositories.ErrRefundExceedsQuantity
+		}
+	}
+	return items, nil
+}
+
+func refundRepoError(err error) error {
+	if errors.Is(err, repositories.ErrRefundExceedsQuantity) || errors.Is(err, repositories.ErrOrderNotPaid) || errors.Is(err, repositories.ErrOrderNotFound) {
+		return err
+	}
+	return fmt.Errorf("service error recording refund: %w", err)
+}
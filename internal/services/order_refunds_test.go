@@ -0,0 +1,376 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// MockRefundsRepo is a mock implementation of IRefundsRepo
+type MockRefundsRepo struct {
+	CreateConsumerRefundFunc    func(refund *models.Refund) error
+	CreateRetailerRefundFunc    func(refund *models.Refund) error
+	CompleteRefundFunc          func(refundID int, stripeRefundID string) error
+	FailRefundFunc              func(refundID int) error
+	GetConsumerOrderRefundsFunc func(orderID int) ([]models.Refund, error)
+	GetRetailerOrderRefundsFunc func(orderID int) ([]models.Refund, error)
+}
+
+func (m *MockRefundsRepo) CreateConsumerRefund(refund *models.Refund) error {
+	if m.CreateConsumerRefundFunc != nil {
+		return m.CreateConsumerRefundFunc(refund)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockRefundsRepo) CreateRetailerRefund(refund *models.Refund) error {
+	if m.CreateRetailerRefundFunc != nil {
+		return m.CreateRetailerRefundFunc(refund)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockRefundsRepo) CompleteRefund(refundID int, stripeRefundID string) error {
+	if m.CompleteRefundFunc != nil {
+		return m.CompleteRefundFunc(refundID, stripeRefundID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockRefundsRepo) FailRefund(refundID int) error {
+	if m.FailRefundFunc != nil {
+		return m.FailRefundFunc(refundID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockRefundsRepo) GetConsumerOrderRefunds(orderID int) ([]models.Refund, error) {
+	if m.GetConsumerOrderRefundsFunc != nil {
+		return m.GetConsumerOrderRefundsFunc(orderID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockRefundsRepo) GetRetailerOrderRefunds(orderID int) ([]models.Refund, error) {
+	if m.GetRetailerOrderRefundsFunc != nil {
+		return m.GetRetailerOrderRefundsFunc(orderID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func TestOrdersService_RefundRetailerSaleByEmail(t *testing.T) {
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 1, Email: email}, nil
+		},
+	}
+
+	// Order 10 is a paid order with one line already partly refunded
+	newOrder := func(orderID int, status models.OrderStatus) *models.ConsumerOrder {
+		return &models.ConsumerOrder{
+			Id:         orderID,
+			RetailerId: 1,
+			Status:     status,
+			Items: []models.ConsumerOrderItem{
+				{Id: 100, OrderId: orderID, Quantity: 3, Price: 250, RefundedQty: 1},
+				{Id: 101, OrderId: orderID, Quantity: 1, Price: 400},
+			},
+		}
+	}
+	orders := map[int]*models.ConsumerOrder{
+		10: newOrder(10, models.OrderStatusPaid),
+		11: newOrder(11, models.OrderStatusPending),
+		12: newOrder(12, models.OrderStatusCancelled),
+		13: {Id: 13, RetailerId: 2, Status: models.OrderStatusPaid},
+	}
+	ordersRepo := &MockOrdersRepo{
+		GetConsumerOrderByIDFunc: func(orderID int) (*models.ConsumerOrder, error) {
+			if order, ok := orders[orderID]; ok {
+				return order, nil
+			}
+			return nil, repositories.ErrOrderNotFound
+		},
+	}
+
+	tests := []struct {
+		name            string
+		orderID         int
+		lines           []models.RefundItem
+		restock         bool
+		expectedError   error
+		expectedItems   []models.RefundItem
+		expectedRestock bool
+		unknownPayment  bool
+		expectFailed    bool
+	}{
+		{
+			name:            "whole order refunds what is left",
+			orderID:         10,
+			restock:         true,
+			expectedItems:   []models.RefundItem{{OrderItemId: 100, Quantity: 2}, {OrderItemId: 101, Quantity: 1}},
+			expectedRestock: true,
+		},
+		{
+			name:          "partial refund merges repeated lines",
+			orderID:       10,
+			lines:         []models.RefundItem{{OrderItemId: 100, Quantity: 1}, {OrderItemId: 100, Quantity: 1}},
+			expectedItems: []models.RefundItem{{OrderItemId: 100, Quantity: 2}},
+		},
+		{
+			name:            "cancelled order is not restocked twice",
+			orderID:         12,
+			lines:           []models.RefundItem{{OrderItemId: 101, Quantity: 1}},
+			restock:         true,
+			expectedItems:   []models.RefundItem{{OrderItemId: 101, Quantity: 1}},
+			expectedRestock: false,
+		},
+		{
+			name:          "more than remains",
+			orderID:       10,
+			lines:         []models.RefundItem{{OrderItemId: 100, Quantity: 3}},
+			expectedError: repositories.ErrRefundExceedsQuantity,
+		},
+		{
+			name:          "item from another order",
+			orderID:       10,
+			lines:         []models.RefundItem{{OrderItemId: 999, Quantity: 1}},
+			expectedError: ErrInvalidRefund,
+		},
+		{
+			name:          "non-positive quantity",
+			orderID:       10,
+			lines:         []models.RefundItem{{OrderItemId: 100, Quantity: 0}},
+			expectedError: ErrInvalidRefund,
+		},
+		{
+			name:          "unpaid order",
+			orderID:       11,
+			expectedError: ErrOrderNotRefundable,
+		},
+		{
+			name:          "another retailer's order",
+			orderID:       13,
+			expectedError: ErrOrderForbidden,
+		},
+		{
+			name:           "provider rejects refund",
+			orderID:        10,
+			unknownPayment: true,
+			expectedError:  ErrRefundFailed,
+			expectFailed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A paid session on the fake gateway for the refund to go back to
+			gateway := NewFakePaymentGateway("http://localhost:8000")
+			session, err := gateway.CreateCheckoutSession(CheckoutSessionRequest{
+				LineItems: []CheckoutLineItem{{Name: "Tea", Currency: "inr", UnitAmount: 250, Quantity: 3}, {Name: "Jam", Currency: "inr", UnitAmount: 400, Quantity: 1}},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create fake session: %v", err)
+			}
+			paid, err := gateway.CompleteSession(session.ID)
+			if err != nil {
+				t.Fatalf("Failed to complete fake session: %v", err)
+			}
+			paymentIntentID := paid.PaymentIntentID
+			if tt.unknownPayment {
+				paymentIntentID = "pi_unknown"
+			}
+
+			var created *models.Refund
+			var completedID, failedID int
+			refundsRepo := &MockRefundsRepo{
+				CreateConsumerRefundFunc: func(refund *models.Refund) error {
+					refund.Id = 7
+					refund.PaymentIntentId = paymentIntentID
+					refund.Amount = 905
+					created = refund
+					return nil
+				},
+				CompleteRefundFunc: func(refundID int, stripeRefundID string) error {
+					completedID = refundID
+					return nil
+				},
+				FailRefundFunc: func(refundID int) error {
+					failedID = refundID
+					return nil
+				},
+			}
+			service := NewOrdersService(ordersRepo, CartService{}, RetailerCartService{}, gateway, nil, &MockUsersRepo{}, retailersRepo, &MockWholesalersRepo{}, nil, refundsRepo, nil)
+
+			refund, err := service.RefundRetailerSaleByEmail("shop@example.com", tt.orderID, tt.lines, tt.restock, "damaged")
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+				}
+				if tt.expectFailed && failedID != 7 {
+					t.Error("Expected the pending refund to be marked failed")
+				}
+				if !tt.expectFailed && created != nil {
+					t.Error("No refund must be recorded for a rejected request")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(created.Items, tt.expectedItems) {
+				t.Errorf("Expected items %+v, got %+v", tt.expectedItems, created.Items)
+			}
+			if created.Restock != tt.expectedRestock {
+				t.Errorf("Expected restock %v, got %v", tt.expectedRestock, created.Restock)
+			}
+			if created.Actor != (models.OrderActor{Type: models.OrderActorRetailer, Id: 1}) {
+				t.Errorf("Unexpected actor %+v", created.Actor)
+			}
+			refunded, _ := gateway.GetSession(session.ID)
+			if refunded.AmountRefunded != 905 {
+				t.Errorf("Expected 905 to be refunded on the gateway, got %d", refunded.AmountRefunded)
+			}
+			if completedID != 7 {
+				t.Errorf("Expected refund 7 to be completed, got %d", completedID)
+			}
+			if refund.Status != models.RefundStatusSucceeded || !strings.HasPrefix(refund.StripeRefundId, "re_fake_") {
+				t.Errorf("Unexpected refund result %+v", refund)
+			}
+		})
+	}
+}
+
+func TestOrdersService_OrderCancelledBeforePaymentIsRefunded(t *testing.T) {
+	cartRepo := &MockCartRepo{
+		GetCartItemsByUserIDFunc: func(userID int) ([]models.CartItem, error) {
+			return []models.CartItem{
+				{Product_id: 10, Quantity: 2, Product: models.RetailerProduct{Id: 10, Retailer_id: 1, Name: "Tea", Price: 250, Currency: "eur"}},
+				{Product_id: 20, Quantity: 1, Product: models.RetailerProduct{Id: 20, Retailer_id: 2, Name: "Jam", Price: 400, Currency: "eur"}},
+			}, nil
+		},
+	}
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 1, Email: email}, nil
+		},
+	}
+
+	// The orders repo keeps the orders in memory so the cancellation is seen by the payment event
+	orders := map[int]*models.ConsumerOrder{}
+	paymentIntents := map[string]string{}
+	ordersRepo := &MockOrdersRepo{
+		CreateConsumerOrdersFunc: func(created []*models.ConsumerOrder) error {
+			for _, order := range created {
+				order.Id = len(orders) + 1
+				for i := range order.Items {
+					order.Items[i].Id = order.Id*100 + i
+				}
+				orders[order.Id] = order
+			}
+			return nil
+		},
+		UpdateConsumerOrderStripeSessionFunc: func(orderID int, sessionID string) error {
+			orders[orderID].StripeSessionId = sessionID
+			return nil
+		},
+		GetConsumerOrderByIDFunc: func(orderID int) (*models.ConsumerOrder, error) {
+			return orders[orderID], nil
+		},
+		TransitionConsumerOrderStatusFunc: func(orderID int, from, to models.OrderStatus, actor models.OrderActor) error {
+			if orders[orderID].Status != from {
+				return repositories.ErrOrderStatusConflict
+			}
+			orders[orderID].Status = to
+			return nil
+		},
+		UpdateConsumerOrderStatusFunc: func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+			for _, order := range orders {
+				if order.StripeSessionId == sessionID && CanTransition(order.Status, to) {
+					order.Status = to
+				}
+			}
+			return nil
+		},
+		UpdateRetailerOrderStatusFunc: func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+			return nil
+		},
+		SetSessionPaymentIntentFunc: func(sessionID string, paymentIntentID string) error {
+			paymentIntents[sessionID] = paymentIntentID
+			return nil
+		},
+		GetConsumerOrdersBySessionIDFunc: func(sessionID string) ([]models.ConsumerOrder, error) {
+			var found []models.ConsumerOrder
+			for id := 1; id <= len(orders); id++ {
+				if orders[id].StripeSessionId == sessionID {
+					found = append(found, *orders[id])
+				}
+			}
+			return found, nil
+		},
+		GetRetailerOrdersBySessionIDFunc: func(sessionID string) ([]models.RetailerOrder, error) {
+			return nil, nil
+		},
+		GetConsumerOrderBySessionIDFunc: func(sessionID string) (*models.ConsumerOrder, error) {
+			return nil, repositories.ErrOrderNotFound
+		},
+	}
+
+	var refunds []*models.Refund
+	refundsRepo := &MockRefundsRepo{
+		CreateConsumerRefundFunc: func(refund *models.Refund) error {
+			order := orders[refund.OrderId]
+			refund.Id = len(refunds) + 1
+			refund.PaymentIntentId = paymentIntents[order.StripeSessionId]
+			refund.Amount = order.TotalPrice
+			refunds = append(refunds, refund)
+			return nil
+		},
+		CompleteRefundFunc: func(refundID int, stripeRefundID string) error {
+			return nil
+		},
+	}
+
+	gateway := NewFakePaymentGateway("http://localhost:8000")
+	service := NewOrdersService(ordersRepo, *NewCartService(cartRepo, &MockUsersRepo{}), RetailerCartService{}, gateway, NewEmailService(""), &MockUsersRepo{}, retailersRepo, &MockWholesalersRepo{}, newSeenEventsRepo(), refundsRepo, nil)
+
+	checkout, err := service.CreateConsumerCheckout(5, "http://shop/success", "http://shop/cancel", 3)
+	if err != nil {
+		t.Fatalf("CreateConsumerCheckout returned error: %v", err)
+	}
+	sessionID := checkout.Orders[0].StripeSessionId
+
+	// Retailer 1 cancels its order while the customer is still on the checkout page
+	if _, err := service.UpdateRetailerSaleStatusByEmail("shop@example.com", 1, models.OrderStatusCancelled); err != nil {
+		t.Fatalf("Cancelling the pending order returned error: %v", err)
+	}
+
+	event, err := gateway.CompleteSession(sessionID)
+	if err != nil {
+		t.Fatalf("CompleteSession returned error: %v", err)
+	}
+	if err := service.HandlePaymentEvent(event); err != nil {
+		t.Fatalf("HandlePaymentEvent returned error: %v", err)
+	}
+
+	if orders[1].Status != models.OrderStatusCancelled || orders[2].Status != models.OrderStatusPaid {
+		t.Errorf("Expected the cancelled order to stay cancelled and the other to be paid, got %s and %s", orders[1].Status, orders[2].Status)
+	}
+	if len(refunds) != 1 || refunds[0].OrderId != 1 || refunds[0].Restock || refunds[0].Actor.Type != models.OrderActorSystem {
+		t.Fatalf("Expected one system refund of the cancelled order, got %+v", refunds)
+	}
+	if len(refunds[0].Items) != 1 || refunds[0].Items[0] != (models.RefundItem{OrderItemId: 100, Quantity: 2}) {
+		t.Errorf("Expected every unit of the cancelled order to be refunded, got %+v", refunds[0].Items)
+	}
+	session, err := gateway.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("GetSession returned error: %v", err)
+	}
+	if session.AmountRefunded != 500 {
+		t.Errorf("Expected the 500 paid for the cancelled order to be refunded, got %d", session.AmountRefunded)
+	}
+}
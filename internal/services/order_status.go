@@ -0,0 +1,52 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+)
+
+var (
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrStatusNotSettable       = errors.New("order status cannot be set manually")
+)
+
+// orderTransitions lists, for each status, the statuses an order may move to next.
+// Statuses with no entry (failed, refunded) are terminal. Confirmed is a wholesale order placed
+// on account: it ships like a paid order but is settled through its invoice.
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusPending:   {models.OrderStatusPaid, models.OrderStatusConfirmed, models.OrderStatusFailed, models.OrderStatusCancelled},
+	models.OrderStatusPaid:      {models.OrderStatusShipped, models.OrderStatusCancelled, models.OrderStatusRefunded},
+	models.OrderStatusConfirmed: {models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusShipped:   {models.OrderStatusDelivered, models.OrderStatusRefunded},
+	models.OrderStatusDelivered: {models.OrderStatusRefunded},
+	models.OrderStatusCancelled: {models.OrderStatusRefunded},
+}
+
+// sellerSettableStatuses are the statuses a retailer or wholesaler may move their own orders into.
+// Everything else is driven by payment events.
+var sellerSettableStatuses = map[models.OrderStatus]bool{
+	models.OrderStatusShipped:   true,
+	models.OrderStatusDelivered: true,
+	models.OrderStatusCancelled: true,
+}
+
+// CanTransition reports whether an order in status from may move to status to.
+func CanTransition(from, to models.OrderStatus) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// statusesLeadingTo returns every status from which an order may legally move to status to.
+func statusesLeadingTo(to models.OrderStatus) []models.OrderStatus {
+	var from []models.OrderStatus
+	for status := range orderTransitions {
+		if CanTransition(status, to) {
+			from = append(from, status)
+		}
+	}
+	return from
+}
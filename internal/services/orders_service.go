@@ -3,123 +3,159 @@ package services
 import (
 	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+)
 
-	"github.com/stripe/stripe-go/v79"
+var (
+	ErrOrderForbidden  = errors.New("order does not belong to caller")
+	ErrMixedCurrencies = errors.New("cart has items priced in more than one currency")
+	// ErrPaymentEventInProgress is returned for a delivery of an event that another delivery is
+	// still processing; the gateway retries it later.
+	ErrPaymentEventInProgress = errors.New("payment event is already being processed")
 )
 
 type OrdersService struct {
 	ordersRepo          repositories.IOrdersRepo
 	cartService         CartService
 	retailerCartService RetailerCartService
-	stripeService       *StripeService
+	paymentGateway      PaymentGateway
 	emailService        *EmailService
 	usersRepo           repositories.IUsersRepo
 	retailersRepo       repositories.IRetailersRepo
+	wholesalersRepo     repositories.IWholesalersRepo
+	stripeEventsRepo    repositories.IStripeEventsRepo
+	refundsRepo         repositories.IRefundsRepo
+	invoicesRepo        repositories.IInvoicesRepo
 }
 
-func NewOrdersService(ordersRepo repositories.IOrdersRepo, cartService CartService, retailerCartService RetailerCartService, stripeService *StripeService, emailService *EmailService, usersRepo repositories.IUsersRepo, retailersRepo repositories.IRetailersRepo) *OrdersService {
+func NewOrdersService(ordersRepo repositories.IOrdersRepo, cartService CartService, retailerCartService RetailerCartService, paymentGateway PaymentGateway, emailService *EmailService, usersRepo repositories.IUsersRepo, retailersRepo repositories.IRetailersRepo, wholesalersRepo repositories.IWholesalersRepo, stripeEventsRepo repositories.IStripeEventsRepo, refundsRepo repositories.IRefundsRepo, invoicesRepo repositories.IInvoicesRepo) *OrdersService {
 	return &OrdersService{
 		ordersRepo:          ordersRepo,
 		cartService:         cartService,
 		retailerCartService: retailerCartService,
-		stripeService:       stripeService,
+		paymentGateway:      paymentGateway,
 		emailService:        emailService,
 		usersRepo:           usersRepo,
 		retailersRepo:       retailersRepo,
+		wholesalersRepo:     wholesalersRepo,
+		stripeEventsRepo:    stripeEventsRepo,
+		refundsRepo:         refundsRepo,
+		invoicesRepo:        invoicesRepo,
 	}
 }
 
-func (s *OrdersService) CreateConsumerCheckoutByEmail(email, successURL, cancelURL string, addressID int) (string, error) {
+// ConsumerCheckout is the result of starting a consumer checkout: the URL to pay at and the
+// per-retailer orders the cart was split into, all sharing one checkout session.
+type ConsumerCheckout struct {
+	URL    string                  `json:"url"`
+	Orders []*models.ConsumerOrder `json:"orders"`
+}
+
+func (s *OrdersService) CreateConsumerCheckoutByEmail(email, successURL, cancelURL string, addressID int) (*ConsumerCheckout, error) {
 	// Get user ID from email
 	user, err := s.usersRepo.GetUserByEmail(email)
 	if err != nil {
-		return "", fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	return s.CreateConsumerCheckout(user.Id, successURL, cancelURL, addressID)
 }
 
-func (s *OrdersService) CreateConsumerCheckout(userID int, successURL, cancelURL string, addressID int) (string, error) {
+func (s *OrdersService) CreateConsumerCheckout(userID int, successURL, cancelURL string, addressID int) (*ConsumerCheckout, error) {
 	// Get cart items for the user
 	cartItems, err := s.cartService.GetCartItemsByUserID(userID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get cart items: %w", err)
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
 	}
 
 	if len(cartItems) == 0 {
-		return "", fmt.Errorf("cart is empty")
+		return nil, fmt.Errorf("cart is empty")
 	}
 
-	// Convert cart items to Stripe line items
-	var lineItems []*stripe.CheckoutSessionLineItemParams
-	var totalPrice float64
+	// One checkout session is paid in a single currency
+	currency := cartItems[0].Product.Currency
+	var lineItems []CheckoutLineItem
+	ordersByRetailer := make(map[int]*models.ConsumerOrder)
+	var orders []*models.ConsumerOrder
+
 	for _, item := range cartItems {
-		price := item.Product.Price * float64(item.Quantity)
-		totalPrice += price
-
-		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
-			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-				Currency: stripe.String("inr"),
-				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-					Name: stripe.String(item.Product.Name),
-				},
-				UnitAmount: stripe.Int64(int64(item.Product.Price * 100)), // Convert to paise
-			},
-			Quantity: stripe.Int64(int64(item.Quantity)),
-		})
-	}
+		if item.Product.Currency != currency {
+			return nil, ErrMixedCurrencies
+		}
 
-	// Get retailer ID from first cart item (all items should be from same retailer in a single checkout)
-	if len(cartItems) == 0 {
-		return "", fmt.Errorf("cart is empty")
-	}
-	retailerID := cartItems[0].Product.Retailer_id
+		// Convert cart items to checkout line items
+		lineItems = append(lineItems, CheckoutLineItem{
+			Name:       lineItemName(item.Product.Name, item.Variant),
+			Currency:   item.Product.Currency,
+			UnitAmount: int64(item.UnitPrice()),
+			Quantity:   int64(item.Quantity),
+		})
 
-	// Create order items
-	orderItems := make([]models.ConsumerOrderItem, len(cartItems))
-	for i, item := range cartItems {
-		orderItems[i] = models.ConsumerOrderItem{
+		// Group by Retailer for DB Orders, keeping the cart's order of shops
+		retailerID := item.Product.Retailer_id
+		if _, ok := ordersByRetailer[retailerID]; !ok {
+			ordersByRetailer[retailerID] = &models.ConsumerOrder{
+				RetailerId: retailerID,
+				UserId:     userID,
+				AddressId:  addressID,
+				Currency:   currency,
+				Status:     models.OrderStatusPending,
+				Items:      []models.ConsumerOrderItem{},
+			}
+			orders = append(orders, ordersByRetailer[retailerID])
+		}
+		order := ordersByRetailer[retailerID]
+		order.TotalPrice += item.UnitPrice().Times(item.Quantity)
+		order.Items = append(order.Items, models.ConsumerOrderItem{
 			ProductId: item.Product_id,
+			VariantId: item.Variant_id,
 			Quantity:  item.Quantity,
-			Price:     item.Product.Price,
-		}
+			Price:     item.UnitPrice(),
+		})
 	}
 
-	// Create order in database
-	order := models.ConsumerOrder{
-		RetailerId: retailerID,
-		UserId:     userID,
-		AddressId:  addressID,
-		TotalPrice: totalPrice,
-		Status:     models.OrderStatusPending,
-		Items:      orderItems,
+	// Create orders in database, reserving stock for all shops at once
+	if err := s.ordersRepo.CreateConsumerOrders(orders); err != nil {
+		var stockErr *repositories.InsufficientStockError
+		if errors.As(err, &stockErr) || errors.Is(err, repositories.ErrVariantRequired) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create order in db: %w", err)
 	}
 
-	if err := s.ordersRepo.CreateConsumerOrder(&order); err != nil {
-		return "", fmt.Errorf("failed to create order in db: %w", err)
+	orderIDs := make([]string, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = strconv.Itoa(order.Id)
 	}
 
-	// Create Stripe checkout session
-	sessionID, sessionURL, err := s.stripeService.CreateCheckoutSession(
-		lineItems,
-		successURL,
-		cancelURL,
-		fmt.Sprintf("%d", userID),
-		map[string]string{"type": "consumer", "order_id": fmt.Sprintf("%d", order.Id)},
-	)
+	// Create one checkout session covering every shop's order
+	session, err := s.paymentGateway.CreateCheckoutSession(CheckoutSessionRequest{
+		LineItems:         lineItems,
+		SuccessURL:        successURL,
+		CancelURL:         cancelURL,
+		ClientReferenceID: strconv.Itoa(userID),
+		Metadata:          map[string]string{"type": "consumer", "order_ids": strings.Join(orderIDs, ",")},
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create stripe session: %w", err)
+		// Release the reservations, the orders can never be paid
+		for _, order := range orders {
+			s.failPendingConsumerOrder(order.Id)
+		}
+		return nil, fmt.Errorf("failed to create checkout session: %w", err)
 	}
 
-	// Update order with stripe session ID
-	if err := s.ordersRepo.UpdateConsumerOrderStripeSession(order.Id, sessionID); err != nil {
-		return "", fmt.Errorf("failed to update order with stripe session: %w", err)
+	// Update orders with the checkout session ID
+	for _, order := range orders {
+		if err := s.ordersRepo.UpdateConsumerOrderStripeSession(order.Id, session.ID); err != nil {
+			return nil, fmt.Errorf("failed to update order with checkout session: %w", err)
+		}
+		order.StripeSessionId = session.ID
 	}
 
-	return sessionURL, nil
+	return &ConsumerCheckout{URL: session.URL, Orders: orders}, nil
 }
 
 func (s *OrdersService) CreateRetailerCheckoutByEmail(email string, successURL, cancelURL string) (string, error) {
@@ -131,28 +167,102 @@ func (s *OrdersService) CreateRetailerCheckoutByEmail(email string, successURL,
 }
 
 func (s *OrdersService) CreateRetailerCheckout(retailerID int, successURL, cancelURL string) (string, error) {
+	orders, lineItems, err := s.retailerCartOrders(retailerID)
+	if err != nil {
+		return "", err
+	}
+
+	// Save Orders to DB, reserving stock before the customer is sent to pay
+	if err := s.ordersRepo.CreateRetailerOrders(orders); err != nil {
+		var stockErr *repositories.InsufficientStockError
+		if errors.As(err, &stockErr) || errors.Is(err, repositories.ErrVariantRequired) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to create order in db: %w", err)
+	}
+
+	return s.startRetailerCheckout(retailerID, orders, lineItems, successURL, cancelURL)
+}
+
+// RetailerInvoiceCheckout is the result of a retailer checkout on account: the confirmed orders,
+// one per wholesaler, and the invoice for each.
+type RetailerInvoiceCheckout struct {
+	Orders   []*models.RetailerOrder `json:"orders"`
+	Invoices []models.Invoice        `json:"invoices"`
+}
+
+func (s *OrdersService) CreateRetailerInvoiceCheckoutByEmail(email string) (*RetailerInvoiceCheckout, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+	return s.CreateRetailerInvoiceCheckout(retailer.Id)
+}
+
+// CreateRetailerInvoiceCheckout places the retailer's cart on account instead of taking a card
+// payment. Every wholesaler in the cart must have granted the retailer credit terms with enough
+// of its limit left for the order; the orders are then confirmed straight away and invoiced.
+func (s *OrdersService) CreateRetailerInvoiceCheckout(retailerID int) (*RetailerInvoiceCheckout, error) {
+	orders, _, err := s.retailerCartOrders(retailerID)
+	if err != nil {
+		return nil, err
+	}
+
+	invoices, err := s.invoicesRepo.CreateInvoicedOrders(orders)
+	if err != nil {
+		var stockErr *repositories.InsufficientStockError
+		var creditErr *repositories.CreditLimitError
+		if errors.As(err, &stockErr) || errors.As(err, &creditErr) || errors.Is(err, repositories.ErrVariantRequired) ||
+			errors.Is(err, repositories.ErrCreditTermsNotFound) || errors.Is(err, repositories.ErrCreditCurrency) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create invoiced orders: %w", err)
+	}
+
+	return &RetailerInvoiceCheckout{Orders: orders, Invoices: invoices}, nil
+}
+
+// retailerCartOrders prices the retailer's cart and splits it into pending orders, one per
+// wholesaler, along with the checkout lines that pay for them.
+func (s *OrdersService) retailerCartOrders(retailerID int) ([]*models.RetailerOrder, []CheckoutLineItem, error) {
 	cartItems, err := s.retailerCartService.GetCartItemsByRetailerID(retailerID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get cart items: %w", err)
+		return nil, nil, fmt.Errorf("failed to get cart items: %w", err)
 	}
 	if len(cartItems) == 0 {
-		return "", fmt.Errorf("cart is empty")
+		return nil, nil, fmt.Errorf("cart is empty")
+	}
+
+	cart, err := s.retailerCartService.PriceCart(cartItems)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to price cart: %w", err)
+	}
+	if len(cart.Issues) > 0 {
+		return nil, nil, &MinimumOrderError{Issues: cart.Issues}
+	}
+	cartItems = cart.Items
+
+	// Access to a private catalog may have been revoked since the items were added
+	if err := s.retailerCartService.CheckCatalogAccess(retailerID, cartItems); err != nil {
+		return nil, nil, err
 	}
 
-	var lineItems []*stripe.CheckoutSessionLineItemParams
+	// One checkout session is paid in a single currency
+	currency := cartItems[0].Product.Currency
+	var lineItems []CheckoutLineItem
 	ordersByWholesaler := make(map[int]*models.RetailerOrder)
 
 	for _, item := range cartItems {
-		// Add to Stripe Line Items
-		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
-			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-				Currency: stripe.String("usd"), // Assuming USD for now
-				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-					Name: stripe.String(item.Product.Name),
-				},
-				UnitAmount: stripe.Int64(int64(item.Product.Price * 100)),
-			},
-			Quantity: stripe.Int64(int64(item.Quantity)),
+		if item.Product.Currency != currency {
+			return nil, nil, ErrMixedCurrencies
+		}
+
+		// Add to checkout line items
+		lineItems = append(lineItems, CheckoutLineItem{
+			Name:       lineItemName(item.Product.Name, item.Variant),
+			Currency:   item.Product.Currency,
+			UnitAmount: int64(item.UnitPrice()),
+			Quantity:   int64(item.Quantity),
 		})
 
 		// Group by Wholesaler for DB Orders
@@ -161,88 +271,415 @@ func (s *OrdersService) CreateRetailerCheckout(retailerID int, successURL, cance
 			ordersByWholesaler[wholesalerID] = &models.RetailerOrder{
 				WholesalerId: wholesalerID,
 				RetailerId:   retailerID,
+				Currency:     currency,
 				Status:       models.OrderStatusPending,
 				Items:        []models.RetailerOrderItem{},
 			}
 		}
 		order := ordersByWholesaler[wholesalerID]
-		order.TotalPrice += item.Product.Price * float64(item.Quantity)
+		order.TotalPrice += item.UnitPrice().Times(item.Quantity)
 		order.Items = append(order.Items, models.RetailerOrderItem{
 			ProductId: item.Product_id,
+			VariantId: item.Variant_id,
 			Quantity:  item.Quantity,
-			Price:     item.Product.Price,
+			Price:     item.UnitPrice(),
 		})
 	}
 
-	// Create Stripe Session
-	sessionID, sessionURL, err := s.stripeService.CreateCheckoutSession(lineItems, successURL, cancelURL, strconv.Itoa(retailerID), map[string]string{"type": "retailer"})
+	orders := make([]*models.RetailerOrder, 0, len(ordersByWholesaler))
+	for _, order := range ordersByWholesaler {
+		orders = append(orders, order)
+	}
+	return orders, lineItems, nil
+}
+
+// CreateQuoteCheckout starts the checkout of an order placed by accepting a quote. The order's
+// stock is already reserved and its items carry the names of their products.
+func (s *OrdersService) CreateQuoteCheckout(order *models.RetailerOrder, successURL, cancelURL string) (string, error) {
+	lineItems := make([]CheckoutLineItem, len(order.Items))
+	for i, item := range order.Items {
+		lineItems[i] = CheckoutLineItem{
+			Name:       lineItemName(item.Product.Name, item.Variant),
+			Currency:   order.Currency,
+			UnitAmount: int64(item.Price),
+			Quantity:   int64(item.Quantity),
+		}
+	}
+	return s.startRetailerCheckout(order.RetailerId, []*models.RetailerOrder{order}, lineItems, successURL, cancelURL)
+}
+
+// startRetailerCheckout opens a checkout session paying for pending retailer orders. If the
+// session cannot be created the orders are failed, releasing their stock.
+func (s *OrdersService) startRetailerCheckout(retailerID int, orders []*models.RetailerOrder, lineItems []CheckoutLineItem, successURL, cancelURL string) (string, error) {
+	session, err := s.paymentGateway.CreateCheckoutSession(CheckoutSessionRequest{
+		LineItems:         lineItems,
+		SuccessURL:        successURL,
+		CancelURL:         cancelURL,
+		ClientReferenceID: strconv.Itoa(retailerID),
+		Metadata:          map[string]string{"type": "retailer"},
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create stripe session: %w", err)
+		for _, order := range orders {
+			s.failPendingRetailerOrder(order.Id)
+		}
+		return "", fmt.Errorf("failed to create checkout session: %w", err)
 	}
 
-	// Save Orders to DB
-	for _, order := range ordersByWholesaler {
-		order.StripeSessionId = sessionID
-		err := s.ordersRepo.CreateRetailerOrder(order)
-		if err != nil {
-			return "", fmt.Errorf("failed to create order in db: %w", err)
+	for _, order := range orders {
+		if err := s.ordersRepo.UpdateRetailerOrderStripeSession(order.Id, session.ID); err != nil {
+			return "", fmt.Errorf("failed to update order with checkout session: %w", err)
 		}
 	}
 
-	return sessionURL, nil
+	return session.URL, nil
 }
 
-func (s *OrdersService) HandleStripeWebhook(payload []byte, header string, webhookSecret string) error {
-	event, err := s.stripeService.ConstructEvent(payload, header, webhookSecret)
+// lineItemName names a checkout line after the product, followed by the variant's SKU if the
+// line is for a variant.
+func lineItemName(productName string, variant *models.ProductVariant) string {
+	if variant == nil {
+		return productName
+	}
+	return productName + " (" + variant.Sku + ")"
+}
+
+// failPendingConsumerOrder marks an order whose checkout could not be started as failed,
+// which releases its stock reservation.
+func (s *OrdersService) failPendingConsumerOrder(orderID int) {
+	actor := models.OrderActor{Type: models.OrderActorSystem}
+	if err := s.ordersRepo.TransitionConsumerOrderStatus(orderID, models.OrderStatusPending, models.OrderStatusFailed, actor); err != nil {
+		fmt.Printf("failed to release stock for order %d: %v\n", orderID, err)
+	}
+}
+
+// failPendingRetailerOrder is the wholesaler_orders counterpart of failPendingConsumerOrder.
+func (s *OrdersService) failPendingRetailerOrder(orderID int) {
+	actor := models.OrderActor{Type: models.OrderActorSystem}
+	if err := s.ordersRepo.TransitionRetailerOrderStatus(orderID, models.OrderStatusPending, models.OrderStatusFailed, actor); err != nil {
+		fmt.Printf("failed to release stock for order %d: %v\n", orderID, err)
+	}
+}
+
+// HandlePaymentWebhook verifies a webhook delivery from the payment gateway and applies it.
+func (s *OrdersService) HandlePaymentWebhook(payload []byte, header string) error {
+	event, err := s.paymentGateway.ParseWebhookEvent(payload, header)
 	if err != nil {
-		return fmt.Errorf("failed to construct stripe event: %w", err)
+		return fmt.Errorf("failed to verify payment event: %w", err)
 	}
+	return s.HandlePaymentEvent(event)
+}
 
-	if event.Type == "checkout.session.completed" {
-		var session stripe.CheckoutSession
-		err := json.Unmarshal(event.Data.Raw, &session)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal checkout session: %w", err)
+// HandlePaymentEvent applies a verified payment event to the orders of the checkout session it
+// refers to. Gateways retry deliveries, so each event ID is processed once. The event is claimed
+// while it is processed: a delivery arriving meanwhile gets ErrPaymentEventInProgress, so the
+// gateway retries it rather than treating the event as done, and if processing fails the claim
+// is released for the retry to try again.
+func (s *OrdersService) HandlePaymentEvent(event *PaymentEvent) error {
+	claim, err := s.stripeEventsRepo.ClaimEvent(event.ID, string(event.Type))
+	if err != nil {
+		return fmt.Errorf("failed to record payment event: %w", err)
+	}
+	switch claim {
+	case repositories.EventProcessed:
+		// Already handled an earlier delivery of this event
+		return nil
+	case repositories.EventInProgress:
+		return ErrPaymentEventInProgress
+	}
+
+	if err := s.processPaymentEvent(event); err != nil {
+		if releaseErr := s.stripeEventsRepo.ReleaseEvent(event.ID); releaseErr != nil {
+			return fmt.Errorf("%w (and failed to release payment event: %v)", err, releaseErr)
 		}
+		return err
+	}
+	if err := s.stripeEventsRepo.CompleteEvent(event.ID); err != nil {
+		// The claim runs out and a retry processes the event again, which the status checks make safe
+		return fmt.Errorf("failed to mark payment event processed: %w", err)
+	}
+	return nil
+}
 
-		// Update Order Status
-		// We need to find the order(s) associated with this session ID.
-		// Since we might have split orders (one per retailer), we need to update all of them.
-		// But wait, CreateConsumerCheckout creates multiple orders with the SAME session ID?
-		// Yes, that's what I implemented: `order.StripeSessionId = sessionID`.
-		// So one Stripe payment covers multiple DB orders.
-		// When that payment succeeds, all those orders should be marked as Paid.
+func (s *OrdersService) processPaymentEvent(event *PaymentEvent) error {
+	switch event.Type {
+	case PaymentEventCheckoutPaid:
+		return s.markSessionPaid(event.SessionID, event.PaymentIntentID)
 
-		// Update Consumer Orders
-		err = s.ordersRepo.UpdateConsumerOrderStatus(session.ID, models.OrderStatusPaid)
-		if err != nil {
-			return fmt.Errorf("failed to update consumer order status: %w", err)
-		}
+	case PaymentEventCheckoutAwaitingPayment:
+		// The paid or failed event settles the order once the money arrives
+		return s.recordPaymentIntent(event.SessionID, event.PaymentIntentID)
 
-		// Update Retailer Orders (if any - for retailer purchasing from wholesaler)
-		err = s.ordersRepo.UpdateRetailerOrderStatus(session.ID, models.OrderStatusPaid)
+	case PaymentEventCheckoutFailed, PaymentEventCheckoutExpired:
+		// The customer never paid, so release the stock held for the session
+		return s.updateSessionStatus(event.SessionID, models.OrderStatusFailed)
+
+	case PaymentEventPaymentRefunded:
+		// A partial refund leaves the order standing
+		if !event.FullyRefunded || event.PaymentIntentID == "" {
+			return nil
+		}
+		sessionID, err := s.ordersRepo.GetSessionIDByPaymentIntent(event.PaymentIntentID)
 		if err != nil {
-			return fmt.Errorf("failed to update retailer order status: %w", err)
-		}
-
-		// Send Emails
-		// Fetch orders to get user email and details
-		consumerOrders, err := s.ordersRepo.GetConsumerOrderBySessionID(session.ID)
-		if err == nil && consumerOrders != nil {
-			// Send email to consumer
-			// We need to fetch the user email.
-			// consumerOrders is just one order? No, GetConsumerOrderBySessionID returns *ConsumerOrder.
-			// But there might be multiple orders for one session.
-			// My Repo method GetConsumerOrderBySessionID returns a SINGLE order.
-			// This is a bug in my Repo design if I have multiple orders per session.
-			// However, for the email, we just need the user ID from one of them.
-			user, err := s.usersRepo.GetUserByID(consumerOrders.UserId)
-			if err == nil {
-				s.emailService.SendEmail(user.Email, "Order Confirmation", "Your order has been placed successfully!")
+			if errors.Is(err, repositories.ErrOrderNotFound) {
+				// Not a payment taken through our checkout
+				return nil
 			}
+			return fmt.Errorf("failed to find orders for payment intent: %w", err)
 		}
+		return s.updateSessionStatus(sessionID, models.OrderStatusRefunded)
+	}
+
+	return nil
+}
 
+// recordPaymentIntent remembers which payment intent pays for a session, so that later
+// refund events can be traced back to its orders.
+func (s *OrdersService) recordPaymentIntent(sessionID, paymentIntentID string) error {
+	if paymentIntentID == "" {
+		return nil
 	}
+	if err := s.ordersRepo.SetSessionPaymentIntent(sessionID, paymentIntentID); err != nil {
+		return fmt.Errorf("failed to record payment intent: %w", err)
+	}
+	return nil
+}
+
+// markSessionPaid moves every order of the session to paid, which also turns the stock
+// reservation into a sale, refunds the orders cancelled before the payment went through, and
+// sends the consumer a confirmation email.
+func (s *OrdersService) markSessionPaid(sessionID, paymentIntentID string) error {
+	if err := s.recordPaymentIntent(sessionID, paymentIntentID); err != nil {
+		return err
+	}
+	if err := s.updateSessionStatus(sessionID, models.OrderStatusPaid); err != nil {
+		return err
+	}
+	// A seller may have cancelled one of the orders while the customer was still paying
+	if err := s.refundCancelledSessionOrders(sessionID); err != nil {
+		return err
+	}
+
+	consumerOrder, err := s.ordersRepo.GetConsumerOrderBySessionID(sessionID)
+	if err == nil && consumerOrder != nil {
+		user, err := s.usersRepo.GetUserByID(consumerOrder.UserId)
+		if err == nil {
+			s.emailService.SendEmail(user.Email, "Order Confirmation", "Your order has been placed successfully!")
+		}
+	}
+	return nil
+}
 
+// updateSessionStatus moves the consumer and retailer orders of a checkout session to the given
+// status. Orders that cannot legally reach it, for instance because a retried event already
+// moved them, are left untouched.
+func (s *OrdersService) updateSessionStatus(sessionID string, to models.OrderStatus) error {
+	systemActor := models.OrderActor{Type: models.OrderActorSystem}
+	from := statusesLeadingTo(to)
+
+	if err := s.ordersRepo.UpdateConsumerOrderStatus(sessionID, from, to, systemActor); err != nil {
+		return fmt.Errorf("failed to update consumer order status: %w", err)
+	}
+	// Retailer orders exist when a retailer bought from wholesalers in this session
+	if err := s.ordersRepo.UpdateRetailerOrderStatus(sessionID, from, to, systemActor); err != nil {
+		return fmt.Errorf("failed to update retailer order status: %w", err)
+	}
 	return nil
 }
+
+// GetConsumerOrdersByEmail returns the order history of the consumer with the given email.
+func (s *OrdersService) GetConsumerOrdersByEmail(email string) ([]models.ConsumerOrder, error) {
+	user, err := s.usersRepo.GetUserByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	orders, err := s.ordersRepo.GetConsumerOrdersByUserID(user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching orders: %w", err)
+	}
+	return orders, nil
+}
+
+// GetConsumerOrderByEmail returns a single order placed by the consumer with the given email.
+func (s *OrdersService) GetConsumerOrderByEmail(email string, orderID int) (*models.ConsumerOrder, error) {
+	user, err := s.usersRepo.GetUserByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	order, err := s.getConsumerOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserId != user.Id {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+// GetRetailerSalesByEmail returns the consumer orders received by the retailer with the given email.
+func (s *OrdersService) GetRetailerSalesByEmail(email string) ([]models.ConsumerOrder, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	orders, err := s.ordersRepo.GetConsumerOrdersByRetailerID(retailer.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching orders: %w", err)
+	}
+	return orders, nil
+}
+
+// GetRetailerSaleByEmail returns a single consumer order received by the retailer with the given email.
+func (s *OrdersService) GetRetailerSaleByEmail(email string, orderID int) (*models.ConsumerOrder, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	order, err := s.getConsumerOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.RetailerId != retailer.Id {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+// GetRetailerPurchasesByEmail returns the wholesale orders placed by the retailer with the given email.
+func (s *OrdersService) GetRetailerPurchasesByEmail(email string) ([]models.RetailerOrder, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	orders, err := s.ordersRepo.GetRetailerOrdersByRetailerID(retailer.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching orders: %w", err)
+	}
+	return orders, nil
+}
+
+// GetRetailerPurchaseByEmail returns a single wholesale order placed by the retailer with the given email.
+func (s *OrdersService) GetRetailerPurchaseByEmail(email string, orderID int) (*models.RetailerOrder, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	order, err := s.getRetailerOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.RetailerId != retailer.Id {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+// GetWholesalerOrdersByEmail returns the retailer orders received by the wholesaler with the given email.
+func (s *OrdersService) GetWholesalerOrdersByEmail(email string) ([]models.RetailerOrder, error) {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	orders, err := s.ordersRepo.GetRetailerOrdersByWholesalerID(wholesaler.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching orders: %w", err)
+	}
+	return orders, nil
+}
+
+// GetWholesalerOrderByEmail returns a single retailer order received by the wholesaler with the given email.
+func (s *OrdersService) GetWholesalerOrderByEmail(email string, orderID int) (*models.RetailerOrder, error) {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	order, err := s.getRetailerOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.WholesalerId != wholesaler.Id {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+// UpdateRetailerSaleStatusByEmail moves a consumer order received by the retailer with the given
+// email to a new status, enforcing the order state machine.
+func (s *OrdersService) UpdateRetailerSaleStatusByEmail(email string, orderID int, status models.OrderStatus) (*models.ConsumerOrder, error) {
+	if !sellerSettableStatuses[status] {
+		return nil, ErrStatusNotSettable
+	}
+
+	order, err := s.GetRetailerSaleByEmail(email, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransition(order.Status, status) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	actor := models.OrderActor{Type: models.OrderActorRetailer, Id: order.RetailerId}
+	if err := s.ordersRepo.TransitionConsumerOrderStatus(order.Id, order.Status, status, actor); err != nil {
+		if err == repositories.ErrOrderStatusConflict {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error updating order status: %w", err)
+	}
+
+	return s.getConsumerOrder(order.Id)
+}
+
+// UpdateWholesalerOrderStatusByEmail moves a retailer order received by the wholesaler with the
+// given email to a new status, enforcing the order state machine.
+func (s *OrdersService) UpdateWholesalerOrderStatusByEmail(email string, orderID int, status models.OrderStatus) (*models.RetailerOrder, error) {
+	if !sellerSettableStatuses[status] {
+		return nil, ErrStatusNotSettable
+	}
+
+	order, err := s.GetWholesalerOrderByEmail(email, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransition(order.Status, status) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	actor := models.OrderActor{Type: models.OrderActorWholesaler, Id: order.WholesalerId}
+	if err := s.ordersRepo.TransitionRetailerOrderStatus(order.Id, order.Status, status, actor); err != nil {
+		if err == repositories.ErrOrderStatusConflict {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error updating order status: %w", err)
+	}
+
+	return s.getRetailerOrder(order.Id)
+}
+
+func (s *OrdersService) getConsumerOrder(orderID int) (*models.ConsumerOrder, error) {
+	order, err := s.ordersRepo.GetConsumerOrderByID(orderID)
+	if err != nil {
+		if err == repositories.ErrOrderNotFound {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching order: %w", err)
+	}
+	return order, nil
+}
+
+func (s *OrdersService) getRetailerOrder(orderID int) (*models.RetailerOrder, error) {
+	order, err := s.ordersRepo.GetRetailerOrderByID(orderID)
+	if err != nil {
+		if err == repositories.ErrOrderNotFound {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching order: %w", err)
+	}
+	return order, nil
+}
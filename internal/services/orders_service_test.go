@@ -0,0 +1,507 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// MockOrdersRepo is a mock implementation of IOrdersRepo
+type MockOrdersRepo struct {
+	CreateConsumerOrdersFunc             func(orders []*models.ConsumerOrder) error
+	CreateRetailerOrdersFunc             func(orders []*models.RetailerOrder) error
+	GetConsumerOrderBySessionIDFunc      func(sessionID string) (*models.ConsumerOrder, error)
+	GetRetailerOrderBySessionIDFunc      func(sessionID string) (*models.RetailerOrder, error)
+	GetConsumerOrdersBySessionIDFunc     func(sessionID string) ([]models.ConsumerOrder, error)
+	GetRetailerOrdersBySessionIDFunc     func(sessionID string) ([]models.RetailerOrder, error)
+	UpdateConsumerOrderStatusFunc        func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error
+	UpdateRetailerOrderStatusFunc        func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error
+	UpdateConsumerOrderStripeSessionFunc func(orderID int, sessionID string) error
+	UpdateRetailerOrderStripeSessionFunc func(orderID int, sessionID string) error
+	GetConsumerOrdersByUserIDFunc        func(userID int) ([]models.ConsumerOrder, error)
+	GetConsumerOrdersByRetailerIDFunc    func(retailerID int) ([]models.ConsumerOrder, error)
+	GetRetailerOrdersByRetailerIDFunc    func(retailerID int) ([]models.RetailerOrder, error)
+	GetRetailerOrdersByWholesalerIDFunc  func(wholesalerID int) ([]models.RetailerOrder, error)
+	GetConsumerOrderByIDFunc             func(orderID int) (*models.ConsumerOrder, error)
+	GetRetailerOrderByIDFunc             func(orderID int) (*models.RetailerOrder, error)
+	TransitionConsumerOrderStatusFunc    func(orderID int, from, to models.OrderStatus, actor models.OrderActor) error
+	TransitionRetailerOrderStatusFunc    func(orderID int, from, to models.OrderStatus, actor models.OrderActor) error
+	SetSessionPaymentIntentFunc          func(sessionID string, paymentIntentID string) error
+	GetSessionIDByPaymentIntentFunc      func(paymentIntentID string) (string, error)
+}
+
+func (m *MockOrdersRepo) CreateConsumerOrders(orders []*models.ConsumerOrder) error {
+	if m.CreateConsumerOrdersFunc != nil {
+		return m.CreateConsumerOrdersFunc(orders)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) CreateRetailerOrders(orders []*models.RetailerOrder) error {
+	if m.CreateRetailerOrdersFunc != nil {
+		return m.CreateRetailerOrdersFunc(orders)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetConsumerOrderBySessionID(sessionID string) (*models.ConsumerOrder, error) {
+	if m.GetConsumerOrderBySessionIDFunc != nil {
+		return m.GetConsumerOrderBySessionIDFunc(sessionID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetRetailerOrderBySessionID(sessionID string) (*models.RetailerOrder, error) {
+	if m.GetRetailerOrderBySessionIDFunc != nil {
+		return m.GetRetailerOrderBySessionIDFunc(sessionID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetConsumerOrdersBySessionID(sessionID string) ([]models.ConsumerOrder, error) {
+	if m.GetConsumerOrdersBySessionIDFunc != nil {
+		return m.GetConsumerOrdersBySessionIDFunc(sessionID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetRetailerOrdersBySessionID(sessionID string) ([]models.RetailerOrder, error) {
+	if m.GetRetailerOrdersBySessionIDFunc != nil {
+		return m.GetRetailerOrdersBySessionIDFunc(sessionID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) UpdateConsumerOrderStatus(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+	if m.UpdateConsumerOrderStatusFunc != nil {
+		return m.UpdateConsumerOrderStatusFunc(sessionID, from, to, actor)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) UpdateRetailerOrderStatus(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+	if m.UpdateRetailerOrderStatusFunc != nil {
+		return m.UpdateRetailerOrderStatusFunc(sessionID, from, to, actor)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) UpdateConsumerOrderStripeSession(orderID int, sessionID string) error {
+	if m.UpdateConsumerOrderStripeSessionFunc != nil {
+		return m.UpdateConsumerOrderStripeSessionFunc(orderID, sessionID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) UpdateRetailerOrderStripeSession(orderID int, sessionID string) error {
+	if m.UpdateRetailerOrderStripeSessionFunc != nil {
+		return m.UpdateRetailerOrderStripeSessionFunc(orderID, sessionID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetConsumerOrdersByUserID(userID int) ([]models.ConsumerOrder, error) {
+	if m.GetConsumerOrdersByUserIDFunc != nil {
+		return m.GetConsumerOrdersByUserIDFunc(userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetConsumerOrdersByRetailerID(retailerID int) ([]models.ConsumerOrder, error) {
+	if m.GetConsumerOrdersByRetailerIDFunc != nil {
+		return m.GetConsumerOrdersByRetailerIDFunc(retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetRetailerOrdersByRetailerID(retailerID int) ([]models.RetailerOrder, error) {
+	if m.GetRetailerOrdersByRetailerIDFunc != nil {
+		return m.GetRetailerOrdersByRetailerIDFunc(retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetRetailerOrdersByWholesalerID(wholesalerID int) ([]models.RetailerOrder, error) {
+	if m.GetRetailerOrdersByWholesalerIDFunc != nil {
+		return m.GetRetailerOrdersByWholesalerIDFunc(wholesalerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetConsumerOrderByID(orderID int) (*models.ConsumerOrder, error) {
+	if m.GetConsumerOrderByIDFunc != nil {
+		return m.GetConsumerOrderByIDFunc(orderID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetRetailerOrderByID(orderID int) (*models.RetailerOrder, error) {
+	if m.GetRetailerOrderByIDFunc != nil {
+		return m.GetRetailerOrderByIDFunc(orderID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) TransitionConsumerOrderStatus(orderID int, from, to models.OrderStatus, actor models.OrderActor) error {
+	if m.TransitionConsumerOrderStatusFunc != nil {
+		return m.TransitionConsumerOrderStatusFunc(orderID, from, to, actor)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) TransitionRetailerOrderStatus(orderID int, from, to models.OrderStatus, actor models.OrderActor) error {
+	if m.TransitionRetailerOrderStatusFunc != nil {
+		return m.TransitionRetailerOrderStatusFunc(orderID, from, to, actor)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) SetSessionPaymentIntent(sessionID string, paymentIntentID string) error {
+	if m.SetSessionPaymentIntentFunc != nil {
+		return m.SetSessionPaymentIntentFunc(sessionID, paymentIntentID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockOrdersRepo) GetSessionIDByPaymentIntent(paymentIntentID string) (string, error) {
+	if m.GetSessionIDByPaymentIntentFunc != nil {
+		return m.GetSessionIDByPaymentIntentFunc(paymentIntentID)
+	}
+	return "", errors.New("not implemented")
+}
+
+func newTestOrdersService(ordersRepo *MockOrdersRepo, usersRepo *MockUsersRepo, retailersRepo *MockRetailersRepo, wholesalersRepo *MockWholesalersRepo) *OrdersService {
+	return NewOrdersService(ordersRepo, CartService{}, RetailerCartService{}, nil, nil, usersRepo, retailersRepo, wholesalersRepo, nil, nil, nil)
+}
+
+func TestOrdersService_GetConsumerOrderByEmail(t *testing.T) {
+	usersRepo := &MockUsersRepo{
+		GetUserByEmailFunc: func(email string) (*models.User, error) {
+			return &models.User{Id: 1, Email: email}, nil
+		},
+	}
+	ordersRepo := &MockOrdersRepo{
+		GetConsumerOrderByIDFunc: func(orderID int) (*models.ConsumerOrder, error) {
+			switch orderID {
+			case 10:
+				return &models.ConsumerOrder{Id: 10, UserId: 1}, nil
+			case 11:
+				return &models.ConsumerOrder{Id: 11, UserId: 2}, nil
+			}
+			return nil, repositories.ErrOrderNotFound
+		},
+	}
+	service := newTestOrdersService(ordersRepo, usersRepo, &MockRetailersRepo{}, &MockWholesalersRepo{})
+
+	tests := []struct {
+		name          string
+		orderID       int
+		expectedError error
+	}{
+		{name: "own order", orderID: 10},
+		{name: "another user's order", orderID: 11, expectedError: ErrOrderForbidden},
+		{name: "missing order", orderID: 12, expectedError: repositories.ErrOrderNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := service.GetConsumerOrderByEmail("test@example.com", tt.orderID)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("Expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if order.Id != tt.orderID {
+				t.Errorf("Expected order %d, got %d", tt.orderID, order.Id)
+			}
+		})
+	}
+}
+
+func TestOrdersService_GetRetailerSaleByEmail(t *testing.T) {
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 5, Email: email}, nil
+		},
+	}
+	ordersRepo := &MockOrdersRepo{
+		GetConsumerOrderByIDFunc: func(orderID int) (*models.ConsumerOrder, error) {
+			return &models.ConsumerOrder{Id: orderID, RetailerId: 6}, nil
+		},
+	}
+	service := newTestOrdersService(ordersRepo, &MockUsersRepo{}, retailersRepo, &MockWholesalersRepo{})
+
+	_, err := service.GetRetailerSaleByEmail("shop@example.com", 1)
+	if !errors.Is(err, ErrOrderForbidden) {
+		t.Errorf("Expected ErrOrderForbidden, got %v", err)
+	}
+}
+
+func TestOrdersService_GetRetailerAndWholesalerOrderOwnership(t *testing.T) {
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 5, Email: email}, nil
+		},
+	}
+	wholesalersRepo := &MockWholesalersRepo{
+		GetWholesalerByEmailFunc: func(email string) (*models.Wholesaler, error) {
+			return &models.Wholesaler{Id: 7, Email: email}, nil
+		},
+	}
+	ordersRepo := &MockOrdersRepo{
+		GetRetailerOrderByIDFunc: func(orderID int) (*models.RetailerOrder, error) {
+			return &models.RetailerOrder{Id: orderID, RetailerId: 5, WholesalerId: 8}, nil
+		},
+	}
+	service := newTestOrdersService(ordersRepo, &MockUsersRepo{}, retailersRepo, wholesalersRepo)
+
+	if _, err := service.GetRetailerPurchaseByEmail("shop@example.com", 1); err != nil {
+		t.Errorf("Expected retailer to see own purchase, got %v", err)
+	}
+	if _, err := service.GetWholesalerOrderByEmail("bulk@example.com", 1); !errors.Is(err, ErrOrderForbidden) {
+		t.Errorf("Expected ErrOrderForbidden, got %v", err)
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to models.OrderStatus
+		allowed  bool
+	}{
+		{models.OrderStatusPending, models.OrderStatusPaid, true},
+		{models.OrderStatusPaid, models.OrderStatusShipped, true},
+		{models.OrderStatusShipped, models.OrderStatusDelivered, true},
+		{models.OrderStatusPending, models.OrderStatusCancelled, true},
+		{models.OrderStatusPaid, models.OrderStatusCancelled, true},
+		{models.OrderStatusPending, models.OrderStatusShipped, false},
+		{models.OrderStatusShipped, models.OrderStatusCancelled, false},
+		{models.OrderStatusDelivered, models.OrderStatusShipped, false},
+		{models.OrderStatusPaid, models.OrderStatusPaid, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.allowed {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
+
+func TestOrdersService_UpdateRetailerSaleStatusByEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		current       models.OrderStatus
+		target        models.OrderStatus
+		expectedError error
+	}{
+		{name: "ship paid order", current: models.OrderStatusPaid, target: models.OrderStatusShipped},
+		{name: "cancel pending order", current: models.OrderStatusPending, target: models.OrderStatusCancelled},
+		{name: "ship unpaid order", current: models.OrderStatusPending, target: models.OrderStatusShipped, expectedError: ErrInvalidStatusTransition},
+		{name: "cancel delivered order", current: models.OrderStatusDelivered, target: models.OrderStatusCancelled, expectedError: ErrInvalidStatusTransition},
+		{name: "mark paid by hand", current: models.OrderStatusPending, target: models.OrderStatusPaid, expectedError: ErrStatusNotSettable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.current
+			var recordedActor models.OrderActor
+			ordersRepo := &MockOrdersRepo{
+				GetConsumerOrderByIDFunc: func(orderID int) (*models.ConsumerOrder, error) {
+					return &models.ConsumerOrder{Id: orderID, RetailerId: 5, Status: status}, nil
+				},
+				TransitionConsumerOrderStatusFunc: func(orderID int, from, to models.OrderStatus, actor models.OrderActor) error {
+					if from != status {
+						return repositories.ErrOrderStatusConflict
+					}
+					status = to
+					recordedActor = actor
+					return nil
+				},
+			}
+			retailersRepo := &MockRetailersRepo{
+				GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+					return &models.Retailer{Id: 5, Email: email}, nil
+				},
+			}
+			service := newTestOrdersService(ordersRepo, &MockUsersRepo{}, retailersRepo, &MockWholesalersRepo{})
+
+			order, err := service.UpdateRetailerSaleStatusByEmail("shop@example.com", 1, tt.target)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("Expected error %v, got %v", tt.expectedError, err)
+				}
+				if status != tt.current {
+					t.Errorf("Expected status to stay %s, got %s", tt.current, status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if order.Status != tt.target {
+				t.Errorf("Expected status %s, got %s", tt.target, order.Status)
+			}
+			if recordedActor.Type != models.OrderActorRetailer || recordedActor.Id != 5 {
+				t.Errorf("Expected retailer 5 as actor, got %+v", recordedActor)
+			}
+		})
+	}
+}
+
+func TestOrdersService_ConsumerCheckoutOnFakeGateway(t *testing.T) {
+	cartRepo := &MockCartRepo{
+		GetCartItemsByUserIDFunc: func(userID int) ([]models.CartItem, error) {
+			return []models.CartItem{
+				{Product_id: 10, Quantity: 2, Product: models.RetailerProduct{Id: 10, Retailer_id: 1, Name: "Tea", Price: 250, Currency: "eur"}},
+				{Product_id: 20, Quantity: 1, Product: models.RetailerProduct{Id: 20, Retailer_id: 2, Name: "Jam", Price: 400, Currency: "eur"}},
+			}, nil
+		},
+	}
+
+	var statusUpdates []statusUpdate
+	paymentIntents := map[string]string{}
+	nextOrderID := 0
+	ordersRepo := &MockOrdersRepo{
+		CreateConsumerOrdersFunc: func(orders []*models.ConsumerOrder) error {
+			for _, order := range orders {
+				nextOrderID++
+				order.Id = nextOrderID
+			}
+			return nil
+		},
+		UpdateConsumerOrderStripeSessionFunc: func(orderID int, sessionID string) error {
+			return nil
+		},
+		UpdateConsumerOrderStatusFunc: func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+			statusUpdates = append(statusUpdates, statusUpdate{sessionID, to})
+			return nil
+		},
+		UpdateRetailerOrderStatusFunc: func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+			return nil
+		},
+		SetSessionPaymentIntentFunc: func(sessionID string, paymentIntentID string) error {
+			paymentIntents[sessionID] = paymentIntentID
+			return nil
+		},
+		GetConsumerOrderBySessionIDFunc: func(sessionID string) (*models.ConsumerOrder, error) {
+			return nil, repositories.ErrOrderNotFound
+		},
+		GetConsumerOrdersBySessionIDFunc: func(sessionID string) ([]models.ConsumerOrder, error) {
+			return nil, nil
+		},
+		GetRetailerOrdersBySessionIDFunc: func(sessionID string) ([]models.RetailerOrder, error) {
+			return nil, nil
+		},
+	}
+
+	gateway := NewFakePaymentGateway("http://localhost:8000")
+	service := NewOrdersService(ordersRepo, *NewCartService(cartRepo, &MockUsersRepo{}), RetailerCartService{}, gateway, NewEmailService(""), &MockUsersRepo{}, &MockRetailersRepo{}, &MockWholesalersRepo{}, newSeenEventsRepo(), nil, nil)
+
+	checkout, err := service.CreateConsumerCheckout(5, "http://shop/success", "http://shop/cancel", 3)
+	if err != nil {
+		t.Fatalf("CreateConsumerCheckout returned error: %v", err)
+	}
+	if len(checkout.Orders) != 2 {
+		t.Fatalf("Expected one order per retailer, got %d", len(checkout.Orders))
+	}
+	sessionID := checkout.Orders[0].StripeSessionId
+	if sessionID == "" || checkout.Orders[1].StripeSessionId != sessionID {
+		t.Fatalf("Expected both orders to share a session, got %q and %q", sessionID, checkout.Orders[1].StripeSessionId)
+	}
+	for i, total := range []models.Money{500, 400} {
+		if order := checkout.Orders[i]; order.TotalPrice != total || order.Currency != "eur" {
+			t.Errorf("Expected order %d to total %s eur, got %s %s", i, total, order.TotalPrice, order.Currency)
+		}
+	}
+	if checkout.URL != "http://localhost:8000/api/dev/payments/sessions/"+sessionID {
+		t.Errorf("Unexpected session URL %s", checkout.URL)
+	}
+
+	session, err := gateway.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Session was not created on the gateway: %v", err)
+	}
+	if session.AmountTotal != 900 {
+		t.Errorf("Expected session total 900, got %d", session.AmountTotal)
+	}
+	for _, item := range session.LineItems {
+		if item.Currency != "eur" {
+			t.Errorf("Expected line items in the sellers' currency, got %s for %s", item.Currency, item.Name)
+		}
+	}
+
+	event, err := gateway.CompleteSession(sessionID)
+	if err != nil {
+		t.Fatalf("CompleteSession returned error: %v", err)
+	}
+	if err := service.HandlePaymentEvent(event); err != nil {
+		t.Fatalf("HandlePaymentEvent returned error: %v", err)
+	}
+
+	if len(statusUpdates) != 1 || statusUpdates[0] != (statusUpdate{sessionID, models.OrderStatusPaid}) {
+		t.Errorf("Expected the session's orders to be paid, got %v", statusUpdates)
+	}
+	if paymentIntents[sessionID] != event.PaymentIntentID {
+		t.Errorf("Expected payment intent %s to be recorded, got %q", event.PaymentIntentID, paymentIntents[sessionID])
+	}
+
+	if _, err := gateway.ExpireSession(sessionID); !errors.Is(err, ErrFakeSessionClosed) {
+		t.Errorf("Expected a paid session to stay closed, got %v", err)
+	}
+}
+
+func TestOrdersService_ConsumerCheckoutRejectsMixedCurrencies(t *testing.T) {
+	cartRepo := &MockCartRepo{
+		GetCartItemsByUserIDFunc: func(userID int) ([]models.CartItem, error) {
+			return []models.CartItem{
+				{Product_id: 10, Quantity: 1, Product: models.RetailerProduct{Id: 10, Retailer_id: 1, Name: "Tea", Price: 250, Currency: "inr"}},
+				{Product_id: 20, Quantity: 1, Product: models.RetailerProduct{Id: 20, Retailer_id: 2, Name: "Jam", Price: 400, Currency: "usd"}},
+			}, nil
+		},
+	}
+	ordersRepo := &MockOrdersRepo{
+		CreateConsumerOrdersFunc: func(orders []*models.ConsumerOrder) error {
+			t.Error("No orders must be created for a mixed-currency cart")
+			return nil
+		},
+	}
+
+	gateway := NewFakePaymentGateway("http://localhost:8000")
+	service := NewOrdersService(ordersRepo, *NewCartService(cartRepo, &MockUsersRepo{}), RetailerCartService{}, gateway, nil, &MockUsersRepo{}, &MockRetailersRepo{}, &MockWholesalersRepo{}, nil, nil, nil)
+
+	if _, err := service.CreateConsumerCheckout(5, "http://shop/success", "http://shop/cancel", 3); !errors.Is(err, ErrMixedCurrencies) {
+		t.Fatalf("Expected ErrMixedCurrencies, got %v", err)
+	}
+}
+
+func TestOrdersService_ConsumerCheckoutRejectsStaleLineWithoutVariant(t *testing.T) {
+	// The line was added before the retailer gave the shirt sizes, so it names no variant
+	cartRepo := &MockCartRepo{
+		GetCartItemsByUserIDFunc: func(userID int) ([]models.CartItem, error) {
+			return []models.CartItem{
+				{Product_id: 10, Quantity: 1, Product: models.RetailerProduct{Id: 10, Retailer_id: 1, Name: "Shirt", Price: 1000, Currency: "inr"}},
+			}, nil
+		},
+	}
+	ordersRepo := &MockOrdersRepo{
+		CreateConsumerOrdersFunc: func(orders []*models.ConsumerOrder) error {
+			return fmt.Errorf("Shirt: %w", repositories.ErrVariantRequired)
+		},
+	}
+
+	gateway := NewFakePaymentGateway("http://localhost:8000")
+	service := NewOrdersService(ordersRepo, *NewCartService(cartRepo, &MockUsersRepo{}), RetailerCartService{}, gateway, nil, &MockUsersRepo{}, &MockRetailersRepo{}, &MockWholesalersRepo{}, nil, nil, nil)
+
+	_, err := service.CreateConsumerCheckout(5, "http://shop/success", "http://shop/cancel", 3)
+	if !errors.Is(err, repositories.ErrVariantRequired) {
+		t.Fatalf("Expected ErrVariantRequired, got %v", err)
+	}
+	if err.Error() != "Shirt: "+repositories.ErrVariantRequired.Error() {
+		t.Errorf("Expected the error to name the product only, got %q", err)
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+// PaymentGateway is the payment provider behind checkout: it hosts the checkout page, tells us
+// what happened to a payment through webhook events, and returns money on refunds.
+// StripeService is the production implementation; FakePaymentGateway runs in process for tests
+// and offline development.
+type PaymentGateway interface {
+	CreateCheckoutSession(req CheckoutSessionRequest) (*CheckoutSession, error)
+	ParseWebhookEvent(payload []byte, header string) (*PaymentEvent, error)
+	RefundPayment(paymentIntentID string, amount int64, metadata map[string]string) (string, error)
+}
+
+// CheckoutLineItem is one line on the checkout page. UnitAmount is in the smallest unit of
+// Currency, e.g. paise for "inr".
+type CheckoutLineItem struct {
+	Name       string `json:"name"`
+	Currency   string `json:"currency"`
+	UnitAmount int64  `json:"unit_amount"`
+	Quantity   int64  `json:"quantity"`
+}
+
+type CheckoutSessionRequest struct {
+	LineItems         []CheckoutLineItem `json:"line_items"`
+	SuccessURL        string             `json:"success_url"`
+	CancelURL         string             `json:"cancel_url"`
+	ClientReferenceID string             `json:"client_reference_id"`
+	Metadata          map[string]string  `json:"metadata"`
+}
+
+// CheckoutSession is a hosted checkout page the buyer is redirected to.
+type CheckoutSession struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+type PaymentEventType string
+
+const (
+	// PaymentEventCheckoutPaid means the buyer paid for every order of the session.
+	PaymentEventCheckoutPaid PaymentEventType = "checkout.paid"
+	// PaymentEventCheckoutAwaitingPayment means the buyer finished checkout with a delayed
+	// payment method; a paid or failed event follows once the money settles.
+	PaymentEventCheckoutAwaitingPayment PaymentEventType = "checkout.awaiting_payment"
+	PaymentEventCheckoutFailed          PaymentEventType = "checkout.failed"
+	PaymentEventCheckoutExpired         PaymentEventType = "checkout.expired"
+	PaymentEventPaymentRefunded         PaymentEventType = "payment.refunded"
+	// PaymentEventIgnored covers provider events that do not affect orders.
+	PaymentEventIgnored PaymentEventType = "ignored"
+)
+
+// PaymentEvent is a verified webhook event translated out of the provider's own format.
+// SessionID is set for checkout events, PaymentIntentID once a payment exists, and
+// FullyRefunded only for refund events.
+type PaymentEvent struct {
+	ID              string           `json:"id"`
+	Type            PaymentEventType `json:"type"`
+	SessionID       string           `json:"session_id,omitempty"`
+	PaymentIntentID string           `json:"payment_intent_id,omitempty"`
+	FullyRefunded   bool             `json:"fully_refunded,omitempty"`
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+)
+
+// PriceListsService manages the price lists and retailer groups of wholesalers and applies the
+// negotiated prices to what a retailer sees of the wholesale catalog.
+type PriceListsService struct {
+	priceListsRepo repositories.IPriceListsRepo
+	retailersRepo  repositories.IRetailersRepo
+}
+
+func NewPriceListsService(priceListsRepo repositories.IPriceListsRepo, retailersRepo repositories.IRetailersRepo) *PriceListsService {
+	return &PriceListsService{
+		priceListsRepo: priceListsRepo,
+		retailersRepo:  retailersRepo,
+	}
+}
+
+// isPriceListError reports whether err is one the handlers report to the wholesaler as is.
+func isPriceListError(err error) bool {
+	return errors.Is(err, repositories.ErrPriceListNotFound) ||
+		errors.Is(err, repositories.ErrRetailerGroupNotFound) ||
+		errors.Is(err, repositories.ErrDuplicatePriceList) ||
+		errors.Is(err, repositories.ErrDuplicateGroup) ||
+		errors.Is(err, repositories.ErrRetailerNotFound) ||
+		errors.Is(err, repositories.ErrWholesalerProductNotFound)
+}
+
+func (s *PriceListsService) GetPriceLists(wholesalerID int) ([]models.PriceList, error) {
+	lists, err := s.priceListsRepo.GetPriceLists(wholesalerID)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching price lists: %w", err)
+	}
+	return lists, nil
+}
+
+func (s *PriceListsService) GetPriceList(id int, wholesalerID int) (*models.PriceList, error) {
+	list, err := s.priceListsRepo.GetPriceList(id, wholesalerID)
+	if err != nil {
+		if isPriceListError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching price list: %w", err)
+	}
+	return list, nil
+}
+
+func (s *PriceListsService) CreatePriceList(list *models.PriceList) (*models.PriceList, error) {
+	created, err := s.priceListsRepo.CreatePriceList(list)
+	if err != nil {
+		if isPriceListError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error creating price list: %w", err)
+	}
+	return created, nil
+}
+
+func (s *PriceListsService) UpdatePriceList(list *models.PriceList) (*models.PriceList, error) {
+	updated, err := s.priceListsRepo.UpdatePriceList(list)
+	if err != nil {
+		if isPriceListError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error updating price list: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *PriceListsService) DeletePriceList(id int, wholesalerID int) error {
+	if err := s.priceListsRepo.DeletePriceList(id, wholesalerID); err != nil {
+		if isPriceListError(err) {
+			return err
+		}
+		return fmt.Errorf("service error deleting price list: %w", err)
+	}
+	return nil
+}
+
+func (s *PriceListsService) GetRetailerGroups(wholesalerID int) ([]models.RetailerGroup, error) {
+	groups, err := s.priceListsRepo.GetRetailerGroups(wholesalerID)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching retailer groups: %w", err)
+	}
+	return groups, nil
+}
+
+func (s *PriceListsService) GetRetailerGroup(id int, wholesalerID int) (*models.RetailerGroup, error) {
+	group, err := s.priceListsRepo.GetRetailerGroup(id, wholesalerID)
+	if err != nil {
+		if isPriceListError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching retailer group: %w", err)
+	}
+	return group, nil
+}
+
+func (s *PriceListsService) CreateRetailerGroup(group *models.RetailerGroup) (*models.RetailerGroup, error) {
+	created, err := s.priceListsRepo.CreateRetailerGroup(group)
+	if err != nil {
+		if isPriceListError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error creating retailer group: %w", err)
+	}
+	return created, nil
+}
+
+func (s *PriceListsService) UpdateRetailerGroup(group *models.RetailerGroup) (*models.RetailerGroup, error) {
+	updated, err := s.priceListsRepo.UpdateRetailerGroup(group)
+	if err != nil {
+		if isPriceListError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error updating retailer group: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *PriceListsService) DeleteRetailerGroup(id int, wholesalerID int) error {
+	if err := s.priceListsRepo.DeleteRetailerGroup(id, wholesalerID); err != nil {
+		if isPriceListError(err) {
+			return err
+		}
+		return fmt.Errorf("service error deleting retailer group: %w", err)
+	}
+	return nil
+}
+
+// ApplyToProducts gives the products the prices negotiated by the retailer with the given
+// email. Products are left at their list prices for an empty email, i.e. an anonymous visitor.
+func (s *PriceListsService) ApplyToProducts(email string, products []models.WholesalerProduct) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int, len(products))
+	for i, product := range products {
+		ids[i] = product.Id
+	}
+
+	prices, err := s.negotiatedPricesForEmail(email, ids)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		prices.Apply(&products[i])
+	}
+	return nil
+}
+
+// ApplyToProduct is ApplyToProducts for a single product and its variants.
+func (s *PriceListsService) ApplyToProduct(email string, product *models.WholesalerProduct, variants []models.ProductVariant) error {
+	prices, err := s.negotiatedPricesForEmail(email, []int{product.Id})
+	if err != nil {
+		return err
+	}
+	prices.Apply(product)
+	for i := range variants {
+		prices.ApplyToVariant(&variants[i])
+	}
+	return nil
+}
+
+func (s *PriceListsService) negotiatedPricesForEmail(email string, productIDs []int) (models.NegotiatedPrices, error) {
+	if email == "" {
+		return nil, nil
+	}
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching retailer: %w", err)
+	}
+	return negotiatedPrices(s.priceListsRepo, retailer.Id, productIDs)
+}
+
+func negotiatedPrices(repo repositories.IPriceListsRepo, retailerID int, productIDs []int) (models.NegotiatedPrices, error) {
+	rules, err := repo.GetPriceRules(retailerID, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching negotiated prices: %w", err)
+	}
+	return models.NewNegotiatedPrices(rules), nil
+}
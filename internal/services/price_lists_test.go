@@ -0,0 +1,149 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+	"testing"
+)
+
+// MockPriceListsRepo is a mock implementation of IPriceListsRepo
+type MockPriceListsRepo struct {
+	GetPriceRulesFunc func(retailerID int, productIDs []int) ([]models.PriceRule, error)
+}
+
+func (m *MockPriceListsRepo) GetPriceLists(wholesalerID int) ([]models.PriceList, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) GetPriceList(id int, wholesalerID int) (*models.PriceList, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) CreatePriceList(list *models.PriceList) (*models.PriceList, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) UpdatePriceList(list *models.PriceList) (*models.PriceList, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) DeletePriceList(id int, wholesalerID int) error {
+	return errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) GetRetailerGroups(wholesalerID int) ([]models.RetailerGroup, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) GetRetailerGroup(id int, wholesalerID int) (*models.RetailerGroup, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) CreateRetailerGroup(group *models.RetailerGroup) (*models.RetailerGroup, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) UpdateRetailerGroup(group *models.RetailerGroup) (*models.RetailerGroup, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) DeleteRetailerGroup(id int, wholesalerID int) error {
+	return errors.New("not implemented")
+}
+
+func (m *MockPriceListsRepo) GetPriceRules(retailerID int, productIDs []int) ([]models.PriceRule, error) {
+	if m.GetPriceRulesFunc != nil {
+		return m.GetPriceRulesFunc(retailerID, productIDs)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func noPriceRules(retailerID int, productIDs []int) ([]models.PriceRule, error) {
+	return nil, nil
+}
+
+func TestPriceListsService_ApplyToProducts(t *testing.T) {
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 4, Email: email}, nil
+		},
+	}
+	priceListsRepo := &MockPriceListsRepo{
+		GetPriceRulesFunc: func(retailerID int, productIDs []int) ([]models.PriceRule, error) {
+			if retailerID != 4 {
+				t.Errorf("Expected retailer 4, got %d", retailerID)
+			}
+			return []models.PriceRule{
+				{PriceListId: 1, PriceList: "Clubs", ProductId: 1, DiscountPercent: 10},
+				{PriceListId: 2, PriceList: "Gold", ProductId: 1, Price: 850},
+				{PriceListId: 1, PriceList: "Clubs", ProductId: 2, DiscountPercent: 10},
+				{PriceListId: 2, PriceList: "Gold", ProductId: 3, Price: 2500},
+			}, nil
+		},
+	}
+	service := NewPriceListsService(priceListsRepo, retailersRepo)
+
+	products := []models.WholesalerProduct{
+		{Id: 1, Price: 1000},
+		{Id: 2, Price: 333},
+		{Id: 3, Price: 2000},
+		{Id: 4, Price: 700},
+	}
+	if err := service.ApplyToProducts("shop@example.com", products); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []struct {
+		price, listPrice models.Money
+		list             string
+	}{
+		{850, 1000, "Gold"}, // the lowest of the lists' prices
+		{300, 333, "Clubs"}, // 10% off, rounded to the nearest cent
+		{2000, 0, ""},       // a negotiated price above the list price is ignored
+		{700, 0, ""},        // no list covers the product
+	}
+	for i, w := range want {
+		p := products[i]
+		if p.Price != w.price || p.ListPrice != w.listPrice || p.PriceList != w.list {
+			t.Errorf("Product %d: got price %d, list price %d, list %q; want %d, %d, %q", p.Id, p.Price, p.ListPrice, p.PriceList, w.price, w.listPrice, w.list)
+		}
+	}
+
+	t.Run("anonymous visitors see list prices", func(t *testing.T) {
+		products := []models.WholesalerProduct{{Id: 1, Price: 1000}}
+		if err := NewPriceListsService(&MockPriceListsRepo{}, &MockRetailersRepo{}).ApplyToProducts("", products); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if products[0].Price != 1000 || products[0].ListPrice != 0 {
+			t.Errorf("Expected list price, got %+v", products[0])
+		}
+	})
+}
+
+func TestPriceListsService_ApplyToProduct_Variants(t *testing.T) {
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 4}, nil
+		},
+	}
+	priceListsRepo := &MockPriceListsRepo{
+		GetPriceRulesFunc: func(retailerID int, productIDs []int) ([]models.PriceRule, error) {
+			return []models.PriceRule{
+				{PriceList: "Gold", ProductId: 1, Price: 800, DiscountPercent: 5},
+				{PriceList: "Clubs", ProductId: 1, DiscountPercent: 20},
+			}, nil
+		},
+	}
+
+	product := &models.WholesalerProduct{Id: 1, Price: 1000}
+	variants := []models.ProductVariant{{Id: 10, ProductId: 1, Price: 1500}}
+	if err := NewPriceListsService(priceListsRepo, retailersRepo).ApplyToProduct("shop@example.com", product, variants); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if product.Price != 800 {
+		t.Errorf("Expected the fixed price to win, got %d", product.Price)
+	}
+	if variants[0].Price != 1200 || variants[0].ListPrice != 1500 {
+		t.Errorf("Expected the largest discount off the variant, got %+v", variants[0])
+	}
+}
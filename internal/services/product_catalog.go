@@ -0,0 +1,237 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxCatalogRows is the most products a single CSV import may contain.
+const MaxCatalogRows = 5000
+
+var ErrTooManyCatalogRows = fmt.Errorf("a catalog import can contain at most %d products", MaxCatalogRows)
+
+// requiredCatalogColumns are the columns every import file must have.
+var requiredCatalogColumns = []string{"sku", "name", "price", "stock_qty", "image_url"}
+
+// ProductCatalogService imports and exports one catalog as CSV, so the shop and the wholesale
+// catalog each get their own instance.
+type ProductCatalogService struct {
+	catalogRepo repositories.IProductCatalogRepo
+}
+
+func NewProductCatalogService(catalogRepo repositories.IProductCatalogRepo) *ProductCatalogService {
+	return &ProductCatalogService{
+		catalogRepo: catalogRepo,
+	}
+}
+
+// ParseCSV reads a catalog file. A line whose values cannot be read is returned with its Error
+// set rather than failing the whole file; an error is only returned when the file itself is
+// unusable, e.g. a missing header column or too many lines.
+func (s *ProductCatalogService) ParseCSV(r io.Reader) ([]models.CatalogLine, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns, err := catalogColumnIndexes(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []models.CatalogLine
+	firstLine := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		lineNumber, _ := reader.FieldPos(0)
+
+		var parseErr *csv.ParseError
+		if err != nil && !(errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount)) {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(lines) == MaxCatalogRows {
+			return nil, ErrTooManyCatalogRows
+		}
+
+		line := models.CatalogLine{Line: lineNumber}
+		if err != nil {
+			line.Error = fmt.Sprintf("Expected %d values, got %d", len(header), len(record))
+		} else {
+			line.Row, line.Error = parseCatalogRecord(record, columns)
+		}
+
+		if line.Error == "" {
+			if first, seen := firstLine[line.Row.Sku]; seen {
+				line.Error = fmt.Sprintf("SKU %s is already used on line %d", line.Row.Sku, first)
+			} else {
+				firstLine[line.Row.Sku] = line.Line
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return nil, errors.New("file has no products")
+	}
+	return lines, nil
+}
+
+// ImportProducts upserts the seller's products from the lines of a parsed and validated file.
+// Nothing is written on a dry run or when any line has an error; the report then describes what
+// the import would have done.
+func (s *ProductCatalogService) ImportProducts(sellerID int, lines []models.CatalogLine, dryRun bool) (*models.CatalogImportReport, error) {
+	report := &models.CatalogImportReport{DryRun: dryRun, Rows: make([]models.CatalogImportRow, len(lines))}
+
+	var rows []models.CatalogRow
+	var rowLines []int
+	for i, line := range lines {
+		report.Rows[i] = models.CatalogImportRow{Line: line.Line, Sku: line.Row.Sku, Error: line.Error}
+		if line.Error != "" {
+			report.Failed++
+			continue
+		}
+		rows = append(rows, line.Row)
+		rowLines = append(rowLines, i)
+	}
+
+	results, applied, err := s.catalogRepo.UpsertProducts(sellerID, rows, !dryRun && report.Failed == 0)
+	if err != nil {
+		return nil, fmt.Errorf("service error importing products: %w", err)
+	}
+
+	for i, result := range results {
+		row := &report.Rows[rowLines[i]]
+		switch {
+		case result.Err != nil:
+			row.Error = catalogErrorMessage(result.Err)
+			report.Failed++
+		case result.Created:
+			row.Action = models.CatalogImportCreate
+			row.ProductId = result.ProductId
+			report.Created++
+		default:
+			row.Action = models.CatalogImportUpdate
+			row.ProductId = result.ProductId
+			report.Updated++
+		}
+	}
+	report.Applied = applied
+	return report, nil
+}
+
+// ExportCSV writes the seller's whole catalog to w as CSV, in the format ParseCSV reads. Rows are
+// written as they are read from the database, so w receives the file in pieces.
+func (s *ProductCatalogService) ExportCSV(sellerID int, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(models.CatalogColumns); err != nil {
+		return err
+	}
+
+	err := s.catalogRepo.ExportProducts(sellerID, func(row models.CatalogRow) error {
+		category := ""
+		if row.CategoryID != 0 {
+			category = strconv.Itoa(row.CategoryID)
+		}
+		return writer.Write([]string{
+			row.Sku,
+			row.Name,
+			row.Price.String(),
+			strconv.Itoa(row.StockQty),
+			row.ImageURL,
+			row.Description,
+			category,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("service error exporting products: %w", err)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// catalogColumnIndexes maps each known column to its position in the header.
+func catalogColumnIndexes(header []string) (map[string]int, error) {
+	known := make(map[string]bool, len(models.CatalogColumns))
+	for _, column := range models.CatalogColumns {
+		known[column] = true
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, dup := columns[name]; dup {
+			return nil, fmt.Errorf("column %q appears more than once", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredCatalogColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing required column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+// parseCatalogRecord reads the values of one line. It only checks that they have the right
+// type; the product rules are the caller's to apply.
+func parseCatalogRecord(record []string, columns map[string]int) (models.CatalogRow, string) {
+	value := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row := models.CatalogRow{
+		Sku:         value("sku"),
+		Name:        value("name"),
+		ImageURL:    value("image_url"),
+		Description: value("description"),
+	}
+	if row.Sku == "" {
+		return row, "SKU is required"
+	}
+
+	price, err := models.ParseMoney(value("price"))
+	if err != nil {
+		return row, "Price must be a decimal number with at most two decimal places"
+	}
+	row.Price = price
+
+	if stock := value("stock_qty"); stock != "" {
+		if row.StockQty, err = strconv.Atoi(stock); err != nil {
+			return row, "Stock quantity must be a whole number"
+		}
+	}
+	if category := value("category_id"); category != "" {
+		if row.CategoryID, err = strconv.Atoi(category); err != nil {
+			return row, "Category ID must be a whole number"
+		}
+	}
+	return row, ""
+}
+
+func catalogErrorMessage(err error) string {
+	if errors.Is(err, repositories.ErrCategoryNotFound) {
+		return "Category not found"
+	}
+	return err.Error()
+}
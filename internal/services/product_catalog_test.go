@@ -0,0 +1,158 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// MockProductCatalogRepo is a mock implementation of IProductCatalogRepo
+type MockProductCatalogRepo struct {
+	UpsertProductsFunc func(sellerID int, rows []models.CatalogRow, commit bool) ([]repositories.CatalogUpsert, bool, error)
+	ExportProductsFunc func(sellerID int, fn func(models.CatalogRow) error) error
+}
+
+func (m *MockProductCatalogRepo) UpsertProducts(sellerID int, rows []models.CatalogRow, commit bool) ([]repositories.CatalogUpsert, bool, error) {
+	if m.UpsertProductsFunc != nil {
+		return m.UpsertProductsFunc(sellerID, rows, commit)
+	}
+	return nil, false, errors.New("not implemented")
+}
+
+func (m *MockProductCatalogRepo) ExportProducts(sellerID int, fn func(models.CatalogRow) error) error {
+	if m.ExportProductsFunc != nil {
+		return m.ExportProductsFunc(sellerID, fn)
+	}
+	return errors.New("not implemented")
+}
+
+func TestProductCatalogService_ParseCSV(t *testing.T) {
+	service := NewProductCatalogService(&MockProductCatalogRepo{})
+
+	t.Run("reads rows and reports bad lines", func(t *testing.T) {
+		file := "name,sku,price,stock_qty,image_url\n" +
+			"Red Dot Finder,RDF-1,12.5,4,https://example.com/rdf.jpg\n" +
+			"Moon Filter,MF-1,abc,2,https://example.com/mf.jpg\n" +
+			"Moon Filter,RDF-1,9,2,https://example.com/mf.jpg\n" +
+			"Star Atlas,,20,1,https://example.com/atlas.jpg\n" +
+			"Too,Few\n"
+
+		lines, err := service.ParseCSV(strings.NewReader(file))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(lines) != 5 {
+			t.Fatalf("Expected 5 lines, got %d", len(lines))
+		}
+
+		first := lines[0]
+		if first.Line != 2 || first.Error != "" || first.Row.Sku != "RDF-1" || first.Row.Price != 1250 || first.Row.StockQty != 4 {
+			t.Errorf("Unexpected first line: %+v", first)
+		}
+		wantErrors := []string{"", "Price must be", "already used on line 2", "SKU is required", "Expected 5 values"}
+		for i, want := range wantErrors {
+			if want == "" {
+				continue
+			}
+			if !strings.Contains(lines[i].Error, want) {
+				t.Errorf("Line %d: expected error containing %q, got %q", lines[i].Line, want, lines[i].Error)
+			}
+		}
+	})
+
+	t.Run("missing required column", func(t *testing.T) {
+		_, err := service.ParseCSV(strings.NewReader("sku,name,price\nA,B,1\n"))
+		if err == nil || !strings.Contains(err.Error(), "stock_qty") {
+			t.Errorf("Expected missing column error, got %v", err)
+		}
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, err := service.ParseCSV(strings.NewReader("sku,name,price,stock_qty,image_url,colour\n"))
+		if err == nil || !strings.Contains(err.Error(), "colour") {
+			t.Errorf("Expected unknown column error, got %v", err)
+		}
+	})
+}
+
+func TestProductCatalogService_ImportProducts(t *testing.T) {
+	valid := []models.CatalogLine{
+		{Line: 2, Row: models.CatalogRow{Sku: "A", Name: "Eyepiece", Price: 1000}},
+		{Line: 3, Row: models.CatalogRow{Sku: "B", Name: "Filter", Price: 500, CategoryID: 99}},
+	}
+
+	t.Run("writes when every line passes", func(t *testing.T) {
+		mockRepo := &MockProductCatalogRepo{
+			UpsertProductsFunc: func(sellerID int, rows []models.CatalogRow, commit bool) ([]repositories.CatalogUpsert, bool, error) {
+				if !commit {
+					t.Error("Expected commit")
+				}
+				return []repositories.CatalogUpsert{{ProductId: 10, Created: true}, {ProductId: 4}}, true, nil
+			},
+		}
+		report, err := NewProductCatalogService(mockRepo).ImportProducts(1, valid, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !report.Applied || report.Created != 1 || report.Updated != 1 || report.Failed != 0 {
+			t.Errorf("Unexpected report: %+v", report)
+		}
+		if report.Rows[1].Action != models.CatalogImportUpdate || report.Rows[1].ProductId != 4 {
+			t.Errorf("Unexpected row: %+v", report.Rows[1])
+		}
+	})
+
+	t.Run("nothing is written when a line fails", func(t *testing.T) {
+		lines := append([]models.CatalogLine{{Line: 4, Row: models.CatalogRow{Sku: "C"}, Error: "Product name is required"}}, valid...)
+		var got []models.CatalogRow
+		mockRepo := &MockProductCatalogRepo{
+			UpsertProductsFunc: func(sellerID int, rows []models.CatalogRow, commit bool) ([]repositories.CatalogUpsert, bool, error) {
+				if commit {
+					t.Error("Expected no commit")
+				}
+				got = rows
+				return []repositories.CatalogUpsert{{Created: true}, {Err: repositories.ErrCategoryNotFound}}, false, nil
+			},
+		}
+		report, err := NewProductCatalogService(mockRepo).ImportProducts(1, lines, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(got) != 2 {
+			t.Errorf("Expected the 2 valid rows to be checked, got %d", len(got))
+		}
+		if report.Applied || report.Failed != 2 || report.Created != 1 {
+			t.Errorf("Unexpected report: %+v", report)
+		}
+		if report.Rows[2].Error != "Category not found" {
+			t.Errorf("Expected category error on line 3, got %+v", report.Rows[2])
+		}
+	})
+}
+
+func TestProductCatalogService_ExportCSV(t *testing.T) {
+	mockRepo := &MockProductCatalogRepo{
+		ExportProductsFunc: func(sellerID int, fn func(models.CatalogRow) error) error {
+			return fn(models.CatalogRow{Sku: "A", Name: "Eyepiece, 25mm", Price: 4599, StockQty: 3, ImageURL: "/img.jpg"})
+		},
+	}
+
+	var out bytes.Buffer
+	if err := NewProductCatalogService(mockRepo).ExportCSV(1, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "sku,name,price,stock_qty,image_url,description,category_id\n" +
+		"A,\"Eyepiece, 25mm\",45.99,3,/img.jpg,,\n"
+	if out.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, out.String())
+	}
+
+	lines, err := NewProductCatalogService(mockRepo).ParseCSV(&out)
+	if err != nil || len(lines) != 1 || lines[0].Row.Price != 4599 {
+		t.Errorf("Export should read back as an import, got %+v, %v", lines, err)
+	}
+}
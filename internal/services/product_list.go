@@ -0,0 +1,22 @@
+package services
+
+import "Obsonarium-backend/internal/models"
+
+const (
+	defaultProductPageSize = 20
+	maxProductPageSize     = 100
+)
+
+// normalizeProductFilter fills in the default sort and page size and caps the page size.
+func normalizeProductFilter(filter models.ProductFilter) models.ProductFilter {
+	if filter.Sort == "" {
+		filter.Sort = models.ProductSortNewest
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultProductPageSize
+	}
+	if filter.Limit > maxProductPageSize {
+		filter.Limit = maxProductPageSize
+	}
+	return filter
+}
@@ -3,32 +3,265 @@ package services
 import (
 	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
+	"errors"
 	"fmt"
+	"mime/multipart"
+	"strings"
+)
+
+const (
+	maxReportReason       = 500
+	maxSellerReply        = 2000
+	defaultReviewPageSize = 20
+	maxReviewPageSize     = 100
+)
+
+var (
+	ErrNotVerifiedPurchase = errors.New("only customers who have bought this product can review it")
+	ErrInvalidReport       = errors.New("a report needs a reason of at most 500 characters")
+	ErrInvalidReply        = errors.New("a reply must be 1 to 2000 characters")
 )
 
 type ProductReviewsService struct {
-	reviewsRepo repositories.IProductReviewsRepo
+	reviewsRepo   repositories.IProductReviewsRepo
+	uploadService *UploadService
 }
 
-func NewProductReviewsService(reviewsRepo repositories.IProductReviewsRepo) *ProductReviewsService {
+func NewProductReviewsService(reviewsRepo repositories.IProductReviewsRepo, uploadService *UploadService) *ProductReviewsService {
 	return &ProductReviewsService{
-		reviewsRepo: reviewsRepo,
+		reviewsRepo:   reviewsRepo,
+		uploadService: uploadService,
 	}
 }
 
-func (s *ProductReviewsService) GetReviewsByProductID(productID int) ([]models.ProductReview, error) {
-	reviews, err := s.reviewsRepo.GetReviewsByProductID(productID)
+// GetReviewsByProductID returns one page of the visible reviews of a product, newest first
+// unless the filter asks otherwise.
+func (s *ProductReviewsService) GetReviewsByProductID(productID int, filter models.ReviewFilter) ([]models.ProductReview, models.PageInfo, error) {
+	if filter.Sort == "" {
+		filter.Sort = models.ReviewSortNewest
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultReviewPageSize
+	}
+	if filter.Limit > maxReviewPageSize {
+		filter.Limit = maxReviewPageSize
+	}
+
+	reviews, page, err := s.reviewsRepo.GetReviewsByProductID(productID, filter)
 	if err != nil {
-		return nil, fmt.Errorf("service error fetching reviews: %w", err)
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			return nil, models.PageInfo{}, err
+		}
+		return nil, models.PageInfo{}, fmt.Errorf("service error fetching reviews: %w", err)
 	}
-	return reviews, nil
+	return reviews, page, nil
 }
 
+// CreateReview adds a review from a customer who has paid for the product, once per customer.
 func (s *ProductReviewsService) CreateReview(review *models.ProductReview) (*models.ProductReview, error) {
+	purchased, err := s.reviewsRepo.HasPurchased(review.User_id, review.Product_id)
+	if err != nil {
+		return nil, fmt.Errorf("service error checking purchase: %w", err)
+	}
+	if !purchased {
+		return nil, ErrNotVerifiedPurchase
+	}
+
 	createdReview, err := s.reviewsRepo.CreateReview(review)
 	if err != nil {
+		if errors.Is(err, repositories.ErrDuplicateReview) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("service error creating review: %w", err)
 	}
 	return createdReview, nil
 }
 
+func (s *ProductReviewsService) UpdateReview(review *models.ProductReview) (*models.ProductReview, error) {
+	updatedReview, err := s.reviewsRepo.UpdateReview(review)
+	if err != nil {
+		if errors.Is(err, repositories.ErrReviewNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error updating review: %w", err)
+	}
+	return updatedReview, nil
+}
+
+func (s *ProductReviewsService) DeleteReview(reviewID int, productID int, userID int) error {
+	images, err := s.reviewsRepo.DeleteReview(reviewID, productID, userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrReviewNotFound) {
+			return err
+		}
+		return fmt.Errorf("service error deleting review: %w", err)
+	}
+	s.deleteImageFiles(images)
+	return nil
+}
+
+// ReportReview files a customer's report of someone else's review, with the reason it should
+// be taken down.
+func (s *ProductReviewsService) ReportReview(reviewID int, productID int, userID int, reason string) (*models.ReviewReport, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > maxReportReason {
+		return nil, ErrInvalidReport
+	}
+
+	report, err := s.reviewsRepo.ReportReview(&models.ReviewReport{Review_id: reviewID, User_id: userID, Reason: reason}, productID)
+	if err != nil {
+		if isReviewError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error reporting review: %w", err)
+	}
+	return report, nil
+}
+
+// GetModerationQueue returns the reported and hidden reviews of the retailer's products.
+func (s *ProductReviewsService) GetModerationQueue(retailerID int) ([]models.ReportedReview, error) {
+	queue, err := s.reviewsRepo.GetModerationQueue(retailerID)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching moderation queue: %w", err)
+	}
+	return queue, nil
+}
+
+// HideReview upholds the reports against a review of one of the retailer's products and hides
+// it from the product page and ratings.
+func (s *ProductReviewsService) HideReview(reviewID int, retailerID int) (*models.ProductReview, error) {
+	return s.setReviewHidden(reviewID, retailerID, true)
+}
+
+// RestoreReview dismisses the reports against a review of one of the retailer's products and
+// shows it again if it was hidden.
+func (s *ProductReviewsService) RestoreReview(reviewID int, retailerID int) (*models.ProductReview, error) {
+	return s.setReviewHidden(reviewID, retailerID, false)
+}
+
+func (s *ProductReviewsService) setReviewHidden(reviewID int, retailerID int, hidden bool) (*models.ProductReview, error) {
+	review, err := s.reviewsRepo.SetReviewHidden(reviewID, retailerID, hidden)
+	if err != nil {
+		if isReviewError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error moderating review: %w", err)
+	}
+	return review, nil
+}
+
+// DeleteReportedReview removes a reported or hidden review of one of the retailer's products.
+func (s *ProductReviewsService) DeleteReportedReview(reviewID int, retailerID int) error {
+	images, err := s.reviewsRepo.DeleteReportedReview(reviewID, retailerID)
+	if err != nil {
+		if isReviewError(err) {
+			return err
+		}
+		return fmt.Errorf("service error deleting review: %w", err)
+	}
+	s.deleteImageFiles(images)
+	return nil
+}
+
+// SetSellerReply posts or replaces the retailer's public reply to a review of its product.
+func (s *ProductReviewsService) SetSellerReply(reviewID int, retailerID int, reply string) (*models.ProductReview, error) {
+	reply = strings.TrimSpace(reply)
+	if reply == "" || len(reply) > maxSellerReply {
+		return nil, ErrInvalidReply
+	}
+	return s.saveSellerReply(reviewID, retailerID, reply)
+}
+
+// DeleteSellerReply removes the retailer's reply to a review of its product.
+func (s *ProductReviewsService) DeleteSellerReply(reviewID int, retailerID int) (*models.ProductReview, error) {
+	return s.saveSellerReply(reviewID, retailerID, "")
+}
+
+func (s *ProductReviewsService) saveSellerReply(reviewID int, retailerID int, reply string) (*models.ProductReview, error) {
+	review, err := s.reviewsRepo.SetSellerReply(reviewID, retailerID, reply)
+	if err != nil {
+		if isReviewError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error saving reply: %w", err)
+	}
+	return review, nil
+}
+
+// VoteReview records whether a customer found someone else's review helpful. Voting again
+// replaces the earlier vote.
+func (s *ProductReviewsService) VoteReview(reviewID int, productID int, userID int, helpful bool) (*models.ProductReview, error) {
+	review, err := s.reviewsRepo.VoteReview(reviewID, productID, userID, helpful)
+	if err != nil {
+		if isReviewError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error voting on review: %w", err)
+	}
+	return review, nil
+}
+
+// RemoveVote takes back a customer's vote on a review.
+func (s *ProductReviewsService) RemoveVote(reviewID int, productID int, userID int) (*models.ProductReview, error) {
+	review, err := s.reviewsRepo.RemoveVote(reviewID, productID, userID)
+	if err != nil {
+		if isReviewError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error removing vote: %w", err)
+	}
+	return review, nil
+}
+
+// AddReviewImage stores a photo and attaches it to the customer's own review.
+func (s *ProductReviewsService) AddReviewImage(reviewID int, productID int, userID int, file multipart.File, header *multipart.FileHeader) (*models.ReviewImage, error) {
+	url, err := s.uploadService.SaveReviewImage(file, header)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFileExtension) || errors.Is(err, ErrFileTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error saving review image: %w", err)
+	}
+
+	image, err := s.reviewsRepo.AddReviewImage(reviewID, productID, userID, url)
+	if err != nil {
+		s.deleteImageFiles([]string{url})
+		if isReviewError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error adding review image: %w", err)
+	}
+	return image, nil
+}
+
+// DeleteReviewImage removes a photo from the customer's own review.
+func (s *ProductReviewsService) DeleteReviewImage(imageID int, reviewID int, productID int, userID int) error {
+	url, err := s.reviewsRepo.DeleteReviewImage(imageID, reviewID, productID, userID)
+	if err != nil {
+		if isReviewError(err) {
+			return err
+		}
+		return fmt.Errorf("service error deleting review image: %w", err)
+	}
+	s.deleteImageFiles([]string{url})
+	return nil
+}
+
+// deleteImageFiles removes the files of review photos that are no longer referenced. By then the
+// database no longer points at them, so a file that cannot be removed is left behind rather than
+// failing the request.
+func (s *ProductReviewsService) deleteImageFiles(urls []string) {
+	for _, url := range urls {
+		_ = s.uploadService.DeleteImage(url)
+	}
+}
+
+func isReviewError(err error) bool {
+	return errors.Is(err, repositories.ErrReviewNotFound) ||
+		errors.Is(err, repositories.ErrOwnReview) ||
+		errors.Is(err, repositories.ErrAlreadyReported) ||
+		errors.Is(err, repositories.ErrReviewNotReported) ||
+		errors.Is(err, repositories.ErrVoteNotFound) ||
+		errors.Is(err, repositories.ErrTooManyImages) ||
+		errors.Is(err, repositories.ErrImageNotFound)
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// MockProductReviewsRepo is a mock implementation of IProductReviewsRepo
+type MockProductReviewsRepo struct {
+	GetReviewsByProductIDFunc func(productID int, filter models.ReviewFilter) ([]models.ProductReview, models.PageInfo, error)
+	HasPurchasedFunc          func(userID int, productID int) (bool, error)
+	CreateReviewFunc          func(review *models.ProductReview) (*models.ProductReview, error)
+	UpdateReviewFunc          func(review *models.ProductReview) (*models.ProductReview, error)
+	DeleteReviewFunc          func(reviewID int, productID int, userID int) ([]string, error)
+	ReportReviewFunc          func(report *models.ReviewReport, productID int) (*models.ReviewReport, error)
+	GetModerationQueueFunc    func(retailerID int) ([]models.ReportedReview, error)
+	SetReviewHiddenFunc       func(reviewID int, retailerID int, hidden bool) (*models.ProductReview, error)
+	DeleteReportedReviewFunc  func(reviewID int, retailerID int) ([]string, error)
+	SetSellerReplyFunc        func(reviewID int, retailerID int, reply string) (*models.ProductReview, error)
+	VoteReviewFunc            func(reviewID int, productID int, userID int, helpful bool) (*models.ProductReview, error)
+	RemoveVoteFunc            func(reviewID int, productID int, userID int) (*models.ProductReview, error)
+	AddReviewImageFunc        func(reviewID int, productID int, userID int, url string) (*models.ReviewImage, error)
+	DeleteReviewImageFunc     func(imageID int, reviewID int, productID int, userID int) (string, error)
+}
+
+func (m *MockProductReviewsRepo) GetReviewsByProductID(productID int, filter models.ReviewFilter) ([]models.ProductReview, models.PageInfo, error) {
+	if m.GetReviewsByProductIDFunc != nil {
+		return m.GetReviewsByProductIDFunc(productID, filter)
+	}
+	return nil, models.PageInfo{}, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) HasPurchased(userID int, productID int) (bool, error) {
+	if m.HasPurchasedFunc != nil {
+		return m.HasPurchasedFunc(userID, productID)
+	}
+	return false, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) CreateReview(review *models.ProductReview) (*models.ProductReview, error) {
+	if m.CreateReviewFunc != nil {
+		return m.CreateReviewFunc(review)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) UpdateReview(review *models.ProductReview) (*models.ProductReview, error) {
+	if m.UpdateReviewFunc != nil {
+		return m.UpdateReviewFunc(review)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) DeleteReview(reviewID int, productID int, userID int) ([]string, error) {
+	if m.DeleteReviewFunc != nil {
+		return m.DeleteReviewFunc(reviewID, productID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) ReportReview(report *models.ReviewReport, productID int) (*models.ReviewReport, error) {
+	if m.ReportReviewFunc != nil {
+		return m.ReportReviewFunc(report, productID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) GetModerationQueue(retailerID int) ([]models.ReportedReview, error) {
+	if m.GetModerationQueueFunc != nil {
+		return m.GetModerationQueueFunc(retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) SetReviewHidden(reviewID int, retailerID int, hidden bool) (*models.ProductReview, error) {
+	if m.SetReviewHiddenFunc != nil {
+		return m.SetReviewHiddenFunc(reviewID, retailerID, hidden)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) DeleteReportedReview(reviewID int, retailerID int) ([]string, error) {
+	if m.DeleteReportedReviewFunc != nil {
+		return m.DeleteReportedReviewFunc(reviewID, retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) SetSellerReply(reviewID int, retailerID int, reply string) (*models.ProductReview, error) {
+	if m.SetSellerReplyFunc != nil {
+		return m.SetSellerReplyFunc(reviewID, retailerID, reply)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) VoteReview(reviewID int, productID int, userID int, helpful bool) (*models.ProductReview, error) {
+	if m.VoteReviewFunc != nil {
+		return m.VoteReviewFunc(reviewID, productID, userID, helpful)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) RemoveVote(reviewID int, productID int, userID int) (*models.ProductReview, error) {
+	if m.RemoveVoteFunc != nil {
+		return m.RemoveVoteFunc(reviewID, productID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) AddReviewImage(reviewID int, productID int, userID int, url string) (*models.ReviewImage, error) {
+	if m.AddReviewImageFunc != nil {
+		return m.AddReviewImageFunc(reviewID, productID, userID, url)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductReviewsRepo) DeleteReviewImage(imageID int, reviewID int, productID int, userID int) (string, error) {
+	if m.DeleteReviewImageFunc != nil {
+		return m.DeleteReviewImageFunc(imageID, reviewID, productID, userID)
+	}
+	return "", errors.New("not implemented")
+}
+
+func TestProductReviewsService_GetReviewsByProductID(t *testing.T) {
+	var got models.ReviewFilter
+	repo := &MockProductReviewsRepo{
+		GetReviewsByProductIDFunc: func(productID int, filter models.ReviewFilter) ([]models.ProductReview, models.PageInfo, error) {
+			got = filter
+			if filter.Cursor == "bad" {
+				return nil, models.PageInfo{}, repositories.ErrInvalidCursor
+			}
+			return []models.ProductReview{}, models.PageInfo{}, nil
+		},
+	}
+	service := NewProductReviewsService(repo, NewUploadService())
+
+	if _, _, err := service.GetReviewsByProductID(1, models.ReviewFilter{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Sort != models.ReviewSortNewest || got.Limit != defaultReviewPageSize {
+		t.Errorf("Expected the default sort and page size, got %+v", got)
+	}
+
+	if _, _, err := service.GetReviewsByProductID(1, models.ReviewFilter{Limit: 1000}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Limit != maxReviewPageSize {
+		t.Errorf("Expected the page size to be capped, got %d", got.Limit)
+	}
+
+	if _, _, err := service.GetReviewsByProductID(1, models.ReviewFilter{Cursor: "bad"}); !errors.Is(err, repositories.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestProductReviewsService_VoteReview_OwnReview(t *testing.T) {
+	repo := &MockProductReviewsRepo{
+		VoteReviewFunc: func(reviewID int, productID int, userID int, helpful bool) (*models.ProductReview, error) {
+			return nil, repositories.ErrOwnReview
+		},
+	}
+	if _, err := NewProductReviewsService(repo, NewUploadService()).VoteReview(9, 1, 2, true); !errors.Is(err, repositories.ErrOwnReview) {
+		t.Errorf("Expected ErrOwnReview, got %v", err)
+	}
+}
+
+func TestProductReviewsService_CreateReview(t *testing.T) {
+	t.Run("not purchased", func(t *testing.T) {
+		repo := &MockProductReviewsRepo{
+			HasPurchasedFunc: func(userID int, productID int) (bool, error) { return false, nil },
+		}
+		_, err := NewProductReviewsService(repo, NewUploadService()).CreateReview(&models.ProductReview{Product_id: 1, User_id: 2, Rating: 5, Comment: "Great"})
+		if !errors.Is(err, ErrNotVerifiedPurchase) {
+			t.Errorf("Expected ErrNotVerifiedPurchase, got %v", err)
+		}
+	})
+
+	t.Run("already reviewed", func(t *testing.T) {
+		repo := &MockProductReviewsRepo{
+			HasPurchasedFunc: func(userID int, productID int) (bool, error) { return true, nil },
+			CreateReviewFunc: func(review *models.ProductReview) (*models.ProductReview, error) {
+				return nil, repositories.ErrDuplicateReview
+			},
+		}
+		_, err := NewProductReviewsService(repo, NewUploadService()).CreateReview(&models.ProductReview{Product_id: 1, User_id: 2, Rating: 5, Comment: "Great"})
+		if !errors.Is(err, repositories.ErrDuplicateReview) {
+			t.Errorf("Expected ErrDuplicateReview, got %v", err)
+		}
+	})
+
+	t.Run("verified purchase", func(t *testing.T) {
+		repo := &MockProductReviewsRepo{
+			HasPurchasedFunc: func(userID int, productID int) (bool, error) {
+				if userID != 2 || productID != 1 {
+					t.Errorf("Unexpected purchase check for user %d, product %d", userID, productID)
+				}
+				return true, nil
+			},
+			CreateReviewFunc: func(review *models.ProductReview) (*models.ProductReview, error) {
+				created := *review
+				created.Id = 9
+				created.Verified_purchase = true
+				return &created, nil
+			},
+		}
+		review, err := NewProductReviewsService(repo, NewUploadService()).CreateReview(&models.ProductReview{Product_id: 1, User_id: 2, Rating: 5, Comment: "Great"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if review.Id != 9 || !review.Verified_purchase {
+			t.Errorf("Unexpected review: %+v", review)
+		}
+	})
+}
+
+func TestProductReviewsService_DeleteReview_NotAuthor(t *testing.T) {
+	repo := &MockProductReviewsRepo{
+		DeleteReviewFunc: func(reviewID int, productID int, userID int) ([]string, error) {
+			return nil, repositories.ErrReviewNotFound
+		},
+	}
+	if err := NewProductReviewsService(repo, NewUploadService()).DeleteReview(9, 1, 3); !errors.Is(err, repositories.ErrReviewNotFound) {
+		t.Errorf("Expected ErrReviewNotFound, got %v", err)
+	}
+}
+
+func TestProductReviewsService_ReportReview(t *testing.T) {
+	service := NewProductReviewsService(&MockProductReviewsRepo{}, NewUploadService())
+	if _, err := service.ReportReview(9, 1, 2, "  "); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("Expected ErrInvalidReport, got %v", err)
+	}
+
+	repo := &MockProductReviewsRepo{
+		ReportReviewFunc: func(report *models.ReviewReport, productID int) (*models.ReviewReport, error) {
+			return nil, repositories.ErrAlreadyReported
+		},
+	}
+	if _, err := NewProductReviewsService(repo, NewUploadService()).ReportReview(9, 1, 2, "Spam"); !errors.Is(err, repositories.ErrAlreadyReported) {
+		t.Errorf("Expected ErrAlreadyReported, got %v", err)
+	}
+}
+
+func TestProductReviewsService_SellerReply(t *testing.T) {
+	var saved []string
+	repo := &MockProductReviewsRepo{
+		SetSellerReplyFunc: func(reviewID int, retailerID int, reply string) (*models.ProductReview, error) {
+			saved = append(saved, reply)
+			return &models.ProductReview{Id: reviewID}, nil
+		},
+	}
+	service := NewProductReviewsService(repo, NewUploadService())
+
+	if _, err := service.SetSellerReply(9, 4, ""); !errors.Is(err, ErrInvalidReply) {
+		t.Errorf("Expected ErrInvalidReply, got %v", err)
+	}
+	if _, err := service.SetSellerReply(9, 4, " Sorry to hear that, we have sent a replacement. "); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.DeleteSellerReply(9, 4); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(saved) != 2 || saved[0] != "Sorry to hear that, we have sent a replacement." || saved[1] != "" {
+		t.Errorf("Unexpected replies saved: %q", saved)
+	}
+}
+
+// reviewImageFile is an uploaded image held in memory.
+type reviewImageFile struct {
+	*bytes.Reader
+}
+
+func (reviewImageFile) Close() error { return nil }
+
+func TestProductReviewsService_AddReviewImage(t *testing.T) {
+	uploads := &UploadService{uploadDir: t.TempDir()}
+	header := &multipart.FileHeader{Filename: "photo.JPG", Size: 4}
+
+	t.Run("invalid extension", func(t *testing.T) {
+		service := NewProductReviewsService(&MockProductReviewsRepo{}, uploads)
+		_, err := service.AddReviewImage(9, 1, 2, reviewImageFile{bytes.NewReader([]byte("GIF8"))}, &multipart.FileHeader{Filename: "photo.gif", Size: 4})
+		if !errors.Is(err, ErrInvalidFileExtension) {
+			t.Errorf("Expected ErrInvalidFileExtension, got %v", err)
+		}
+	})
+
+	t.Run("rejected by repo", func(t *testing.T) {
+		var saved string
+		repo := &MockProductReviewsRepo{
+			AddReviewImageFunc: func(reviewID int, productID int, userID int, url string) (*models.ReviewImage, error) {
+				saved = url
+				return nil, repositories.ErrTooManyImages
+			},
+		}
+		_, err := NewProductReviewsService(repo, uploads).AddReviewImage(9, 1, 2, reviewImageFile{bytes.NewReader([]byte("jpeg"))}, header)
+		if !errors.Is(err, repositories.ErrTooManyImages) {
+			t.Fatalf("Expected ErrTooManyImages, got %v", err)
+		}
+		if !strings.HasPrefix(saved, "/api/uploads/reviews/") {
+			t.Fatalf("Expected a review image URL, got %q", saved)
+		}
+		// The file saved before the repo refused it is removed again
+		name := strings.TrimPrefix(saved, "/api/uploads/")
+		if _, err := os.Stat(filepath.Join(uploads.uploadDir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected the rejected image to be deleted, got %v", err)
+		}
+	})
+}
+
+func TestProductReviewsService_DeleteReview_RemovesImages(t *testing.T) {
+	uploads := &UploadService{uploadDir: t.TempDir()}
+	url, err := uploads.SaveReviewImage(reviewImageFile{bytes.NewReader([]byte("jpeg"))}, &multipart.FileHeader{Filename: "photo.jpg", Size: 4})
+	if err != nil {
+		t.Fatalf("Failed to save image: %v", err)
+	}
+
+	repo := &MockProductReviewsRepo{
+		DeleteReviewFunc: func(reviewID int, productID int, userID int) ([]string, error) {
+			return []string{url}, nil
+		},
+	}
+	if err := NewProductReviewsService(repo, uploads).DeleteReview(9, 1, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	name := strings.TrimPrefix(url, "/api/uploads/")
+	if _, err := os.Stat(filepath.Join(uploads.uploadDir, name)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the review's image to be deleted, got %v", err)
+	}
+}
@@ -3,24 +3,35 @@ package services
 import (
 	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
+	"errors"
 	"fmt"
 )
 
+var (
+	ErrInvalidMarkup    = errors.New("markup cannot be negative")
+	ErrSourceCurrency   = errors.New("wholesale product is priced in a different currency")
+	ErrInvalidThreshold = errors.New("reorder threshold cannot be negative")
+)
+
 type ProductRepository interface {
 	GetProductsByRetailerID(retailerID int) ([]models.RetailerProduct, error)
 	GetProductByIDForRetailer(productID int, retailerID int) (*models.RetailerProduct, error)
 	CreateProduct(product *models.RetailerProduct) (*models.RetailerProduct, error)
 	UpdateProduct(product *models.RetailerProduct) (*models.RetailerProduct, error)
 	DeleteProduct(productID int, retailerID int) error
+	SetSourceProduct(productID int, retailerID int, sourceProductID int) (*models.RetailerProduct, error)
+	SetReorderThreshold(productID int, retailerID int, threshold int) (*models.RetailerProduct, error)
 }
 
 type ProductService struct {
-	productRepo ProductRepository
+	productRepo           ProductRepository
+	wholesaleProductsRepo repositories.IWholesalerProductRepository
 }
 
-func NewProductService(productRepo ProductRepository) *ProductService {
+func NewProductService(productRepo ProductRepository, wholesaleProductsRepo repositories.IWholesalerProductRepository) *ProductService {
 	return &ProductService{
-		productRepo: productRepo,
+		productRepo:           productRepo,
+		wholesaleProductsRepo: wholesaleProductsRepo,
 	}
 }
 
@@ -78,3 +89,93 @@ func (s *ProductService) DeleteProduct(productID int, retailerID int) error {
 	return nil
 }
 
+// LinkSourceProduct records that a retailer product is bought from a wholesaler as
+// sourceProductID, so delivered wholesale orders of it add to the product's stock. The
+// wholesale product has to be one the retailer can see.
+func (s *ProductService) LinkSourceProduct(productID int, retailerID int, sourceProductID int) (*models.RetailerProduct, error) {
+	if _, err := s.wholesaleProductsRepo.GetProduct(sourceProductID, retailerID); err != nil {
+		if errors.Is(err, repositories.ErrWholesalerProductNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching wholesale product: %w", err)
+	}
+
+	product, err := s.productRepo.SetSourceProduct(productID, retailerID, sourceProductID)
+	if err != nil {
+		if isSourceLinkError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error linking product: %w", err)
+	}
+	return product, nil
+}
+
+func (s *ProductService) UnlinkSourceProduct(productID int, retailerID int) (*models.RetailerProduct, error) {
+	product, err := s.productRepo.SetSourceProduct(productID, retailerID, 0)
+	if err != nil {
+		if isSourceLinkError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error unlinking product: %w", err)
+	}
+	return product, nil
+}
+
+// ImportWholesaleProduct copies a wholesale product into the retailer's catalog, linked to it,
+// priced at its catalog price plus markupPercent and with no stock until an order of it is
+// delivered.
+func (s *ProductService) ImportWholesaleProduct(retailer *models.Retailer, sourceProductID int, markupPercent float64) (*models.RetailerProduct, error) {
+	if markupPercent < 0 {
+		return nil, ErrInvalidMarkup
+	}
+
+	source, err := s.wholesaleProductsRepo.GetProduct(sourceProductID, retailer.Id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrWholesalerProductNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching wholesale product: %w", err)
+	}
+	if source.Currency != retailer.Currency {
+		return nil, ErrSourceCurrency
+	}
+
+	product := &models.RetailerProduct{
+		Retailer_id:     retailer.Id,
+		Name:            source.Name,
+		Price:           source.Price.Markup(markupPercent),
+		Image_url:       source.Image_url,
+		Description:     source.Description,
+		CategoryId:      source.CategoryId,
+		SourceProductId: source.Id,
+	}
+	created, err := s.productRepo.CreateProduct(product)
+	if err != nil {
+		if isSourceLinkError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error importing product: %w", err)
+	}
+	return created, nil
+}
+
+// SetReorderThreshold sets the available stock below which the daily low-stock job alerts the
+// retailer about the product; 0 turns the alerts off.
+func (s *ProductService) SetReorderThreshold(productID int, retailerID int, threshold int) (*models.RetailerProduct, error) {
+	if threshold < 0 {
+		return nil, ErrInvalidThreshold
+	}
+
+	product, err := s.productRepo.SetReorderThreshold(productID, retailerID, threshold)
+	if err != nil {
+		if err == repositories.ErrProductNotFound {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error setting reorder threshold: %w", err)
+	}
+	return product, nil
+}
+
+func isSourceLinkError(err error) bool {
+	return errors.Is(err, repositories.ErrProductNotFound) || errors.Is(err, repositories.ErrSourceAlreadyLinked)
+}
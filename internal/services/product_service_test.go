@@ -0,0 +1,247 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"testing"
+)
+
+// MockProductRepository is a mock implementation of ProductRepository
+type MockProductRepository struct {
+	GetProductsByRetailerIDFunc   func(retailerID int) ([]models.RetailerProduct, error)
+	GetProductByIDForRetailerFunc func(productID int, retailerID int) (*models.RetailerProduct, error)
+	CreateProductFunc             func(product *models.RetailerProduct) (*models.RetailerProduct, error)
+	UpdateProductFunc             func(product *models.RetailerProduct) (*models.RetailerProduct, error)
+	DeleteProductFunc             func(productID int, retailerID int) error
+	SetSourceProductFunc          func(productID int, retailerID int, sourceProductID int) (*models.RetailerProduct, error)
+	SetReorderThresholdFunc       func(productID int, retailerID int, threshold int) (*models.RetailerProduct, error)
+}
+
+func (m *MockProductRepository) GetProductsByRetailerID(retailerID int) ([]models.RetailerProduct, error) {
+	if m.GetProductsByRetailerIDFunc != nil {
+		return m.GetProductsByRetailerIDFunc(retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductRepository) GetProductByIDForRetailer(productID int, retailerID int) (*models.RetailerProduct, error) {
+	if m.GetProductByIDForRetailerFunc != nil {
+		return m.GetProductByIDForRetailerFunc(productID, retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductRepository) CreateProduct(product *models.RetailerProduct) (*models.RetailerProduct, error) {
+	if m.CreateProductFunc != nil {
+		return m.CreateProductFunc(product)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductRepository) UpdateProduct(product *models.RetailerProduct) (*models.RetailerProduct, error) {
+	if m.UpdateProductFunc != nil {
+		return m.UpdateProductFunc(product)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductRepository) DeleteProduct(productID int, retailerID int) error {
+	if m.DeleteProductFunc != nil {
+		return m.DeleteProductFunc(productID, retailerID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockProductRepository) SetSourceProduct(productID int, retailerID int, sourceProductID int) (*models.RetailerProduct, error) {
+	if m.SetSourceProductFunc != nil {
+		return m.SetSourceProductFunc(productID, retailerID, sourceProductID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductRepository) SetReorderThreshold(productID int, retailerID int, threshold int) (*models.RetailerProduct, error) {
+	if m.SetReorderThresholdFunc != nil {
+		return m.SetReorderThresholdFunc(productID, retailerID, threshold)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// MockWholesalerProductRepository is a mock implementation of IWholesalerProductRepository
+type MockWholesalerProductRepository struct {
+	ListProductsFunc                func(filter models.ProductFilter) ([]models.WholesalerProduct, models.PageInfo, error)
+	GetProductFunc                  func(id int, viewerID int) (*models.WholesalerProduct, error)
+	GetProductsByWholesalerIDFunc   func(wholesalerID int) ([]models.WholesalerProduct, error)
+	GetProductByIDForWholesalerFunc func(productID int, wholesalerID int) (*models.WholesalerProduct, error)
+	CreateProductFunc               func(product *models.WholesalerProduct) (*models.WholesalerProduct, error)
+	UpdateProductFunc               func(product *models.WholesalerProduct) (*models.WholesalerProduct, error)
+	DeleteProductFunc               func(productID int, wholesalerID int) error
+	SetPricingFunc                  func(productID int, wholesalerID int, minOrderQty int, tiers []models.PriceTier) error
+}
+
+func (m *MockWholesalerProductRepository) ListProducts(filter models.ProductFilter) ([]models.WholesalerProduct, models.PageInfo, error) {
+	if m.ListProductsFunc != nil {
+		return m.ListProductsFunc(filter)
+	}
+	return nil, models.PageInfo{}, errors.New("not implemented")
+}
+
+func (m *MockWholesalerProductRepository) GetProduct(id int, viewerID int) (*models.WholesalerProduct, error) {
+	if m.GetProductFunc != nil {
+		return m.GetProductFunc(id, viewerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockWholesalerProductRepository) GetProductsByWholesalerID(wholesalerID int) ([]models.WholesalerProduct, error) {
+	if m.GetProductsByWholesalerIDFunc != nil {
+		return m.GetProductsByWholesalerIDFunc(wholesalerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockWholesalerProductRepository) GetProductByIDForWholesaler(productID int, wholesalerID int) (*models.WholesalerProduct, error) {
+	if m.GetProductByIDForWholesalerFunc != nil {
+		return m.GetProductByIDForWholesalerFunc(productID, wholesalerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockWholesalerProductRepository) CreateProduct(product *models.WholesalerProduct) (*models.WholesalerProduct, error) {
+	if m.CreateProductFunc != nil {
+		return m.CreateProductFunc(product)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockWholesalerProductRepository) UpdateProduct(product *models.WholesalerProduct) (*models.WholesalerProduct, error) {
+	if m.UpdateProductFunc != nil {
+		return m.UpdateProductFunc(product)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockWholesalerProductRepository) DeleteProduct(productID int, wholesalerID int) error {
+	if m.DeleteProductFunc != nil {
+		return m.DeleteProductFunc(productID, wholesalerID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockWholesalerProductRepository) SetPricing(productID int, wholesalerID int, minOrderQty int, tiers []models.PriceTier) error {
+	if m.SetPricingFunc != nil {
+		return m.SetPricingFunc(productID, wholesalerID, minOrderQty, tiers)
+	}
+	return errors.New("not implemented")
+}
+
+func TestProductService_ImportWholesaleProduct(t *testing.T) {
+	source := &models.WholesalerProduct{
+		Id:          12,
+		Name:        "Eyepiece 25mm",
+		Price:       1999,
+		Currency:    "usd",
+		Image_url:   "https://example.com/ep.jpg",
+		Description: "Plossl eyepiece",
+		CategoryId:  3,
+	}
+	retailer := &models.Retailer{Id: 5, Currency: "usd"}
+
+	tests := []struct {
+		name          string
+		markup        float64
+		retailer      *models.Retailer
+		sourceErr     error
+		expectedPrice models.Money
+		expectedError error
+	}{
+		{name: "applies markup", markup: 40, retailer: retailer, expectedPrice: 2799},
+		{name: "no markup", markup: 0, retailer: retailer, expectedPrice: 1999},
+		{name: "negative markup", markup: -5, retailer: retailer, expectedError: ErrInvalidMarkup},
+		{name: "hidden source", markup: 10, retailer: retailer, sourceErr: repositories.ErrWholesalerProductNotFound, expectedError: repositories.ErrWholesalerProductNotFound},
+		{name: "other currency", markup: 10, retailer: &models.Retailer{Id: 5, Currency: "eur"}, expectedError: ErrSourceCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *models.RetailerProduct
+			productRepo := &MockProductRepository{
+				CreateProductFunc: func(product *models.RetailerProduct) (*models.RetailerProduct, error) {
+					created = product
+					return product, nil
+				},
+			}
+			wholesaleRepo := &MockWholesalerProductRepository{
+				GetProductFunc: func(id int, viewerID int) (*models.WholesalerProduct, error) {
+					if id != source.Id || viewerID != tt.retailer.Id {
+						t.Errorf("GetProduct(%d, %d), want (%d, %d)", id, viewerID, source.Id, tt.retailer.Id)
+					}
+					if tt.sourceErr != nil {
+						return nil, tt.sourceErr
+					}
+					return source, nil
+				},
+			}
+			service := NewProductService(productRepo, wholesaleRepo)
+
+			_, err := service.ImportWholesaleProduct(tt.retailer, source.Id, tt.markup)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+				}
+				if created != nil {
+					t.Error("Product should not have been created")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if created.Price != tt.expectedPrice {
+				t.Errorf("Expected price %d, got %d", tt.expectedPrice, created.Price)
+			}
+			if created.SourceProductId != source.Id || created.Retailer_id != retailer.Id || created.Stock_qty != 0 {
+				t.Errorf("Unexpected product: %+v", created)
+			}
+			if created.Name != source.Name || created.CategoryId != source.CategoryId {
+				t.Errorf("Product details not copied: %+v", created)
+			}
+		})
+	}
+}
+
+func TestProductService_LinkSourceProduct(t *testing.T) {
+	t.Run("hidden source is not linked", func(t *testing.T) {
+		productRepo := &MockProductRepository{}
+		wholesaleRepo := &MockWholesalerProductRepository{
+			GetProductFunc: func(id int, viewerID int) (*models.WholesalerProduct, error) {
+				return nil, repositories.ErrWholesalerProductNotFound
+			},
+		}
+		service := NewProductService(productRepo, wholesaleRepo)
+
+		_, err := service.LinkSourceProduct(1, 5, 12)
+		if !errors.Is(err, repositories.ErrWholesalerProductNotFound) {
+			t.Errorf("Expected ErrWholesalerProductNotFound, got %v", err)
+		}
+	})
+
+	t.Run("source already linked", func(t *testing.T) {
+		productRepo := &MockProductRepository{
+			SetSourceProductFunc: func(productID int, retailerID int, sourceProductID int) (*models.RetailerProduct, error) {
+				return nil, repositories.ErrSourceAlreadyLinked
+			},
+		}
+		wholesaleRepo := &MockWholesalerProductRepository{
+			GetProductFunc: func(id int, viewerID int) (*models.WholesalerProduct, error) {
+				return &models.WholesalerProduct{Id: id}, nil
+			},
+		}
+		service := NewProductService(productRepo, wholesaleRepo)
+
+		_, err := service.LinkSourceProduct(1, 5, 12)
+		if !errors.Is(err, repositories.ErrSourceAlreadyLinked) {
+			t.Errorf("Expected ErrSourceAlreadyLinked, got %v", err)
+		}
+	})
+}
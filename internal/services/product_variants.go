@@ -0,0 +1,130 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+)
+
+var ErrVariantOptionsMismatch = errors.New("variant must have exactly one value for each of the product's option types")
+
+// ProductVariantsService manages the option types and variants of one catalog, so the shop and
+// the wholesale catalog each get their own instance.
+type ProductVariantsService struct {
+	variantsRepo repositories.IProductVariantsRepo
+}
+
+func NewProductVariantsService(variantsRepo repositories.IProductVariantsRepo) *ProductVariantsService {
+	return &ProductVariantsService{
+		variantsRepo: variantsRepo,
+	}
+}
+
+// GetVariantMatrix returns a product's option types with the values its variants use, and the
+// variants themselves. Both are empty for a product without variants.
+func (s *ProductVariantsService) GetVariantMatrix(productID int) (*models.VariantMatrix, error) {
+	names, err := s.variantsRepo.GetOptions(productID)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching product options: %w", err)
+	}
+	variants, err := s.variantsRepo.GetVariants(productID)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching product variants: %w", err)
+	}
+
+	matrix := &models.VariantMatrix{Options: make([]models.ProductOption, len(names)), Variants: variants}
+	for i, name := range names {
+		option := models.ProductOption{Name: name, Values: []string{}}
+		seen := make(map[string]bool)
+		for _, variant := range variants {
+			value := variant.Options[name]
+			if value != "" && !seen[value] {
+				seen[value] = true
+				option.Values = append(option.Values, value)
+			}
+		}
+		matrix.Options[i] = option
+	}
+	return matrix, nil
+}
+
+func (s *ProductVariantsService) SetOptions(productID int, sellerID int, names []string) error {
+	err := s.variantsRepo.SetOptions(productID, sellerID, names)
+	if err != nil {
+		if isVariantError(err) {
+			return err
+		}
+		return fmt.Errorf("service error setting product options: %w", err)
+	}
+	return nil
+}
+
+func (s *ProductVariantsService) CreateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	if err := s.checkOptions(variant); err != nil {
+		return nil, err
+	}
+	created, err := s.variantsRepo.CreateVariant(sellerID, variant)
+	if err != nil {
+		if isVariantError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error creating variant: %w", err)
+	}
+	return created, nil
+}
+
+func (s *ProductVariantsService) UpdateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	if err := s.checkOptions(variant); err != nil {
+		return nil, err
+	}
+	updated, err := s.variantsRepo.UpdateVariant(sellerID, variant)
+	if err != nil {
+		if isVariantError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error updating variant: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *ProductVariantsService) DeleteVariant(productID int, variantID int, sellerID int) error {
+	err := s.variantsRepo.DeleteVariant(productID, variantID, sellerID)
+	if err != nil {
+		if isVariantError(err) {
+			return err
+		}
+		return fmt.Errorf("service error deleting variant: %w", err)
+	}
+	return nil
+}
+
+// checkOptions makes sure a variant has a value for every option type of its product and
+// nothing else.
+func (s *ProductVariantsService) checkOptions(variant *models.ProductVariant) error {
+	names, err := s.variantsRepo.GetOptions(variant.ProductId)
+	if err != nil {
+		return fmt.Errorf("service error fetching product options: %w", err)
+	}
+	if len(names) == 0 || len(variant.Options) != len(names) {
+		return ErrVariantOptionsMismatch
+	}
+	for _, name := range names {
+		if variant.Options[name] == "" {
+			return ErrVariantOptionsMismatch
+		}
+	}
+	return nil
+}
+
+// isVariantError reports whether err is one of the repository errors the handlers turn into a
+// client error, which are returned unwrapped.
+func isVariantError(err error) bool {
+	switch err {
+	case repositories.ErrProductNotFound, repositories.ErrWholesalerProductNotFound,
+		repositories.ErrVariantNotFound, repositories.ErrDuplicateSKU, repositories.ErrDuplicateVariant,
+		repositories.ErrVariantOrdered, repositories.ErrVariantsExist:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// MockProductVariantsRepo is a mock implementation of IProductVariantsRepo
+type MockProductVariantsRepo struct {
+	GetOptionsFunc    func(productID int) ([]string, error)
+	SetOptionsFunc    func(productID int, sellerID int, names []string) error
+	GetVariantsFunc   func(productID int) ([]models.ProductVariant, error)
+	CreateVariantFunc func(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error)
+	UpdateVariantFunc func(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error)
+	DeleteVariantFunc func(productID int, variantID int, sellerID int) error
+}
+
+func (m *MockProductVariantsRepo) GetOptions(productID int) ([]string, error) {
+	if m.GetOptionsFunc != nil {
+		return m.GetOptionsFunc(productID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductVariantsRepo) SetOptions(productID int, sellerID int, names []string) error {
+	if m.SetOptionsFunc != nil {
+		return m.SetOptionsFunc(productID, sellerID, names)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockProductVariantsRepo) GetVariants(productID int) ([]models.ProductVariant, error) {
+	if m.GetVariantsFunc != nil {
+		return m.GetVariantsFunc(productID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductVariantsRepo) CreateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	if m.CreateVariantFunc != nil {
+		return m.CreateVariantFunc(sellerID, variant)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductVariantsRepo) UpdateVariant(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	if m.UpdateVariantFunc != nil {
+		return m.UpdateVariantFunc(sellerID, variant)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockProductVariantsRepo) DeleteVariant(productID int, variantID int, sellerID int) error {
+	if m.DeleteVariantFunc != nil {
+		return m.DeleteVariantFunc(productID, variantID, sellerID)
+	}
+	return errors.New("not implemented")
+}
+
+func TestProductVariantsService_GetVariantMatrix(t *testing.T) {
+	mockRepo := &MockProductVariantsRepo{
+		GetOptionsFunc: func(productID int) ([]string, error) {
+			return []string{"size", "colour"}, nil
+		},
+		GetVariantsFunc: func(productID int) ([]models.ProductVariant, error) {
+			return []models.ProductVariant{
+				{Id: 1, Sku: "TEE-M-RED", Options: models.VariantOptions{"size": "M", "colour": "red"}},
+				{Id: 2, Sku: "TEE-S-RED", Options: models.VariantOptions{"size": "S", "colour": "red"}},
+				{Id: 3, Sku: "TEE-M-BLUE", Options: models.VariantOptions{"size": "M", "colour": "blue"}},
+			}, nil
+		},
+	}
+	service := NewProductVariantsService(mockRepo)
+
+	matrix, err := service.GetVariantMatrix(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []models.ProductOption{
+		{Name: "size", Values: []string{"M", "S"}},
+		{Name: "colour", Values: []string{"red", "blue"}},
+	}
+	if !reflect.DeepEqual(matrix.Options, want) {
+		t.Errorf("Expected options %+v, got %+v", want, matrix.Options)
+	}
+	if len(matrix.Variants) != 3 {
+		t.Errorf("Expected 3 variants, got %d", len(matrix.Variants))
+	}
+}
+
+func TestProductVariantsService_CreateVariant(t *testing.T) {
+	created := false
+	mockRepo := &MockProductVariantsRepo{
+		GetOptionsFunc: func(productID int) ([]string, error) {
+			return []string{"size", "colour"}, nil
+		},
+		CreateVariantFunc: func(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+			created = true
+			return variant, nil
+		},
+	}
+	service := NewProductVariantsService(mockRepo)
+
+	tests := []struct {
+		name    string
+		options models.VariantOptions
+		wantErr error
+	}{
+		{name: "every option set", options: models.VariantOptions{"size": "M", "colour": "red"}},
+		{name: "missing option", options: models.VariantOptions{"size": "M"}, wantErr: ErrVariantOptionsMismatch},
+		{name: "unknown option", options: models.VariantOptions{"size": "M", "fit": "slim"}, wantErr: ErrVariantOptionsMismatch},
+		{name: "empty value", options: models.VariantOptions{"size": "M", "colour": ""}, wantErr: ErrVariantOptionsMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created = false
+			_, err := service.CreateVariant(3, &models.ProductVariant{ProductId: 1, Sku: "TEE", Options: tt.options, Price: 1999})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if created != (tt.wantErr == nil) {
+				t.Errorf("Expected repo to be called: %v", tt.wantErr == nil)
+			}
+		})
+	}
+
+	t.Run("duplicate sku passes through", func(t *testing.T) {
+		mockRepo.CreateVariantFunc = func(sellerID int, variant *models.ProductVariant) (*models.ProductVariant, error) {
+			return nil, repositories.ErrDuplicateSKU
+		}
+		_, err := service.CreateVariant(3, &models.ProductVariant{ProductId: 1, Sku: "TEE", Options: models.VariantOptions{"size": "M", "colour": "red"}, Price: 1999})
+		if err != repositories.ErrDuplicateSKU {
+			t.Errorf("Expected ErrDuplicateSKU, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidQuote   = errors.New("invalid quote")
+	ErrQuoteForbidden = errors.New("quote belongs to another account")
+)
+
+// QuotesService runs request-for-quote negotiations. A retailer requests products and quantities
+// from a wholesaler, the wholesaler offers prices valid until an expiry, and the retailer can
+// counter, which the wholesaler answers with a new offer. Accepting an offer places a wholesale
+// order at the quoted prices.
+type QuotesService struct {
+	quotesRepo      repositories.IQuotesRepo
+	retailersRepo   repositories.IRetailersRepo
+	wholesalersRepo repositories.IWholesalersRepo
+}
+
+func NewQuotesService(quotesRepo repositories.IQuotesRepo, retailersRepo repositories.IRetailersRepo, wholesalersRepo repositories.IWholesalersRepo) *QuotesService {
+	return &QuotesService{
+		quotesRepo:      quotesRepo,
+		retailersRepo:   retailersRepo,
+		wholesalersRepo: wholesalersRepo,
+	}
+}
+
+// isQuoteError reports whether err is one the handlers report to the user as is.
+func isQuoteError(err error) bool {
+	return errors.Is(err, repositories.ErrQuoteNotFound) ||
+		errors.Is(err, repositories.ErrQuoteStatusConflict) ||
+		errors.Is(err, repositories.ErrQuoteExpired) ||
+		errors.Is(err, repositories.ErrWholesalerNotFound) ||
+		errors.Is(err, repositories.ErrWholesalerProductNotFound) ||
+		errors.Is(err, repositories.ErrVariantRequired) ||
+		errors.Is(err, repositories.ErrVariantNotFound)
+}
+
+// validateQuoteItems checks the lines of a request, offer or counter-offer. Offers and
+// counter-offers must price every line; a request may leave prices at 0.
+func validateQuoteItems(items []models.QuoteItem, pricesRequired bool) error {
+	if len(items) == 0 {
+		return fmt.Errorf("%w: at least one item is required", ErrInvalidQuote)
+	}
+	type line struct{ productID, variantID int }
+	seen := make(map[line]bool, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("%w: quantity for product %d must be positive", ErrInvalidQuote, item.ProductId)
+		}
+		if item.Price < 0 || (pricesRequired && item.Price == 0) {
+			return fmt.Errorf("%w: price for product %d must be positive", ErrInvalidQuote, item.ProductId)
+		}
+		key := line{item.ProductId, item.VariantId}
+		if seen[key] {
+			return fmt.Errorf("%w: product %d is listed more than once", ErrInvalidQuote, item.ProductId)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// RequestQuoteByEmail asks a wholesaler to quote for the items.
+func (s *QuotesService) RequestQuoteByEmail(email string, wholesalerID int, items []models.QuoteItem, note string) (*models.Quote, error) {
+	if err := validateQuoteItems(items, false); err != nil {
+		return nil, err
+	}
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	quote, err := s.quotesRepo.CreateQuote(wholesalerID, retailer.Id, items, note)
+	if err != nil {
+		if isQuoteError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error requesting quote: %w", err)
+	}
+	return quote, nil
+}
+
+// GetRetailerQuotesByEmail returns the quotes requested by the retailer with the given email.
+func (s *QuotesService) GetRetailerQuotesByEmail(email string) ([]models.Quote, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	quotes, err := s.quotesRepo.GetQuotesByRetailer(retailer.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching quotes: %w", err)
+	}
+	return quotes, nil
+}
+
+// GetRetailerQuoteByEmail returns a single quote requested by the retailer with the given email.
+func (s *QuotesService) GetRetailerQuoteByEmail(email string, quoteID int) (*models.Quote, error) {
+	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retailer by email: %w", err)
+	}
+
+	quote, err := s.getQuote(quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if quote.RetailerId != retailer.Id {
+		return nil, ErrQuoteForbidden
+	}
+	return quote, nil
+}
+
+// CounterQuoteByEmail answers the wholesaler's offer with the retailer's own terms.
+func (s *QuotesService) CounterQuoteByEmail(email string, quoteID int, items []models.QuoteItem, note string) (*models.Quote, error) {
+	if err := validateQuoteItems(items, true); err != nil {
+		return nil, err
+	}
+	if _, err := s.GetRetailerQuoteByEmail(email, quoteID); err != nil {
+		return nil, err
+	}
+
+	return s.revise(quoteID, models.OrderActorRetailer, []models.QuoteStatus{models.QuoteOffered}, models.QuoteCountered, items, note, nil)
+}
+
+// AcceptQuoteByEmail accepts the wholesaler's offer and places a pending order for it, with its
+// stock reserved. The order is paid through the usual checkout.
+func (s *QuotesService) AcceptQuoteByEmail(email string, quoteID int) (*models.RetailerOrder, error) {
+	if _, err := s.GetRetailerQuoteByEmail(email, quoteID); err != nil {
+		return nil, err
+	}
+
+	order, err := s.quotesRepo.AcceptQuote(quoteID)
+	if err != nil {
+		var stockErr *repositories.InsufficientStockError
+		if isQuoteError(err) || errors.As(err, &stockErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error accepting quote: %w", err)
+	}
+	return order, nil
+}
+
+// DeclineQuoteAsRetailer ends a negotiation on the retailer's side.
+func (s *QuotesService) DeclineQuoteAsRetailer(email string, quoteID int) (*models.Quote, error) {
+	if _, err := s.GetRetailerQuoteByEmail(email, quoteID); err != nil {
+		return nil, err
+	}
+	return s.decline(quoteID)
+}
+
+// GetWholesalerQuotesByEmail returns the quotes asked of the wholesaler with the given email.
+func (s *QuotesService) GetWholesalerQuotesByEmail(email string) ([]models.Quote, error) {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	quotes, err := s.quotesRepo.GetQuotesByWholesaler(wholesaler.Id)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching quotes: %w", err)
+	}
+	return quotes, nil
+}
+
+// GetWholesalerQuoteByEmail returns a single quote asked of the wholesaler with the given email.
+func (s *QuotesService) GetWholesalerQuoteByEmail(email string, quoteID int) (*models.Quote, error) {
+	wholesaler, err := s.wholesalersRepo.GetWholesalerByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesaler by email: %w", err)
+	}
+
+	quote, err := s.getQuote(quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if quote.WholesalerId != wholesaler.Id {
+		return nil, ErrQuoteForbidden
+	}
+	return quote, nil
+}
+
+// OfferQuoteByEmail prices a request or counter-offer, valid until expiresAt. An offer that has
+// not been accepted can be replaced, for instance to extend its expiry.
+func (s *QuotesService) OfferQuoteByEmail(email string, quoteID int, items []models.QuoteItem, note string, expiresAt time.Time) (*models.Quote, error) {
+	if err := validateQuoteItems(items, true); err != nil {
+		return nil, err
+	}
+	if !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidQuote)
+	}
+	if _, err := s.GetWholesalerQuoteByEmail(email, quoteID); err != nil {
+		return nil, err
+	}
+
+	from := []models.QuoteStatus{models.QuoteRequested, models.QuoteCountered, models.QuoteOffered}
+	return s.revise(quoteID, models.OrderActorWholesaler, from, models.QuoteOffered, items, note, &expiresAt)
+}
+
+// DeclineQuoteAsWholesaler ends a negotiation on the wholesaler's side.
+func (s *QuotesService) DeclineQuoteAsWholesaler(email string, quoteID int) (*models.Quote, error) {
+	if _, err := s.GetWholesalerQuoteByEmail(email, quoteID); err != nil {
+		return nil, err
+	}
+	return s.decline(quoteID)
+}
+
+func (s *QuotesService) getQuote(quoteID int) (*models.Quote, error) {
+	quote, err := s.quotesRepo.GetQuote(quoteID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrQuoteNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error fetching quote: %w", err)
+	}
+	return quote, nil
+}
+
+func (s *QuotesService) revise(quoteID int, author models.OrderActorType, from []models.QuoteStatus, to models.QuoteStatus, items []models.QuoteItem, note string, expiresAt *time.Time) (*models.Quote, error) {
+	quote, err := s.quotesRepo.ReviseQuote(quoteID, author, from, to, items, note, expiresAt)
+	if err != nil {
+		if isQuoteError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error updating quote: %w", err)
+	}
+	return quote, nil
+}
+
+func (s *QuotesService) decline(quoteID int) (*models.Quote, error) {
+	quote, err := s.quotesRepo.DeclineQuote(quoteID)
+	if err != nil {
+		if isQuoteError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service error declining quote: %w", err)
+	}
+	return quote, nil
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// MockQuotesRepo is a mock implementation of IQuotesRepo
+type MockQuotesRepo struct {
+	CreateQuoteFunc           func(wholesalerID int, retailerID int, items []models.QuoteItem, note string) (*models.Quote, error)
+	GetQuoteFunc              func(id int) (*models.Quote, error)
+	GetQuotesByRetailerFunc   func(retailerID int) ([]models.Quote, error)
+	GetQuotesByWholesalerFunc func(wholesalerID int) ([]models.Quote, error)
+	ReviseQuoteFunc           func(id int, author models.OrderActorType, from []models.QuoteStatus, to models.QuoteStatus, items []models.QuoteItem, note string, expiresAt *time.Time) (*models.Quote, error)
+	DeclineQuoteFunc          func(id int) (*models.Quote, error)
+	AcceptQuoteFunc           func(id int) (*models.RetailerOrder, error)
+}
+
+func (m *MockQuotesRepo) CreateQuote(wholesalerID int, retailerID int, items []models.QuoteItem, note string) (*models.Quote, error) {
+	if m.CreateQuoteFunc != nil {
+		return m.CreateQuoteFunc(wholesalerID, retailerID, items, note)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockQuotesRepo) GetQuote(id int) (*models.Quote, error) {
+	if m.GetQuoteFunc != nil {
+		return m.GetQuoteFunc(id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockQuotesRepo) GetQuotesByRetailer(retailerID int) ([]models.Quote, error) {
+	if m.GetQuotesByRetailerFunc != nil {
+		return m.GetQuotesByRetailerFunc(retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockQuotesRepo) GetQuotesByWholesaler(wholesalerID int) ([]models.Quote, error) {
+	if m.GetQuotesByWholesalerFunc != nil {
+		return m.GetQuotesByWholesalerFunc(wholesalerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockQuotesRepo) ReviseQuote(id int, author models.OrderActorType, from []models.QuoteStatus, to models.QuoteStatus, items []models.QuoteItem, note string, expiresAt *time.Time) (*models.Quote, error) {
+	if m.ReviseQuoteFunc != nil {
+		return m.ReviseQuoteFunc(id, author, from, to, items, note, expiresAt)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockQuotesRepo) DeclineQuote(id int) (*models.Quote, error) {
+	if m.DeclineQuoteFunc != nil {
+		return m.DeclineQuoteFunc(id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockQuotesRepo) AcceptQuote(id int) (*models.RetailerOrder, error) {
+	if m.AcceptQuoteFunc != nil {
+		return m.AcceptQuoteFunc(id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func newQuotesTestService(repo *MockQuotesRepo) *QuotesService {
+	retailersRepo := &MockRetailersRepo{
+		GetRetailerByEmailFunc: func(email string) (*models.Retailer, error) {
+			return &models.Retailer{Id: 3, Email: email}, nil
+		},
+	}
+	wholesalersRepo := &MockWholesalersRepo{
+		GetWholesalerByEmailFunc: func(email string) (*models.Wholesaler, error) {
+			return &models.Wholesaler{Id: 7, Email: email}, nil
+		},
+	}
+	if repo.GetQuoteFunc == nil {
+		repo.GetQuoteFunc = func(id int) (*models.Quote, error) {
+			return &models.Quote{Id: id, WholesalerId: 7, RetailerId: 3, Status: models.QuoteOffered}, nil
+		}
+	}
+	return NewQuotesService(repo, retailersRepo, wholesalersRepo)
+}
+
+func TestQuotesService_Validation(t *testing.T) {
+	// The repo must not be reached with invalid terms
+	service := newQuotesTestService(&MockQuotesRepo{})
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"request without items", func() error {
+			_, err := service.RequestQuoteByEmail("shop@example.com", 7, nil, "")
+			return err
+		}},
+		{"request with zero quantity", func() error {
+			_, err := service.RequestQuoteByEmail("shop@example.com", 7, []models.QuoteItem{{ProductId: 1}}, "")
+			return err
+		}},
+		{"duplicate lines", func() error {
+			items := []models.QuoteItem{{ProductId: 1, Quantity: 5}, {ProductId: 1, Quantity: 2}}
+			_, err := service.RequestQuoteByEmail("shop@example.com", 7, items, "")
+			return err
+		}},
+		{"offer without a price", func() error {
+			_, err := service.OfferQuoteByEmail("depot@example.com", 1, []models.QuoteItem{{ProductId: 1, Quantity: 5}}, "", tomorrow)
+			return err
+		}},
+		{"offer that has already expired", func() error {
+			_, err := service.OfferQuoteByEmail("depot@example.com", 1, []models.QuoteItem{{ProductId: 1, Quantity: 5, Price: 900}}, "", time.Now().Add(-time.Hour))
+			return err
+		}},
+		{"counter without a price", func() error {
+			_, err := service.CounterQuoteByEmail("shop@example.com", 1, []models.QuoteItem{{ProductId: 1, Quantity: 5}}, "")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrInvalidQuote) {
+				t.Errorf("Expected ErrInvalidQuote, got %v", err)
+			}
+		})
+	}
+}
+
+func TestQuotesService_Negotiation(t *testing.T) {
+	var gotAuthor models.OrderActorType
+	var gotFrom []models.QuoteStatus
+	var gotTo models.QuoteStatus
+	var gotExpiry *time.Time
+	repo := &MockQuotesRepo{
+		ReviseQuoteFunc: func(id int, author models.OrderActorType, from []models.QuoteStatus, to models.QuoteStatus, items []models.QuoteItem, note string, expiresAt *time.Time) (*models.Quote, error) {
+			gotAuthor, gotFrom, gotTo, gotExpiry = author, from, to, expiresAt
+			return &models.Quote{Id: id, Status: to, Items: items}, nil
+		},
+	}
+	service := newQuotesTestService(repo)
+	items := []models.QuoteItem{{ProductId: 1, Quantity: 50, Price: 850}}
+
+	expiry := time.Now().Add(48 * time.Hour)
+	if _, err := service.OfferQuoteByEmail("depot@example.com", 1, items, "Best we can do", expiry); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wantFrom := []models.QuoteStatus{models.QuoteRequested, models.QuoteCountered, models.QuoteOffered}
+	if gotAuthor != models.OrderActorWholesaler || gotTo != models.QuoteOffered || !reflect.DeepEqual(gotFrom, wantFrom) {
+		t.Errorf("Unexpected offer: %s %v -> %s", gotAuthor, gotFrom, gotTo)
+	}
+	if gotExpiry == nil || !gotExpiry.Equal(expiry) {
+		t.Errorf("Expected the offer to expire at %v, got %v", expiry, gotExpiry)
+	}
+
+	if _, err := service.CounterQuoteByEmail("shop@example.com", 1, items, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotAuthor != models.OrderActorRetailer || gotTo != models.QuoteCountered || !reflect.DeepEqual(gotFrom, []models.QuoteStatus{models.QuoteOffered}) {
+		t.Errorf("Unexpected counter: %s %v -> %s", gotAuthor, gotFrom, gotTo)
+	}
+	if gotExpiry != nil {
+		t.Errorf("A counter-offer should not carry an expiry, got %v", gotExpiry)
+	}
+}
+
+func TestQuotesService_Forbidden(t *testing.T) {
+	repo := &MockQuotesRepo{
+		GetQuoteFunc: func(id int) (*models.Quote, error) {
+			return &models.Quote{Id: id, WholesalerId: 8, RetailerId: 4, Status: models.QuoteOffered}, nil
+		},
+	}
+	service := newQuotesTestService(repo)
+
+	if _, err := service.AcceptQuoteByEmail("shop@example.com", 1); err != ErrQuoteForbidden {
+		t.Errorf("Expected ErrQuoteForbidden accepting another retailer's quote, got %v", err)
+	}
+	if _, err := service.DeclineQuoteAsWholesaler("depot@example.com", 1); err != ErrQuoteForbidden {
+		t.Errorf("Expected ErrQuoteForbidden declining another wholesaler's quote, got %v", err)
+	}
+}
+
+func TestOrdersService_CreateQuoteCheckout(t *testing.T) {
+	var sessionOrders []int
+	ordersRepo := &MockOrdersRepo{
+		UpdateRetailerOrderStripeSessionFunc: func(orderID int, sessionID string) error {
+			sessionOrders = append(sessionOrders, orderID)
+			return nil
+		},
+	}
+	gateway := NewFakePaymentGateway("http://localhost:8000")
+	service := NewOrdersService(ordersRepo, CartService{}, RetailerCartService{}, gateway, nil, &MockUsersRepo{}, &MockRetailersRepo{}, &MockWholesalersRepo{}, nil, nil, nil)
+
+	order := &models.RetailerOrder{
+		Id: 12, WholesalerId: 7, RetailerId: 3, Currency: "eur", TotalPrice: 42500,
+		Items: []models.RetailerOrderItem{
+			{ProductId: 1, Quantity: 50, Price: 850, Product: &models.WholesalerProduct{Id: 1, Name: "Eyepiece"}},
+		},
+	}
+	url, err := service.CreateQuoteCheckout(order, "http://shop/success", "http://shop/cancel")
+	if err != nil {
+		t.Fatalf("CreateQuoteCheckout returned error: %v", err)
+	}
+	if len(sessionOrders) != 1 || sessionOrders[0] != 12 {
+		t.Errorf("Expected the session to be recorded on order 12, got %v", sessionOrders)
+	}
+
+	session, err := gateway.GetSession(url[len("http://localhost:8000/api/dev/payments/sessions/"):])
+	if err != nil {
+		t.Fatalf("Session was not created on the gateway: %v", err)
+	}
+	if session.AmountTotal != 42500 || session.LineItems[0].Currency != "eur" {
+		t.Errorf("Unexpected session: total %d, currency %s", session.AmountTotal, session.LineItems[0].Currency)
+	}
+}
+
+func TestQuoteItems_Total(t *testing.T) {
+	items := models.QuoteItems{{ProductId: 1, Quantity: 3, Price: 250}, {ProductId: 2, Quantity: 2, Price: 1000}}
+	if total := items.Total(); total != 2750 {
+		t.Errorf("Expected total 2750, got %d", total)
+	}
+}
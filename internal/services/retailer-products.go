@@ -3,6 +3,7 @@ package services
 import (
 	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
+	"errors"
 	"fmt"
 )
 
@@ -16,14 +17,19 @@ func NewRetailerProductsService(productsRepo repositories.IRetailerProductsRepo)
 	}
 }
 
-func (s *RetailerProductsService) GetProducts(q string) ([]models.RetailerProduct, error) {
-	if q != "" {
-		// full-text search
-		return s.productsRepo.SearchProducts(q)
+// GetProducts returns one page of the catalog. Invalid cursors and sorts or filters the catalog
+// does not support are returned unwrapped so the handler can report them as bad requests.
+func (s *RetailerProductsService) GetProducts(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+	products, page, err := s.productsRepo.ListProducts(normalizeProductFilter(filter))
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) || errors.Is(err, repositories.ErrUnsupportedSort) ||
+			errors.Is(err, repositories.ErrUnsupportedFilter) {
+			return nil, models.PageInfo{}, err
+		}
+		return nil, models.PageInfo{}, fmt.Errorf("service error listing products: %w", err)
 	}
 
-	// default 10 products
-	return s.productsRepo.GetProducts()
+	return products, page, nil
 }
 
 func (s *RetailerProductsService) GetProduct(id int) (*models.RetailerProduct, error) {
@@ -9,24 +9,16 @@ import (
 
 // MockRetailerProductsRepo is a mock implementation of IRetailerProductsRepo
 type MockRetailerProductsRepo struct {
-	GetProductsFunc           func() ([]models.RetailerProduct, error)
-	SearchProductsFunc        func(keyword string) ([]models.RetailerProduct, error)
-	GetProductFunc            func(id int) (*models.RetailerProduct, error)
+	ListProductsFunc            func(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error)
+	GetProductFunc              func(id int) (*models.RetailerProduct, error)
 	GetProductsByRetailerIDFunc func(retailerID int) ([]models.RetailerProduct, error)
 }
 
-func (m *MockRetailerProductsRepo) GetProducts() ([]models.RetailerProduct, error) {
-	if m.GetProductsFunc != nil {
-		return m.GetProductsFunc()
+func (m *MockRetailerProductsRepo) ListProducts(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+	if m.ListProductsFunc != nil {
+		return m.ListProductsFunc(filter)
 	}
-	return nil, errors.New("not implemented")
-}
-
-func (m *MockRetailerProductsRepo) SearchProducts(keyword string) ([]models.RetailerProduct, error) {
-	if m.SearchProductsFunc != nil {
-		return m.SearchProductsFunc(keyword)
-	}
-	return nil, errors.New("not implemented")
+	return nil, models.PageInfo{}, errors.New("not implemented")
 }
 
 func (m *MockRetailerProductsRepo) GetProduct(id int) (*models.RetailerProduct, error) {
@@ -58,62 +50,64 @@ func TestNewRetailerProductsService(t *testing.T) {
 func TestRetailerProductsService_GetProducts(t *testing.T) {
 	tests := []struct {
 		name          string
-		query         string
-		setupMock     func() *MockRetailerProductsRepo
-		expectedCount int
-		expectedError bool
+		filter        models.ProductFilter
+		repoErr       error
+		expectedLimit int
+		expectedSort  models.ProductSort
+		expectedError error
 	}{
 		{
-			name:  "get products without query",
-			query: "",
-			setupMock: func() *MockRetailerProductsRepo {
-				return &MockRetailerProductsRepo{
-					GetProductsFunc: func() ([]models.RetailerProduct, error) {
-						return []models.RetailerProduct{
-							{Id: 1, Name: "Product 1"},
-							{Id: 2, Name: "Product 2"},
-						}, nil
-					},
-				}
-			},
-			expectedCount: 2,
-			expectedError: false,
+			name:          "defaults",
+			filter:        models.ProductFilter{},
+			expectedLimit: defaultProductPageSize,
+			expectedSort:  models.ProductSortNewest,
 		},
 		{
-			name:  "search products with query",
-			query: "telescope",
-			setupMock: func() *MockRetailerProductsRepo {
-				return &MockRetailerProductsRepo{
-					SearchProductsFunc: func(keyword string) ([]models.RetailerProduct, error) {
-						return []models.RetailerProduct{
-							{Id: 1, Name: "Telescope Product"},
-						}, nil
-					},
-				}
-			},
-			expectedCount: 1,
-			expectedError: false,
+			name:          "page size is capped",
+			filter:        models.ProductFilter{Query: "telescope", Sort: models.ProductSortPriceAsc, Limit: 500},
+			expectedLimit: maxProductPageSize,
+			expectedSort:  models.ProductSortPriceAsc,
+		},
+		{
+			name:          "invalid cursor is passed through",
+			filter:        models.ProductFilter{Cursor: "bogus", Limit: 5},
+			repoErr:       repositories.ErrInvalidCursor,
+			expectedLimit: 5,
+			expectedSort:  models.ProductSortNewest,
+			expectedError: repositories.ErrInvalidCursor,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := tt.setupMock()
+			var received models.ProductFilter
+			mockRepo := &MockRetailerProductsRepo{
+				ListProductsFunc: func(filter models.ProductFilter) ([]models.RetailerProduct, models.PageInfo, error) {
+					received = filter
+					if tt.repoErr != nil {
+						return nil, models.PageInfo{}, tt.repoErr
+					}
+					return []models.RetailerProduct{{Id: 1, Name: "Telescope"}}, models.PageInfo{NextCursor: "next", Total: 7}, nil
+				},
+			}
 			service := NewRetailerProductsService(mockRepo)
 
-			products, err := service.GetProducts(tt.query)
+			products, page, err := service.GetProducts(tt.filter)
 
-			if tt.expectedError {
-				if err == nil {
-					t.Error("Expected error, got nil")
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Unexpected error: %v", err)
-				}
-				if len(products) != tt.expectedCount {
-					t.Errorf("Expected %d products, got %d", tt.expectedCount, len(products))
+			if received.Limit != tt.expectedLimit || received.Sort != tt.expectedSort {
+				t.Errorf("Expected limit %d and sort %s, repo got %+v", tt.expectedLimit, tt.expectedSort, received)
+			}
+			if tt.expectedError != nil {
+				if err != tt.expectedError {
+					t.Errorf("Expected error %v, got %v", tt.expectedError, err)
 				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(products) != 1 || page.Total != 7 || page.NextCursor != "next" {
+				t.Errorf("Unexpected result %+v %+v", products, page)
 			}
 		})
 	}
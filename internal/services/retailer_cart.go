@@ -4,17 +4,42 @@ import (
 	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
 	"fmt"
+	"strings"
 )
 
+// MinimumOrderError is returned when a retailer checks out a cart that does not meet the
+// wholesale minimums. Nothing is ordered when it is returned.
+type MinimumOrderError struct {
+	Issues []models.MinimumIssue
+}
+
+func (e *MinimumOrderError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		if issue.Type == models.MinimumOrderValue {
+			parts[i] = fmt.Sprintf("%s: order value %s, minimum %s", issue.Name, issue.Value, issue.MinValue)
+		} else {
+			parts[i] = fmt.Sprintf("%s: quantity %d, minimum %d", issue.Name, issue.Quantity, issue.MinQty)
+		}
+	}
+	return "order minimums not met for " + strings.Join(parts, "; ")
+}
+
 type RetailerCartService struct {
-	cartRepo      repositories.IRetailerCartRepo
-	retailersRepo repositories.IRetailersRepo
+	cartRepo        repositories.IRetailerCartRepo
+	retailersRepo   repositories.IRetailersRepo
+	wholesalersRepo repositories.IWholesalersRepo
+	priceListsRepo  repositories.IPriceListsRepo
+	accessRepo      repositories.ICatalogAccessRepo
 }
 
-func NewRetailerCartService(cartRepo repositories.IRetailerCartRepo, retailersRepo repositories.IRetailersRepo) *RetailerCartService {
+func NewRetailerCartService(cartRepo repositories.IRetailerCartRepo, retailersRepo repositories.IRetailersRepo, wholesalersRepo repositories.IWholesalersRepo, priceListsRepo repositories.IPriceListsRepo, accessRepo repositories.ICatalogAccessRepo) *RetailerCartService {
 	return &RetailerCartService{
-		cartRepo:      cartRepo,
-		retailersRepo: retailersRepo,
+		cartRepo:        cartRepo,
+		retailersRepo:   retailersRepo,
+		wholesalersRepo: wholesalersRepo,
+		priceListsRepo:  priceListsRepo,
+		accessRepo:      accessRepo,
 	}
 }
 
@@ -32,6 +57,113 @@ func (s *RetailerCartService) GetCartItemsByEmail(email string) ([]models.Retail
 	return cartItems, nil
 }
 
+// GetCartByEmail returns the retailer's cart priced at the quantity breaks it reaches.
+func (s *RetailerCartService) GetCartByEmail(email string) (*models.RetailerCart, error) {
+	cartItems, err := s.GetCartItemsByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	return s.PriceCart(cartItems)
+}
+
+// PriceCart sets the unit price of every line, at the retailer's negotiated prices, and lists
+// the minimums the cart falls short of. A product's minimum order quantity counts the units of
+// all its variants together, and a wholesaler's minimum order value is held against the cart's
+// total for that wholesaler.
+func (s *RetailerCartService) PriceCart(items []models.RetailerCartItem) (*models.RetailerCart, error) {
+	cart := &models.RetailerCart{Items: items, Issues: []models.MinimumIssue{}}
+	if len(items) > 0 {
+		productIDs := make([]int, len(items))
+		for i, item := range items {
+			productIDs[i] = item.Product_id
+		}
+		prices, err := negotiatedPrices(s.priceListsRepo, items[0].Retailer_id, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range cart.Items {
+			prices.Apply(&cart.Items[i].Product)
+			if cart.Items[i].Variant != nil {
+				prices.ApplyToVariant(cart.Items[i].Variant)
+			}
+		}
+	}
+
+	quantities := make(map[int]int)
+	subtotals := make(map[int]models.Money)
+	var productOrder, wholesalerOrder []int
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		item.Unit_price = item.UnitPrice()
+		cart.Total += item.Unit_price.Times(item.Quantity)
+
+		if _, seen := quantities[item.Product_id]; !seen {
+			productOrder = append(productOrder, i)
+		}
+		quantities[item.Product_id] += item.Quantity
+
+		wholesalerID := item.Product.Wholesaler_id
+		if _, seen := subtotals[wholesalerID]; !seen {
+			wholesalerOrder = append(wholesalerOrder, wholesalerID)
+		}
+		subtotals[wholesalerID] += item.Unit_price.Times(item.Quantity)
+	}
+
+	for _, i := range productOrder {
+		product := cart.Items[i].Product
+		if quantity := quantities[product.Id]; quantity < product.MinOrderQty {
+			cart.Issues = append(cart.Issues, models.MinimumIssue{
+				Type:         models.MinimumOrderQty,
+				WholesalerId: product.Wholesaler_id,
+				ProductId:    product.Id,
+				Name:         product.Name,
+				MinQty:       product.MinOrderQty,
+				Quantity:     quantity,
+			})
+		}
+	}
+
+	for _, wholesalerID := range wholesalerOrder {
+		wholesaler, err := s.wholesalersRepo.GetWholesalerByID(wholesalerID)
+		if err != nil {
+			return nil, fmt.Errorf("service error fetching wholesaler: %w", err)
+		}
+		if subtotal := subtotals[wholesalerID]; subtotal < wholesaler.MinOrderValue {
+			cart.Issues = append(cart.Issues, models.MinimumIssue{
+				Type:         models.MinimumOrderValue,
+				WholesalerId: wholesalerID,
+				Name:         wholesaler.BusinessName,
+				MinValue:     wholesaler.MinOrderValue,
+				Value:        subtotal,
+			})
+		}
+	}
+
+	return cart, nil
+}
+
+// CheckCatalogAccess returns ErrCatalogAccessDenied if the cart holds products of a private
+// catalog the retailer is no longer approved for.
+func (s *RetailerCartService) CheckCatalogAccess(retailerID int, items []models.RetailerCartItem) error {
+	checked := make(map[int]bool)
+	for _, item := range items {
+		wholesalerID := item.Product.Wholesaler_id
+		if checked[wholesalerID] {
+			continue
+		}
+		checked[wholesalerID] = true
+
+		visible, err := s.accessRepo.CanViewWholesaler(retailerID, wholesalerID)
+		if err != nil {
+			return fmt.Errorf("service error checking catalog access: %w", err)
+		}
+		if !visible {
+			return repositories.ErrCatalogAccessDenied
+		}
+	}
+	return nil
+}
+
 func (s *RetailerCartService) GetCartItemsByRetailerID(retailerID int) ([]models.RetailerCartItem, error) {
 	cartItems, err := s.cartRepo.GetCartItemsByRetailerID(retailerID)
 	if err != nil {
@@ -40,34 +172,51 @@ func (s *RetailerCartService) GetCartItemsByRetailerID(retailerID int) ([]models
 	return cartItems, nil
 }
 
-func (s *RetailerCartService) AddCartItem(email string, productID int, quantity int) (int, error) {
+// AddCartItem adds quantity units of a product to the retailer's cart, or takes one away when
+// quantity is -1. Products of a private catalog can only be added once the retailer has been
+// approved for it.
+func (s *RetailerCartService) AddCartItem(email string, productID int, variantID int, quantity int) (int, error) {
 	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
 	if err != nil {
 		return 0, fmt.Errorf("service error fetching retailer: %w", err)
 	}
 
 	var newQuantity int
-	if quantity == 1 {
-		newQuantity, err = s.cartRepo.AddCartItem(retailer.Id, productID, quantity)
+	if quantity > 0 {
+		var visible bool
+		visible, err = s.accessRepo.CanViewProduct(retailer.Id, productID)
+		if err != nil {
+			if err == repositories.ErrWholesalerProductNotFound {
+				return 0, err
+			}
+			return 0, fmt.Errorf("service error checking catalog access: %w", err)
+		}
+		if !visible {
+			return 0, repositories.ErrCatalogAccessDenied
+		}
+		newQuantity, err = s.cartRepo.AddCartItem(retailer.Id, productID, variantID, quantity)
 	}
 
 	if quantity == -1 {
-		newQuantity, err = s.cartRepo.DecreaseCartItem(retailer.Id, productID)
+		newQuantity, err = s.cartRepo.DecreaseCartItem(retailer.Id, productID, variantID)
 	}
 	if err != nil {
+		if err == repositories.ErrVariantRequired || err == repositories.ErrVariantNotFound {
+			return 0, err
+		}
 		return 0, fmt.Errorf("service error adding cart item: %w", err)
 	}
 
 	return newQuantity, nil
 }
 
-func (s *RetailerCartService) RemoveCartItem(email string, productID int) error {
+func (s *RetailerCartService) RemoveCartItem(email string, productID int, variantID int) error {
 	retailer, err := s.retailersRepo.GetRetailerByEmail(email)
 	if err != nil {
 		return fmt.Errorf("service error fetching retailer: %w", err)
 	}
 
-	err = s.cartRepo.RemoveCartItem(retailer.Id, productID)
+	err = s.cartRepo.RemoveCartItem(retailer.Id, productID, variantID)
 	if err != nil {
 		if err == repositories.ErrRetailerCartItemNotFound {
 			return err
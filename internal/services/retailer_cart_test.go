@@ -0,0 +1,173 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestWholesalerProduct_PriceFor(t *testing.T) {
+	product := models.WholesalerProduct{
+		Price:      1000,
+		PriceTiers: models.PriceTiers{{MinQty: 10, Price: 900}, {MinQty: 50, Price: 800}},
+	}
+
+	tests := []struct {
+		qty  int
+		want models.Money
+	}{
+		{1, 1000},
+		{9, 1000},
+		{10, 900},
+		{49, 900},
+		{50, 800},
+		{500, 800},
+	}
+	for _, tt := range tests {
+		if got := product.PriceFor(tt.qty); got != tt.want {
+			t.Errorf("PriceFor(%d) = %d, want %d", tt.qty, got, tt.want)
+		}
+	}
+}
+
+func TestRetailerCartService_PriceCart(t *testing.T) {
+	wholesalersRepo := &MockWholesalersRepo{
+		GetWholesalerByIDFunc: func(id int) (*models.Wholesaler, error) {
+			return &models.Wholesaler{Id: id, BusinessName: "Optics Depot", MinOrderValue: 50000}, nil
+		},
+	}
+	service := NewRetailerCartService(nil, &MockRetailersRepo{}, wholesalersRepo, &MockPriceListsRepo{GetPriceRulesFunc: noPriceRules}, &MockCatalogAccessRepo{})
+
+	eyepiece := models.WholesalerProduct{
+		Id: 1, Wholesaler_id: 7, Name: "Eyepiece", Price: 1000, MinOrderQty: 10,
+		PriceTiers: models.PriceTiers{{MinQty: 20, Price: 900}},
+	}
+	filter := models.WholesalerProduct{Id: 2, Wholesaler_id: 7, Name: "Filter", Price: 500, MinOrderQty: 1}
+
+	t.Run("quantity breaks and unmet minimums", func(t *testing.T) {
+		cart, err := service.PriceCart([]models.RetailerCartItem{
+			{Product_id: 1, Quantity: 6, Product: eyepiece},
+			{Product_id: 2, Quantity: 2, Product: filter},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cart.Items[0].Unit_price != 1000 || cart.Total != 7000 {
+			t.Errorf("Unexpected pricing: unit %d, total %d", cart.Items[0].Unit_price, cart.Total)
+		}
+		if len(cart.Issues) != 2 {
+			t.Fatalf("Expected 2 issues, got %+v", cart.Issues)
+		}
+		if issue := cart.Issues[0]; issue.Type != models.MinimumOrderQty || issue.ProductId != 1 || issue.Quantity != 6 || issue.MinQty != 10 {
+			t.Errorf("Unexpected quantity issue: %+v", issue)
+		}
+		if issue := cart.Issues[1]; issue.Type != models.MinimumOrderValue || issue.WholesalerId != 7 || issue.Value != 7000 || issue.MinValue != 50000 {
+			t.Errorf("Unexpected value issue: %+v", issue)
+		}
+	})
+
+	t.Run("minimums met at a quantity break", func(t *testing.T) {
+		cart, err := service.PriceCart([]models.RetailerCartItem{
+			{Product_id: 1, Quantity: 60, Product: eyepiece},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cart.Items[0].Unit_price != 900 || cart.Total != 54000 {
+			t.Errorf("Unexpected pricing: unit %d, total %d", cart.Items[0].Unit_price, cart.Total)
+		}
+		if len(cart.Issues) != 0 {
+			t.Errorf("Expected no issues, got %+v", cart.Issues)
+		}
+	})
+}
+
+func TestRetailerCartService_PriceCart_NegotiatedPrices(t *testing.T) {
+	wholesalersRepo := &MockWholesalersRepo{
+		GetWholesalerByIDFunc: func(id int) (*models.Wholesaler, error) {
+			return &models.Wholesaler{Id: id, MinOrderValue: 10000}, nil
+		},
+	}
+	priceListsRepo := &MockPriceListsRepo{
+		GetPriceRulesFunc: func(retailerID int, productIDs []int) ([]models.PriceRule, error) {
+			return []models.PriceRule{{PriceList: "Gold", ProductId: 1, Price: 950}}, nil
+		},
+	}
+	service := NewRetailerCartService(nil, &MockRetailersRepo{}, wholesalersRepo, priceListsRepo, &MockCatalogAccessRepo{})
+
+	product := models.WholesalerProduct{
+		Id: 1, Wholesaler_id: 7, Price: 1000, MinOrderQty: 1,
+		PriceTiers: models.PriceTiers{{MinQty: 20, Price: 900}},
+	}
+
+	// Below the quantity break the negotiated price applies; from it on the cheaper tier does
+	cart, err := service.PriceCart([]models.RetailerCartItem{{Retailer_id: 3, Product_id: 1, Quantity: 10, Product: product}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cart.Items[0].Unit_price != 950 || cart.Total != 9500 {
+		t.Errorf("Unexpected pricing: unit %d, total %d", cart.Items[0].Unit_price, cart.Total)
+	}
+	if len(cart.Issues) != 1 || cart.Issues[0].Value != 9500 {
+		t.Errorf("Expected the minimum order value to be held against negotiated prices, got %+v", cart.Issues)
+	}
+
+	cart, err = service.PriceCart([]models.RetailerCartItem{{Retailer_id: 3, Product_id: 1, Quantity: 20, Product: product}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cart.Items[0].Unit_price != 900 {
+		t.Errorf("Expected the tier price, got %d", cart.Items[0].Unit_price)
+	}
+}
+
+func TestOrdersService_CreateRetailerCheckout_MinimumsNotMet(t *testing.T) {
+	// The repo must not be reached: a checkout that misses a minimum reserves nothing
+	service := NewOrdersService(&MockOrdersRepo{}, CartService{}, RetailerCartService{}, nil, nil, &MockUsersRepo{}, &MockRetailersRepo{}, &MockWholesalersRepo{}, nil, nil, nil)
+	service.retailerCartService = *NewRetailerCartService(&MockRetailerCartRepo{
+		GetCartItemsByRetailerIDFunc: func(retailerID int) ([]models.RetailerCartItem, error) {
+			return []models.RetailerCartItem{{Product_id: 1, Quantity: 2, Product: models.WholesalerProduct{Id: 1, Wholesaler_id: 7, Name: "Eyepiece", Price: 1000, MinOrderQty: 5}}}, nil
+		},
+	}, &MockRetailersRepo{}, &MockWholesalersRepo{
+		GetWholesalerByIDFunc: func(id int) (*models.Wholesaler, error) {
+			return &models.Wholesaler{Id: id}, nil
+		},
+	}, &MockPriceListsRepo{GetPriceRulesFunc: noPriceRules}, &MockCatalogAccessRepo{})
+
+	_, err := service.CreateRetailerCheckout(3, "https://example.com/ok", "https://example.com/cancel")
+	minimumErr, ok := err.(*MinimumOrderError)
+	if !ok {
+		t.Fatalf("Expected MinimumOrderError, got %v", err)
+	}
+	if len(minimumErr.Issues) != 1 || minimumErr.Issues[0].ProductId != 1 {
+		t.Errorf("Unexpected issues: %+v", minimumErr.Issues)
+	}
+}
+
+// MockRetailerCartRepo is a mock implementation of IRetailerCartRepo
+type MockRetailerCartRepo struct {
+	GetCartItemsByRetailerIDFunc func(retailerID int) ([]models.RetailerCartItem, error)
+}
+
+func (m *MockRetailerCartRepo) GetCartItemsByRetailerID(retailerID int) ([]models.RetailerCartItem, error) {
+	if m.GetCartItemsByRetailerIDFunc != nil {
+		return m.GetCartItemsByRetailerIDFunc(retailerID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockRetailerCartRepo) AddCartItem(retailerID int, productID int, variantID int, quantity int) (int, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (m *MockRetailerCartRepo) RemoveCartItem(retailerID int, productID int, variantID int) error {
+	return errors.New("not implemented")
+}
+
+func (m *MockRetailerCartRepo) DecreaseCartItem(retailerID int, productID int, variantID int) (int, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (m *MockRetailerCartRepo) GetCartNumber(retailerID int) (int, error) {
+	return 0, errors.New("not implemented")
+}
@@ -40,7 +40,9 @@ func (s *RetailersService) GetRetailerByEmail(email string) (*models.Retailer, e
 	return retailer, nil
 }
 
-func (s *RetailersService) UpdateRetailer(email string, businessName, phone, address string) (*models.Retailer, error) {
+// UpdateRetailer saves the profile of the retailer with the given email. An empty currency keeps the
+// current one; a different one is refused with ErrCurrencyInUse once products or orders exist.
+func (s *RetailersService) UpdateRetailer(email string, businessName, phone, address, currency string) (*models.Retailer, error) {
 	// First, get the current retailer to preserve the name (which comes from Google OAuth)
 	currentRetailer, err := s.GetRetailerByEmail(email)
 	if err != nil {
@@ -53,11 +55,12 @@ func (s *RetailersService) UpdateRetailer(email string, businessName, phone, add
 		BusinessName: businessName,
 		Phone:        phone,
 		Address:      address,
+		Currency:     currency,
 	}
 
 	err = s.retailersRepo.UpdateRetailer(retailer)
 	if err != nil {
-		if err == repositories.ErrRetailerNotFound {
+		if err == repositories.ErrRetailerNotFound || err == repositories.ErrCurrencyInUse {
 			return &models.Retailer{}, err
 		}
 		return &models.Retailer{}, fmt.Errorf("service error updating retailer: %w", err)
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultSalesWindowDays = 30
+	maxSalesWindowDays     = 365
+	defaultCoverDays       = 14
+	maxCoverDays           = 180
+)
+
+var ErrInvalidReorderWindow = errors.New("sales window must be 1 to 365 days and cover 1 to 180 days")
+
+type StockAlertsService struct {
+	stockAlertsRepo       repositories.IStockAlertsRepo
+	wholesaleProductsRepo repositories.IWholesalerProductRepository
+	retailerCartService   RetailerCartService
+	emailService          *EmailService
+}
+
+func NewStockAlertsService(stockAlertsRepo repositories.IStockAlertsRepo, wholesaleProductsRepo repositories.IWholesalerProductRepository, retailerCartService RetailerCartService, emailService *EmailService) *StockAlertsService {
+	return &StockAlertsService{
+		stockAlertsRepo:       stockAlertsRepo,
+		wholesaleProductsRepo: wholesaleProductsRepo,
+		retailerCartService:   retailerCartService,
+		emailService:          emailService,
+	}
+}
+
+// SendLowStockAlerts emails every retailer a list of its products that have fallen below their
+// reorder threshold since it was last alerted, and returns how many retailers it emailed. A
+// retailer whose email fails is left unmarked so the next run tries again; the failures are
+// returned together once every retailer has been tried.
+func (s *StockAlertsService) SendLowStockAlerts() (int, error) {
+	if err := s.stockAlertsRepo.ClearRecoveredAlerts(); err != nil {
+		return 0, fmt.Errorf("service error clearing stock alerts: %w", err)
+	}
+
+	products, err := s.stockAlertsRepo.GetLowStockProducts()
+	if err != nil {
+		return 0, fmt.Errorf("service error fetching low stock products: %w", err)
+	}
+
+	sent := 0
+	var errs []error
+	for start := 0; start < len(products); {
+		end := start
+		for end < len(products) && products[end].RetailerId == products[start].RetailerId {
+			end++
+		}
+		batch := products[start:end]
+		start = end
+
+		if err := s.emailService.SendEmail(batch[0].RetailerEmail, "Products running low on stock", lowStockEmailBody(batch)); err != nil {
+			errs = append(errs, fmt.Errorf("retailer %d: %w", batch[0].RetailerId, err))
+			continue
+		}
+
+		productIDs := make([]int, len(batch))
+		for i, product := range batch {
+			productIDs[i] = product.ProductId
+		}
+		if err := s.stockAlertsRepo.MarkLowStockAlerted(productIDs); err != nil {
+			errs = append(errs, fmt.Errorf("service error marking stock alerts: %w", err))
+			continue
+		}
+		sent++
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+func lowStockEmailBody(products []models.LowStockProduct) string {
+	var b strings.Builder
+	b.WriteString("These products have fallen below their reorder threshold:\n\n")
+	for _, product := range products {
+		name := product.Name
+		if product.Sku != "" {
+			name += " (" + product.Sku + ")"
+		}
+		fmt.Fprintf(&b, "- %s: %d available, threshold %d\n", name, product.Available, product.ReorderThreshold)
+	}
+	b.WriteString("\nSee your reorder suggestions to restock them from your wholesalers.")
+	return b.String()
+}
+
+// GetReorderSuggestions proposes a retailer cart that restocks the retailer's linked products,
+// sized so each stays at its reorder threshold for coverDays days of sales at the rate of the
+// last salesWindowDays days. Zero takes the defaults. Each line is raised to the wholesale
+// product's minimum order quantity, and sources the retailer can no longer see are skipped.
+// Sources sold in variants cannot go in the cart as they are, so they are listed apart.
+func (s *StockAlertsService) GetReorderSuggestions(retailerID int, salesWindowDays int, coverDays int) (*models.ReorderProposal, error) {
+	if salesWindowDays == 0 {
+		salesWindowDays = defaultSalesWindowDays
+	}
+	if coverDays == 0 {
+		coverDays = defaultCoverDays
+	}
+	if salesWindowDays < 1 || salesWindowDays > maxSalesWindowDays || coverDays < 1 || coverDays > maxCoverDays {
+		return nil, ErrInvalidReorderWindow
+	}
+
+	candidates, err := s.stockAlertsRepo.GetReorderCandidates(retailerID, salesWindowDays)
+	if err != nil {
+		return nil, fmt.Errorf("service error fetching reorder candidates: %w", err)
+	}
+
+	proposal := &models.ReorderProposal{Suggestions: []models.ReorderSuggestion{}, NeedsVariant: []models.ReorderSuggestion{}}
+	items := []models.RetailerCartItem{}
+	for _, candidate := range candidates {
+		quantity := candidate.SuggestedQty(coverDays)
+		if quantity == 0 {
+			continue
+		}
+
+		source, err := s.wholesaleProductsRepo.GetProduct(candidate.SourceProductId, retailerID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrWholesalerProductNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("service error fetching wholesale product: %w", err)
+		}
+		quantity = max(quantity, source.MinOrderQty)
+
+		suggestion := models.ReorderSuggestion{ReorderCandidate: candidate, Quantity: quantity}
+		if candidate.SourceHasVariants {
+			proposal.NeedsVariant = append(proposal.NeedsVariant, suggestion)
+			continue
+		}
+		proposal.Suggestions = append(proposal.Suggestions, suggestion)
+		items = append(items, models.RetailerCartItem{
+			Retailer_id: retailerID,
+			Product_id:  source.Id,
+			Quantity:    quantity,
+			Product:     *source,
+		})
+	}
+
+	proposal.Cart, err = s.retailerCartService.PriceCart(items)
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"testing"
+)
+
+// MockStockAlertsRepo is a mock implementation of IStockAlertsRepo
+type MockStockAlertsRepo struct {
+	ClearRecoveredAlertsFunc func() error
+	GetLowStockProductsFunc  func() ([]models.LowStockProduct, error)
+	MarkLowStockAlertedFunc  func(productIDs []int) error
+	GetReorderCandidatesFunc func(retailerID int, salesWindowDays int) ([]models.ReorderCandidate, error)
+}
+
+func (m *MockStockAlertsRepo) ClearRecoveredAlerts() error {
+	if m.ClearRecoveredAlertsFunc != nil {
+		return m.ClearRecoveredAlertsFunc()
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockStockAlertsRepo) GetLowStockProducts() ([]models.LowStockProduct, error) {
+	if m.GetLowStockProductsFunc != nil {
+		return m.GetLowStockProductsFunc()
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockStockAlertsRepo) MarkLowStockAlerted(productIDs []int) error {
+	if m.MarkLowStockAlertedFunc != nil {
+		return m.MarkLowStockAlertedFunc(productIDs)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockStockAlertsRepo) GetReorderCandidates(retailerID int, salesWindowDays int) ([]models.ReorderCandidate, error) {
+	if m.GetReorderCandidatesFunc != nil {
+		return m.GetReorderCandidatesFunc(retailerID, salesWindowDays)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func TestReorderCandidate_SuggestedQty(t *testing.T) {
+	tests := []struct {
+		name      string
+		candidate models.ReorderCandidate
+		coverDays int
+		expected  int
+	}{
+		{name: "covers threshold and demand", candidate: models.ReorderCandidate{ReorderThreshold: 5, Available: 2, SoldQty: 30, SalesWindowDays: 30}, coverDays: 14, expected: 17},
+		{name: "demand rounds up", candidate: models.ReorderCandidate{SoldQty: 1, SalesWindowDays: 30}, coverDays: 14, expected: 1},
+		{name: "incoming stock counts", candidate: models.ReorderCandidate{ReorderThreshold: 5, Available: 2, IncomingQty: 10, SoldQty: 30, SalesWindowDays: 30}, coverDays: 14, expected: 7},
+		{name: "well stocked", candidate: models.ReorderCandidate{ReorderThreshold: 5, Available: 40, SoldQty: 30, SalesWindowDays: 30}, coverDays: 14, expected: 0},
+		{name: "no sales and no threshold", candidate: models.ReorderCandidate{SalesWindowDays: 30}, coverDays: 14, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.candidate.SuggestedQty(tt.coverDays); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStockAlertsService_SendLowStockAlerts(t *testing.T) {
+	t.Run("failed email leaves products unmarked", func(t *testing.T) {
+		marked := false
+		repo := &MockStockAlertsRepo{
+			ClearRecoveredAlertsFunc: func() error { return nil },
+			GetLowStockProductsFunc: func() ([]models.LowStockProduct, error) {
+				return []models.LowStockProduct{
+					{RetailerId: 1, RetailerEmail: "shop@example.com", ProductId: 10, Name: "Eyepiece", Available: 1, ReorderThreshold: 3},
+					{RetailerId: 1, RetailerEmail: "shop@example.com", ProductId: 11, Name: "Filter", Available: 0, ReorderThreshold: 2},
+				}, nil
+			},
+			MarkLowStockAlertedFunc: func(productIDs []int) error {
+				marked = true
+				return nil
+			},
+		}
+		// Without an API token the email service fails before sending anything
+		service := NewStockAlertsService(repo, &MockWholesalerProductRepository{}, RetailerCartService{}, NewEmailService(""))
+
+		sent, err := service.SendLowStockAlerts()
+		if err == nil {
+			t.Error("Expected the email failure to be returned")
+		}
+		if sent != 0 || marked {
+			t.Errorf("Expected nothing sent or marked, got sent %d, marked %v", sent, marked)
+		}
+	})
+
+	t.Run("nothing low", func(t *testing.T) {
+		repo := &MockStockAlertsRepo{
+			ClearRecoveredAlertsFunc: func() error { return nil },
+			GetLowStockProductsFunc:  func() ([]models.LowStockProduct, error) { return nil, nil },
+		}
+		service := NewStockAlertsService(repo, &MockWholesalerProductRepository{}, RetailerCartService{}, NewEmailService(""))
+
+		sent, err := service.SendLowStockAlerts()
+		if err != nil || sent != 0 {
+			t.Errorf("Expected no alerts and no error, got %d, %v", sent, err)
+		}
+	})
+}
+
+func TestLowStockEmailBody(t *testing.T) {
+	body := lowStockEmailBody([]models.LowStockProduct{
+		{Name: "Eyepiece", Sku: "EP-25", Available: 1, ReorderThreshold: 3},
+	})
+	expected := "- Eyepiece (EP-25): 1 available, threshold 3\n"
+	if !contains(body, expected) {
+		t.Errorf("Expected body to contain %q, got %q", expected, body)
+	}
+}
+
+func TestStockAlertsService_GetReorderSuggestions(t *testing.T) {
+	repo := &MockStockAlertsRepo{
+		GetReorderCandidatesFunc: func(retailerID int, salesWindowDays int) ([]models.ReorderCandidate, error) {
+			if salesWindowDays != defaultSalesWindowDays {
+				t.Errorf("Expected the default sales window, got %d", salesWindowDays)
+			}
+			return []models.ReorderCandidate{
+				{ProductId: 1, Name: "Eyepiece", SourceProductId: 21, ReorderThreshold: 5, Available: 2, SalesWindowDays: salesWindowDays},
+				{ProductId: 2, Name: "Filter", SourceProductId: 22, ReorderThreshold: 5, Available: 40, SalesWindowDays: salesWindowDays},
+				{ProductId: 3, Name: "Mount", SourceProductId: 23, ReorderThreshold: 2, SalesWindowDays: salesWindowDays},
+				{ProductId: 4, Name: "T-shirt", SourceProductId: 24, ReorderThreshold: 3, SalesWindowDays: salesWindowDays, SourceHasVariants: true},
+			}, nil
+		},
+	}
+	wholesaleRepo := &MockWholesalerProductRepository{
+		GetProductFunc: func(id int, viewerID int) (*models.WholesalerProduct, error) {
+			if id == 23 {
+				return nil, repositories.ErrWholesalerProductNotFound
+			}
+			return &models.WholesalerProduct{Id: id, Wholesaler_id: 7, Name: "Eyepiece", Price: 1000, MinOrderQty: 10}, nil
+		},
+	}
+	cartService := *NewRetailerCartService(nil, &MockRetailersRepo{}, &MockWholesalersRepo{
+		GetWholesalerByIDFunc: func(id int) (*models.Wholesaler, error) {
+			return &models.Wholesaler{Id: id}, nil
+		},
+	}, &MockPriceListsRepo{GetPriceRulesFunc: noPriceRules}, &MockCatalogAccessRepo{})
+	service := NewStockAlertsService(repo, wholesaleRepo, cartService, NewEmailService(""))
+
+	proposal, err := service.GetReorderSuggestions(5, 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(proposal.Suggestions) != 1 || proposal.Suggestions[0].ProductId != 1 {
+		t.Fatalf("Expected only the eyepiece to be suggested, got %+v", proposal.Suggestions)
+	}
+	// 3 units would do, but the wholesaler sells no fewer than 10
+	if proposal.Suggestions[0].Quantity != 10 {
+		t.Errorf("Expected the minimum order quantity, got %d", proposal.Suggestions[0].Quantity)
+	}
+	if len(proposal.Cart.Items) != 1 || proposal.Cart.Total != 10000 {
+		t.Errorf("Unexpected cart: %+v", proposal.Cart)
+	}
+	// The retailer has to choose which of the T-shirt's variants to order, so it is not in the cart
+	if len(proposal.NeedsVariant) != 1 || proposal.NeedsVariant[0].ProductId != 4 || proposal.NeedsVariant[0].Quantity != 10 {
+		t.Errorf("Expected the T-shirt to need a variant, got %+v", proposal.NeedsVariant)
+	}
+
+	if _, err := service.GetReorderSuggestions(5, 400, 0); !errors.Is(err, ErrInvalidReorderWindow) {
+		t.Errorf("Expected ErrInvalidReorderWindow, got %v", err)
+	}
+}
@@ -1,41 +1,129 @@
 package services
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/stripe/stripe-go/v79"
 	"github.com/stripe/stripe-go/v79/checkout/session"
+	"github.com/stripe/stripe-go/v79/refund"
 	"github.com/stripe/stripe-go/v79/webhook"
 )
 
+// StripeService is the Stripe implementation of PaymentGateway.
 type StripeService struct {
-	secretKey string
+	secretKey     string
+	webhookSecret string
 }
 
-func NewStripeService(secretKey string) *StripeService {
+func NewStripeService(secretKey, webhookSecret string) *StripeService {
 	stripe.Key = secretKey
-	return &StripeService{secretKey: secretKey}
+	return &StripeService{secretKey: secretKey, webhookSecret: webhookSecret}
 }
 
-func (s *StripeService) CreateCheckoutSession(lineItems []*stripe.CheckoutSessionLineItemParams, successURL, cancelURL, clientReferenceID string, metadata map[string]string) (string, string, error) {
+func (s *StripeService) CreateCheckoutSession(req CheckoutSessionRequest) (*CheckoutSession, error) {
+	lineItems := make([]*stripe.CheckoutSessionLineItemParams, len(req.LineItems))
+	for i, item := range req.LineItems {
+		lineItems[i] = &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency: stripe.String(item.Currency),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name: stripe.String(item.Name),
+				},
+				UnitAmount: stripe.Int64(item.UnitAmount),
+			},
+			Quantity: stripe.Int64(item.Quantity),
+		}
+	}
+
 	params := &stripe.CheckoutSessionParams{
 		PaymentMethodTypes: stripe.StringSlice([]string{
 			"card",
 		}),
 		LineItems:         lineItems,
 		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
-		SuccessURL:        stripe.String(successURL),
-		CancelURL:         stripe.String(cancelURL),
-		ClientReferenceID: stripe.String(clientReferenceID),
-		Metadata:          metadata,
+		SuccessURL:        stripe.String(req.SuccessURL),
+		CancelURL:         stripe.String(req.CancelURL),
+		ClientReferenceID: stripe.String(req.ClientReferenceID),
+		Metadata:          req.Metadata,
 	}
 
 	sess, err := session.New(params)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+
+	return &CheckoutSession{ID: sess.ID, URL: sess.URL}, nil
+}
+
+// ParseWebhookEvent verifies the Stripe-Signature header of a webhook delivery and translates
+// the checkout and charge events that affect orders.
+func (s *StripeService) ParseWebhookEvent(payload []byte, header string) (*PaymentEvent, error) {
+	event, err := webhook.ConstructEvent(payload, header, s.webhookSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PaymentEvent{ID: event.ID, Type: PaymentEventIgnored}
+
+	switch event.Type {
+	case stripe.EventTypeCheckoutSessionCompleted,
+		stripe.EventTypeCheckoutSessionAsyncPaymentSucceeded,
+		stripe.EventTypeCheckoutSessionAsyncPaymentFailed,
+		stripe.EventTypeCheckoutSessionExpired:
+		var sess stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &sess); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkout session: %w", err)
+		}
+		result.SessionID = sess.ID
+		if sess.PaymentIntent != nil {
+			result.PaymentIntentID = sess.PaymentIntent.ID
+		}
+
+		switch event.Type {
+		case stripe.EventTypeCheckoutSessionCompleted:
+			// Delayed payment methods complete the session before the money arrives
+			if sess.PaymentStatus == stripe.CheckoutSessionPaymentStatusUnpaid {
+				result.Type = PaymentEventCheckoutAwaitingPayment
+			} else {
+				result.Type = PaymentEventCheckoutPaid
+			}
+		case stripe.EventTypeCheckoutSessionAsyncPaymentSucceeded:
+			result.Type = PaymentEventCheckoutPaid
+		case stripe.EventTypeCheckoutSessionAsyncPaymentFailed:
+			result.Type = PaymentEventCheckoutFailed
+		case stripe.EventTypeCheckoutSessionExpired:
+			result.Type = PaymentEventCheckoutExpired
+		}
+
+	case stripe.EventTypeChargeRefunded:
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal charge: %w", err)
+		}
+		result.Type = PaymentEventPaymentRefunded
+		result.FullyRefunded = charge.Refunded
+		if charge.PaymentIntent != nil {
+			result.PaymentIntentID = charge.PaymentIntent.ID
+		}
 	}
 
-	return sess.ID, sess.URL, nil
+	return result, nil
 }
 
-func (s *StripeService) ConstructEvent(payload []byte, header string, secret string) (stripe.Event, error) {
-	return webhook.ConstructEvent(payload, header, secret)
+// RefundPayment refunds amount, in the smallest currency unit, of a captured payment intent
+// and returns the Stripe refund ID.
+func (s *StripeService) RefundPayment(paymentIntentID string, amount int64, metadata map[string]string) (string, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+		Amount:        stripe.Int64(amount),
+		Metadata:      metadata,
+	}
+
+	ref, err := refund.New(params)
+	if err != nil {
+		return "", err
+	}
+
+	return ref.ID, nil
 }
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"Obsonarium-backend/internal/models"
+	"Obsonarium-backend/internal/repositories"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stripe/stripe-go/v79/webhook"
+)
+
+const testWebhookSecret = "whsec_test_secret"
+
+// MockStripeEventsRepo is a mock implementation of IStripeEventsRepo
+type MockStripeEventsRepo struct {
+	ClaimEventFunc    func(eventID string, eventType string) (repositories.EventClaim, error)
+	CompleteEventFunc func(eventID string) error
+	ReleaseEventFunc  func(eventID string) error
+}
+
+func (m *MockStripeEventsRepo) ClaimEvent(eventID string, eventType string) (repositories.EventClaim, error) {
+	if m.ClaimEventFunc != nil {
+		return m.ClaimEventFunc(eventID, eventType)
+	}
+	return repositories.EventInProgress, errors.New("not implemented")
+}
+
+func (m *MockStripeEventsRepo) CompleteEvent(eventID string) error {
+	if m.CompleteEventFunc != nil {
+		return m.CompleteEventFunc(eventID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockStripeEventsRepo) ReleaseEvent(eventID string) error {
+	if m.ReleaseEventFunc != nil {
+		return m.ReleaseEventFunc(eventID)
+	}
+	return errors.New("not implemented")
+}
+
+// newSeenEventsRepo returns a MockStripeEventsRepo that tracks claimed and processed event IDs
+// like the stripe_events table does.
+func newSeenEventsRepo() *MockStripeEventsRepo {
+	processed := map[string]bool{}
+	return &MockStripeEventsRepo{
+		ClaimEventFunc: func(eventID string, eventType string) (repositories.EventClaim, error) {
+			done, seen := processed[eventID]
+			switch {
+			case !seen:
+				processed[eventID] = false
+				return repositories.EventClaimed, nil
+			case done:
+				return repositories.EventProcessed, nil
+			default:
+				return repositories.EventInProgress, nil
+			}
+		},
+		CompleteEventFunc: func(eventID string) error {
+			processed[eventID] = true
+			return nil
+		},
+		ReleaseEventFunc: func(eventID string) error {
+			delete(processed, eventID)
+			return nil
+		},
+	}
+}
+
+// signedFixture loads a webhook payload from testdata and signs it the way Stripe would.
+func signedFixture(t *testing.T, name string) ([]byte, string) {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "stripe_events", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", name, err)
+	}
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload: payload,
+		Secret:  testWebhookSecret,
+	})
+	return payload, signed.Header
+}
+
+type statusUpdate struct {
+	sessionID string
+	to        models.OrderStatus
+}
+
+// newWebhookTestOrdersRepo returns a MockOrdersRepo that records the session status updates
+// and payment intents written by the webhook handler.
+func newWebhookTestOrdersRepo(consumerUpdates, retailerUpdates *[]statusUpdate, paymentIntents map[string]string) *MockOrdersRepo {
+	return &MockOrdersRepo{
+		UpdateConsumerOrderStatusFunc: func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+			if actor.Type != models.OrderActorSystem {
+				return errors.New("webhook updates must be made by the system actor")
+			}
+			*consumerUpdates = append(*consumerUpdates, statusUpdate{sessionID, to})
+			return nil
+		},
+		UpdateRetailerOrderStatusFunc: func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+			*retailerUpdates = append(*retailerUpdates, statusUpdate{sessionID, to})
+			return nil
+		},
+		SetSessionPaymentIntentFunc: func(sessionID string, paymentIntentID string) error {
+			paymentIntents[sessionID] = paymentIntentID
+			return nil
+		},
+		GetSessionIDByPaymentIntentFunc: func(paymentIntentID string) (string, error) {
+			for sessionID, pi := range paymentIntents {
+				if pi == paymentIntentID {
+					return sessionID, nil
+				}
+			}
+			return "", repositories.ErrOrderNotFound
+		},
+		GetConsumerOrderBySessionIDFunc: func(sessionID string) (*models.ConsumerOrder, error) {
+			return nil, repositories.ErrOrderNotFound
+		},
+		GetConsumerOrdersBySessionIDFunc: func(sessionID string) ([]models.ConsumerOrder, error) {
+			return nil, nil
+		},
+		GetRetailerOrdersBySessionIDFunc: func(sessionID string) ([]models.RetailerOrder, error) {
+			return nil, nil
+		},
+	}
+}
+
+func newWebhookTestOrdersService(ordersRepo *MockOrdersRepo, eventsRepo *MockStripeEventsRepo) *OrdersService {
+	return NewOrdersService(ordersRepo, CartService{}, RetailerCartService{}, NewStripeService("sk_test_dummy", testWebhookSecret), NewEmailService(""), &MockUsersRepo{}, &MockRetailersRepo{}, &MockWholesalersRepo{}, eventsRepo, nil, nil)
+}
+
+func TestOrdersService_HandlePaymentWebhook(t *testing.T) {
+	tests := []struct {
+		name                   string
+		fixture                string
+		paymentIntents         map[string]string
+		expectedUpdates        []statusUpdate
+		expectedPaymentIntents map[string]string
+	}{
+		{
+			name:                   "completed session is paid",
+			fixture:                "checkout_session_completed.json",
+			paymentIntents:         map[string]string{},
+			expectedUpdates:        []statusUpdate{{"cs_test_123", models.OrderStatusPaid}},
+			expectedPaymentIntents: map[string]string{"cs_test_123": "pi_test_123"},
+		},
+		{
+			name:                   "completed session awaiting async payment stays pending",
+			fixture:                "checkout_session_completed_unpaid.json",
+			paymentIntents:         map[string]string{},
+			expectedUpdates:        nil,
+			expectedPaymentIntents: map[string]string{"cs_test_123": "pi_test_123"},
+		},
+		{
+			name:                   "async payment succeeded is paid",
+			fixture:                "checkout_session_async_payment_succeeded.json",
+			paymentIntents:         map[string]string{},
+			expectedUpdates:        []statusUpdate{{"cs_test_123", models.OrderStatusPaid}},
+			expectedPaymentIntents: map[string]string{"cs_test_123": "pi_test_123"},
+		},
+		{
+			name:                   "async payment failed is failed",
+			fixture:                "checkout_session_async_payment_failed.json",
+			paymentIntents:         map[string]string{},
+			expectedUpdates:        []statusUpdate{{"cs_test_123", models.OrderStatusFailed}},
+			expectedPaymentIntents: map[string]string{},
+		},
+		{
+			name:                   "expired session is failed",
+			fixture:                "checkout_session_expired.json",
+			paymentIntents:         map[string]string{},
+			expectedUpdates:        []statusUpdate{{"cs_test_123", models.OrderStatusFailed}},
+			expectedPaymentIntents: map[string]string{},
+		},
+		{
+			name:                   "full refund is refunded",
+			fixture:                "charge_refunded.json",
+			paymentIntents:         map[string]string{"cs_test_123": "pi_test_123"},
+			expectedUpdates:        []statusUpdate{{"cs_test_123", models.OrderStatusRefunded}},
+			expectedPaymentIntents: map[string]string{"cs_test_123": "pi_test_123"},
+		},
+		{
+			name:                   "partial refund leaves order alone",
+			fixture:                "charge_refunded_partial.json",
+			paymentIntents:         map[string]string{"cs_test_123": "pi_test_123"},
+			expectedUpdates:        nil,
+			expectedPaymentIntents: map[string]string{"cs_test_123": "pi_test_123"},
+		},
+		{
+			name:                   "refund of unknown payment intent is ignored",
+			fixture:                "charge_refunded.json",
+			paymentIntents:         map[string]string{},
+			expectedUpdates:        nil,
+			expectedPaymentIntents: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var consumerUpdates, retailerUpdates []statusUpdate
+			ordersRepo := newWebhookTestOrdersRepo(&consumerUpdates, &retailerUpdates, tt.paymentIntents)
+			service := newWebhookTestOrdersService(ordersRepo, newSeenEventsRepo())
+
+			payload, header := signedFixture(t, tt.fixture)
+			if err := service.HandlePaymentWebhook(payload, header); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(consumerUpdates, tt.expectedUpdates) {
+				t.Errorf("Expected consumer updates %v, got %v", tt.expectedUpdates, consumerUpdates)
+			}
+			if !reflect.DeepEqual(retailerUpdates, tt.expectedUpdates) {
+				t.Errorf("Expected retailer updates %v, got %v", tt.expectedUpdates, retailerUpdates)
+			}
+			if !reflect.DeepEqual(tt.paymentIntents, tt.expectedPaymentIntents) {
+				t.Errorf("Expected payment intents %v, got %v", tt.expectedPaymentIntents, tt.paymentIntents)
+			}
+		})
+	}
+}
+
+func TestOrdersService_HandlePaymentWebhook_DuplicateDelivery(t *testing.T) {
+	var consumerUpdates, retailerUpdates []statusUpdate
+	ordersRepo := newWebhookTestOrdersRepo(&consumerUpdates, &retailerUpdates, map[string]string{})
+	service := newWebhookTestOrdersService(ordersRepo, newSeenEventsRepo())
+
+	payload, header := signedFixture(t, "checkout_session_completed.json")
+	for i := 0; i < 2; i++ {
+		if err := service.HandlePaymentWebhook(payload, header); err != nil {
+			t.Fatalf("Delivery %d returned error: %v", i+1, err)
+		}
+	}
+
+	if len(consumerUpdates) != 1 {
+		t.Errorf("Expected the event to be processed once, got %d updates", len(consumerUpdates))
+	}
+}
+
+func TestOrdersService_HandlePaymentWebhook_DeliveryDuringProcessing(t *testing.T) {
+	var consumerUpdates, retailerUpdates []statusUpdate
+	ordersRepo := newWebhookTestOrdersRepo(&consumerUpdates, &retailerUpdates, map[string]string{})
+	service := newWebhookTestOrdersService(ordersRepo, newSeenEventsRepo())
+	payload, header := signedFixture(t, "checkout_session_expired.json")
+
+	// The gateway redelivers the event while the first delivery is still updating the orders,
+	// which then fails
+	var duplicateErr error
+	ordersRepo.UpdateConsumerOrderStatusFunc = func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+		duplicateErr = service.HandlePaymentWebhook(payload, header)
+		return errors.New("database error")
+	}
+	if err := service.HandlePaymentWebhook(payload, header); err == nil {
+		t.Fatal("Expected the first delivery to fail, got nil")
+	}
+	if !errors.Is(duplicateErr, ErrPaymentEventInProgress) {
+		t.Fatalf("Expected the concurrent delivery to be refused, got %v", duplicateErr)
+	}
+
+	// Neither delivery was acknowledged, so the event is delivered again and processed
+	ordersRepo.UpdateConsumerOrderStatusFunc = func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+		consumerUpdates = append(consumerUpdates, statusUpdate{sessionID, to})
+		return nil
+	}
+	if err := service.HandlePaymentWebhook(payload, header); err != nil {
+		t.Fatalf("Retry returned error: %v", err)
+	}
+	if len(consumerUpdates) != 1 || consumerUpdates[0].to != models.OrderStatusFailed {
+		t.Errorf("Expected the retry to fail the orders, got %v", consumerUpdates)
+	}
+}
+
+func TestOrdersService_HandlePaymentWebhook_FailureReleasesEvent(t *testing.T) {
+	var consumerUpdates, retailerUpdates []statusUpdate
+	ordersRepo := newWebhookTestOrdersRepo(&consumerUpdates, &retailerUpdates, map[string]string{})
+	dbDown := true
+	ordersRepo.UpdateConsumerOrderStatusFunc = func(sessionID string, from []models.OrderStatus, to models.OrderStatus, actor models.OrderActor) error {
+		if dbDown {
+			return errors.New("database error")
+		}
+		consumerUpdates = append(consumerUpdates, statusUpdate{sessionID, to})
+		return nil
+	}
+	service := newWebhookTestOrdersService(ordersRepo, newSeenEventsRepo())
+
+	payload, header := signedFixture(t, "checkout_session_expired.json")
+	if err := service.HandlePaymentWebhook(payload, header); err == nil {
+		t.Fatal("Expected error while the database is down, got nil")
+	}
+
+	// Stripe retries the delivery once we answer with an error
+	dbDown = false
+	if err := service.HandlePaymentWebhook(payload, header); err != nil {
+		t.Fatalf("Retry returned error: %v", err)
+	}
+	if len(consumerUpdates) != 1 || consumerUpdates[0].to != models.OrderStatusFailed {
+		t.Errorf("Expected the retry to fail the orders, got %v", consumerUpdates)
+	}
+}
+
+func TestOrdersService_HandlePaymentWebhook_InvalidSignature(t *testing.T) {
+	eventsRepo := &MockStripeEventsRepo{
+		ClaimEventFunc: func(eventID string, eventType string) (repositories.EventClaim, error) {
+			t.Error("ClaimEvent must not be called for an unverified payload")
+			return repositories.EventInProgress, nil
+		},
+	}
+	service := NewOrdersService(&MockOrdersRepo{}, CartService{}, RetailerCartService{}, NewStripeService("sk_test_dummy", "whsec_other_secret"), NewEmailService(""), &MockUsersRepo{}, &MockRetailersRepo{}, &MockWholesalersRepo{}, eventsRepo, nil, nil)
+
+	payload, header := signedFixture(t, "checkout_session_completed.json")
+	if err := service.HandlePaymentWebhook(payload, header); err == nil {
+		t.Fatal("Expected signature error, got nil")
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -14,6 +15,11 @@ const (
 	maxFileSize = 10 * 1024 * 1024 // 10MB
 )
 
+var (
+	ErrInvalidFileExtension = errors.New("invalid file extension")
+	ErrFileTooLarge         = errors.New("file size exceeds maximum allowed size of 10MB")
+)
+
 var allowedExtensions = map[string]bool{
 	".jpg":  true,
 	".jpeg": true,
@@ -22,33 +28,58 @@ var allowedExtensions = map[string]bool{
 }
 
 type UploadService struct {
+	// uploadDir is served under /api/uploads/, with one subdirectory for each kind of image
 	uploadDir string
 }
 
 func NewUploadService() *UploadService {
 	return &UploadService{
-		uploadDir: "./uploads/products",
+		uploadDir: "./uploads",
 	}
 }
 
 func (s *UploadService) SaveProductImage(file multipart.File, header *multipart.FileHeader) (string, error) {
+	return s.saveImage("products", file, header)
+}
+
+// SaveReviewImage stores a photo attached to a product review, checked the same way as product
+// images.
+func (s *UploadService) SaveReviewImage(file multipart.File, header *multipart.FileHeader) (string, error) {
+	return s.saveImage("reviews", file, header)
+}
+
+// DeleteImage removes the uploaded file at a public URL returned by one of the Save methods. A
+// file that is already gone is not an error.
+func (s *UploadService) DeleteImage(publicURL string) error {
+	name, ok := strings.CutPrefix(publicURL, "/api/uploads/")
+	if !ok || !filepath.IsLocal(name) {
+		return fmt.Errorf("not an uploaded file: %s", publicURL)
+	}
+	if err := os.Remove(filepath.Join(s.uploadDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (s *UploadService) saveImage(kind string, file multipart.File, header *multipart.FileHeader) (string, error) {
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	if !allowedExtensions[ext] {
-		return "", fmt.Errorf("invalid file extension: %s. Allowed extensions: .jpg, .jpeg, .png, .webp", ext)
+		return "", fmt.Errorf("%w: %s. Allowed extensions: .jpg, .jpeg, .png, .webp", ErrInvalidFileExtension, ext)
 	}
 
 	// Validate file size
 	if header.Size > maxFileSize {
-		return "", fmt.Errorf("file size exceeds maximum allowed size of 10MB")
+		return "", ErrFileTooLarge
 	}
 
 	// Generate unique filename using UnixNano timestamp
+	dir := filepath.Join(s.uploadDir, kind)
 	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-	filePath := filepath.Join(s.uploadDir, filename)
+	filePath := filepath.Join(dir, filename)
 
 	// Ensure upload directory exists
-	if err := os.MkdirAll(s.uploadDir, 0755); err != nil {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create upload directory: %w", err)
 	}
 
@@ -67,6 +98,6 @@ func (s *UploadService) SaveProductImage(file multipart.File, header *multipart.
 	}
 
 	// Return public URL
-	publicURL := fmt.Sprintf("/api/uploads/products/%s", filename)
+	publicURL := fmt.Sprintf("/api/uploads/%s/%s", kind, filename)
 	return publicURL, nil
 }
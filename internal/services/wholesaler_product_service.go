@@ -3,7 +3,14 @@ package services
 import (
 	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
+	"errors"
 	"fmt"
+	"sort"
+)
+
+var (
+	ErrTierNotCheaper   = errors.New("each quantity break must be cheaper than the price below it")
+	ErrTierBelowMinimum = errors.New("quantity breaks must be above the minimum order quantity")
 )
 
 type WholesalerProductService struct {
@@ -64,3 +71,34 @@ func (s *WholesalerProductService) DeleteProduct(productID int, wholesalerID int
 	}
 	return nil
 }
+
+// SetPricing replaces the minimum order quantity and quantity breaks of one of the wholesaler's
+// products and returns the updated product. Breaks are sorted by quantity and each must lower
+// the unit price. They apply to the product's own price, not to its variants.
+func (s *WholesalerProductService) SetPricing(productID int, wholesalerID int, minOrderQty int, tiers []models.PriceTier) (*models.WholesalerProduct, error) {
+	product, err := s.GetProductByIDForWholesaler(productID, wholesalerID)
+	if err != nil {
+		return &models.WholesalerProduct{}, err
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinQty < tiers[j].MinQty })
+	price := product.Price
+	for _, tier := range tiers {
+		if tier.MinQty <= minOrderQty {
+			return &models.WholesalerProduct{}, ErrTierBelowMinimum
+		}
+		if tier.Price >= price {
+			return &models.WholesalerProduct{}, ErrTierNotCheaper
+		}
+		price = tier.Price
+	}
+
+	if err := s.productRepo.SetPricing(productID, wholesalerID, minOrderQty, tiers); err != nil {
+		if err == repositories.ErrWholesalerProductNotFound {
+			return &models.WholesalerProduct{}, err
+		}
+		return &models.WholesalerProduct{}, fmt.Errorf("service error setting pricing: %w", err)
+	}
+
+	return s.GetProductByIDForWholesaler(productID, wholesalerID)
+}
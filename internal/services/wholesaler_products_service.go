@@ -3,6 +3,7 @@ package services
 import (
 	"Obsonarium-backend/internal/models"
 	"Obsonarium-backend/internal/repositories"
+	"errors"
 	"fmt"
 )
 
@@ -16,18 +17,24 @@ func NewWholesalerProductsService(productsRepo repositories.IWholesalerProductRe
 	}
 }
 
-func (s *WholesalerProductsService) GetProducts(q string) ([]models.WholesalerProduct, error) {
-	if q != "" {
-		// full-text search
-		return s.productsRepo.SearchProducts(q)
+// GetProducts returns one page of the catalog. Invalid cursors and sorts or filters the catalog
+// does not support are returned unwrapped so the handler can report them as bad requests.
+func (s *WholesalerProductsService) GetProducts(filter models.ProductFilter) ([]models.WholesalerProduct, models.PageInfo, error) {
+	products, page, err := s.productsRepo.ListProducts(normalizeProductFilter(filter))
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) || errors.Is(err, repositories.ErrUnsupportedSort) ||
+			errors.Is(err, repositories.ErrUnsupportedFilter) {
+			return nil, models.PageInfo{}, err
+		}
+		return nil, models.PageInfo{}, fmt.Errorf("service error listing products: %w", err)
 	}
 
-	// default 10 products
-	return s.productsRepo.GetProducts()
+	return products, page, nil
 }
 
-func (s *WholesalerProductsService) GetProduct(id int) (*models.WholesalerProduct, error) {
-	product, err := s.productsRepo.GetProduct(id)
+// GetProduct returns a product of the wholesale catalog as viewerID sees it, see ProductFilter.
+func (s *WholesalerProductsService) GetProduct(id int, viewerID int) (*models.WholesalerProduct, error) {
+	product, err := s.productsRepo.GetProduct(id, viewerID)
 	if err != nil {
 		if err == repositories.ErrWholesalerProductNotFound {
 			return &models.WholesalerProduct{}, err
@@ -40,7 +40,10 @@ func (s *WholesalersService) GetWholesalerByEmail(email string) (*models.Wholesa
 	return wholesaler, nil
 }
 
-func (s *WholesalersService) UpdateWholesaler(email string, businessName, phone, address string) (*models.Wholesaler, error) {
+// UpdateWholesaler saves the profile of the wholesaler with the given email. An empty currency keeps the
+// current one, as do a nil minimum order value and a nil catalog visibility. A different currency is
+// refused with ErrCurrencyInUse once anything is priced in the current one.
+func (s *WholesalersService) UpdateWholesaler(email string, businessName, phone, address, currency string, minOrderValue *models.Money, privateCatalog *bool) (*models.Wholesaler, error) {
 	// First, get the current wholesaler to preserve the name (which comes from Google OAuth)
 	currentWholesaler, err := s.GetWholesalerByEmail(email)
 	if err != nil {
@@ -53,11 +56,22 @@ func (s *WholesalersService) UpdateWholesaler(email string, businessName, phone,
 		BusinessName: businessName,
 		Phone:        phone,
 		Address:      address,
+		Currency:     currency,
+	}
+
+	// Keep the current minimum order value unless a new one is given
+	wholesaler.MinOrderValue = currentWholesaler.MinOrderValue
+	if minOrderValue != nil {
+		wholesaler.MinOrderValue = *minOrderValue
+	}
+	wholesaler.PrivateCatalog = currentWholesaler.PrivateCatalog
+	if privateCatalog != nil {
+		wholesaler.PrivateCatalog = *privateCatalog
 	}
 
 	err = s.wholesalersRepo.UpdateWholesaler(wholesaler)
 	if err != nil {
-		if err == repositories.ErrWholesalerNotFound {
+		if err == repositories.ErrWholesalerNotFound || err == repositories.ErrCurrencyInUse {
 			return &models.Wholesaler{}, err
 		}
 		return &models.Wholesaler{}, fmt.Errorf("service error updating wholesaler: %w", err)
@@ -0,0 +1,17 @@
+CREATE TABLE order_status_history (
+    id SERIAL PRIMARY KEY,
+
+    -- 'retailer_orders' or 'wholesaler_orders'
+    order_type TEXT NOT NULL,
+    order_id INT NOT NULL,
+
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+
+    actor_type TEXT NOT NULL, -- consumer, retailer, wholesaler or system
+    actor_id INT,
+
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_status_history_order ON order_status_history(order_type, order_id);
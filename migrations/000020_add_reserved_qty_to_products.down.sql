@@ -0,0 +1,2 @@
+ALTER TABLE wholesaler_products DROP COLUMN reserved_qty;
+ALTER TABLE retailer_products DROP COLUMN reserved_qty;
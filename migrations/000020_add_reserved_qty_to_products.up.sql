@@ -0,0 +1,6 @@
+-- Units held by pending checkouts. Available stock is stock_qty - reserved_qty.
+ALTER TABLE retailer_products
+ADD COLUMN reserved_qty INT NOT NULL DEFAULT 0 CHECK (reserved_qty >= 0);
+
+ALTER TABLE wholesaler_products
+ADD COLUMN reserved_qty INT NOT NULL DEFAULT 0 CHECK (reserved_qty >= 0);
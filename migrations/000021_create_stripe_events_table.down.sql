@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_wholesaler_orders_payment_intent;
+DROP INDEX IF EXISTS idx_retailer_orders_payment_intent;
+
+ALTER TABLE wholesaler_orders DROP COLUMN stripe_payment_intent_id;
+ALTER TABLE retailer_orders DROP COLUMN stripe_payment_intent_id;
+
+DROP TABLE IF EXISTS stripe_events;
@@ -0,0 +1,13 @@
+-- Stripe delivers webhooks at least once; each event ID is processed only once.
+CREATE TABLE stripe_events (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    processed_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Refund events reference the payment intent rather than the checkout session
+ALTER TABLE retailer_orders ADD COLUMN stripe_payment_intent_id TEXT;
+ALTER TABLE wholesaler_orders ADD COLUMN stripe_payment_intent_id TEXT;
+
+CREATE INDEX idx_retailer_orders_payment_intent ON retailer_orders(stripe_payment_intent_id);
+CREATE INDEX idx_wholesaler_orders_payment_intent ON wholesaler_orders(stripe_payment_intent_id);
@@ -0,0 +1,5 @@
+ALTER TABLE wholesaler_order_items DROP COLUMN refunded_qty;
+ALTER TABLE retailer_order_items DROP COLUMN refunded_qty;
+
+DROP TABLE IF EXISTS refund_items;
+DROP TABLE IF EXISTS refunds;
@@ -0,0 +1,35 @@
+CREATE TABLE refunds (
+    id SERIAL PRIMARY KEY,
+
+    -- 'retailer_orders' or 'wholesaler_orders'
+    order_type TEXT NOT NULL,
+    order_id INT NOT NULL,
+
+    amount NUMERIC(10,2) NOT NULL,
+    reason TEXT,
+    restock BOOLEAN NOT NULL DEFAULT FALSE,
+
+    -- pending until the payment provider confirms, then succeeded or failed
+    status TEXT NOT NULL DEFAULT 'pending',
+    stripe_refund_id TEXT,
+
+    actor_type TEXT NOT NULL,
+    actor_id INT,
+
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_refunds_order ON refunds(order_type, order_id);
+
+-- order_item_id points into retailer_order_items or wholesaler_order_items depending on the refund's order_type
+CREATE TABLE refund_items (
+    id SERIAL PRIMARY KEY,
+    refund_id INT NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    order_item_id INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount NUMERIC(10,2) NOT NULL
+);
+
+-- How much of each line has been refunded so far, so it can never be refunded twice
+ALTER TABLE retailer_order_items ADD COLUMN refunded_qty INT NOT NULL DEFAULT 0 CHECK (refunded_qty >= 0 AND refunded_qty <= quantity);
+ALTER TABLE wholesaler_order_items ADD COLUMN refunded_qty INT NOT NULL DEFAULT 0 CHECK (refunded_qty >= 0 AND refunded_qty <= quantity);
@@ -0,0 +1,15 @@
+ALTER TABLE wholesaler_orders DROP COLUMN currency;
+ALTER TABLE retailer_orders DROP COLUMN currency;
+ALTER TABLE wholesaler_products DROP COLUMN currency;
+ALTER TABLE retailer_products DROP COLUMN currency;
+ALTER TABLE wholesalers DROP COLUMN currency;
+ALTER TABLE retailers DROP COLUMN currency;
+
+ALTER TABLE refund_items ALTER COLUMN amount TYPE NUMERIC(10,2) USING amount / 100.0;
+ALTER TABLE refunds ALTER COLUMN amount TYPE NUMERIC(10,2) USING amount / 100.0;
+ALTER TABLE wholesaler_order_items ALTER COLUMN price TYPE NUMERIC(10,2) USING price / 100.0;
+ALTER TABLE retailer_order_items ALTER COLUMN price TYPE NUMERIC(10,2) USING price / 100.0;
+ALTER TABLE wholesaler_orders ALTER COLUMN total_price TYPE NUMERIC(10,2) USING total_price / 100.0;
+ALTER TABLE retailer_orders ALTER COLUMN total_price TYPE NUMERIC(10,2) USING total_price / 100.0;
+ALTER TABLE wholesaler_products ALTER COLUMN price TYPE NUMERIC(10,2) USING price / 100.0;
+ALTER TABLE retailer_products ALTER COLUMN price TYPE NUMERIC(10,2) USING price / 100.0;
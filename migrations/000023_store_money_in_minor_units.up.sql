@@ -0,0 +1,19 @@
+-- Amounts are stored as integers in the currency's minor unit (paise, cents), so 12.50 becomes 1250.
+-- Every supported currency has two decimal places.
+ALTER TABLE retailer_products ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100);
+ALTER TABLE wholesaler_products ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100);
+ALTER TABLE retailer_orders ALTER COLUMN total_price TYPE BIGINT USING ROUND(total_price * 100);
+ALTER TABLE wholesaler_orders ALTER COLUMN total_price TYPE BIGINT USING ROUND(total_price * 100);
+ALTER TABLE retailer_order_items ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100);
+ALTER TABLE wholesaler_order_items ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100);
+ALTER TABLE refunds ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100);
+ALTER TABLE refund_items ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100);
+
+-- Each seller prices in one currency; consumer checkout used to charge INR and wholesale checkout USD
+ALTER TABLE retailers ADD COLUMN currency TEXT NOT NULL DEFAULT 'inr';
+ALTER TABLE wholesalers ADD COLUMN currency TEXT NOT NULL DEFAULT 'usd';
+
+ALTER TABLE retailer_products ADD COLUMN currency TEXT NOT NULL DEFAULT 'inr';
+ALTER TABLE wholesaler_products ADD COLUMN currency TEXT NOT NULL DEFAULT 'usd';
+ALTER TABLE retailer_orders ADD COLUMN currency TEXT NOT NULL DEFAULT 'inr';
+ALTER TABLE wholesaler_orders ADD COLUMN currency TEXT NOT NULL DEFAULT 'usd';
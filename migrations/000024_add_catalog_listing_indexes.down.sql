@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS wholesaler_products_search_idx;
+DROP INDEX IF EXISTS wholesaler_products_price_id_idx;
+DROP INDEX IF EXISTS wholesaler_products_created_at_id_idx;
+DROP INDEX IF EXISTS retailer_products_price_id_idx;
+DROP INDEX IF EXISTS retailer_products_created_at_id_idx;
@@ -0,0 +1,15 @@
+-- Keyset pagination of /api/shop and /api/wholesale orders by (created_at, id) or (price, id)
+CREATE INDEX IF NOT EXISTS retailer_products_created_at_id_idx ON retailer_products (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS retailer_products_price_id_idx ON retailer_products (price, id);
+CREATE INDEX IF NOT EXISTS wholesaler_products_created_at_id_idx ON wholesaler_products (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS wholesaler_products_price_id_idx ON wholesaler_products (price, id);
+
+-- The wholesale catalog is searched with the same expression as retailer_products_search_idx
+CREATE INDEX IF NOT EXISTS wholesaler_products_search_idx
+ON wholesaler_products
+USING GIN (
+    to_tsvector(
+        'simple',
+        name || ' ' || coalesce(description, '')
+    )
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_wholesaler_products_category_id;
+DROP INDEX IF EXISTS idx_retailer_products_category_id;
+
+ALTER TABLE wholesaler_products DROP COLUMN IF EXISTS category_id;
+ALTER TABLE retailer_products DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,41 @@
+-- One category tree is shared by the shop and wholesale catalogs
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INT REFERENCES categories(id) ON DELETE RESTRICT,
+    name TEXT NOT NULL,
+    slug TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+
+ALTER TABLE retailer_products ADD COLUMN category_id INT REFERENCES categories(id) ON DELETE SET NULL;
+ALTER TABLE wholesaler_products ADD COLUMN category_id INT REFERENCES categories(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_retailer_products_category_id ON retailer_products(category_id);
+CREATE INDEX idx_wholesaler_products_category_id ON wholesaler_products(category_id);
+
+INSERT INTO categories (name, slug) VALUES
+    ('Telescopes', 'telescopes'),
+    ('Binoculars', 'binoculars'),
+    ('Mounts & Tripods', 'mounts-tripods'),
+    ('Eyepieces & Accessories', 'eyepieces-accessories'),
+    ('Astrophotography', 'astrophotography'),
+    ('Books & Charts', 'books-charts');
+
+INSERT INTO categories (parent_id, name, slug)
+SELECT p.id, c.name, c.slug
+FROM (VALUES
+    ('telescopes', 'Refractors', 'refractors'),
+    ('telescopes', 'Reflectors', 'reflectors'),
+    ('telescopes', 'Catadioptrics', 'catadioptrics'),
+    ('mounts-tripods', 'Equatorial Mounts', 'equatorial-mounts'),
+    ('mounts-tripods', 'Alt-Azimuth Mounts', 'alt-azimuth-mounts'),
+    ('eyepieces-accessories', 'Eyepieces', 'eyepieces'),
+    ('eyepieces-accessories', 'Filters', 'filters'),
+    ('eyepieces-accessories', 'Barlow Lenses', 'barlow-lenses'),
+    ('astrophotography', 'Cameras', 'astro-cameras'),
+    ('astrophotography', 'Guiding', 'guiding')
+) AS c(parent_slug, name, slug)
+JOIN categories p ON p.slug = c.parent_slug;
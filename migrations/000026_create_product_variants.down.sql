@@ -0,0 +1,17 @@
+ALTER TABLE wholesaler_order_items DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE retailer_order_items DROP COLUMN IF EXISTS variant_id;
+
+DROP INDEX IF EXISTS retailer_cart_items_retailer_product_variant_key;
+DELETE FROM retailer_cart_items WHERE variant_id IS NOT NULL;
+ALTER TABLE retailer_cart_items DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE retailer_cart_items ADD CONSTRAINT retailer_cart_items_retailer_id_product_id_key UNIQUE (retailer_id, product_id);
+
+DROP INDEX IF EXISTS cart_items_user_product_variant_key;
+DELETE FROM cart_items WHERE variant_id IS NOT NULL;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE cart_items ADD CONSTRAINT cart_items_user_id_product_id_key UNIQUE (user_id, product_id);
+
+DROP TABLE IF EXISTS wholesaler_product_variants;
+DROP TABLE IF EXISTS retailer_product_variants;
+DROP TABLE IF EXISTS wholesaler_product_options;
+DROP TABLE IF EXISTS retailer_product_options;
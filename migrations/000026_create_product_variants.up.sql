@@ -0,0 +1,61 @@
+-- Option types a product varies by, e.g. size and colour, in display order
+CREATE TABLE retailer_product_options (
+    product_id INT NOT NULL REFERENCES retailer_products(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (product_id, name)
+);
+
+CREATE TABLE wholesaler_product_options (
+    product_id INT NOT NULL REFERENCES wholesaler_products(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (product_id, name)
+);
+
+-- A variant is one combination of option values, e.g. {"size": "M", "colour": "red"}, with its
+-- own SKU, price and stock. A product with variants is sold only through them. The seller is
+-- copied onto each variant so SKUs can be kept unique within the seller's catalog.
+CREATE TABLE retailer_product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES retailer_products(id) ON DELETE CASCADE,
+    retailer_id INT NOT NULL REFERENCES retailers(id) ON DELETE CASCADE,
+    sku TEXT NOT NULL,
+    options JSONB NOT NULL DEFAULT '{}',
+    price BIGINT NOT NULL CHECK (price > 0),
+    stock_qty INT NOT NULL DEFAULT 0 CHECK (stock_qty >= 0),
+    reserved_qty INT NOT NULL DEFAULT 0 CHECK (reserved_qty >= 0),
+    image_url TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (product_id, options),
+    UNIQUE (retailer_id, sku)
+);
+
+CREATE TABLE wholesaler_product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES wholesaler_products(id) ON DELETE CASCADE,
+    wholesaler_id INT NOT NULL REFERENCES wholesalers(id) ON DELETE CASCADE,
+    sku TEXT NOT NULL,
+    options JSONB NOT NULL DEFAULT '{}',
+    price BIGINT NOT NULL CHECK (price > 0),
+    stock_qty INT NOT NULL DEFAULT 0 CHECK (stock_qty >= 0),
+    reserved_qty INT NOT NULL DEFAULT 0 CHECK (reserved_qty >= 0),
+    image_url TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (product_id, options),
+    UNIQUE (wholesaler_id, sku)
+);
+
+-- Cart lines and order lines name the variant bought; it is NULL for products without variants
+ALTER TABLE cart_items ADD COLUMN variant_id INT REFERENCES retailer_product_variants(id) ON DELETE CASCADE;
+ALTER TABLE cart_items DROP CONSTRAINT cart_items_user_id_product_id_key;
+CREATE UNIQUE INDEX cart_items_user_product_variant_key ON cart_items(user_id, product_id, (COALESCE(variant_id, 0)));
+
+ALTER TABLE retailer_cart_items ADD COLUMN variant_id INT REFERENCES wholesaler_product_variants(id) ON DELETE CASCADE;
+ALTER TABLE retailer_cart_items DROP CONSTRAINT retailer_cart_items_retailer_id_product_id_key;
+CREATE UNIQUE INDEX retailer_cart_items_retailer_product_variant_key ON retailer_cart_items(retailer_id, product_id, (COALESCE(variant_id, 0)));
+
+ALTER TABLE retailer_order_items ADD COLUMN variant_id INT REFERENCES retailer_product_variants(id);
+ALTER TABLE wholesaler_order_items ADD COLUMN variant_id INT REFERENCES wholesaler_product_variants(id);
@@ -0,0 +1,5 @@
+ALTER TABLE wholesaler_products DROP CONSTRAINT IF EXISTS wholesaler_products_wholesaler_id_sku_key;
+ALTER TABLE retailer_products DROP CONSTRAINT IF EXISTS retailer_products_retailer_id_sku_key;
+
+ALTER TABLE wholesaler_products DROP COLUMN IF EXISTS sku;
+ALTER TABLE retailer_products DROP COLUMN IF EXISTS sku;
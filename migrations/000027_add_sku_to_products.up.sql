@@ -0,0 +1,6 @@
+-- A seller-supplied SKU identifies a product across CSV imports. It is optional, but unique per seller
+ALTER TABLE retailer_products ADD COLUMN sku TEXT;
+ALTER TABLE wholesaler_products ADD COLUMN sku TEXT;
+
+ALTER TABLE retailer_products ADD CONSTRAINT retailer_products_retailer_id_sku_key UNIQUE (retailer_id, sku);
+ALTER TABLE wholesaler_products ADD CONSTRAINT wholesaler_products_wholesaler_id_sku_key UNIQUE (wholesaler_id, sku);
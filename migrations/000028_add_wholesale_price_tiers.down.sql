@@ -0,0 +1,4 @@
+ALTER TABLE wholesalers DROP COLUMN IF EXISTS min_order_value;
+ALTER TABLE wholesaler_products DROP COLUMN IF EXISTS min_order_qty;
+
+DROP TABLE IF EXISTS wholesaler_product_price_tiers;
@@ -0,0 +1,12 @@
+-- Quantity breaks: buying at least min_qty units of a product in one order lowers its unit price
+CREATE TABLE wholesaler_product_price_tiers (
+    product_id INT NOT NULL REFERENCES wholesaler_products(id) ON DELETE CASCADE,
+    min_qty INT NOT NULL CHECK (min_qty > 1),
+    price BIGINT NOT NULL CHECK (price > 0),
+    PRIMARY KEY (product_id, min_qty)
+);
+
+ALTER TABLE wholesaler_products ADD COLUMN min_order_qty INT NOT NULL DEFAULT 1 CHECK (min_order_qty >= 1);
+
+-- 0 means the wholesaler has no minimum order value
+ALTER TABLE wholesalers ADD COLUMN min_order_value BIGINT NOT NULL DEFAULT 0 CHECK (min_order_value >= 0);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS price_list_groups;
+DROP TABLE IF EXISTS price_list_retailers;
+DROP TABLE IF EXISTS price_list_items;
+DROP TABLE IF EXISTS price_lists;
+DROP TABLE IF EXISTS retailer_group_members;
+DROP TABLE IF EXISTS retailer_groups;
@@ -0,0 +1,55 @@
+-- Retailer groups let a wholesaler give the same terms to several retailers at once
+CREATE TABLE retailer_groups (
+    id SERIAL PRIMARY KEY,
+    wholesaler_id INT NOT NULL REFERENCES wholesalers(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT retailer_groups_wholesaler_id_name_key UNIQUE (wholesaler_id, name)
+);
+
+CREATE TABLE retailer_group_members (
+    group_id INT NOT NULL REFERENCES retailer_groups(id) ON DELETE CASCADE,
+    retailer_id INT NOT NULL REFERENCES retailers(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, retailer_id)
+);
+
+CREATE INDEX idx_retailer_group_members_retailer_id ON retailer_group_members(retailer_id);
+
+-- A price list is a set of negotiated prices. discount_percent, when set, takes that much off
+-- every product of the wholesaler; an item overrides it for one product with a fixed price or
+-- its own percentage.
+CREATE TABLE price_lists (
+    id SERIAL PRIMARY KEY,
+    wholesaler_id INT NOT NULL REFERENCES wholesalers(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    discount_percent NUMERIC(5, 2) CHECK (discount_percent > 0 AND discount_percent < 100),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT price_lists_wholesaler_id_name_key UNIQUE (wholesaler_id, name)
+);
+
+CREATE TABLE price_list_items (
+    price_list_id INT NOT NULL REFERENCES price_lists(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES wholesaler_products(id) ON DELETE CASCADE,
+    price BIGINT CHECK (price > 0),
+    discount_percent NUMERIC(5, 2) CHECK (discount_percent > 0 AND discount_percent < 100),
+    PRIMARY KEY (price_list_id, product_id),
+    CHECK ((price IS NULL) <> (discount_percent IS NULL))
+);
+
+-- A list applies to the retailers it is assigned to and to every member of its groups
+CREATE TABLE price_list_retailers (
+    price_list_id INT NOT NULL REFERENCES price_lists(id) ON DELETE CASCADE,
+    retailer_id INT NOT NULL REFERENCES retailers(id) ON DELETE CASCADE,
+    PRIMARY KEY (price_list_id, retailer_id)
+);
+
+CREATE TABLE price_list_groups (
+    price_list_id INT NOT NULL REFERENCES price_lists(id) ON DELETE CASCADE,
+    group_id INT NOT NULL REFERENCES retailer_groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (price_list_id, group_id)
+);
+
+CREATE INDEX idx_price_list_retailers_retailer_id ON price_list_retailers(retailer_id);
+CREATE INDEX idx_price_list_groups_group_id ON price_list_groups(group_id);
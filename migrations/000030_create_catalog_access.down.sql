@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS catalog_access;
+
+ALTER TABLE wholesalers DROP COLUMN IF EXISTS private_catalog;
@@ -0,0 +1,17 @@
+-- A private catalog is only shown to retailers the wholesaler has approved
+ALTER TABLE wholesalers ADD COLUMN private_catalog BOOLEAN NOT NULL DEFAULT false;
+
+-- One row per wholesaler and retailer that have dealt with each other: the retailer asks for
+-- access (pending), and the wholesaler approves or rejects it; approved access can be revoked.
+CREATE TABLE catalog_access (
+    id SERIAL PRIMARY KEY,
+    wholesaler_id INT NOT NULL REFERENCES wholesalers(id) ON DELETE CASCADE,
+    retailer_id INT NOT NULL REFERENCES retailers(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'revoked')),
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (wholesaler_id, retailer_id)
+);
+
+CREATE INDEX idx_catalog_access_retailer_id ON catalog_access(retailer_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS quote_revisions;
+DROP TABLE IF EXISTS quote_items;
+DROP TABLE IF EXISTS quotes;
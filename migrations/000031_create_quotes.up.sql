@@ -0,0 +1,42 @@
+-- A quote is a negotiation over a wholesale order. The retailer requests products and
+-- quantities, the wholesaler offers prices with an expiry, and either side can counter until the
+-- retailer accepts an offer, which places the order, or one of them declines.
+CREATE TABLE quotes (
+    id SERIAL PRIMARY KEY,
+    wholesaler_id INT NOT NULL REFERENCES wholesalers(id) ON DELETE CASCADE,
+    retailer_id INT NOT NULL REFERENCES retailers(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'offered', 'countered', 'accepted', 'declined')),
+    currency TEXT NOT NULL,
+    expires_at TIMESTAMPTZ,
+    order_id INT REFERENCES wholesaler_orders(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_quotes_wholesaler_id ON quotes(wholesaler_id);
+CREATE INDEX idx_quotes_retailer_id ON quotes(retailer_id);
+
+-- The lines of the terms currently on the table. price is 0 on a request that names no price.
+CREATE TABLE quote_items (
+    id SERIAL PRIMARY KEY,
+    quote_id INT NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES wholesaler_products(id) ON DELETE CASCADE,
+    variant_id INT REFERENCES wholesaler_product_variants(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    price BIGINT NOT NULL DEFAULT 0 CHECK (price >= 0)
+);
+
+CREATE INDEX idx_quote_items_quote_id ON quote_items(quote_id);
+
+-- Every request, offer and counter-offer made on a quote, oldest first
+CREATE TABLE quote_revisions (
+    id SERIAL PRIMARY KEY,
+    quote_id INT NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+    author TEXT NOT NULL CHECK (author IN ('retailer', 'wholesaler')),
+    items JSONB NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_quote_revisions_quote_id ON quote_revisions(quote_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS invoice_payments;
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS credit_terms;
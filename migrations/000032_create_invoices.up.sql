@@ -0,0 +1,49 @@
+-- Payment terms a wholesaler grants a retailer: orders may be placed on account as long as the
+-- unpaid invoices stay within credit_limit, and each invoice is due terms_days after the order.
+CREATE TABLE credit_terms (
+    id SERIAL PRIMARY KEY,
+    wholesaler_id INT NOT NULL REFERENCES wholesalers(id) ON DELETE CASCADE,
+    retailer_id INT NOT NULL REFERENCES retailers(id) ON DELETE CASCADE,
+    credit_limit BIGINT NOT NULL CHECK (credit_limit >= 0),
+    currency TEXT NOT NULL,
+    terms_days INT NOT NULL CHECK (terms_days > 0),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (wholesaler_id, retailer_id)
+);
+
+CREATE INDEX idx_credit_terms_retailer_id ON credit_terms(retailer_id);
+
+-- One invoice per wholesale order placed on account. An open invoice past its due date is
+-- overdue; a cancelled order voids its invoice.
+CREATE TABLE invoices (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL UNIQUE REFERENCES wholesaler_orders(id),
+    wholesaler_id INT NOT NULL REFERENCES wholesalers(id) ON DELETE CASCADE,
+    retailer_id INT NOT NULL REFERENCES retailers(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+    amount_paid BIGINT NOT NULL DEFAULT 0 CHECK (amount_paid >= 0 AND amount_paid <= amount),
+    currency TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'overdue', 'paid', 'void')),
+    due_at TIMESTAMPTZ NOT NULL,
+    paid_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_invoices_wholesaler_id ON invoices(wholesaler_id);
+CREATE INDEX idx_invoices_retailer_id ON invoices(retailer_id);
+CREATE INDEX idx_invoices_due_at ON invoices(due_at) WHERE status = 'open';
+
+-- Payments received outside the platform, such as bank transfers, recorded by the wholesaler
+CREATE TABLE invoice_payments (
+    id SERIAL PRIMARY KEY,
+    invoice_id INT NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    method TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_invoice_payments_invoice_id ON invoice_payments(invoice_id);
@@ -0,0 +1,2 @@
+ALTER TABLE retailer_products DROP CONSTRAINT IF EXISTS retailer_products_retailer_id_source_product_id_key;
+ALTER TABLE retailer_products DROP COLUMN IF EXISTS source_product_id;
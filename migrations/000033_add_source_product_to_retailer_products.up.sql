@@ -0,0 +1,5 @@
+-- The wholesale product a retailer product is bought as. Delivered wholesale orders restock the
+-- retailer products linked to their lines, so a retailer links each source at most once.
+ALTER TABLE retailer_products ADD COLUMN source_product_id INT REFERENCES wholesaler_products(id) ON DELETE SET NULL;
+
+ALTER TABLE retailer_products ADD CONSTRAINT retailer_products_retailer_id_source_product_id_key UNIQUE (retailer_id, source_product_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS retailer_products_reorder_threshold_idx;
+
+ALTER TABLE retailer_products
+DROP COLUMN IF EXISTS low_stock_alerted_at,
+DROP COLUMN IF EXISTS reorder_threshold;
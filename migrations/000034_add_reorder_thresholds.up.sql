@@ -0,0 +1,8 @@
+-- A retailer is alerted when a product's available stock falls below its reorder threshold; 0
+-- turns alerts off. low_stock_alerted_at is set when the alert is sent and cleared once the
+-- product is back at or above the threshold, so each shortage is reported once.
+ALTER TABLE retailer_products
+ADD COLUMN reorder_threshold INT NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0),
+ADD COLUMN low_stock_alerted_at TIMESTAMPTZ;
+
+CREATE INDEX retailer_products_reorder_threshold_idx ON retailer_products(retailer_id) WHERE reorder_threshold > 0;
@@ -0,0 +1,37 @@
+-- Every change to the stock_qty of a product or variant, with why it happened. The stock of a
+-- product or variant is the sum of its movements, so existing stock is brought in below as an
+-- opening adjustment.
+CREATE TABLE inventory_movements (
+    id SERIAL PRIMARY KEY,
+
+    -- 'retailer_products' or 'wholesaler_products'
+    product_type TEXT NOT NULL,
+    product_id INT NOT NULL,
+    variant_id INT, -- NULL for stock held on the product itself
+
+    quantity INT NOT NULL CHECK (quantity <> 0),
+    stock_after INT NOT NULL,
+    reason TEXT NOT NULL CHECK (reason IN ('adjustment', 'sale', 'cancellation', 'refund_restock', 'wholesale_receipt', 'import')),
+    note TEXT NOT NULL DEFAULT '',
+    order_id INT, -- the order of a sale, cancellation, refund or receipt
+
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_inventory_movements_product ON inventory_movements(product_type, product_id, id);
+
+INSERT INTO inventory_movements (product_type, product_id, quantity, stock_after, reason, note)
+SELECT 'retailer_products', id, stock_qty, stock_qty, 'adjustment', 'Opening balance'
+FROM retailer_products WHERE COALESCE(stock_qty, 0) <> 0;
+
+INSERT INTO inventory_movements (product_type, product_id, variant_id, quantity, stock_after, reason, note)
+SELECT 'retailer_products', product_id, id, stock_qty, stock_qty, 'adjustment', 'Opening balance'
+FROM retailer_product_variants WHERE stock_qty <> 0;
+
+INSERT INTO inventory_movements (product_type, product_id, quantity, stock_after, reason, note)
+SELECT 'wholesaler_products', id, stock_qty, stock_qty, 'adjustment', 'Opening balance'
+FROM wholesaler_products WHERE COALESCE(stock_qty, 0) <> 0;
+
+INSERT INTO inventory_movements (product_type, product_id, variant_id, quantity, stock_after, reason, note)
+SELECT 'wholesaler_products', product_id, id, stock_qty, stock_qty, 'adjustment', 'Opening balance'
+FROM wholesaler_product_variants WHERE stock_qty <> 0;
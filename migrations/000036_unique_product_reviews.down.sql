@@ -0,0 +1,9 @@
+ALTER TABLE product_reviews DROP CONSTRAINT product_reviews_product_id_user_id_key;
+
+INSERT INTO product_reviews (id, product_id, user_id, rating, comment, created_at, updated_at)
+SELECT a.id, a.product_id, a.user_id, a.rating, a.comment, a.created_at, a.updated_at
+FROM product_reviews_duplicates_archive a
+WHERE EXISTS (SELECT 1 FROM retailer_products p WHERE p.id = a.product_id)
+  AND EXISTS (SELECT 1 FROM users u WHERE u.id = a.user_id);
+
+DROP TABLE product_reviews_duplicates_archive;
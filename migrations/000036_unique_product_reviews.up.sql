@@ -0,0 +1,17 @@
+-- Keep only each customer's latest review of a product before allowing one per customer.
+-- The older reviews are moved to an archive table rather than lost; the down migration puts them back.
+CREATE TABLE product_reviews_duplicates_archive (
+    LIKE product_reviews,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+WITH removed AS (
+    DELETE FROM product_reviews r
+    USING product_reviews newer
+    WHERE newer.product_id = r.product_id AND newer.user_id = r.user_id AND newer.id > r.id
+    RETURNING r.id, r.product_id, r.user_id, r.rating, r.comment, r.created_at, r.updated_at
+)
+INSERT INTO product_reviews_duplicates_archive (id, product_id, user_id, rating, comment, created_at, updated_at)
+SELECT DISTINCT id, product_id, user_id, rating, comment, created_at, updated_at FROM removed;
+
+ALTER TABLE product_reviews ADD CONSTRAINT product_reviews_product_id_user_id_key UNIQUE (product_id, user_id);
@@ -0,0 +1,27 @@
+-- Running totals of each retailer product's reviews, kept up to date with every review written,
+-- edited or deleted so listings can show and sort by them without reading the reviews
+CREATE TABLE product_ratings (
+    product_id INT PRIMARY KEY REFERENCES retailer_products(id) ON DELETE CASCADE,
+    review_count INT NOT NULL DEFAULT 0 CHECK (review_count >= 0),
+    rating_total INT NOT NULL DEFAULT 0 CHECK (rating_total >= 0),
+    rating_1 INT NOT NULL DEFAULT 0 CHECK (rating_1 >= 0),
+    rating_2 INT NOT NULL DEFAULT 0 CHECK (rating_2 >= 0),
+    rating_3 INT NOT NULL DEFAULT 0 CHECK (rating_3 >= 0),
+    rating_4 INT NOT NULL DEFAULT 0 CHECK (rating_4 >= 0),
+    rating_5 INT NOT NULL DEFAULT 0 CHECK (rating_5 >= 0),
+    average_rating NUMERIC(3,2) GENERATED ALWAYS AS (
+        CASE WHEN review_count = 0 THEN 0 ELSE ROUND(rating_total::numeric / review_count, 2) END
+    ) STORED
+);
+
+CREATE INDEX idx_product_ratings_average_rating ON product_ratings(average_rating);
+
+INSERT INTO product_ratings (product_id, review_count, rating_total, rating_1, rating_2, rating_3, rating_4, rating_5)
+SELECT product_id, COUNT(*), SUM(rating),
+    COUNT(*) FILTER (WHERE rating = 1),
+    COUNT(*) FILTER (WHERE rating = 2),
+    COUNT(*) FILTER (WHERE rating = 3),
+    COUNT(*) FILTER (WHERE rating = 4),
+    COUNT(*) FILTER (WHERE rating = 5)
+FROM product_reviews
+GROUP BY product_id;
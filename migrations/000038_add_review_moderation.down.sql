@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS review_reports;
+
+ALTER TABLE product_reviews
+    DROP COLUMN IF EXISTS hidden_at,
+    DROP COLUMN IF EXISTS seller_reply,
+    DROP COLUMN IF EXISTS seller_replied_at;
@@ -0,0 +1,19 @@
+ALTER TABLE product_reviews
+    ADD COLUMN hidden_at TIMESTAMPTZ,
+    ADD COLUMN seller_reply TEXT,
+    ADD COLUMN seller_replied_at TIMESTAMPTZ;
+
+-- A customer's complaint about a review. It stays open until the retailer whose product was
+-- reviewed hides the review or dismisses the complaint.
+CREATE TABLE review_reports (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL REFERENCES product_reviews(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    resolution TEXT CHECK (resolution IN ('hidden', 'dismissed')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ,
+    UNIQUE (review_id, user_id)
+);
+
+CREATE INDEX idx_review_reports_open ON review_reports(review_id) WHERE resolved_at IS NULL;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_product_reviews_product_helpful;
+
+ALTER TABLE product_reviews
+    DROP COLUMN IF EXISTS helpful_count,
+    DROP COLUMN IF EXISTS unhelpful_count;
+
+DROP TABLE IF EXISTS review_votes;
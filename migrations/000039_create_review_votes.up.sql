@@ -0,0 +1,15 @@
+-- A customer's verdict on whether someone else's review was helpful, one per customer and
+-- review. The counts on product_reviews are kept in step with it so feeds can sort by them.
+CREATE TABLE review_votes (
+    review_id INT NOT NULL REFERENCES product_reviews(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    helpful BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (review_id, user_id)
+);
+
+ALTER TABLE product_reviews
+    ADD COLUMN helpful_count INT NOT NULL DEFAULT 0 CHECK (helpful_count >= 0),
+    ADD COLUMN unhelpful_count INT NOT NULL DEFAULT 0 CHECK (unhelpful_count >= 0);
+
+CREATE INDEX idx_product_reviews_product_helpful ON product_reviews(product_id, helpful_count DESC, id DESC);
@@ -0,0 +1,10 @@
+-- Photos the author of a review attached to it. The files live in the uploads directory; url is
+-- the public path UploadService returned for them.
+CREATE TABLE review_images (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL REFERENCES product_reviews(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_review_images_review_id ON review_images(review_id);
@@ -0,0 +1,2 @@
+ALTER TABLE wholesaler_order_items DROP COLUMN IF EXISTS restocked_qty;
+ALTER TABLE retailer_order_items DROP COLUMN IF EXISTS restocked_qty;
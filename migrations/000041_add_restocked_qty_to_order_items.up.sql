@@ -0,0 +1,24 @@
+-- How much of each line has been put back into stock by refunds, so cancelling the order
+-- afterwards only restocks the rest
+ALTER TABLE retailer_order_items ADD COLUMN restocked_qty INT NOT NULL DEFAULT 0 CHECK (restocked_qty >= 0 AND restocked_qty <= quantity);
+ALTER TABLE wholesaler_order_items ADD COLUMN restocked_qty INT NOT NULL DEFAULT 0 CHECK (restocked_qty >= 0 AND restocked_qty <= quantity);
+
+UPDATE retailer_order_items i SET restocked_qty = r.quantity
+FROM (
+    SELECT ri.order_item_id, SUM(ri.quantity) AS quantity
+    FROM refund_items ri
+    JOIN refunds r ON r.id = ri.refund_id
+    WHERE r.order_type = 'retailer_orders' AND r.status = 'succeeded' AND r.restock
+    GROUP BY ri.order_item_id
+) r
+WHERE i.id = r.order_item_id;
+
+UPDATE wholesaler_order_items i SET restocked_qty = r.quantity
+FROM (
+    SELECT ri.order_item_id, SUM(ri.quantity) AS quantity
+    FROM refund_items ri
+    JOIN refunds r ON r.id = ri.refund_id
+    WHERE r.order_type = 'wholesaler_orders' AND r.status = 'succeeded' AND r.restock
+    GROUP BY ri.order_item_id
+) r
+WHERE i.id = r.order_item_id;
@@ -0,0 +1,4 @@
+DELETE FROM stripe_events WHERE status = 'processing';
+ALTER TABLE stripe_events ALTER COLUMN processed_at SET DEFAULT NOW();
+ALTER TABLE stripe_events DROP COLUMN IF EXISTS claimed_at;
+ALTER TABLE stripe_events DROP COLUMN IF EXISTS status;
@@ -0,0 +1,6 @@
+-- An event is claimed as processing when a delivery starts on it and marked processed once its
+-- changes are saved. Deliveries that arrive in between are answered with an error so Stripe
+-- retries them, instead of being acknowledged for work that may still fail.
+ALTER TABLE stripe_events ADD COLUMN status TEXT NOT NULL DEFAULT 'processed' CHECK (status IN ('processing', 'processed'));
+ALTER TABLE stripe_events ADD COLUMN claimed_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE stripe_events ALTER COLUMN processed_at DROP DEFAULT;